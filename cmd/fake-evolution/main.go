@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp/fakeevolution"
+)
+
+const defaultPort = "8081"
+
+func main() {
+	port := os.Getenv("EVOLUTION_PORT")
+	if port == "" {
+		port = defaultPort
+	}
+	addr := flag.String("addr", ":"+port, "endereço do servidor")
+	apiKey := flag.String("apikey", os.Getenv("AUTHENTICATION_API_KEY"), "apikey exigida nas requisições (vazia aceita qualquer uma)")
+	pairAfter := flag.Duration("pair-after", 5*time.Second, "tempo até a instância conectar sozinha depois do QR (0 exige POST /fake/pair/{instance})")
+	webhookURL := flag.String("webhook", os.Getenv("WEBHOOK_GLOBAL_URL"), "URL que recebe os recibos MESSAGES_UPDATE e as respostas MESSAGES_UPSERT (vazia desativa)")
+	missing := flag.String("missing", "", "números sem WhatsApp, separados por vírgula")
+	flag.Parse()
+
+	server := fakeevolution.New(fakeevolution.Options{
+		APIKey:         *apiKey,
+		PairAfter:      *pairAfter,
+		WebhookURL:     *webhookURL,
+		MissingNumbers: splitList(*missing),
+	})
+
+	fmt.Printf("Evolution falsa ouvindo em %s\n", *addr)
+	if *pairAfter > 0 {
+		fmt.Printf("Instâncias conectam %s depois do QR.\n", *pairAfter)
+	}
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
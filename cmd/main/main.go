@@ -1,23 +1,34 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	_ "github.com/ThalysSilva/unicast-backend/docs"
+	"github.com/ThalysSilva/unicast-backend/internal/account"
+	"github.com/ThalysSilva/unicast-backend/internal/attachment"
 	"github.com/ThalysSilva/unicast-backend/internal/auth"
 	"github.com/ThalysSilva/unicast-backend/internal/backdoor"
 	"github.com/ThalysSilva/unicast-backend/internal/campus"
 	"github.com/ThalysSilva/unicast-backend/internal/config"
 	configenv "github.com/ThalysSilva/unicast-backend/internal/config/env"
 	"github.com/ThalysSilva/unicast-backend/internal/discipline"
+	"github.com/ThalysSilva/unicast-backend/internal/inbox"
 	"github.com/ThalysSilva/unicast-backend/internal/invite"
 	"github.com/ThalysSilva/unicast-backend/internal/message"
 	"github.com/ThalysSilva/unicast-backend/internal/middleware"
 	"github.com/ThalysSilva/unicast-backend/internal/program"
 	"github.com/ThalysSilva/unicast-backend/internal/repository"
+	"github.com/ThalysSilva/unicast-backend/internal/schedule"
+	"github.com/ThalysSilva/unicast-backend/internal/sms"
 	"github.com/ThalysSilva/unicast-backend/internal/smtp"
 	"github.com/ThalysSilva/unicast-backend/internal/student"
 	"github.com/ThalysSilva/unicast-backend/internal/user"
@@ -63,9 +74,11 @@ func main() {
 	repos := repository.NewRepositories(db)
 
 	// Serviços
+	evolutionClient := whatsapp.NewEvolutionClient(envCfg.Evolution, nil)
 	authService := auth.NewService(repos.User, secrets)
-	whatsappService := whatsapp.NewService(repos.WhatsAppInstance, repos.User)
-	smtpService := smtp.NewService(repos.SmtpInstance, secrets.Jwe, envCfg.OAuth)
+	whatsappService := whatsapp.NewService(repos.WhatsAppInstance, repos.User, repos.Student, evolutionClient, envCfg.Defaults.CountryCode)
+	smtpService := smtp.NewService(repos.SmtpInstance, secrets.Jwe, envCfg.OAuth, nil)
+	smsService := sms.NewService(repos.SmsInstance, secrets.Jwe, sms.NewGateway(nil))
 	campusService := campus.NewService(repos.Campus)
 	disciplineService := discipline.NewService(repos.Discipline, repos.Program)
 	programService := program.NewService(repos.Program, repos.Campus)
@@ -73,25 +86,71 @@ func main() {
 	studentImportService := student.NewImportService(repos.Student, repos.Enrollment, repos.Discipline)
 	userService := user.NewService(repos.User)
 	inviteService := invite.NewService(repos.Invite, repos.Discipline, repos.Enrollment, repos.Student)
+	attachmentService := attachment.NewService(repos.Attachment, attachment.NewDiskStore(envCfg.Storage.AttachmentsDir))
 	messageLogRepo := message.NewLogRepository(db)
-	messageService := message.NewMessageService(repos.WhatsAppInstance, smtpService, repos.SmtpInstance, repos.User, repos.Student, messageLogRepo, secrets.Jwe)
-	backdoorService := backdoor.NewService(repos.User, envCfg.Admin.Secret)
+	messageOutboxRepo := message.NewOutboxRepository(db)
+	messageTemplateRepo := message.NewTemplateRepository(db)
+	messageLayoutRepo := message.NewLayoutRepository(db)
+	messageIdempotencyRepo := message.NewIdempotencyRepository(db)
+	studentFilterRepo := student.NewFilterRepository(db)
+	studentFilterService := student.NewFilterService(studentFilterRepo)
+	messageProgress := message.NewProgressBroker()
+	messageService := message.NewMessageService(message.ServiceDeps{
+		WhatsAppRepository:    repos.WhatsAppInstance,
+		Evolution:             evolutionClient,
+		SmtpService:           smtpService,
+		SmtpRepository:        repos.SmtpInstance,
+		SmsService:            smsService,
+		SmsRepository:         repos.SmsInstance,
+		UserRepository:        repos.User,
+		StudentRepository:     repos.Student,
+		FilterRepository:      studentFilterRepo,
+		LogRepository:         messageLogRepo,
+		OutboxRepository:      messageOutboxRepo,
+		TemplateRepository:    messageTemplateRepo,
+		LayoutRepository:      messageLayoutRepo,
+		IdempotencyRepository: messageIdempotencyRepo,
+		AttachmentService:     attachmentService,
+		DisciplineRepository:  repos.Discipline,
+		JweSecret:             secrets.Jwe,
+		Progress:              messageProgress,
+	}, envCfg.Defaults.CountryCode)
+	messageTemplateService := message.NewTemplateService(messageTemplateRepo)
+	messageLayoutService := message.NewLayoutService(messageLayoutRepo)
+	messageHistoryService := message.NewHistoryService(messageLogRepo)
+	inboxService := inbox.NewService(inbox.NewRepository(db), repos.WhatsAppInstance, envCfg.Defaults.CountryCode)
+	messageWebhookService := message.NewWebhookService(messageLogRepo, inboxService, envCfg.Evolution.WebhookSecret)
+	scheduleRepo := schedule.NewRepository(db)
+	scheduleService := schedule.NewService(scheduleRepo, messageService)
+	backdoorService := backdoor.NewService(repos.User, repos.SmtpInstance, scheduleRepo, messageOutboxRepo, envCfg.Admin.Secret)
+	accountService := account.NewService(repos.User, repos.SmtpInstance, scheduleRepo, messageOutboxRepo, secrets.Jwe)
 
 	// Handlers
 	authHandler := auth.NewHandler(authService)
+	accountHandler := account.NewHandler(accountService)
 	whatsappHandler := whatsapp.NewHandler(whatsappService)
 	smtpHandler := smtp.NewHandler(smtpService)
+	smsHandler := sms.NewHandler(smsService)
 	campusHandler := campus.NewHandler(campusService)
 	disciplineHandler := discipline.NewHandler(disciplineService)
 	programHandler := program.NewHandler(programService)
 	studentHandler := student.NewHandler(studentService, studentImportService)
+	studentFilterHandler := student.NewFilterHandler(studentFilterService)
 	userHandler := user.NewHandler(userService)
 	inviteHandler := invite.NewHandler(inviteService)
+	attachmentHandler := attachment.NewHandler(attachmentService)
 	messageHandler := message.NewHandler(messageService)
+	messageTemplateHandler := message.NewTemplateHandler(messageTemplateService)
+	messageLayoutHandler := message.NewLayoutHandler(messageLayoutService)
+	messageHistoryHandler := message.NewHistoryHandler(messageHistoryService)
+	messageWebhookHandler := message.NewWebhookHandler(messageWebhookService)
+	inboxHandler := inbox.NewHandler(inboxService)
+	scheduleHandler := schedule.NewHandler(scheduleService)
 	backdoorHandler := backdoor.NewHandler(backdoorService)
 
-	r := gin.Default()
+	r := gin.New()
 
+	r.Use(middleware.RequestLogger(gin.DefaultWriter), gin.Recovery())
 	r.Use(middleware.SecurityHeaders())
 	r.Use(middleware.CORS())
 	r.Use(middleware.ValidationErrorHandler())
@@ -110,6 +169,7 @@ func main() {
 		// Com autenticação
 		authGroup.Use(middleware.UseAuthentication(secrets.AccessToken))
 		authGroup.POST("/logout", authHandler.Logout())
+		authGroup.POST("/change-password", authRateLimit, accountHandler.ChangePassword())
 	}
 	// Rotas de campus
 	campusGroup := r.Group("/campus")
@@ -154,8 +214,10 @@ func main() {
 		whatsappGroup.DELETE("/instance/:id", whatsappHandler.DeleteInstance())
 		whatsappGroup.POST("/instance/:id/connect", sensitiveRateLimit, whatsappHandler.ConnectInstance())
 		whatsappGroup.GET("/instance/:id/status", whatsappHandler.ConnectionState())
+		whatsappGroup.GET("/instance/:id/qrcode/stream", whatsappHandler.QRCodeStream())
 		whatsappGroup.DELETE("/instance/:id/logout", whatsappHandler.LogoutInstance())
 		whatsappGroup.POST("/instance/:id/restart", sensitiveRateLimit, whatsappHandler.RestartInstance())
+		whatsappGroup.POST("/instance/:id/check-numbers", sensitiveRateLimit, whatsappHandler.CheckNumbers())
 	}
 
 	// Rotas do smtp
@@ -167,8 +229,19 @@ func main() {
 		smtpGroup.POST("/instance", sensitiveRateLimit, smtpHandler.Create(secrets.Jwe))
 		smtpGroup.GET("/instance", smtpHandler.GetInstances())
 		smtpGroup.DELETE("/instance/:id", smtpHandler.DeleteInstance())
+		smtpGroup.PUT("/instance/:id/password", sensitiveRateLimit, smtpHandler.UpdatePassword())
 	}
 	r.GET("/smtp/oauth/google/callback", smtpHandler.OAuthCallback(smtp.ProviderGoogle))
+	r.GET("/smtp/oauth/microsoft/callback", smtpHandler.OAuthCallback(smtp.ProviderMicrosoft))
+
+	// Rotas do SMS
+	smsGroup := r.Group("/sms")
+	{
+		smsGroup.Use(middleware.UseAuthentication(secrets.AccessToken))
+		smsGroup.POST("/instance", sensitiveRateLimit, smsHandler.Create())
+		smsGroup.GET("/instance", smsHandler.GetInstances())
+		smsGroup.DELETE("/instance/:id", smsHandler.DeleteInstance())
+	}
 
 	// Rotas do usuario
 	userGroup := r.Group("/user")
@@ -182,6 +255,11 @@ func main() {
 	{
 		studentGroup.Use(middleware.UseAuthentication(secrets.AccessToken))
 		studentGroup.POST("/create", studentHandler.Create())
+		studentGroup.POST("/filter", studentFilterHandler.Create())
+		studentGroup.GET("/filter", studentFilterHandler.List())
+		studentGroup.GET("/filter/:id", studentFilterHandler.Get())
+		studentGroup.PUT("/filter/:id", studentFilterHandler.Update())
+		studentGroup.DELETE("/filter/:id", studentFilterHandler.Delete())
 		studentGroup.GET("/:id", studentHandler.GetStudent())
 		studentGroup.GET("/:id/delivery-summary", studentHandler.GetDeliverySummary())
 		studentGroup.GET("", studentHandler.GetStudents())
@@ -194,8 +272,50 @@ func main() {
 	{
 		messageGroup.Use(middleware.UseAuthentication(secrets.AccessToken))
 		messageGroup.POST("/send", messageRateLimit, messageHandler.Send())
+		messageGroup.POST("/preview", messageHandler.Preview())
+		messageGroup.GET("/job/:id", messageHandler.GetJob())
+		messageGroup.GET("/delivery/:deliveryGroupId/events", messageHandler.Events())
+		messageGroup.GET("/history", messageHistoryHandler.List())
+		messageGroup.GET("/history/:deliveryGroupId", messageHistoryHandler.Get())
+		messageGroup.POST("/history/:deliveryGroupId/retry", messageRateLimit, messageHandler.Retry())
+		messageGroup.POST("/template", messageTemplateHandler.Create())
+		messageGroup.GET("/template", messageTemplateHandler.List())
+		messageGroup.GET("/template/:id", messageTemplateHandler.Get())
+		messageGroup.PUT("/template/:id", messageTemplateHandler.Update())
+		messageGroup.DELETE("/template/:id", messageTemplateHandler.Delete())
+		messageGroup.GET("/layout", messageLayoutHandler.Get())
+		messageGroup.PUT("/layout", messageLayoutHandler.Save())
+		messageGroup.DELETE("/layout", messageLayoutHandler.Delete())
+		messageGroup.POST("/scheduled", messageRateLimit, scheduleHandler.Create())
+		messageGroup.GET("/scheduled", scheduleHandler.List())
+		messageGroup.GET("/scheduled/:id", scheduleHandler.Get())
+		messageGroup.PUT("/scheduled/:id", messageRateLimit, scheduleHandler.Update())
+		messageGroup.POST("/scheduled/:id/pause", scheduleHandler.Pause())
+		messageGroup.POST("/scheduled/:id/resume", scheduleHandler.Resume())
+		messageGroup.DELETE("/scheduled/:id", scheduleHandler.Cancel())
+	}
+
+	// Rotas de anexos
+	attachmentGroup := r.Group("/attachment")
+	{
+		attachmentGroup.Use(middleware.UseAuthentication(secrets.AccessToken))
+		attachmentGroup.POST("", sensitiveRateLimit, attachmentHandler.Upload())
+		attachmentGroup.GET("", attachmentHandler.List())
+		attachmentGroup.GET("/:id", attachmentHandler.Get())
+		attachmentGroup.DELETE("/:id", attachmentHandler.Delete())
+	}
+
+	// Respostas dos alunos pelo WhatsApp
+	inboxGroup := r.Group("/inbox")
+	{
+		inboxGroup.Use(middleware.UseAuthentication(secrets.AccessToken))
+		inboxGroup.GET("", inboxHandler.List())
+		inboxGroup.POST("/read", inboxHandler.MarkRead())
 	}
 
+	// Webhooks da Evolution (proteção via secret)
+	r.POST("/webhook/evolution", messageWebhookHandler.Evolution())
+
 	// Backdoor administrativo (proteção via secret)
 	r.POST("/backdoor/reset-password", backdoorRateLimit, backdoorHandler.ResetPassword())
 
@@ -213,8 +333,42 @@ func main() {
 	// Swagger
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Worker do outbox, scheduler de mensagens e monitor de conexão do WhatsApp
+	messageWorker := message.NewWorker(messageService, messageOutboxRepo, messageLogRepo, messageProgress, message.WorkerOptions{})
+	messageScheduler := schedule.NewScheduler(scheduleRepo, messageService, 30*time.Second)
+	whatsappMonitor := whatsapp.NewMonitor(repos.WhatsAppInstance, evolutionClient, whatsapp.NewEmailNotifier(repos.User, repos.SmtpInstance, smtpService), time.Minute)
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		messageWorker.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		messageScheduler.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		whatsappMonitor.Run(ctx)
+	}()
+
 	// Inicia o servidor
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal(err)
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Encerrando servidor...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Erro ao encerrar servidor: %v", err)
 	}
+	workers.Wait()
 }
@@ -1702,7 +1702,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ThalysSilva_unicast-backend_pkg_api.DefaultResponse-internal_whatsapp_connectResponse"
+                            "$ref": "#/definitions/github_com_ThalysSilva_unicast-backend_pkg_api.DefaultResponse-internal_whatsapp_ConnectResponse"
                         }
                     },
                     "400": {
@@ -2044,11 +2044,11 @@ const docTemplate = `{
                 }
             }
         },
-        "github_com_ThalysSilva_unicast-backend_pkg_api.DefaultResponse-internal_whatsapp_connectResponse": {
+        "github_com_ThalysSilva_unicast-backend_pkg_api.DefaultResponse-internal_whatsapp_ConnectResponse": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/internal_whatsapp.connectResponse"
+                    "$ref": "#/definitions/internal_whatsapp.ConnectResponse"
                 },
                 "message": {
                     "type": "string"
@@ -2699,7 +2699,7 @@ const docTemplate = `{
                 }
             }
         },
-        "internal_whatsapp.connectResponse": {
+        "internal_whatsapp.ConnectResponse": {
             "type": "object",
             "properties": {
                 "base64": {
@@ -0,0 +1,52 @@
+package account
+
+import (
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/gin-gonic/gin"
+)
+
+type changePasswordInput struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+type handler struct {
+	service Service
+}
+
+type Handler interface {
+	ChangePassword() gin.HandlerFunc
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Troca a senha do usuário
+// @Description Re-cifra as senhas SMTP salvas com a nova senha e encerra as sessões; faça login novamente para obter um novo JWE.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param body body changePasswordInput true "Senha atual e nova senha"
+// @Success 200 {object} api.MessageResponse
+// @Failure 403 {object} api.ErrorResponse
+// @Router /auth/change-password [post]
+func (h *handler) ChangePassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input changePasswordInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		if err := h.service.ChangePassword(c.Request.Context(), c.GetString("userID"), input.CurrentPassword, input.NewPassword); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.MessageResponse{Message: "Senha alterada com sucesso. Faça login novamente."})
+	}
+}
@@ -0,0 +1,109 @@
+package account
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+
+	"github.com/ThalysSilva/unicast-backend/internal/auth"
+	"github.com/ThalysSilva/unicast-backend/internal/encryption"
+	"github.com/ThalysSilva/unicast-backend/internal/message"
+	"github.com/ThalysSilva/unicast-backend/internal/schedule"
+	"github.com/ThalysSilva/unicast-backend/internal/smtp"
+	"github.com/ThalysSilva/unicast-backend/internal/user"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Service interface {
+	ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error
+}
+
+type service struct {
+	userRepository     user.Repository
+	smtpRepository     smtp.Repository
+	scheduleRepository schedule.Repository
+	outboxRepository   message.OutboxRepository
+	jweSecret          []byte
+}
+
+var (
+	ErrUserNotFound    = customerror.Make("usuário não encontrado", 404, errors.New("ErrUserNotFound"))
+	ErrInvalidPassword = customerror.Make("senha atual incorreta", 403, errors.New("ErrInvalidPassword"))
+	ErrSamePassword    = customerror.Make("a nova senha deve ser diferente da atual", 400, errors.New("ErrSamePassword"))
+)
+
+func NewService(userRepository user.Repository, smtpRepository smtp.Repository, scheduleRepository schedule.Repository, outboxRepository message.OutboxRepository, jweSecret []byte) Service {
+	return &service{
+		userRepository:     userRepository,
+		smtpRepository:     smtpRepository,
+		scheduleRepository: scheduleRepository,
+		outboxRepository:   outboxRepository,
+		jweSecret:          jweSecret,
+	}
+}
+
+// ChangePassword troca a senha da conta e re-cifra as senhas SMTP com a chave derivada da nova senha,
+// na mesma transação. Os JWEs guardados em agendamentos e jobs pendentes são reemitidos com a nova chave.
+// As sessões são encerradas, porque o JWE em uso carrega a chave antiga.
+func (s *service) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	u, err := s.userRepository.FindByID(ctx, userID)
+	if err != nil {
+		return customerror.Trace("ChangePassword", err)
+	}
+	if u == nil {
+		return customerror.Trace("ChangePassword", ErrUserNotFound)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(currentPassword)); err != nil {
+		return customerror.Trace("ChangePassword", ErrInvalidPassword)
+	}
+	if currentPassword == newPassword {
+		return customerror.Trace("ChangePassword", ErrSamePassword)
+	}
+
+	oldKey, err := encryption.GenerateSmtpKey(currentPassword, u.Salt)
+	if err != nil {
+		return customerror.Trace("ChangePassword", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return customerror.Trace("ChangePassword", err)
+	}
+	salt, err := auth.GenerateSalt(16)
+	if err != nil {
+		return customerror.Trace("ChangePassword", err)
+	}
+	newKey, err := encryption.GenerateSmtpKey(newPassword, salt)
+	if err != nil {
+		return customerror.Trace("ChangePassword", err)
+	}
+	jwe, err := auth.GenerateJWE(auth.JwePayload{SmtpKeyEncoded: base64.StdEncoding.EncodeToString(newKey)}, s.jweSecret)
+	if err != nil {
+		return customerror.Trace("ChangePassword", err)
+	}
+
+	u.Password = string(hash)
+	u.Salt = salt
+	u.RefreshToken = nil
+
+	repos := []database.Transactional{s.userRepository, s.smtpRepository, s.scheduleRepository, s.outboxRepository}
+	_, err = database.MakeTransaction(ctx, repos, func(txRepos []database.Transactional) (any, error) {
+		userRepo := txRepos[0].(user.Repository)
+		smtpRepo := txRepos[1].(smtp.Repository)
+		if err := smtp.ReencryptPasswords(ctx, smtpRepo, u.ID, oldKey, newKey); err != nil {
+			return nil, err
+		}
+		if err := txRepos[2].(schedule.Repository).ReplaceJwe(ctx, u.ID, &jwe); err != nil {
+			return nil, err
+		}
+		if err := txRepos[3].(message.OutboxRepository).ReplaceJwe(ctx, u.ID, &jwe); err != nil {
+			return nil, err
+		}
+		return nil, userRepo.Update(ctx, u)
+	})
+	if err != nil {
+		return customerror.Trace("ChangePassword", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/auth"
+	"github.com/ThalysSilva/unicast-backend/internal/encryption"
+	"github.com/ThalysSilva/unicast-backend/internal/message"
+	"github.com/ThalysSilva/unicast-backend/internal/schedule"
+	"github.com/ThalysSilva/unicast-backend/internal/smtp"
+	"github.com/ThalysSilva/unicast-backend/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// noopDriver permite usar database.MakeTransaction com repositórios falsos.
+type noopDriver struct{}
+
+type noopConn struct{}
+
+func (noopDriver) Open(string) (driver.Conn, error) { return noopConn{}, nil }
+
+func (noopConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (noopConn) Close() error                        { return nil }
+func (noopConn) Begin() (driver.Tx, error)           { return noopConn{}, nil }
+func (noopConn) Commit() error                       { return nil }
+func (noopConn) Rollback() error                     { return nil }
+
+var testDB = func() *sql.DB {
+	sql.Register("account-noop", noopDriver{})
+	db, _ := sql.Open("account-noop", "")
+	return db
+}()
+
+type fakeUserRepository struct {
+	user.Repository
+	user *user.User
+}
+
+func (r *fakeUserRepository) WithTransaction(any) any { return r }
+func (r *fakeUserRepository) TransactionBackend() any { return testDB }
+
+func (r *fakeUserRepository) FindByID(context.Context, string) (*user.User, error) {
+	return r.user, nil
+}
+
+func (r *fakeUserRepository) Update(_ context.Context, u *user.User) error {
+	r.user = u
+	return nil
+}
+
+type fakeSmtpRepository struct {
+	smtp.Repository
+	instance *smtp.Instance
+}
+
+func (r *fakeSmtpRepository) WithTransaction(any) any { return r }
+func (r *fakeSmtpRepository) TransactionBackend() any { return testDB }
+
+func (r *fakeSmtpRepository) GetInstances(context.Context, string) ([]*smtp.Instance, error) {
+	return []*smtp.Instance{r.instance}, nil
+}
+
+func (r *fakeSmtpRepository) UpdatePassword(_ context.Context, _ string, password, iv []byte) error {
+	r.instance.Password = password
+	r.instance.IV = iv
+	return nil
+}
+
+type fakeScheduleRepository struct {
+	schedule.Repository
+	schedule *schedule.Schedule
+}
+
+func (r *fakeScheduleRepository) WithTransaction(any) any { return r }
+func (r *fakeScheduleRepository) TransactionBackend() any { return testDB }
+
+func (r *fakeScheduleRepository) ReplaceJwe(_ context.Context, _ string, jwe *string) error {
+	r.schedule.Jwe = jwe
+	r.schedule.JweReentryRequired = jwe == nil
+	return nil
+}
+
+func (r *fakeScheduleRepository) LockDue(context.Context, time.Time, int) ([]*schedule.Schedule, error) {
+	return []*schedule.Schedule{r.schedule}, nil
+}
+
+func (r *fakeScheduleRepository) Advance(context.Context, string, time.Time, *time.Time, schedule.Status) error {
+	return nil
+}
+
+func (r *fakeScheduleRepository) RecordRun(_ context.Context, _ string, jobID *string, errText *string) error {
+	r.schedule.LastJobID = jobID
+	r.schedule.LastError = errText
+	return nil
+}
+
+type fakeOutboxRepository struct {
+	message.OutboxRepository
+}
+
+func (r *fakeOutboxRepository) WithTransaction(any) any { return r }
+func (r *fakeOutboxRepository) TransactionBackend() any { return testDB }
+
+func (r *fakeOutboxRepository) ReplaceJwe(context.Context, string, *string) error { return nil }
+
+// fakeMessageService abre a senha SMTP com o JWE da mensagem, como faz o envio por e-mail.
+type fakeMessageService struct {
+	message.Service
+	jweSecret []byte
+	smtp      *fakeSmtpRepository
+	password  string
+}
+
+func (s *fakeMessageService) Send(_ context.Context, msg *message.Message) (*message.Job, error) {
+	payload, err := auth.DecryptJWE[auth.JwePayload](msg.Jwe, s.jweSecret)
+	if err != nil {
+		return nil, err
+	}
+	smtpKey, err := base64.StdEncoding.DecodeString(payload.SmtpKeyEncoded)
+	if err != nil {
+		return nil, err
+	}
+	s.password, err = encryption.DecryptSmtpPassword(s.smtp.instance.Password, smtpKey, s.smtp.instance.IV)
+	if err != nil {
+		return nil, message.ErrSmtpCredentials
+	}
+	return &message.Job{ID: "job-1"}, nil
+}
+
+func TestScheduleStillSendsAfterPasswordChange(t *testing.T) {
+	ctx := context.Background()
+	jweSecret := []byte("0123456789abcdef0123456789abcdef")
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("senha-antiga"), bcrypt.MinCost)
+	require.NoError(t, err)
+	salt := []byte("salt-de-teste-16")
+	oldKey, err := encryption.GenerateSmtpKey("senha-antiga", salt)
+	require.NoError(t, err)
+	encryptedPassword, iv, err := encryption.EncryptSmtpPassword("senha-smtp", oldKey)
+	require.NoError(t, err)
+	oldJwe, err := auth.GenerateJWE(auth.JwePayload{SmtpKeyEncoded: base64.StdEncoding.EncodeToString(oldKey)}, jweSecret)
+	require.NoError(t, err)
+
+	userRepo := &fakeUserRepository{user: &user.User{ID: "user-1", Password: string(hash), Salt: salt}}
+	smtpRepo := &fakeSmtpRepository{instance: &smtp.Instance{
+		ID: "smtp-1", UserID: "user-1", AuthMode: smtp.AuthModePassword, Password: encryptedPassword, IV: iv,
+	}}
+	smtpID := "smtp-1"
+	scheduleRepo := &fakeScheduleRepository{schedule: &schedule.Schedule{
+		ID: "schedule-1", UserID: "user-1", SmtpID: &smtpID, Jwe: &oldJwe, Status: schedule.StatusActive, SendAt: time.Now().Add(-time.Minute),
+	}}
+
+	svc := NewService(userRepo, smtpRepo, scheduleRepo, &fakeOutboxRepository{}, jweSecret)
+	require.NoError(t, svc.ChangePassword(ctx, "user-1", "senha-antiga", "senha-nova"))
+	assert.NotEqual(t, oldJwe, *scheduleRepo.schedule.Jwe)
+
+	messageService := &fakeMessageService{jweSecret: jweSecret, smtp: smtpRepo}
+	processed, err := schedule.NewScheduler(scheduleRepo, messageService, time.Minute).RunDue(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, processed)
+	assert.Nil(t, scheduleRepo.schedule.LastError)
+	assert.Equal(t, "senha-smtp", messageService.password)
+}
@@ -0,0 +1,84 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var storeKeyPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+var errInvalidStoreKey = errors.New("chave de anexo inválida")
+
+type diskStore struct {
+	dir string
+}
+
+// NewDiskStore guarda os anexos em dir/<2 primeiros caracteres do hash>/<hash>.
+func NewDiskStore(dir string) Store {
+	return &diskStore{dir: dir}
+}
+
+func (s *diskStore) path(key string) (string, error) {
+	if !storeKeyPattern.MatchString(key) {
+		return "", errInvalidStoreKey
+	}
+	return filepath.Join(s.dir, key[:2], key), nil
+}
+
+func (s *diskStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("falha ao criar diretório de anexos: %w", err)
+	}
+
+	// Grava em um temporário e renomeia, para que leituras concorrentes nunca vejam um arquivo pela metade.
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("falha ao gravar anexo: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("falha ao gravar anexo: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("falha ao gravar anexo: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("falha ao gravar anexo: %w", err)
+	}
+	return nil
+}
+
+func (s *diskStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler anexo: %w", err)
+	}
+	return data, nil
+}
+
+func (s *diskStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("falha ao remover anexo: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package attachment
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+// Attachment é um arquivo enviado pelo professor e reutilizado nos envios pelo ID.
+// O conteúdo fica no Store sob o SHA-256, então arquivos iguais ocupam espaço uma única vez.
+type Attachment struct {
+	ID          string    `json:"id"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	Size        int       `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UserID      string    `json:"-"`
+}
+
+type Repository interface {
+	database.Transactional
+	// Create grava o anexo; se o usuário já enviou o mesmo arquivo com o mesmo nome, devolve o registro existente.
+	Create(ctx context.Context, attachment *Attachment) error
+	FindByID(ctx context.Context, id string) (*Attachment, error)
+	FindByUserID(ctx context.Context, userID string) ([]*Attachment, error)
+	Delete(ctx context.Context, id string) error
+	// CountBySHA256 conta os registros que apontam para o mesmo conteúdo, de qualquer usuário.
+	CountBySHA256(ctx context.Context, sha256 string) (int, error)
+	// CountReferences conta os jobs do outbox e os agendamentos ativos ou pausados que usam o anexo.
+	// message_job_attachments não tem chave estrangeira para attachments, então a checagem é feita aqui.
+	CountReferences(ctx context.Context, id string) (int, error)
+}
+
+// Store guarda o conteúdo dos anexos, endereçado pelo SHA-256 em hexadecimal.
+type Store interface {
+	// Put grava o conteúdo; gravar de novo uma chave existente não faz nada.
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return newSQLRepository(db)
+}
@@ -0,0 +1,131 @@
+package attachment
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/gin-gonic/gin"
+)
+
+// maxUploadBodyBytes dá folga ao cabeçalho multipart sobre o limite do arquivo.
+const maxUploadBodyBytes = MaxBytes + 1<<20
+
+type handler struct {
+	service Service
+}
+
+type Handler interface {
+	Upload() gin.HandlerFunc
+	List() gin.HandlerFunc
+	Get() gin.HandlerFunc
+	Delete() gin.HandlerFunc
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Envia um anexo
+// @Description Guarda o arquivo para ser usado nos envios por ID (attachments: [{"id": "..."}]). Nome, tamanho (até 10 MB) e MIME são validados no upload.
+// @Description Reenviar o mesmo arquivo com o mesmo nome devolve o anexo já existente.
+// @OperationId uploadAttachment
+// @Tags attachment
+// @Accept mpfd
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param file formData file true "Arquivo do anexo"
+// @Success 201 {object} api.DefaultResponse[Attachment]
+// @Failure 400 {object} api.ErrorResponse
+// @Router /attachment [post]
+func (h *handler) Upload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadBodyBytes)
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			customerror.HandleResponse(c, ErrInvalidUpload)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, MaxBytes+1))
+		if err != nil {
+			customerror.HandleResponse(c, ErrInvalidUpload)
+			return
+		}
+		attachment, err := h.service.Upload(c.Request.Context(), c.GetString("userID"), header.Filename, data)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, api.DefaultResponse[Attachment]{Message: "Anexo enviado com sucesso", Data: *attachment})
+	}
+}
+
+// @Summary Lista os anexos do usuário
+// @OperationId listAttachments
+// @Tags attachment
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Success 200 {object} api.DefaultResponse[[]Attachment]
+// @Router /attachment [get]
+func (h *handler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		attachments, err := h.service.List(c.Request.Context(), c.GetString("userID"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		items := make([]Attachment, 0, len(attachments))
+		for _, attachment := range attachments {
+			items = append(items, *attachment)
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[[]Attachment]{Message: "Anexos listados com sucesso", Data: items})
+	}
+}
+
+// @Summary Busca um anexo
+// @OperationId getAttachment
+// @Tags attachment
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param id path string true "ID do anexo"
+// @Success 200 {object} api.DefaultResponse[Attachment]
+// @Failure 404 {object} api.ErrorResponse
+// @Router /attachment/{id} [get]
+func (h *handler) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		attachment, err := h.service.Get(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[Attachment]{Message: "Anexo encontrado", Data: *attachment})
+	}
+}
+
+// @Summary Remove um anexo
+// @Description Anexos usados por envios (incluindo os que ainda podem ser reenviados) ou por agendamentos ativos ou pausados não podem ser removidos.
+// @OperationId deleteAttachment
+// @Tags attachment
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param id path string true "ID do anexo"
+// @Success 200 {object} api.MessageResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /attachment/{id} [delete]
+func (h *handler) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.service.Delete(c.Request.Context(), c.GetString("userID"), c.Param("id")); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.MessageResponse{Message: "Anexo removido com sucesso"})
+	}
+}
@@ -0,0 +1,136 @@
+package attachment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var (
+	ErrAttachmentNotFound = customerror.Make("anexo não encontrado", http.StatusNotFound, errors.New("ErrAttachmentNotFound"))
+	ErrInvalidUpload      = customerror.Make("envie o arquivo no campo file (multipart/form-data)", http.StatusBadRequest, errors.New("ErrInvalidUpload"))
+	ErrAttachmentInUse    = customerror.Make("o anexo é usado por envios ou agendamentos e não pode ser removido", http.StatusConflict, errors.New("ErrAttachmentInUse"))
+)
+
+type Service interface {
+	// Upload valida nome, tamanho e MIME do arquivo e o guarda; reenviar o mesmo arquivo devolve o anexo existente.
+	Upload(ctx context.Context, userID, fileName string, data []byte) (*Attachment, error)
+	List(ctx context.Context, userID string) ([]*Attachment, error)
+	Get(ctx context.Context, userID, id string) (*Attachment, error)
+	// Load devolve o anexo do usuário junto com o conteúdo.
+	Load(ctx context.Context, userID, id string) (*Attachment, []byte, error)
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type attachmentService struct {
+	repository Repository
+	store      Store
+}
+
+func NewService(repository Repository, store Store) Service {
+	return &attachmentService{repository: repository, store: store}
+}
+
+func (s *attachmentService) Upload(ctx context.Context, userID, fileName string, data []byte) (*Attachment, error) {
+	fileName = filepath.Base(strings.TrimSpace(fileName))
+	if err := ValidateFileName(fileName); err != nil {
+		return nil, customerror.Trace("UploadAttachment", err)
+	}
+	if err := ValidateData(fileName, data, MaxBytes); err != nil {
+		return nil, customerror.Trace("UploadAttachment", err)
+	}
+
+	sum := sha256.Sum256(data)
+	attachment := &Attachment{
+		UserID:      userID,
+		FileName:    fileName,
+		ContentType: contentTypeOf(fileName, data),
+		Size:        len(data),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	// O registro vem antes do conteúdo: assim um Delete concorrente do mesmo hash enxerga a nova referência.
+	if err := s.repository.Create(ctx, attachment); err != nil {
+		return nil, customerror.Trace("UploadAttachment", err)
+	}
+	if err := s.store.Put(ctx, attachment.SHA256, data); err != nil {
+		_ = s.repository.Delete(ctx, attachment.ID)
+		return nil, customerror.Trace("UploadAttachment", err)
+	}
+	return attachment, nil
+}
+
+func (s *attachmentService) List(ctx context.Context, userID string) ([]*Attachment, error) {
+	attachments, err := s.repository.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, customerror.Trace("ListAttachments", err)
+	}
+	return attachments, nil
+}
+
+func (s *attachmentService) Get(ctx context.Context, userID, id string) (*Attachment, error) {
+	attachment, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, customerror.Trace("GetAttachment", err)
+	}
+	if attachment == nil || attachment.UserID != userID {
+		return nil, customerror.Trace("GetAttachment", ErrAttachmentNotFound)
+	}
+	return attachment, nil
+}
+
+func (s *attachmentService) Load(ctx context.Context, userID, id string) (*Attachment, []byte, error) {
+	attachment, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := s.store.Get(ctx, attachment.SHA256)
+	if err != nil {
+		return nil, nil, customerror.Trace("LoadAttachment", err)
+	}
+	return attachment, data, nil
+}
+
+// Delete remove o registro do usuário; o conteúdo só é apagado quando nenhum outro registro aponta para ele.
+// Anexos usados por jobs do outbox ou por agendamentos não são removidos: a entrega e os reenvios
+// carregam o arquivo pelo ID.
+func (s *attachmentService) Delete(ctx context.Context, userID, id string) error {
+	attachment, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	references, err := s.repository.CountReferences(ctx, id)
+	if err != nil {
+		return customerror.Trace("DeleteAttachment", err)
+	}
+	if references > 0 {
+		return customerror.Trace("DeleteAttachment", ErrAttachmentInUse)
+	}
+	if err := s.repository.Delete(ctx, id); err != nil {
+		return customerror.Trace("DeleteAttachment", err)
+	}
+	remaining, err := s.repository.CountBySHA256(ctx, attachment.SHA256)
+	if err != nil {
+		return customerror.Trace("DeleteAttachment", err)
+	}
+	if remaining == 0 {
+		if err := s.store.Delete(ctx, attachment.SHA256); err != nil {
+			return customerror.Trace("DeleteAttachment", err)
+		}
+	}
+	return nil
+}
+
+// contentTypeOf prefere o MIME da extensão, já conferido contra o conteúdo por ValidateData.
+func contentTypeOf(fileName string, data []byte) string {
+	if byExtension := mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName))); byExtension != "" {
+		return byExtension
+	}
+	return DetectContentType(data)
+}
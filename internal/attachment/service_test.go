@@ -0,0 +1,150 @@
+package attachment
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepository struct {
+	Repository
+	attachments map[string]*Attachment
+	// references simula jobs e agendamentos que usam o anexo.
+	references map[string]int
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{attachments: map[string]*Attachment{}, references: map[string]int{}}
+}
+
+func (f *fakeRepository) Create(ctx context.Context, attachment *Attachment) error {
+	for _, existing := range f.attachments {
+		if existing.UserID == attachment.UserID && existing.SHA256 == attachment.SHA256 && existing.FileName == attachment.FileName {
+			attachment.ID = existing.ID
+			return nil
+		}
+	}
+	attachment.ID = fmt.Sprintf("att-%d", len(f.attachments)+1)
+	stored := *attachment
+	f.attachments[attachment.ID] = &stored
+	return nil
+}
+
+func (f *fakeRepository) FindByID(ctx context.Context, id string) (*Attachment, error) {
+	return f.attachments[id], nil
+}
+
+func (f *fakeRepository) Delete(ctx context.Context, id string) error {
+	delete(f.attachments, id)
+	return nil
+}
+
+func (f *fakeRepository) CountReferences(ctx context.Context, id string) (int, error) {
+	return f.references[id], nil
+}
+
+func (f *fakeRepository) CountBySHA256(ctx context.Context, sha256 string) (int, error) {
+	count := 0
+	for _, attachment := range f.attachments {
+		if attachment.SHA256 == sha256 {
+			count++
+		}
+	}
+	return count, nil
+}
+
+var pdf = []byte("%PDF-1.7\n1 0 obj\n<<>>\nendobj\n")
+
+func TestUploadStoresContentOnceAndReusesRecord(t *testing.T) {
+	dir := t.TempDir()
+	svc := NewService(newFakeRepository(), NewDiskStore(dir))
+
+	first, err := svc.Upload(context.Background(), "user-1", "plano.pdf", pdf)
+	require.NoError(t, err)
+	again, err := svc.Upload(context.Background(), "user-1", "plano.pdf", pdf)
+	require.NoError(t, err)
+	other, err := svc.Upload(context.Background(), "user-2", "aula.pdf", pdf)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.ID, again.ID)
+	assert.NotEqual(t, first.ID, other.ID)
+	assert.Equal(t, "application/pdf", first.ContentType)
+	assert.Equal(t, len(pdf), first.Size)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, first.SHA256[:2], first.SHA256)}, files)
+
+	_, data, err := svc.Load(context.Background(), "user-2", other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, pdf, data)
+}
+
+func TestUploadValidatesNameAndContent(t *testing.T) {
+	svc := NewService(newFakeRepository(), NewDiskStore(t.TempDir()))
+
+	_, err := svc.Upload(context.Background(), "user-1", "setup.exe", []byte("MZ"))
+	assert.ErrorContains(t, err, "tipo de arquivo não permitido")
+
+	_, err = svc.Upload(context.Background(), "user-1", "foto.png", pdf)
+	assert.ErrorContains(t, err, "conteúdo do anexo não corresponde ao tipo permitido")
+
+	_, err = svc.Upload(context.Background(), "user-1", "vazio.pdf", nil)
+	assert.ErrorContains(t, err, "anexo sem conteúdo")
+}
+
+func TestAttachmentsAreScopedToTheOwner(t *testing.T) {
+	svc := NewService(newFakeRepository(), NewDiskStore(t.TempDir()))
+	uploaded, err := svc.Upload(context.Background(), "user-1", "plano.pdf", pdf)
+	require.NoError(t, err)
+
+	_, _, err = svc.Load(context.Background(), "user-2", uploaded.ID)
+	assert.ErrorIs(t, err, ErrAttachmentNotFound)
+	assert.ErrorIs(t, svc.Delete(context.Background(), "user-2", uploaded.ID), ErrAttachmentNotFound)
+}
+
+func TestDeleteKeepsContentStillReferenced(t *testing.T) {
+	dir := t.TempDir()
+	svc := NewService(newFakeRepository(), NewDiskStore(dir))
+	first, err := svc.Upload(context.Background(), "user-1", "plano.pdf", pdf)
+	require.NoError(t, err)
+	second, err := svc.Upload(context.Background(), "user-2", "plano.pdf", pdf)
+	require.NoError(t, err)
+	path := filepath.Join(dir, first.SHA256[:2], first.SHA256)
+
+	require.NoError(t, svc.Delete(context.Background(), "user-1", first.ID))
+	assert.FileExists(t, path)
+
+	require.NoError(t, svc.Delete(context.Background(), "user-2", second.ID))
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDeleteRefusesAttachmentUsedByPendingJob(t *testing.T) {
+	dir := t.TempDir()
+	repo := newFakeRepository()
+	svc := NewService(repo, NewDiskStore(dir))
+	uploaded, err := svc.Upload(context.Background(), "user-1", "plano.pdf", pdf)
+	require.NoError(t, err)
+	repo.references[uploaded.ID] = 1
+
+	err = svc.Delete(context.Background(), "user-1", uploaded.ID)
+
+	assert.ErrorIs(t, err, ErrAttachmentInUse)
+	_, data, err := svc.Load(context.Background(), "user-1", uploaded.ID)
+	require.NoError(t, err)
+	assert.Equal(t, pdf, data)
+}
+
+func TestDiskStoreRejectsKeysOutsideTheHashFormat(t *testing.T) {
+	store := NewDiskStore(t.TempDir())
+
+	assert.Error(t, store.Put(context.Background(), "../../etc/passwd", []byte("x")))
+	_, err := store.Get(context.Background(), "ab")
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package attachment
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+type sqlRepository struct {
+	db    database.DB
+	sqlDB *sql.DB
+}
+
+func newSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{
+		db:    database.NewSQLTx(db).DB,
+		sqlDB: db,
+	}
+}
+
+func (r *sqlRepository) WithTransaction(tx any) any {
+	return &sqlRepository{
+		db:    database.NewSQLTx(nil).WithSQLTransaction(tx).DB,
+		sqlDB: r.sqlDB,
+	}
+}
+
+func (r *sqlRepository) TransactionBackend() any {
+	return r.sqlDB
+}
+
+const attachmentColumns = `id, user_id, file_name, content_type, size, sha256, created_at`
+
+func (r *sqlRepository) Create(ctx context.Context, attachment *Attachment) error {
+	// O DO UPDATE sem efeito faz o RETURNING devolver o registro existente em caso de conflito.
+	query := `
+		INSERT INTO attachments (user_id, file_name, content_type, size, sha256)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, sha256, file_name) DO UPDATE SET file_name = EXCLUDED.file_name
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		attachment.UserID,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.SHA256,
+	).Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao registrar anexo: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) FindByID(ctx context.Context, id string) (*Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("falha ao buscar anexo: %w", err)
+	}
+	return attachment, nil
+}
+
+func (r *sqlRepository) FindByUserID(ctx context.Context, userID string) ([]*Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar anexos: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []*Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao ler anexo: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar anexos: %w", err)
+	}
+	return attachments, nil
+}
+
+func (r *sqlRepository) Delete(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("falha ao remover anexo %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) CountBySHA256(ctx context.Context, sha256 string) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM attachments WHERE sha256 = $1`, sha256).Scan(&count); err != nil {
+		return 0, fmt.Errorf("falha ao contar anexos: %w", err)
+	}
+	return count, nil
+}
+
+func (r *sqlRepository) CountReferences(ctx context.Context, id string) (int, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM message_job_attachments WHERE attachment_id = $1) +
+			(SELECT COUNT(*) FROM message_schedules
+			 WHERE status IN ('ACTIVE', 'PAUSED') AND attachments @> jsonb_build_array(jsonb_build_object('id', $2::text)))
+	`
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, id, id).Scan(&count); err != nil {
+		return 0, fmt.Errorf("falha ao contar usos do anexo %s: %w", id, err)
+	}
+	return count, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAttachment(scanner rowScanner) (*Attachment, error) {
+	attachment := &Attachment{}
+	err := scanner.Scan(
+		&attachment.ID,
+		&attachment.UserID,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
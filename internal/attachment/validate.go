@@ -0,0 +1,89 @@
+package attachment
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+// MaxBytes é o tamanho máximo de um anexo, enviado por upload, base64 ou URL.
+const MaxBytes = 10 * 1024 * 1024
+
+var blockedExtensions = map[string]struct{}{
+	".apk": {}, ".app": {}, ".bat": {}, ".cmd": {}, ".com": {}, ".dll": {}, ".dmg": {},
+	".exe": {}, ".hta": {}, ".iso": {}, ".jar": {}, ".js": {}, ".msi": {}, ".ps1": {},
+	".scr": {}, ".sh": {}, ".vbs": {}, ".wsf": {},
+}
+
+var allowedExtensions = map[string]struct{}{
+	".csv": {}, ".doc": {}, ".docx": {}, ".jpeg": {}, ".jpg": {}, ".mp3": {}, ".mp4": {},
+	".ogg": {}, ".pdf": {}, ".png": {}, ".ppt": {}, ".pptx": {}, ".txt": {}, ".webp": {},
+	".xls": {}, ".xlsx": {},
+}
+
+// ValidateFileName exige nome com uma extensão permitida; executáveis e scripts são bloqueados.
+func ValidateFileName(fileName string) error {
+	fileName = strings.TrimSpace(fileName)
+	if fileName == "" {
+		return customerror.Make("anexo deve ter um nome de arquivo", http.StatusBadRequest, errors.New("attachment filename required"))
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if ext == "" {
+		return customerror.Make("anexo deve ter uma extensão permitida", http.StatusBadRequest, errors.New("attachment extension required"))
+	}
+	if _, blocked := blockedExtensions[ext]; blocked {
+		return customerror.Make("tipo de arquivo não permitido", http.StatusBadRequest, errors.New("blocked attachment extension"))
+	}
+	if _, allowed := allowedExtensions[ext]; !allowed {
+		return customerror.Make("tipo de arquivo não permitido", http.StatusBadRequest, errors.New("attachment extension not allowed"))
+	}
+
+	return nil
+}
+
+// ValidateData confere tamanho e se o conteúdo detectado corresponde à extensão do arquivo.
+func ValidateData(fileName string, data []byte, maxBytes int) error {
+	if len(data) == 0 {
+		return customerror.Make("anexo sem conteúdo", http.StatusBadRequest, errors.New("empty attachment data"))
+	}
+	if len(data) > maxBytes {
+		return customerror.Make("anexo excede o tamanho máximo permitido", http.StatusBadRequest, errors.New("attachment too large"))
+	}
+	if err := validateDetectedType(fileName, data); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateDetectedType(fileName string, data []byte) error {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	detected := DetectContentType(data)
+
+	expected := mime.TypeByExtension(ext)
+	if idx := strings.Index(expected, ";"); idx >= 0 {
+		expected = expected[:idx]
+	}
+
+	if detected == "application/octet-stream" || detected == "text/plain" {
+		return nil
+	}
+	if expected != "" && detected != expected {
+		return customerror.Make("conteúdo do anexo não corresponde ao tipo permitido", http.StatusBadRequest, errors.New("attachment mime mismatch"))
+	}
+
+	return nil
+}
+
+// DetectContentType devolve o MIME detectado pelo conteúdo, sem parâmetros como charset.
+func DetectContentType(data []byte) string {
+	detected := http.DetectContentType(data)
+	if idx := strings.Index(detected, ";"); idx >= 0 {
+		detected = detected[:idx]
+	}
+	return detected
+}
@@ -28,7 +28,7 @@ func NewHandler(service Service) Handler {
 }
 
 // @Summary Reseta a senha de um usuário (backdoor)
-// @Description Uso administrativo com segredo estático; requer userId ou email.
+// @Description Uso administrativo com segredo estático; requer userId ou email. As instâncias SMTP com senha ficam com passwordReentryRequired até o usuário informar a senha novamente.
 // @Tags backdoor
 // @Accept json
 // @Produce json
@@ -5,8 +5,12 @@ import (
 	"errors"
 
 	"github.com/ThalysSilva/unicast-backend/internal/auth"
+	"github.com/ThalysSilva/unicast-backend/internal/message"
+	"github.com/ThalysSilva/unicast-backend/internal/schedule"
+	"github.com/ThalysSilva/unicast-backend/internal/smtp"
 	"github.com/ThalysSilva/unicast-backend/internal/user"
 	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -15,8 +19,11 @@ type Service interface {
 }
 
 type service struct {
-	userRepo    user.Repository
-	adminSecret string
+	userRepo     user.Repository
+	smtpRepo     smtp.Repository
+	scheduleRepo schedule.Repository
+	outboxRepo   message.OutboxRepository
+	adminSecret  string
 }
 
 var (
@@ -24,10 +31,13 @@ var (
 	ErrUserNotFound  = customerror.Make("usuário não encontrado", 404, errors.New("ErrUserNotFound"))
 )
 
-func NewService(userRepo user.Repository, adminSecret string) Service {
+func NewService(userRepo user.Repository, smtpRepo smtp.Repository, scheduleRepo schedule.Repository, outboxRepo message.OutboxRepository, adminSecret string) Service {
 	return &service{
-		userRepo:    userRepo,
-		adminSecret: adminSecret,
+		userRepo:     userRepo,
+		smtpRepo:     smtpRepo,
+		scheduleRepo: scheduleRepo,
+		outboxRepo:   outboxRepo,
+		adminSecret:  adminSecret,
 	}
 }
 
@@ -65,5 +75,24 @@ func (s *service) ResetPassword(ctx context.Context, secret, userID, email, newP
 	u.Salt = salt
 	u.RefreshToken = nil // invalida sessões
 
-	return s.userRepo.Update(ctx, u)
+	// Sem a senha antiga não há como abrir as senhas SMTP; elas ficam marcadas até o usuário informá-las de novo.
+	// Os JWEs de agendamentos e jobs pendentes carregam a chave antiga: são descartados e os envios falham
+	// pedindo a senha SMTP até o agendamento receber um JWE novo.
+	repos := []database.Transactional{s.userRepo, s.smtpRepo, s.scheduleRepo, s.outboxRepo}
+	_, err = database.MakeTransaction(ctx, repos, func(txRepos []database.Transactional) (any, error) {
+		if err := txRepos[1].(smtp.Repository).MarkUserPasswordsReentry(ctx, u.ID); err != nil {
+			return nil, err
+		}
+		if err := txRepos[2].(schedule.Repository).ReplaceJwe(ctx, u.ID, nil); err != nil {
+			return nil, err
+		}
+		if err := txRepos[3].(message.OutboxRepository).ReplaceJwe(ctx, u.ID, nil); err != nil {
+			return nil, err
+		}
+		return nil, txRepos[0].(user.Repository).Update(ctx, u)
+	})
+	if err != nil {
+		return customerror.Trace("ResetPassword", err)
+	}
+	return nil
 }
@@ -9,6 +9,8 @@ type Evolution struct {
 	Host   string
 	Port   string
 	APIKey string
+	// WebhookSecret autentica os webhooks da Evolution; vazio desativa o recebimento de recibos.
+	WebhookSecret string
 }
 
 type Auth struct {
@@ -23,10 +25,23 @@ type Defaults struct {
 }
 
 type OAuth struct {
-	FrontendBaseURL    string
-	GoogleClientID     string
-	GoogleClientSecret string
-	GoogleRedirectURL  string
+	FrontendBaseURL       string
+	GoogleClientID        string
+	GoogleClientSecret    string
+	GoogleRedirectURL     string
+	MicrosoftClientID     string
+	MicrosoftClientSecret string
+	MicrosoftRedirectURL  string
+	// MicrosoftTenant restringe as contas aceitas: common, organizations, consumers ou o ID do tenant.
+	MicrosoftTenant string
+	// MicrosoftAuthorityURL e MicrosoftGraphURL podem apontar para um servidor local nos testes.
+	MicrosoftAuthorityURL string
+	MicrosoftGraphURL     string
+}
+
+// Storage define onde ficam os arquivos enviados para /attachment.
+type Storage struct {
+	AttachmentsDir string
 }
 
 type Config struct {
@@ -34,6 +49,7 @@ type Config struct {
 	Auth      Auth
 	Defaults  Defaults
 	OAuth     OAuth
+	Storage   Storage
 	Admin     struct {
 		Secret string
 	}
@@ -47,6 +63,8 @@ func Load() (*Config, error) {
 			Host:   os.Getenv("EVOLUTION_HOST"),
 			Port:   os.Getenv("EVOLUTION_PORT"),
 			APIKey: os.Getenv("AUTHENTICATION_API_KEY"),
+
+			WebhookSecret: os.Getenv("EVOLUTION_WEBHOOK_SECRET"),
 		},
 		Auth: Auth{
 			AccessTokenSecret:  os.Getenv("ACCESS_TOKEN_SECRET"),
@@ -62,6 +80,16 @@ func Load() (*Config, error) {
 			GoogleClientID:     os.Getenv("GOOGLE_OAUTH_CLIENT_ID"),
 			GoogleClientSecret: os.Getenv("GOOGLE_OAUTH_CLIENT_SECRET"),
 			GoogleRedirectURL:  os.Getenv("GOOGLE_OAUTH_REDIRECT_URL"),
+
+			MicrosoftClientID:     os.Getenv("MICROSOFT_OAUTH_CLIENT_ID"),
+			MicrosoftClientSecret: os.Getenv("MICROSOFT_OAUTH_CLIENT_SECRET"),
+			MicrosoftRedirectURL:  os.Getenv("MICROSOFT_OAUTH_REDIRECT_URL"),
+			MicrosoftTenant:       os.Getenv("MICROSOFT_OAUTH_TENANT"),
+			MicrosoftAuthorityURL: os.Getenv("MICROSOFT_OAUTH_AUTHORITY_URL"),
+			MicrosoftGraphURL:     os.Getenv("MICROSOFT_GRAPH_URL"),
+		},
+		Storage: Storage{
+			AttachmentsDir: os.Getenv("ATTACHMENT_STORAGE_DIR"),
 		},
 	}
 
@@ -73,6 +101,18 @@ func Load() (*Config, error) {
 	if cfg.OAuth.FrontendBaseURL == "" {
 		cfg.OAuth.FrontendBaseURL = "http://localhost:3000"
 	}
+	if cfg.OAuth.MicrosoftTenant == "" {
+		cfg.OAuth.MicrosoftTenant = "common"
+	}
+	if cfg.OAuth.MicrosoftAuthorityURL == "" {
+		cfg.OAuth.MicrosoftAuthorityURL = "https://login.microsoftonline.com"
+	}
+	if cfg.OAuth.MicrosoftGraphURL == "" {
+		cfg.OAuth.MicrosoftGraphURL = "https://graph.microsoft.com/v1.0"
+	}
+	if cfg.Storage.AttachmentsDir == "" {
+		cfg.Storage.AttachmentsDir = "data/attachments"
+	}
 
 	if err := validate(cfg); err != nil {
 		return nil, err
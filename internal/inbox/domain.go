@@ -0,0 +1,95 @@
+package inbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+// Message é uma resposta de aluno recebida pelo WhatsApp de uma instância do professor.
+type Message struct {
+	ID      string  `json:"id"`
+	Student Student `json:"student"`
+	// DeliveryGroupID é o último envio por WhatsApp ao aluno, pela mesma instância, antes da resposta.
+	DeliveryGroupID    *string    `json:"deliveryGroupId"`
+	WhatsAppInstanceID *string    `json:"whatsappInstanceId"`
+	Phone              string     `json:"phone"`
+	PushName           *string    `json:"pushName"`
+	MessageType        *string    `json:"messageType"`
+	Body               string     `json:"body"`
+	ReceivedAt         time.Time  `json:"receivedAt"`
+	ReadAt             *time.Time `json:"readAt"`
+	UserID             string     `json:"-"`
+	ProviderMessageID  string     `json:"-"`
+}
+
+type Student struct {
+	ID        string  `json:"id"`
+	StudentID string  `json:"studentId"`
+	Name      *string `json:"name"`
+}
+
+// StudentMatch é um aluno do professor cujo telefone bate com o remetente de uma mensagem recebida.
+type StudentMatch struct {
+	ID    string
+	Phone string
+	// DeliveryGroupID é o último envio por WhatsApp ao aluno pela instância; nil se não houver.
+	DeliveryGroupID *string
+}
+
+type Filter struct {
+	Search          string
+	StudentID       string
+	DeliveryGroupID string
+	Unread          *bool
+	Page            int
+	PageSize        int
+}
+
+type Query struct {
+	// Q busca no texto da resposta, no nome e na matrícula do aluno e no nome do contato no WhatsApp.
+	Q               string `form:"q" binding:"omitempty,max=200"`
+	StudentID       string `form:"studentId" binding:"omitempty,uuid"`
+	DeliveryGroupID string `form:"deliveryGroupId" binding:"omitempty,uuid"`
+	Unread          *bool  `form:"unread"`
+	Page            int    `form:"page" binding:"omitempty,min=1"`
+	PageSize        int    `form:"pageSize" binding:"omitempty,min=1,max=100"`
+}
+
+type Page struct {
+	Items []Message `json:"items"`
+	Total int       `json:"total"`
+	// Unread conta todas as respostas não lidas do professor, independentemente dos filtros.
+	Unread   int `json:"unread"`
+	Page     int `json:"page"`
+	PageSize int `json:"pageSize"`
+}
+
+// ReadRequest marca respostas como lidas: as de ids ou, com all, todas as não lidas.
+type ReadRequest struct {
+	IDs []string `json:"ids" binding:"omitempty,max=500,dive,uuid"`
+	All bool     `json:"all"`
+}
+
+type ReadResult struct {
+	Updated int `json:"updated"`
+}
+
+type Repository interface {
+	database.Transactional
+	// FindStudentsByPhone busca os alunos do usuário cujo telefone, só com dígitos, é um dos números;
+	// os alunos com envio mais recente pela instância vêm primeiro.
+	FindStudentsByPhone(ctx context.Context, userID, instanceID string, phones []string) ([]StudentMatch, error)
+	// Create grava a resposta; devolve false quando ela já tinha sido gravada (webhook repetido).
+	Create(ctx context.Context, message *Message) (bool, error)
+	List(ctx context.Context, userID string, filter Filter) ([]Message, int, error)
+	CountUnread(ctx context.Context, userID string) (int, error)
+	// MarkRead marca como lidas as respostas do usuário em ids, ou todas com all, e devolve quantas mudaram.
+	MarkRead(ctx context.Context, userID string, ids []string, all bool) (int, error)
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return newSQLRepository(db)
+}
@@ -0,0 +1,91 @@
+package inbox
+
+import (
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+
+	"github.com/gin-gonic/gin"
+)
+
+type handler struct {
+	service Service
+}
+
+type Handler interface {
+	List() gin.HandlerFunc
+	MarkRead() gin.HandlerFunc
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Lista as respostas dos alunos pelo WhatsApp
+// @Description Mensagens recebidas nas instâncias do professor de números de alunos cadastrados, da mais recente à mais antiga.
+// @Description Cada resposta aponta para o último envio por WhatsApp ao aluno (deliveryGroupId), quando houver. unread na resposta conta todas as não lidas.
+// @OperationId listInbox
+// @Tags inbox
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param q query string false "Busca no texto, no nome ou na matrícula do aluno e no nome do contato"
+// @Param studentId query string false "ID do aluno"
+// @Param deliveryGroupId query string false "ID do envio respondido"
+// @Param unread query bool false "Só não lidas (true) ou só lidas (false)"
+// @Param page query int false "Página (padrão 1)"
+// @Param pageSize query int false "Itens por página (padrão 20, máximo 100)"
+// @Success 200 {object} api.DefaultResponse[Page]
+// @Failure 400 {object} api.ErrorResponse
+// @Router /inbox [get]
+func (h *handler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var query Query
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.Error(err)
+			return
+		}
+		page, err := h.service.List(c.Request.Context(), c.GetString("userID"), Filter{
+			Search:          query.Q,
+			StudentID:       query.StudentID,
+			DeliveryGroupID: query.DeliveryGroupID,
+			Unread:          query.Unread,
+			Page:            query.Page,
+			PageSize:        query.PageSize,
+		})
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[Page]{Message: "Respostas listadas com sucesso", Data: *page})
+	}
+}
+
+// @Summary Marca respostas como lidas
+// @Description Marca as respostas de ids ou, com all=true, todas as não lidas. IDs de outros usuários são ignorados.
+// @OperationId markInboxRead
+// @Tags inbox
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param body body ReadRequest true "Respostas a marcar"
+// @Success 200 {object} api.DefaultResponse[ReadResult]
+// @Failure 400 {object} api.ErrorResponse
+// @Router /inbox/read [post]
+func (h *handler) MarkRead() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request ReadRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.Error(err)
+			return
+		}
+		updated, err := h.service.MarkRead(c.Request.Context(), c.GetString("userID"), request)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[ReadResult]{Message: "Respostas marcadas como lidas", Data: ReadResult{Updated: updated}})
+	}
+}
@@ -0,0 +1,158 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var ErrNothingToRead = customerror.Make("informe ids ou all=true", http.StatusBadRequest, errors.New("ErrNothingToRead"))
+
+type Service interface {
+	// ReceiveWhatsApp grava as mensagens de um evento MESSAGES_UPSERT enviadas por alunos do dono da instância
+	// e devolve quantas foram gravadas. Mensagens de números que não são de alunos são descartadas.
+	ReceiveWhatsApp(ctx context.Context, event *whatsapp.MessagesUpsertEvent) (int, error)
+	List(ctx context.Context, userID string, filter Filter) (*Page, error)
+	MarkRead(ctx context.Context, userID string, request ReadRequest) (int, error)
+}
+
+type inboxService struct {
+	repository         Repository
+	whatsappRepository whatsapp.Repository
+	defaultCountryCode string
+}
+
+func NewService(repository Repository, whatsappRepo whatsapp.Repository, defaultCountryCode string) Service {
+	return &inboxService{
+		repository:         repository,
+		whatsappRepository: whatsappRepo,
+		defaultCountryCode: defaultCountryCode,
+	}
+}
+
+func (s *inboxService) ReceiveWhatsApp(ctx context.Context, event *whatsapp.MessagesUpsertEvent) (int, error) {
+	if len(event.Messages) == 0 {
+		return 0, nil
+	}
+	instance, err := s.whatsappRepository.FindByInstanceName(ctx, event.Instance)
+	if err != nil {
+		return 0, customerror.Trace("ReceiveWhatsApp", err)
+	}
+	// O webhook global da Evolution também traz instâncias que não são do Unicast.
+	if instance == nil {
+		return 0, nil
+	}
+
+	stored := 0
+	for _, inbound := range event.Messages {
+		match, err := s.findStudent(ctx, instance, inbound.Phone)
+		if err != nil {
+			return stored, customerror.Trace("ReceiveWhatsApp", err)
+		}
+		if match == nil {
+			continue
+		}
+		message := &Message{
+			Student:            Student{ID: match.ID},
+			DeliveryGroupID:    match.DeliveryGroupID,
+			WhatsAppInstanceID: &instance.ID,
+			Phone:              inbound.Phone,
+			PushName:           optional(inbound.PushName),
+			MessageType:        optional(inbound.MessageType),
+			Body:               inbound.Text,
+			ReceivedAt:         inbound.SentAt,
+			UserID:             instance.UserID,
+			ProviderMessageID:  inbound.MessageID,
+		}
+		created, err := s.repository.Create(ctx, message)
+		if err != nil {
+			return stored, customerror.Trace("ReceiveWhatsApp", err)
+		}
+		if created {
+			stored++
+		}
+	}
+	return stored, nil
+}
+
+// findStudent casa o remetente com os alunos do dono da instância pelo telefone normalizado com
+// whatsapp.NormalizeNumber, aceitando a variante brasileira com ou sem o nono dígito.
+func (s *inboxService) findStudent(ctx context.Context, instance *whatsapp.Instance, phone string) (*StudentMatch, error) {
+	variants := whatsapp.NumberVariants(phone)
+	// O banco compara só os dígitos do cadastro, que pode estar sem o DDI; a normalização abaixo confirma.
+	candidates := slices.Clone(variants)
+	for _, variant := range variants {
+		if s.defaultCountryCode != "" && strings.HasPrefix(variant, s.defaultCountryCode) {
+			candidates = append(candidates, strings.TrimPrefix(variant, s.defaultCountryCode))
+		}
+	}
+
+	matches, err := s.repository.FindStudentsByPhone(ctx, instance.UserID, instance.ID, candidates)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		number, err := whatsapp.NormalizeNumber(match.Phone, s.defaultCountryCode)
+		if err == nil && slices.Contains(variants, number) {
+			return &match, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *inboxService) List(ctx context.Context, userID string, filter Filter) (*Page, error) {
+	filter.Search = strings.TrimSpace(filter.Search)
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = defaultPageSize
+	}
+	if filter.PageSize > maxPageSize {
+		filter.PageSize = maxPageSize
+	}
+
+	messages, total, err := s.repository.List(ctx, userID, filter)
+	if err != nil {
+		return nil, customerror.Trace("ListInbox", err)
+	}
+	unread, err := s.repository.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, customerror.Trace("ListInbox", err)
+	}
+	return &Page{
+		Items:    messages,
+		Total:    total,
+		Unread:   unread,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+func (s *inboxService) MarkRead(ctx context.Context, userID string, request ReadRequest) (int, error) {
+	if len(request.IDs) == 0 && !request.All {
+		return 0, customerror.Trace("MarkInboxRead", ErrNothingToRead)
+	}
+	updated, err := s.repository.MarkRead(ctx, userID, request.IDs, request.All)
+	if err != nil {
+		return 0, customerror.Trace("MarkInboxRead", err)
+	}
+	return updated, nil
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,137 @@
+package inbox
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInstanceRepository struct {
+	whatsapp.Repository
+	instances []*whatsapp.Instance
+}
+
+func (f *fakeInstanceRepository) FindByInstanceName(ctx context.Context, instanceName string) (*whatsapp.Instance, error) {
+	for _, instance := range f.instances {
+		if instance.InstanceName == instanceName {
+			return instance, nil
+		}
+	}
+	return nil, nil
+}
+
+type fakeStudent struct {
+	userID, id, phone string
+	deliveryGroupID   *string
+}
+
+type fakeRepository struct {
+	Repository
+	students []fakeStudent
+	created  []*Message
+	lookups  [][]string
+}
+
+func (f *fakeRepository) FindStudentsByPhone(ctx context.Context, userID, instanceID string, phones []string) ([]StudentMatch, error) {
+	f.lookups = append(f.lookups, phones)
+	matches := []StudentMatch{}
+	for _, student := range f.students {
+		digits := ""
+		for _, r := range student.phone {
+			if r >= '0' && r <= '9' {
+				digits += string(r)
+			}
+		}
+		if student.userID == userID && slices.Contains(phones, digits) {
+			matches = append(matches, StudentMatch{ID: student.id, Phone: student.phone, DeliveryGroupID: student.deliveryGroupID})
+		}
+	}
+	return matches, nil
+}
+
+func (f *fakeRepository) Create(ctx context.Context, message *Message) (bool, error) {
+	for _, existing := range f.created {
+		if existing.UserID == message.UserID && existing.ProviderMessageID == message.ProviderMessageID {
+			return false, nil
+		}
+	}
+	f.created = append(f.created, message)
+	return true, nil
+}
+
+func (f *fakeRepository) MarkRead(ctx context.Context, userID string, ids []string, all bool) (int, error) {
+	return len(ids), nil
+}
+
+func newTestService(repo *fakeRepository) Service {
+	instances := &fakeInstanceRepository{instances: []*whatsapp.Instance{{ID: "wa-1", UserID: "prof-1", InstanceName: "prof"}}}
+	return NewService(repo, instances, "55")
+}
+
+func TestReceiveWhatsAppMatchesStudentsByNormalizedPhone(t *testing.T) {
+	group := "c7a4e2b0-0000-4000-8000-000000000001"
+	repo := &fakeRepository{students: []fakeStudent{
+		{userID: "prof-1", id: "ana", phone: "(11) 91234-5678", deliveryGroupID: &group},
+		{userID: "prof-1", id: "bruno", phone: "+55 21 98765-4321"},
+		{userID: "prof-2", id: "outro", phone: "11 93333-4444"},
+	}}
+	svc := newTestService(repo)
+	sentAt := time.Unix(1760000000, 0)
+
+	stored, err := svc.ReceiveWhatsApp(context.Background(), &whatsapp.MessagesUpsertEvent{Instance: "prof", Messages: []whatsapp.InboundMessage{
+		{MessageID: "R1", Phone: "5511912345678", PushName: "Ana", Text: "Recebi", MessageType: "conversation", SentAt: sentAt},
+		// Conta antiga: o JID vem sem o nono dígito.
+		{MessageID: "R2", Phone: "552187654321", Text: "Ok", SentAt: sentAt},
+		// Aluno de outro professor e número desconhecido são descartados.
+		{MessageID: "R3", Phone: "5511933334444", Text: "Oi", SentAt: sentAt},
+		{MessageID: "R4", Phone: "5511900000000", Text: "Oi", SentAt: sentAt},
+	}})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored)
+	require.Len(t, repo.created, 2)
+	ana := repo.created[0]
+	assert.Equal(t, "ana", ana.Student.ID)
+	assert.Equal(t, "prof-1", ana.UserID)
+	assert.Equal(t, &group, ana.DeliveryGroupID)
+	assert.Equal(t, "wa-1", *ana.WhatsAppInstanceID)
+	assert.Equal(t, "Ana", *ana.PushName)
+	assert.Equal(t, sentAt, ana.ReceivedAt)
+	assert.Equal(t, "bruno", repo.created[1].Student.ID)
+	assert.Nil(t, repo.created[1].DeliveryGroupID)
+	assert.Nil(t, repo.created[1].PushName)
+	assert.Contains(t, repo.lookups[0], "11912345678", "cadastro sem DDI também é consultado")
+}
+
+func TestReceiveWhatsAppIgnoresUnknownInstancesAndRepeatedWebhooks(t *testing.T) {
+	repo := &fakeRepository{students: []fakeStudent{{userID: "prof-1", id: "ana", phone: "11912345678"}}}
+	svc := newTestService(repo)
+	reply := whatsapp.InboundMessage{MessageID: "R1", Phone: "5511912345678", Text: "Recebi"}
+
+	stored, err := svc.ReceiveWhatsApp(context.Background(), &whatsapp.MessagesUpsertEvent{Instance: "outra", Messages: []whatsapp.InboundMessage{reply}})
+	require.NoError(t, err)
+	assert.Zero(t, stored)
+
+	for _, want := range []int{1, 0} {
+		stored, err = svc.ReceiveWhatsApp(context.Background(), &whatsapp.MessagesUpsertEvent{Instance: "prof", Messages: []whatsapp.InboundMessage{reply}})
+		require.NoError(t, err)
+		assert.Equal(t, want, stored)
+	}
+	assert.Len(t, repo.created, 1)
+}
+
+func TestMarkReadRequiresIDsOrAll(t *testing.T) {
+	svc := newTestService(&fakeRepository{})
+
+	_, err := svc.MarkRead(context.Background(), "prof-1", ReadRequest{})
+	assert.ErrorIs(t, err, ErrNothingToRead)
+
+	updated, err := svc.MarkRead(context.Background(), "prof-1", ReadRequest{IDs: []string{"a", "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated)
+}
@@ -0,0 +1,207 @@
+package inbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/lib/pq"
+)
+
+type sqlRepository struct {
+	db    database.DB
+	sqlDB *sql.DB
+}
+
+func newSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{
+		db:    database.NewSQLTx(db).DB,
+		sqlDB: db,
+	}
+}
+
+func (r *sqlRepository) WithTransaction(tx any) any {
+	return &sqlRepository{
+		db:    database.NewSQLTx(nil).WithSQLTransaction(tx).DB,
+		sqlDB: r.sqlDB,
+	}
+}
+
+func (r *sqlRepository) TransactionBackend() any {
+	return r.sqlDB
+}
+
+func (r *sqlRepository) FindStudentsByPhone(ctx context.Context, userID, instanceID string, phones []string) ([]StudentMatch, error) {
+	query := `
+		SELECT s.id, s.phone, last.delivery_group_id
+		FROM students s
+		LEFT JOIN LATERAL (
+			SELECT ml.delivery_group_id, ml.created_at
+			FROM message_logs ml
+			WHERE ml.student_id = s.id
+			  AND ml.channel = 'WHATSAPP'
+			  AND ml.whatsapp_instance_id = $2
+			  AND ml.delivery_group_id IS NOT NULL
+			ORDER BY ml.created_at DESC
+			LIMIT 1
+		) last ON TRUE
+		WHERE s.user_owner_id = $1
+		  AND s.phone IS NOT NULL
+		  AND regexp_replace(s.phone, '\D', '', 'g') = ANY($3)
+		ORDER BY last.created_at DESC NULLS LAST, s.created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, instanceID, pq.Array(phones))
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar alunos pelo telefone: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []StudentMatch{}
+	for rows.Next() {
+		var match StudentMatch
+		if err := rows.Scan(&match.ID, &match.Phone, &match.DeliveryGroupID); err != nil {
+			return nil, fmt.Errorf("falha ao ler aluno: %w", err)
+		}
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar alunos: %w", err)
+	}
+	return matches, nil
+}
+
+func (r *sqlRepository) Create(ctx context.Context, message *Message) (bool, error) {
+	query := `
+		INSERT INTO inbound_messages (
+			user_id, student_id, whatsapp_instance_id, delivery_group_id, provider_message_id,
+			phone, push_name, message_type, body, received_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id, provider_message_id) DO NOTHING
+		RETURNING id
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		message.UserID,
+		message.Student.ID,
+		message.WhatsAppInstanceID,
+		message.DeliveryGroupID,
+		message.ProviderMessageID,
+		message.Phone,
+		message.PushName,
+		message.MessageType,
+		message.Body,
+		message.ReceivedAt,
+	).Scan(&message.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("falha ao gravar mensagem recebida %s: %w", message.ProviderMessageID, err)
+	}
+	return true, nil
+}
+
+func (r *sqlRepository) List(ctx context.Context, userID string, filter Filter) ([]Message, int, error) {
+	conditions := []string{"im.user_id = $1"}
+	args := []any{userID}
+	addCondition := func(format string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+	if filter.Search != "" {
+		addCondition("(im.body ILIKE $%[1]d OR s.name ILIKE $%[1]d OR s.student_id ILIKE $%[1]d OR im.push_name ILIKE $%[1]d)", "%"+escapeLike(filter.Search)+"%")
+	}
+	if filter.StudentID != "" {
+		addCondition("im.student_id = $%d", filter.StudentID)
+	}
+	if filter.DeliveryGroupID != "" {
+		addCondition("im.delivery_group_id = $%d", filter.DeliveryGroupID)
+	}
+	if filter.Unread != nil {
+		if *filter.Unread {
+			conditions = append(conditions, "im.read_at IS NULL")
+		} else {
+			conditions = append(conditions, "im.read_at IS NOT NULL")
+		}
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	query := fmt.Sprintf(`
+		SELECT im.id, s.id, s.student_id, s.name, im.delivery_group_id, im.whatsapp_instance_id, im.phone,
+		       im.push_name, im.message_type, im.body, im.received_at, im.read_at,
+		       COUNT(*) OVER ()
+		FROM inbound_messages im
+		JOIN students s ON s.id = im.student_id
+		WHERE %s
+		ORDER BY im.received_at DESC, im.id
+		LIMIT $%d OFFSET $%d
+	`, strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("falha ao buscar mensagens recebidas: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	total := 0
+	for rows.Next() {
+		var message Message
+		if err := rows.Scan(
+			&message.ID,
+			&message.Student.ID,
+			&message.Student.StudentID,
+			&message.Student.Name,
+			&message.DeliveryGroupID,
+			&message.WhatsAppInstanceID,
+			&message.Phone,
+			&message.PushName,
+			&message.MessageType,
+			&message.Body,
+			&message.ReceivedAt,
+			&message.ReadAt,
+			&total,
+		); err != nil {
+			return nil, 0, fmt.Errorf("falha ao ler mensagem recebida: %w", err)
+		}
+		message.UserID = userID
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("erro ao iterar mensagens recebidas: %w", err)
+	}
+	return messages, total, nil
+}
+
+func (r *sqlRepository) CountUnread(ctx context.Context, userID string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM inbound_messages WHERE user_id = $1 AND read_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("falha ao contar mensagens não lidas: %w", err)
+	}
+	return count, nil
+}
+
+func (r *sqlRepository) MarkRead(ctx context.Context, userID string, ids []string, all bool) (int, error) {
+	query := `
+		UPDATE inbound_messages
+		SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL AND ($2::boolean OR id = ANY($3::uuid[]))
+	`
+	result, err := r.db.ExecContext(ctx, query, userID, all, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("falha ao marcar mensagens como lidas: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("falha ao marcar mensagens como lidas: %w", err)
+	}
+	return int(affected), nil
+}
+
+// escapeLike escapa os curingas do ILIKE para a busca tratar % e _ como texto.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,104 @@
+package message
+
+import (
+	"context"
+
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+)
+
+// channelSender entrega mensagens por um canal. O service percorre os canais em senders()
+// para validar, enfileirar, prever e entregar, sem conhecer os detalhes de cada provedor.
+type channelSender interface {
+	Channel() Channel
+	// InstanceID é a instância escolhida na mensagem para o canal; vazio quando o canal não foi pedido.
+	InstanceID(message *Message) string
+	// SendsAttachments indica se os anexos da mensagem acompanham o envio neste canal.
+	SendsAttachments() bool
+	// Load confere que a instância existe e pertence ao usuário e devolve o remetente gravado nos logs.
+	Load(ctx context.Context, userID, instanceID string) (senderDetails, error)
+	// CheckCredentials confirma, antes de enfileirar, que o worker conseguirá usar as credenciais do canal.
+	CheckCredentials(ctx context.Context, message *Message) error
+	// Preview renderiza a mensagem como o aluno a receberia e devolve o destino normalizado.
+	Preview(message *Message, stud *student.Student, renderContext RenderContext) (*renderedMessage, string, error)
+	// Deliver envia a mensagem aos alunos; falhas de um aluno não interrompem os demais.
+	// report é chamado assim que cada aluno termina, para o andamento em tempo real.
+	Deliver(ctx context.Context, message *Message, students []*student.Student, renderContext RenderContext, report progressReporter) channelDelivery
+}
+
+// destinationChecker é implementado pelos canais que confirmam no provedor, em lote, que os destinos existem.
+// Preview chama CheckDestinations antes de prever os alunos; o envio faz a mesma consulta dentro de Deliver.
+type destinationChecker interface {
+	CheckDestinations(ctx context.Context, message *Message, students []*student.Student)
+}
+
+// progressReporter recebe o resultado de um aluno assim que o canal termina a tentativa; err nil indica envio.
+type progressReporter func(studentID string, err error)
+
+// channelDelivery é o resultado de Deliver: a falha e o conteúdo renderizado de cada aluno, o remetente
+// e, quando o provedor informa, o ID da mensagem usado para casar recibos.
+type channelDelivery struct {
+	failures    map[string]error
+	rendered    map[string]renderedMessage
+	sender      senderDetails
+	providerIDs map[string]string
+}
+
+// senderDetails identifica o remetente gravado em message_logs.
+type senderDetails struct {
+	Type               string
+	Provider           string
+	Address            string
+	SMTPID             *string
+	WhatsAppInstanceID *string
+	SMSInstanceID      *string
+	// UsesJwe indica que a entrega precisa do JWE do usuário (SMTP com senha); ele fica no job até o fim.
+	UsesJwe bool
+}
+
+// senders lista os canais suportados, na ordem em que são enfileirados e exibidos.
+func (s *service) senders() []channelSender {
+	return []channelSender{&emailSender{service: s}, &whatsAppSender{service: s}, &smsSender{service: s}}
+}
+
+func (s *service) sender(channel Channel) (channelSender, bool) {
+	for _, sender := range s.senders() {
+		if sender.Channel() == channel {
+			return sender, true
+		}
+	}
+	return nil, false
+}
+
+// selectedSenders carrega as instâncias escolhidas na mensagem, canal a canal.
+func (s *service) selectedSenders(ctx context.Context, message *Message) ([]channelSender, map[Channel]senderDetails, error) {
+	selected := []channelSender{}
+	details := map[Channel]senderDetails{}
+	for _, sender := range s.senders() {
+		instanceID := sender.InstanceID(message)
+		if instanceID == "" {
+			continue
+		}
+		detail, err := sender.Load(ctx, message.UserID, instanceID)
+		if err != nil {
+			return nil, nil, err
+		}
+		selected = append(selected, sender)
+		details[sender.Channel()] = detail
+	}
+	if len(selected) == 0 {
+		return nil, nil, ErrNoChannelSelected
+	}
+	return selected, details, nil
+}
+
+func channelsOf(senders []channelSender) []Channel {
+	channels := make([]Channel, 0, len(senders))
+	for _, sender := range senders {
+		channels = append(channels, sender.Channel())
+	}
+	return channels
+}
+
+func failAllDelivery(students []*student.Student, sender senderDetails, err error) channelDelivery {
+	return channelDelivery{failures: failAll(students, err), sender: sender}
+}
@@ -0,0 +1,263 @@
+package message
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+
+	"github.com/ThalysSilva/unicast-backend/internal/auth"
+	"github.com/ThalysSilva/unicast-backend/internal/encryption"
+	"github.com/ThalysSilva/unicast-backend/internal/smtp"
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
+)
+
+// emailSender entrega por SMTP com senha ou pela API do provedor OAuth da instância.
+type emailSender struct {
+	service *service
+}
+
+func (e *emailSender) Channel() Channel { return ChannelEmail }
+
+func (e *emailSender) InstanceID(message *Message) string { return message.SmtpId }
+
+func (e *emailSender) SendsAttachments() bool { return true }
+
+func (e *emailSender) Load(ctx context.Context, userID, instanceID string) (senderDetails, error) {
+	instance, err := e.service.loadSmtpInstance(ctx, userID, instanceID)
+	if err != nil {
+		return senderDetails{}, err
+	}
+	return emailSenderDetails(instance), nil
+}
+
+// CheckCredentials confere o JWE antes de enfileirar; o worker precisa dele para abrir a senha SMTP.
+func (e *emailSender) CheckCredentials(ctx context.Context, message *Message) error {
+	instance, err := e.service.loadSmtpInstance(ctx, message.UserID, message.SmtpId)
+	if err != nil {
+		return err
+	}
+	if !instance.UsesPassword() {
+		return nil
+	}
+	_, err = e.service.decryptSmtpPassword(message, instance)
+	return err
+}
+
+func (e *emailSender) Preview(message *Message, stud *student.Student, renderContext RenderContext) (*renderedMessage, string, error) {
+	content, err := prepareEmail(message, stud, renderContext)
+	if err != nil {
+		return content, "", err
+	}
+	return content, *stud.Email, nil
+}
+
+func (e *emailSender) Deliver(ctx context.Context, message *Message, students []*student.Student, renderContext RenderContext, report progressReporter) channelDelivery {
+	sender := senderDetails{Type: "EMAIL_SMTP"}
+	smtpInstance, err := e.service.loadSmtpInstance(ctx, message.UserID, message.SmtpId)
+	if err != nil {
+		return failAllDelivery(students, sender, err)
+	}
+	sender = emailSenderDetails(smtpInstance)
+
+	attachments, err := buildEmailAttachments(ctx, message)
+	if err != nil {
+		return failAllDelivery(students, sender, customerror.Trace("Deliver", err))
+	}
+
+	failures, rendered := e.service.sendEmails(ctx, message, smtpInstance, attachments, students, renderContext, report)
+	return channelDelivery{failures: failures, rendered: rendered, sender: sender}
+}
+
+// sendEmails envia um email por conteúdo renderizado: alunos com o mesmo texto compartilham o envio.
+func (s *service) sendEmails(ctx context.Context, message *Message, smtpInstance *smtp.Instance, attachments []mailer.Attachment, students []*student.Student, renderContext RenderContext, report progressReporter) (map[string]error, map[string]renderedMessage) {
+	from := smtpInstance.Email
+	if message.From != "" {
+		from = message.From
+	}
+
+	failures := make(map[string]error)
+	rendered := make(map[string]renderedMessage, len(students))
+	groups := []*emailGroup{}
+	groupByContent := map[renderedMessage]*emailGroup{}
+	for _, stud := range students {
+		content, err := prepareEmail(message, stud, renderContext)
+		if content != nil {
+			rendered[stud.ID] = *content
+		}
+		if err != nil {
+			failures[stud.ID] = err
+			report(stud.ID, err)
+			continue
+		}
+		group, ok := groupByContent[*content]
+		if !ok {
+			group = &emailGroup{content: *content, text: message.Format.plainBody(content.Body)}
+			groupByContent[*content] = group
+			groups = append(groups, group)
+		}
+		group.students = append(group.students, stud)
+	}
+
+	if len(groups) == 0 {
+		return failures, rendered
+	}
+
+	password := ""
+	if smtpInstance.UsesPassword() {
+		decryptedSmtpPassword, err := s.decryptSmtpPassword(message, smtpInstance)
+		if err != nil {
+			for _, group := range groups {
+				maps.Copy(failures, failAll(group.students, err))
+				reportGroup(report, group, failures)
+			}
+			return failures, rendered
+		}
+		password = decryptedSmtpPassword
+	}
+
+	for _, group := range groups {
+		maps.Copy(failures, s.sendEmailGroup(ctx, smtpInstance, password, from, group, attachments))
+		reportGroup(report, group, failures)
+	}
+	return failures, rendered
+}
+
+func reportGroup(report progressReporter, group *emailGroup, failures map[string]error) {
+	for _, stud := range group.students {
+		report(stud.ID, failures[stud.ID])
+	}
+}
+
+// prepareEmail renderiza o email de um aluno; o erro indica por que ele não receberá a mensagem.
+// O conteúdo é nil quando o aluno nem chega a ser renderizado.
+func prepareEmail(message *Message, stud *student.Student, renderContext RenderContext) (*renderedMessage, error) {
+	if stud.Email == nil || *stud.Email == "" {
+		return nil, customerror.Trace("Send", ErrEmailMissing)
+	}
+	renderContext.Student = stud
+	content, missing := renderEmail(message.Subject, message.Body, message.Format, renderContext)
+	content.HTML = emailHTML(message.Format, content, renderContext.Layout)
+	if len(missing) > 0 {
+		return &content, customerror.Trace("Send", missingPlaceholdersError(countPlaceholders(missing)))
+	}
+	return &content, nil
+}
+
+// emailGroup reúne os alunos que recebem o mesmo conteúdo; text é a versão em texto puro do corpo.
+type emailGroup struct {
+	content  renderedMessage
+	text     string
+	students []*student.Student
+}
+
+// sendEmailGroup envia o conteúdo do grupo em um email por aluno e devolve o erro de cada aluno que não o recebeu.
+func (s *service) sendEmailGroup(ctx context.Context, smtpInstance *smtp.Instance, password, from string, group *emailGroup, attachments []mailer.Attachment) map[string]error {
+	failures := make(map[string]error)
+	recipients := make([]string, 0, len(group.students))
+	for _, stud := range group.students {
+		recipients = append(recipients, *stud.Email)
+	}
+
+	mailData := &mailer.MailerData{
+		From:        from,
+		To:          recipients,
+		Subject:     group.content.Subject,
+		Body:        group.text,
+		Attachments: &attachments,
+		ContentType: mailer.TextPlain,
+	}
+	if group.content.HTML != "" {
+		mailData.Body = group.content.HTML
+		mailData.TextBody = group.text
+		mailData.ContentType = mailer.TextHTML
+	}
+
+	if smtpInstance.AuthMode == smtp.AuthModeOAuth {
+		for _, stud := range group.students {
+			studentData := *mailData
+			studentData.To = []string{*stud.Email}
+			if err := s.sendOAuthEmail(ctx, smtpInstance, &studentData); err != nil {
+				failures[stud.ID] = err
+			}
+		}
+		return failures
+	}
+
+	sender := mailer.NewEmailSender(smtpInstance.Authentication(password))
+
+	if err := sender.SetData(mailData); err != nil {
+		return failAll(group.students, customerror.Trace("Send", err))
+	}
+
+	result, err := sender.SendEmails(ctx)
+	if err != nil {
+		return failAll(group.students, customerror.Trace("Send", err))
+	}
+	// Recipients segue a ordem de To, que segue a ordem dos alunos do grupo.
+	for i, stud := range group.students {
+		if recipient := result.Recipients[i]; recipient.Status == mailer.RecipientRejected {
+			failures[stud.ID] = recipient.Err
+		}
+	}
+	return failures
+}
+
+// decryptSmtpPassword abre a senha SMTP com a chave derivada transportada no JWE do usuário.
+func (s *service) decryptSmtpPassword(message *Message, smtpInstance *smtp.Instance) (string, error) {
+	if smtpInstance.PasswordReentryRequired || message.JweReentryRequired {
+		return "", customerror.Trace("Send", ErrSmtpNeedsPassword)
+	}
+	decryptedJwe, err := auth.DecryptJWE[auth.JwePayload](message.Jwe, s.jweSecret)
+	if err != nil {
+		return "", customerror.Trace("Send", fmt.Errorf("%w: %w", ErrSmtpCredentials, err))
+	}
+	smtpKey, err := base64.StdEncoding.DecodeString(decryptedJwe.SmtpKeyEncoded)
+	if err != nil {
+		return "", customerror.Trace("Send", fmt.Errorf("%w: %w", ErrSmtpCredentials, err))
+	}
+
+	decryptedSmtpPassword, err := encryption.DecryptSmtpPassword(smtpInstance.Password, smtpKey, smtpInstance.IV)
+	if err != nil {
+		return "", customerror.Trace("Send", fmt.Errorf("%w: %w", ErrSmtpCredentials, err))
+	}
+	return decryptedSmtpPassword, nil
+}
+
+func (s *service) sendOAuthEmail(ctx context.Context, smtpInstance *smtp.Instance, data *mailer.MailerData) error {
+	if err := s.smtpService.SendOAuthEmail(ctx, smtpInstance, data); err != nil {
+		return customerror.Trace("Send", err)
+	}
+	return nil
+}
+
+func emailSenderDetails(smtpInstance *smtp.Instance) senderDetails {
+	details := senderDetails{
+		Type:    "EMAIL_SMTP",
+		Address: smtpInstance.Email,
+		SMTPID:  &smtpInstance.ID,
+	}
+	if smtpInstance.AuthMode == smtp.AuthModeOAuth {
+		details.Type = "EMAIL_OAUTH"
+		details.Provider = smtpInstance.Provider
+		return details
+	}
+	details.UsesJwe = smtpInstance.UsesPassword()
+	return details
+}
+
+func (s *service) loadSmtpInstance(ctx context.Context, userID, smtpID string) (*smtp.Instance, error) {
+	if smtpID == "" {
+		return nil, customerror.Trace("Send", ErrSmtpNotFound)
+	}
+	instance, err := s.smtpRepository.FindByID(ctx, smtpID)
+	if err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if instance == nil || instance.UserID != userID {
+		return nil, customerror.Trace("Send", ErrSmtpNotFound)
+	}
+	return instance, nil
+}
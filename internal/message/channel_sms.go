@@ -0,0 +1,139 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/internal/sms"
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var ErrSmsNotFound = customerror.Make("instância SMS não encontrada.", 404, errors.New("ErrSmsNotFound"))
+
+// smsSender entrega pelo gateway HTTP de SMS configurado pelo usuário. SMS leva apenas texto:
+// os anexos não acompanham o envio.
+type smsSender struct {
+	service *service
+}
+
+func (m *smsSender) Channel() Channel { return ChannelSMS }
+
+func (m *smsSender) InstanceID(message *Message) string { return message.SmsId }
+
+func (m *smsSender) SendsAttachments() bool { return false }
+
+func (m *smsSender) Load(ctx context.Context, userID, instanceID string) (senderDetails, error) {
+	instance, err := m.service.loadSmsInstance(ctx, userID, instanceID)
+	if err != nil {
+		return senderDetails{}, err
+	}
+	return smsSenderDetails(instance), nil
+}
+
+// CheckCredentials não depende do pedido: o token do gateway é aberto com a chave do servidor.
+func (m *smsSender) CheckCredentials(ctx context.Context, message *Message) error {
+	return nil
+}
+
+func (m *smsSender) Preview(message *Message, stud *student.Student, renderContext RenderContext) (*renderedMessage, string, error) {
+	content, number, err := m.service.prepareSMS(message, stud, renderContext)
+	if content != nil {
+		content = &renderedMessage{Body: formatSMSBody(content.Subject, content.Body, message.Format)}
+	}
+	return content, number, err
+}
+
+func (m *smsSender) Deliver(ctx context.Context, message *Message, students []*student.Student, renderContext RenderContext, report progressReporter) channelDelivery {
+	sender := senderDetails{Type: "SMS", Provider: "http"}
+	instance, err := m.service.loadSmsInstance(ctx, message.UserID, message.SmsId)
+	if err != nil {
+		return failAllDelivery(students, sender, err)
+	}
+	sender = smsSenderDetails(instance)
+
+	delivery := channelDelivery{
+		failures:    map[string]error{},
+		rendered:    make(map[string]renderedMessage, len(students)),
+		sender:      sender,
+		providerIDs: make(map[string]string, len(students)),
+	}
+	for _, stud := range students {
+		content, number, err := m.service.prepareSMS(message, stud, renderContext)
+		if content != nil {
+			delivery.rendered[stud.ID] = *content
+		}
+		if err != nil {
+			delivery.failures[stud.ID] = err
+			report(stud.ID, err)
+			continue
+		}
+
+		messageID, err := m.service.smsService.Send(ctx, instance, number, formatSMSBody(content.Subject, content.Body, message.Format))
+		if err != nil {
+			log.Printf("falha ao enviar sms para %s: %v", number, err)
+			delivery.failures[stud.ID] = err
+			report(stud.ID, err)
+			continue
+		}
+		if messageID != "" {
+			delivery.providerIDs[stud.ID] = messageID
+		}
+		report(stud.ID, nil)
+	}
+	return delivery
+}
+
+// prepareSMS renderiza o texto do SMS de um aluno e devolve o número em E.164 (+DDI...).
+func (s *service) prepareSMS(message *Message, stud *student.Student, renderContext RenderContext) (*renderedMessage, string, error) {
+	if stud.Phone == nil || *stud.Phone == "" {
+		return nil, "", customerror.Trace("Send", ErrPhoneMissing)
+	}
+	renderContext.Student = stud
+	content, missing := renderEmail(message.Subject, message.Body, message.Format, renderContext)
+	if len(missing) > 0 {
+		return &content, "", customerror.Trace("Send", missingPlaceholdersError(countPlaceholders(missing)))
+	}
+	normalized, err := whatsapp.NormalizeNumber(*stud.Phone, s.defaultCountryCode)
+	if err != nil {
+		return &content, "", customerror.Trace("Send", ErrPhoneInvalid)
+	}
+	return &content, "+" + normalized, nil
+}
+
+// formatSMSBody põe o assunto na primeira linha; SMS não tem formatação.
+func formatSMSBody(subject, body string, format BodyFormat) string {
+	body = format.plainBody(body)
+	subject = strings.TrimSpace(strings.ReplaceAll(subject, "\n", " "))
+	if subject == "" {
+		return body
+	}
+	return fmt.Sprintf("%s\n\n%s", subject, body)
+}
+
+func smsSenderDetails(instance *sms.Instance) senderDetails {
+	return senderDetails{
+		Type:          "SMS",
+		Provider:      "http",
+		Address:       instance.Sender,
+		SMSInstanceID: &instance.ID,
+	}
+}
+
+func (s *service) loadSmsInstance(ctx context.Context, userID, smsID string) (*sms.Instance, error) {
+	if smsID == "" {
+		return nil, customerror.Trace("Send", ErrSmsNotFound)
+	}
+	instance, err := s.smsRepository.FindByID(ctx, smsID)
+	if err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if instance == nil || instance.UserID != userID {
+		return nil, customerror.Trace("Send", ErrSmsNotFound)
+	}
+	return instance, nil
+}
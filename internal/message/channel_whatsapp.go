@@ -0,0 +1,269 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/markdown"
+)
+
+// whatsAppSender entrega pela Evolution API: o texto primeiro e os anexos em seguida, sem legenda.
+type whatsAppSender struct {
+	service *service
+	// numbers guarda a consulta de CheckDestinations para o Preview.
+	numbers map[string]whatsapp.NumberCheck
+}
+
+func (w *whatsAppSender) Channel() Channel { return ChannelWhatsApp }
+
+func (w *whatsAppSender) InstanceID(message *Message) string { return message.WhatsappId }
+
+func (w *whatsAppSender) SendsAttachments() bool { return true }
+
+func (w *whatsAppSender) Load(ctx context.Context, userID, instanceID string) (senderDetails, error) {
+	instance, err := w.service.loadWhatsAppInstance(ctx, userID, instanceID)
+	if err != nil {
+		return senderDetails{}, err
+	}
+	return whatsAppSenderDetails(instance), nil
+}
+
+// CheckCredentials recusa o envio por uma instância sabidamente desconectada, antes de enfileirar.
+func (w *whatsAppSender) CheckCredentials(ctx context.Context, message *Message) error {
+	instance, err := w.service.loadWhatsAppInstance(ctx, message.UserID, message.WhatsappId)
+	if err != nil {
+		return err
+	}
+	return w.service.ensureWhatsAppConnected(ctx, instance)
+}
+
+// CheckDestinations consulta de uma vez quais alunos têm WhatsApp; falhas de consulta deixam o Preview sem a verificação.
+func (w *whatsAppSender) CheckDestinations(ctx context.Context, message *Message, students []*student.Student) {
+	instance, err := w.service.loadWhatsAppInstance(ctx, message.UserID, message.WhatsappId)
+	if err != nil {
+		return
+	}
+	w.numbers = w.service.lookupWhatsAppNumbers(ctx, instance.InstanceName, students)
+}
+
+// Preview devolve o corpo já formatado: no WhatsApp o assunto vira o título em negrito do próprio corpo.
+func (w *whatsAppSender) Preview(message *Message, stud *student.Student, renderContext RenderContext) (*renderedMessage, string, error) {
+	content, number, err := w.service.prepareWhatsApp(message, stud, renderContext)
+	if content != nil {
+		content = &renderedMessage{Body: formatWhatsAppBody(content.Subject, content.Body, message.Format)}
+	}
+	if err == nil {
+		sendTo, err := whatsAppDestination(w.numbers, number)
+		if err != nil {
+			return content, number, err
+		}
+		number = sendTo
+	}
+	return content, number, err
+}
+
+// Deliver também devolve o ID da mensagem na Evolution de cada aluno, para casar os recibos do webhook.
+func (w *whatsAppSender) Deliver(ctx context.Context, message *Message, students []*student.Student, renderContext RenderContext, report progressReporter) channelDelivery {
+	sender := senderDetails{Type: "WHATSAPP", Provider: "evolution"}
+	waInstance, err := w.service.loadWhatsAppInstance(ctx, message.UserID, message.WhatsappId)
+	if err != nil {
+		return failAllDelivery(students, sender, err)
+	}
+	sender = whatsAppSenderDetails(waInstance)
+	if err := w.service.ensureWhatsAppConnected(ctx, waInstance); err != nil {
+		return failAllDelivery(students, sender, err)
+	}
+
+	attachments, _, err := buildWhatsAppAttachments(message)
+	if err == nil {
+		attachments, err = downloadWhatsAppAttachments(ctx, attachments)
+	}
+	if err != nil {
+		return failAllDelivery(students, sender, customerror.Trace("Deliver", err))
+	}
+
+	failures, rendered, providerIDs := w.service.sendWhats(ctx, waInstance, students, message, renderContext, attachments, report)
+	return channelDelivery{failures: failures, rendered: rendered, sender: sender, providerIDs: providerIDs}
+}
+
+// prepareWhatsApp renderiza a mensagem de WhatsApp de um aluno e normaliza o número de destino.
+func (s *service) prepareWhatsApp(message *Message, stud *student.Student, renderContext RenderContext) (*renderedMessage, string, error) {
+	if stud.Phone == nil || *stud.Phone == "" {
+		return nil, "", customerror.Trace("Send", ErrPhoneMissing)
+	}
+	renderContext.Student = stud
+	content, missing := renderWhatsApp(message.Subject, message.Body, message.Format, renderContext)
+	if len(missing) > 0 {
+		return &content, "", customerror.Trace("Send", missingPlaceholdersError(countPlaceholders(missing)))
+	}
+	normalized, err := whatsapp.NormalizeNumber(*stud.Phone, s.defaultCountryCode)
+	if err != nil {
+		return &content, "", customerror.Trace("Send", ErrPhoneInvalid)
+	}
+	return &content, normalized, nil
+}
+
+// formatWhatsAppBody põe o assunto como título em negrito; corpo em Markdown vira a marcação do WhatsApp.
+func formatWhatsAppBody(subject, body string, format BodyFormat) string {
+	if format.isMarkdown() {
+		body = markdown.ToWhatsApp(body)
+	}
+	subject = strings.TrimSpace(strings.ReplaceAll(subject, "\n", " "))
+	if subject == "" {
+		return body
+	}
+	return fmt.Sprintf("*%s*\n\n%s", subject, body)
+}
+
+func (s *service) sendWhats(ctx context.Context, waInstance *whatsapp.Instance, students []*student.Student, message *Message, renderContext RenderContext, attachments []Attachment, report progressReporter) (map[string]error, map[string]renderedMessage, map[string]string) {
+	failures := make(map[string]error)
+	rendered := make(map[string]renderedMessage, len(students))
+	providerIDs := make(map[string]string, len(students))
+	numbers := s.lookupWhatsAppNumbers(ctx, waInstance.InstanceName, students)
+
+	for _, stud := range students {
+		content, normalized, err := s.prepareWhatsApp(message, stud, renderContext)
+		if content != nil {
+			rendered[stud.ID] = *content
+		}
+		if err == nil {
+			normalized, err = whatsAppDestination(numbers, normalized)
+		}
+		if err != nil {
+			failures[stud.ID] = err
+			report(stud.ID, err)
+			continue
+		}
+		body := formatWhatsAppBody(content.Subject, content.Body, message.Format)
+
+		messageID, err := s.sendWhatsAppWithRetry(ctx, waInstance.InstanceName, normalized, body, 3, 1*time.Second)
+		if err != nil {
+			log.Printf("falha ao enviar whatsapp para %s: %v", *stud.Phone, err)
+			failures[stud.ID] = err
+			report(stud.ID, err)
+			continue
+		}
+		providerIDs[stud.ID] = messageID
+
+		for _, att := range attachments {
+			if len(att.Data) > 0 {
+				if _, err := s.evolution.SendMedia(ctx, waInstance.InstanceName, normalized, att.FileName, att.Data, ""); err != nil {
+					log.Printf("falha ao enviar anexo via whatsapp para %s: %v", *stud.Phone, err)
+					failures[stud.ID] = err
+					break
+				}
+				continue
+			}
+			// Anexos por URL já chegam baixados; sem conteúdo, o anexo é inválido.
+			log.Printf("falha ao enviar anexo via whatsapp para %s: anexo sem conteúdo", *stud.Phone)
+			failures[stud.ID] = customerror.Trace("Send", ErrInvalidAttachment)
+			break
+		}
+		report(stud.ID, failures[stud.ID])
+	}
+
+	return failures, rendered, providerIDs
+}
+
+// lookupWhatsAppNumbers consulta em lote quais telefones dos alunos têm WhatsApp. Se a consulta falhar,
+// devolve nil e o envio segue sem a verificação.
+func (s *service) lookupWhatsAppNumbers(ctx context.Context, instanceName string, students []*student.Student) map[string]whatsapp.NumberCheck {
+	numbers := make([]string, 0, len(students))
+	for _, stud := range students {
+		if stud.Phone == nil {
+			continue
+		}
+		if normalized, err := whatsapp.NormalizeNumber(*stud.Phone, s.defaultCountryCode); err == nil {
+			numbers = append(numbers, normalized)
+		}
+	}
+	if len(numbers) == 0 {
+		return nil
+	}
+	checks, err := s.evolution.CheckNumbers(ctx, instanceName, numbers)
+	if err != nil {
+		log.Printf("falha ao consultar números no whatsapp: %v", err)
+		return nil
+	}
+	return checks
+}
+
+// whatsAppDestination aplica a consulta ao número normalizado: devolve o número que recebe a mensagem,
+// que pode ser a variante com ou sem o nono dígito. Números fora da consulta seguem como estão.
+func whatsAppDestination(checks map[string]whatsapp.NumberCheck, normalized string) (string, error) {
+	check, ok := checks[normalized]
+	if !ok {
+		return normalized, nil
+	}
+	if !check.Exists {
+		return "", customerror.Trace("Send", ErrNotOnWhatsApp)
+	}
+	return check.SendTo, nil
+}
+
+func whatsAppSenderDetails(waInstance *whatsapp.Instance) senderDetails {
+	return senderDetails{
+		Type:               "WHATSAPP",
+		Provider:           "evolution",
+		Address:            waInstance.Phone,
+		WhatsAppInstanceID: &waInstance.ID,
+	}
+}
+
+// sendWhatsAppWithRetry encapsula retentativa simples para envio de WhatsApp.
+func (s *service) sendWhatsAppWithRetry(ctx context.Context, instanceID, number, body string, attempts int, delay time.Duration) (string, error) {
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		messageID, err := s.evolution.SendText(ctx, instanceID, number, body)
+		if err == nil {
+			return messageID, nil
+		}
+		lastErr = err
+		if i < attempts-1 {
+			time.Sleep(delay)
+		}
+	}
+	return "", lastErr
+}
+
+// ensureWhatsAppConnected falha rápido quando a instância está marcada como desconectada. O estado
+// gravado pode estar atrasado (o professor pode ter lido o QR depois da última consulta do monitor),
+// então a Evolution é consultada antes de recusar.
+func (s *service) ensureWhatsAppConnected(ctx context.Context, instance *whatsapp.Instance) error {
+	if instance.ConnectionStatus != whatsapp.ConnectionClose {
+		return nil
+	}
+	state, err := s.evolution.ConnectionState(ctx, instance.InstanceName)
+	if err != nil {
+		log.Printf("falha ao consultar estado da instância %s: %v", instance.ID, err)
+		return customerror.Trace("Send", ErrWhatsAppOffline)
+	}
+	if _, _, err := s.whatsAppRepository.RecordConnectionState(ctx, instance.ID, state, whatsapp.StateSourceSend, time.Now()); err != nil {
+		log.Printf("falha ao gravar estado da instância %s: %v", instance.ID, err)
+	}
+	instance.ConnectionStatus = state
+	if state == whatsapp.ConnectionClose {
+		return customerror.Trace("Send", ErrWhatsAppOffline)
+	}
+	return nil
+}
+
+func (s *service) loadWhatsAppInstance(ctx context.Context, userID, whatsappID string) (*whatsapp.Instance, error) {
+	if whatsappID == "" {
+		return nil, customerror.Trace("Send", ErrWhatsAppNotFound)
+	}
+	instance, err := s.whatsAppRepository.FindByID(ctx, whatsappID)
+	if err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if instance == nil || instance.UserID != userID {
+		return nil, customerror.Trace("Send", ErrWhatsAppNotFound)
+	}
+	return instance, nil
+}
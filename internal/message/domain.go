@@ -1,6 +1,14 @@
 package message
 
+import (
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+)
+
 type Attachment struct {
+	// ID referencia um arquivo enviado antes em /attachment; sem fileName, vale o nome do upload.
+	ID       string `json:"id,omitempty"`
 	FileName string `json:"fileName"`
 	Data     []byte `json:"data,omitempty"`
 	URL      string `json:"url,omitempty"`
@@ -9,24 +17,65 @@ type Attachment struct {
 type Message struct {
 	UserID      string        `json:"-"`
 	Jwe         string        `json:"jwe"`
-	To          []string      `json:"to" binding:"required"`
+	To          []string      `json:"to"`
 	From        string        `json:"from"`
-	Subject     string        `json:"subject" binding:"required"`
+	Subject     string        `json:"subject"`
 	WhatsappId  string        `json:"whatsapp_id"`
-	Body        string        `json:"body" binding:"required"`
+	Body        string        `json:"body"`
 	Attachments *[]Attachment `json:"attachments"`
 	SmtpId      string        `json:"smtp_id"`
+	SmsId       string        `json:"sms_id"`
+	// TemplateID preenche assunto e corpo não informados com os do template salvo.
+	TemplateID string `json:"template_id"`
+	// DisciplineID fornece o valor de {{discipline}}.
+	DisciplineID string `json:"discipline_id"`
+	// RecipientFilter e FilterID (filtro salvo) são expandidos em alunos e somados a To.
+	student.RecipientFilter
+	FilterID string `json:"filter_id"`
+	// Fallback (opcional) envia cada aluno por um canal principal e redireciona as falhas para o outro.
+	Fallback FallbackPolicy `json:"fallback"`
+	// Format indica como o corpo é escrito: texto puro (padrão) ou Markdown.
+	Format BodyFormat `json:"format"`
+	// IdempotencyKey vem do header Idempotency-Key; vazio desativa a deduplicação.
+	IdempotencyKey string `json:"-"`
+	// JweReentryRequired vem de jobs e agendamentos cujo JWE foi descartado por uma redefinição de senha.
+	JweReentryRequired bool `json:"-"`
 }
 
 type MessageInput struct {
-	Jwe         string        `json:"jwe"`
-	SmtpId      string        `json:"smtp_id"`
-	WhatsappId  string        `json:"whatsapp_id"`
-	Subject     string        `json:"subject" binding:"required"`
-	Body        string        `json:"body" binding:"required"`
-	To          []string      `json:"to" binding:"required"`
-	From        string        `json:"from"`
-	Attachments *[]Attachment `json:"attachments"`
+	Jwe        string `json:"jwe"`
+	SmtpId     string `json:"smtp_id"`
+	WhatsappId string `json:"whatsapp_id"`
+	SmsId      string `json:"sms_id"`
+	// Subject e Body são obrigatórios quando template_id não é informado.
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	// Destinatários: alunos em To e/ou os alcançados por discipline_ids, program_ids, campus_ids e filter_id.
+	// statuses restringe todos os destinatários (ex.: apenas ACTIVE).
+	To            []string                `json:"to"`
+	From          string                  `json:"from"`
+	Attachments   *[]Attachment           `json:"attachments"`
+	TemplateID    string                  `json:"template_id"`
+	DisciplineID  string                  `json:"discipline_id"`
+	DisciplineIDs []string                `json:"discipline_ids" binding:"omitempty,dive,uuid"`
+	ProgramIDs    []string                `json:"program_ids" binding:"omitempty,dive,uuid"`
+	CampusIDs     []string                `json:"campus_ids" binding:"omitempty,dive,uuid"`
+	Statuses      []student.StudentStatus `json:"statuses" binding:"omitempty,dive,oneof=ACTIVE CANCELED GRADUATED LOCKED PENDING"`
+	FilterID      string                  `json:"filter_id" binding:"omitempty,uuid"`
+	// Fallback: WHATSAPP_FIRST, EMAIL_FIRST ou EMAIL_IF_NO_PHONE. Exige smtp_id e whatsapp_id.
+	Fallback FallbackPolicy `json:"fallback" binding:"omitempty,oneof=WHATSAPP_FIRST EMAIL_FIRST EMAIL_IF_NO_PHONE"`
+	// Format: TEXT (padrão) ou MARKDOWN. Em Markdown o email sai em HTML e o WhatsApp com a marcação dele.
+	Format BodyFormat `json:"format" binding:"omitempty,oneof=TEXT MARKDOWN"`
+}
+
+// RecipientFilter reúne os alvos do corpo da requisição no filtro usado para expandir os alunos.
+func (input MessageInput) RecipientFilter() student.RecipientFilter {
+	return student.RecipientFilter{
+		DisciplineIDs: input.DisciplineIDs,
+		ProgramIDs:    input.ProgramIDs,
+		CampusIDs:     input.CampusIDs,
+		Statuses:      input.Statuses,
+	}
 }
 
 type FailedRecipient struct {
@@ -34,7 +83,68 @@ type FailedRecipient struct {
 	StudentID string `json:"studentId"`
 }
 
-type MessageDataResponse struct {
+type SendResponse struct {
+	JobID    string `json:"jobId"`
+	Students int    `json:"students"`
+	// Replayed indica que o Idempotency-Key já tinha sido usado e o job devolvido é o do primeiro pedido.
+	Replayed bool `json:"replayed"`
+}
+
+type JobCounts struct {
+	Pending    int `json:"pending"`
+	Processing int `json:"processing"`
+	Sent       int `json:"sent"`
+	Failed     int `json:"failed"`
+}
+
+type JobResponse struct {
+	ID             string            `json:"id"`
+	Status         JobStatus         `json:"status"`
+	Subject        string            `json:"subject"`
+	Counts         JobCounts         `json:"counts"`
 	EmailsFailed   []FailedRecipient `json:"emailsFailed"`
 	WhatsappFailed []FailedRecipient `json:"whatsappFailed"`
+	SmsFailed      []FailedRecipient `json:"smsFailed"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	CompletedAt    *time.Time        `json:"completedAt,omitempty"`
+}
+
+// PreviewResponse antecipa o resultado de um envio sem entregar nada.
+// Ready indica se /message/send aceitaria o pedido; Problems lista o que o impediria.
+type PreviewResponse struct {
+	Ready         bool                `json:"ready"`
+	Problems      []string            `json:"problems"`
+	Students      int                 `json:"students"`
+	EmailCount    int                 `json:"emailCount"`
+	WhatsAppCount int                 `json:"whatsappCount"`
+	SMSCount      int                 `json:"smsCount"`
+	Attachments   []AttachmentPreview `json:"attachments"`
+	Recipients    []RecipientPreview  `json:"recipients"`
+}
+
+type AttachmentPreview struct {
+	FileName string `json:"fileName"`
+	Error    string `json:"error,omitempty"`
+}
+
+type RecipientPreview struct {
+	ID        string          `json:"id"`
+	StudentID string          `json:"studentId"`
+	Name      *string         `json:"name"`
+	Email     *ChannelPreview `json:"email,omitempty"`
+	WhatsApp  *ChannelPreview `json:"whatsapp,omitempty"`
+	SMS       *ChannelPreview `json:"sms,omitempty"`
+}
+
+// ChannelPreview é a previsão de um canal para um aluno: o conteúdo renderizado ou o motivo de ser ignorado.
+type ChannelPreview struct {
+	WillSend bool   `json:"willSend"`
+	To       string `json:"to,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Body     string `json:"body,omitempty"`
+	// HTML é o corpo do email como será enviado, quando o email sai em HTML.
+	HTML string `json:"html,omitempty"`
+	// Fallback indica que o canal só será usado se o canal principal do aluno falhar.
+	Fallback bool `json:"fallback,omitempty"`
 }
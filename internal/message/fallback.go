@@ -0,0 +1,89 @@
+package message
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+// FallbackPolicy escolhe um canal principal por aluno e, quando aplicável, o canal usado se o principal falhar.
+// Sem política, cada aluno recebe por todos os canais configurados.
+type FallbackPolicy string
+
+const (
+	// FallbackWhatsAppFirst envia por WhatsApp; email para quem não tem telefone ou quando o WhatsApp falha.
+	FallbackWhatsAppFirst FallbackPolicy = "WHATSAPP_FIRST"
+	// FallbackEmailFirst envia por email; WhatsApp para quem não tem email ou quando o email falha.
+	FallbackEmailFirst FallbackPolicy = "EMAIL_FIRST"
+	// FallbackEmailIfNoPhone envia por WhatsApp e por email apenas para quem não tem telefone, sem redirecionar falhas.
+	FallbackEmailIfNoPhone FallbackPolicy = "EMAIL_IF_NO_PHONE"
+)
+
+var ErrFallbackNeedsBothChannels = customerror.Make("a política de fallback exige smtp_id e whatsapp_id", http.StatusBadRequest, errors.New("ErrFallbackNeedsBothChannels"))
+
+// validateFallback exige email e WhatsApp: a política só redistribui alunos entre eles.
+func validateFallback(policy FallbackPolicy, senders map[Channel]senderDetails) error {
+	_, hasEmail := senders[ChannelEmail]
+	_, hasWhatsApp := senders[ChannelWhatsApp]
+	if policy == "" || (hasEmail && hasWhatsApp) {
+		return nil
+	}
+	return ErrFallbackNeedsBothChannels
+}
+
+// routes indica se a política decide quem recebe pelo canal; canais fora dela (SMS) recebem todos os alunos.
+func (p FallbackPolicy) routes(channel Channel) bool {
+	return p != "" && (channel == ChannelEmail || channel == ChannelWhatsApp)
+}
+
+// primaryChannel é o primeiro canal tentado para o aluno dentro da política.
+func (p FallbackPolicy) primaryChannel(stud *student.Student) Channel {
+	switch p {
+	case FallbackEmailFirst:
+		if hasContact(stud.Email) {
+			return ChannelEmail
+		}
+		return ChannelWhatsApp
+	default:
+		if !stud.NoPhone && hasContact(stud.Phone) {
+			return ChannelWhatsApp
+		}
+		return ChannelEmail
+	}
+}
+
+// fallbackFor devolve o canal que recebe o aluno quando a entrega em channel falha definitivamente.
+func (p FallbackPolicy) fallbackFor(channel Channel) (Channel, bool) {
+	switch {
+	case p == FallbackWhatsAppFirst && channel == ChannelWhatsApp:
+		return ChannelEmail, true
+	case p == FallbackEmailFirst && channel == ChannelEmail:
+		return ChannelWhatsApp, true
+	}
+	return "", false
+}
+
+func hasContact(value *string) bool {
+	return value != nil && strings.TrimSpace(*value) != ""
+}
+
+// routeRecipients distribui os alunos entre os canais escolhidos: todos em cada canal sem política;
+// com política, email e WhatsApp recebem apenas os alunos que os têm como canal principal.
+func routeRecipients(policy FallbackPolicy, students []*student.Student, channels []Channel) map[Channel][]string {
+	recipients := map[Channel][]string{}
+	for _, stud := range students {
+		if policy != "" {
+			channel := policy.primaryChannel(stud)
+			recipients[channel] = append(recipients[channel], stud.ID)
+		}
+		for _, channel := range channels {
+			if !policy.routes(channel) {
+				recipients[channel] = append(recipients[channel], stud.ID)
+			}
+		}
+	}
+	return recipients
+}
@@ -0,0 +1,58 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteRecipientsSendsEveryChannelWithoutPolicy(t *testing.T) {
+	students := []*student.Student{{ID: "s1", Phone: strPtr("+5511"), Email: strPtr("a@b.c")}, {ID: "s2", NoPhone: true}}
+
+	recipients := routeRecipients("", students, []Channel{ChannelEmail, ChannelWhatsApp})
+
+	assert.Equal(t, []string{"s1", "s2"}, recipients[ChannelEmail])
+	assert.Equal(t, []string{"s1", "s2"}, recipients[ChannelWhatsApp])
+}
+
+func TestRouteRecipientsUsesPrimaryChannelPerStudent(t *testing.T) {
+	students := []*student.Student{
+		{ID: "s1", Phone: strPtr("+5511"), Email: strPtr("a@b.c")},
+		{ID: "s2", NoPhone: true, Phone: strPtr("+5512"), Email: strPtr("b@b.c")},
+		{ID: "s3", Phone: strPtr("+5513")},
+	}
+
+	whatsFirst := routeRecipients(FallbackWhatsAppFirst, students, []Channel{ChannelEmail, ChannelWhatsApp, ChannelSMS})
+	assert.Equal(t, []string{"s1", "s3"}, whatsFirst[ChannelWhatsApp])
+	assert.Equal(t, []string{"s2"}, whatsFirst[ChannelEmail])
+	assert.Equal(t, []string{"s1", "s2", "s3"}, whatsFirst[ChannelSMS], "SMS fica fora da política")
+
+	emailFirst := routeRecipients(FallbackEmailFirst, students, []Channel{ChannelEmail, ChannelWhatsApp})
+	assert.Equal(t, []string{"s1", "s2"}, emailFirst[ChannelEmail])
+	assert.Equal(t, []string{"s3"}, emailFirst[ChannelWhatsApp])
+}
+
+func TestFallbackForOnlyRedirectsThePrimaryChannel(t *testing.T) {
+	channel, ok := FallbackWhatsAppFirst.fallbackFor(ChannelWhatsApp)
+	assert.True(t, ok)
+	assert.Equal(t, ChannelEmail, channel)
+
+	channel, ok = FallbackEmailFirst.fallbackFor(ChannelEmail)
+	assert.True(t, ok)
+	assert.Equal(t, ChannelWhatsApp, channel)
+
+	_, ok = FallbackWhatsAppFirst.fallbackFor(ChannelEmail)
+	assert.False(t, ok)
+	_, ok = FallbackEmailIfNoPhone.fallbackFor(ChannelWhatsApp)
+	assert.False(t, ok)
+}
+
+func TestValidateFallbackRequiresBothChannels(t *testing.T) {
+	both := map[Channel]senderDetails{ChannelEmail: {}, ChannelWhatsApp: {}}
+	emailAndSMS := map[Channel]senderDetails{ChannelEmail: {}, ChannelSMS: {}}
+
+	assert.NoError(t, validateFallback("", emailAndSMS))
+	assert.NoError(t, validateFallback(FallbackEmailFirst, both))
+	assert.ErrorIs(t, validateFallback(FallbackEmailFirst, emailAndSMS), ErrFallbackNeedsBothChannels)
+}
@@ -0,0 +1,60 @@
+package message
+
+import (
+	"html"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/markdown"
+)
+
+// BodyFormat indica como o professor escreveu o corpo da mensagem.
+type BodyFormat string
+
+const (
+	// BodyFormatText é o texto enviado como está; é o padrão quando o formato não é informado.
+	BodyFormatText BodyFormat = "TEXT"
+	// BodyFormatMarkdown vira HTML no email, marcação do WhatsApp no WhatsApp e texto puro no SMS.
+	BodyFormatMarkdown BodyFormat = "MARKDOWN"
+)
+
+func (f BodyFormat) isMarkdown() bool {
+	return f == BodyFormatMarkdown
+}
+
+// escapeBody protege os valores dos placeholders: em Markdown, o dado do aluno não pode virar marcação.
+func (f BodyFormat) escapeBody(escape func(string) string) func(string) string {
+	if !f.isMarkdown() {
+		return escape
+	}
+	return func(value string) string {
+		if escape != nil {
+			value = escape(value)
+		}
+		return markdown.Escape(value)
+	}
+}
+
+// plainBody devolve o corpo sem marcação: usado no SMS e como alternativa em texto do email HTML.
+func (f BodyFormat) plainBody(body string) string {
+	if f.isMarkdown() {
+		return markdown.ToText(body)
+	}
+	return body
+}
+
+// emailHTML devolve o HTML do email, ou "" quando ele sai em texto puro (texto sem layout institucional).
+func emailHTML(format BodyFormat, content renderedMessage, layout *EmailLayout) string {
+	var fragment string
+	switch {
+	case format.isMarkdown():
+		fragment = markdown.ToHTML(content.Body)
+	case layout != nil:
+		fragment = "<p>" + strings.ReplaceAll(html.EscapeString(content.Body), "\n", "<br>\n") + "</p>"
+	default:
+		return ""
+	}
+	if layout == nil {
+		return fragment
+	}
+	return layout.wrap(content.Subject, fragment)
+}
@@ -1,6 +1,8 @@
 package message
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/ThalysSilva/unicast-backend/internal/student"
@@ -16,6 +18,10 @@ type handler struct {
 
 type Handler interface {
 	Send() gin.HandlerFunc
+	Preview() gin.HandlerFunc
+	GetJob() gin.HandlerFunc
+	Retry() gin.HandlerFunc
+	Events() gin.HandlerFunc
 }
 
 func NewHandler(service Service) Handler {
@@ -23,14 +29,19 @@ func NewHandler(service Service) Handler {
 }
 
 // @Summary Envia uma mensagem
-// @Description Envia uma mensagem via email e WhatsApp
+// @Description Enfileira uma mensagem para envio via email e WhatsApp. A entrega é feita em segundo plano; acompanhe pelo job retornado.
+// @Description Os destinatários podem vir de to, discipline_ids, program_ids, campus_ids ou filter_id; students informa quantos alunos foram resolvidos.
 // @OperationId sendMessage
 // @Tags message
 // @Accept json
 // @Produce json
+// @Description Com o header Idempotency-Key, repetir o pedido em até 24h devolve o mesmo job (replayed=true) em vez de enviar de novo.
 // @Param message body MessageInput true "Message data"
-// @Success 200 {object} api.DefaultResponse[MessageDataResponse]
+// @Param Idempotency-Key header string false "Chave única do envio, gerada pelo cliente"
+// @Success 202 {object} api.DefaultResponse[SendResponse]
 // @Failure 400 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Failure 422 {object} api.ErrorResponse
 // @Router /message/send [post]
 // Send handles the sending of messages via email and WhatsApp
 func (h *handler) Send() gin.HandlerFunc {
@@ -42,31 +53,113 @@ func (h *handler) Send() gin.HandlerFunc {
 			return
 		}
 
-		emailsFailed, whatsappFailed, err := h.service.Send(c.Request.Context(), &Message{
-			UserID:      userID,
-			Jwe:         input.Jwe,
-			To:          input.To,
-			From:        input.From,
-			Subject:     input.Subject,
-			WhatsappId:  input.WhatsappId,
-			Body:        input.Body,
-			Attachments: input.Attachments,
-			SmtpId:      input.SmtpId,
-		})
+		message := input.toMessage(userID)
+		message.IdempotencyKey = c.GetHeader(IdempotencyKeyHeader)
+		job, err := h.service.Send(c.Request.Context(), message)
 		if err != nil {
 			customerror.HandleResponse(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, api.DefaultResponse[MessageDataResponse]{
-			Message: "Mensagem enviada com sucesso",
-			Data: MessageDataResponse{
-				EmailsFailed:   failedRecipients(*emailsFailed),
-				WhatsappFailed: failedRecipients(*whatsappFailed),
+		c.JSON(http.StatusAccepted, api.DefaultResponse[SendResponse]{
+			Message: "Mensagem enfileirada para envio",
+			Data: SendResponse{
+				JobID:    job.ID,
+				Students: job.StudentCount,
+				Replayed: job.Replayed,
 			},
 		})
 	}
 }
 
+// @Summary Simula um envio
+// @Description Executa as validações de /message/send sem enfileirar nem entregar. Retorna, por aluno e canal, o conteúdo renderizado ou o motivo de o aluno ser ignorado, e em problems o que faria o envio ser recusado.
+// @OperationId previewMessage
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param message body MessageInput true "Message data"
+// @Success 200 {object} api.DefaultResponse[PreviewResponse]
+// @Failure 400 {object} api.ErrorResponse
+// @Router /message/preview [post]
+func (h *handler) Preview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input MessageInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+
+		preview, err := h.service.Preview(c.Request.Context(), input.toMessage(c.GetString("userID")))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[PreviewResponse]{
+			Message: "Prévia do envio gerada",
+			Data:    *preview,
+		})
+	}
+}
+
+// @Summary Consulta um envio
+// @Description Retorna o andamento de um envio enfileirado e os destinatários que falharam definitivamente
+// @OperationId getMessageJob
+// @Tags message
+// @Produce json
+// @Param id path string true "ID do job"
+// @Success 200 {object} api.DefaultResponse[JobResponse]
+// @Failure 404 {object} api.ErrorResponse
+// @Router /message/job/{id} [get]
+func (h *handler) GetJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		summary, err := h.service.GetJob(c.Request.Context(), userID, c.Param("id"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[JobResponse]{
+			Message: "Envio encontrado",
+			Data:    jobResponse(summary),
+		})
+	}
+}
+
+func (input MessageInput) toMessage(userID string) *Message {
+	return &Message{
+		UserID:          userID,
+		Jwe:             input.Jwe,
+		To:              input.To,
+		From:            input.From,
+		Subject:         input.Subject,
+		WhatsappId:      input.WhatsappId,
+		Body:            input.Body,
+		Attachments:     input.Attachments,
+		SmtpId:          input.SmtpId,
+		SmsId:           input.SmsId,
+		TemplateID:      input.TemplateID,
+		DisciplineID:    input.DisciplineID,
+		RecipientFilter: input.RecipientFilter(),
+		FilterID:        input.FilterID,
+		Fallback:        input.Fallback,
+		Format:          input.Format,
+	}
+}
+
+func jobResponse(summary *JobSummary) JobResponse {
+	return JobResponse{
+		ID:             summary.Job.ID,
+		Status:         summary.Job.Status,
+		Subject:        summary.Job.Subject,
+		Counts:         summary.counts(),
+		EmailsFailed:   failedRecipients(summary.EmailsFailed),
+		WhatsappFailed: failedRecipients(summary.WhatsappFailed),
+		SmsFailed:      failedRecipients(summary.SmsFailed),
+		CreatedAt:      summary.Job.CreatedAt,
+		CompletedAt:    summary.Job.CompletedAt,
+	}
+}
+
 func failedRecipients(students []student.Student) []FailedRecipient {
 	recipients := make([]FailedRecipient, 0, len(students))
 	for _, student := range students {
@@ -77,3 +170,46 @@ func failedRecipients(students []student.Student) []FailedRecipient {
 	}
 	return recipients
 }
+
+// @Summary Reenvia as falhas de um envio
+// @Description Enfileira novamente o assunto, o corpo e os anexos do envio apenas para os alunos que falharam, e só no canal em que falharam. Os logs do reenvio apontam para o envio original em retryOf. O fallback do envio original não é aplicado.
+// @Description Devolve 409 enquanto o envio original ou um reenvio anterior ainda estiver em andamento.
+// @Description O jwe é necessário quando houver falhas de email por SMTP com senha.
+// @OperationId retryMessageHistory
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param deliveryGroupId path string true "ID do envio (delivery_group_id)"
+// @Param body body RetryInput false "JWE para as credenciais SMTP"
+// @Success 202 {object} api.DefaultResponse[SendResponse]
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /message/history/{deliveryGroupId}/retry [post]
+func (h *handler) Retry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var uri deliveryGroupURI
+		if err := c.ShouldBindUri(&uri); err != nil {
+			c.Error(err)
+			return
+		}
+		var input RetryInput
+		if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+			c.Error(err)
+			return
+		}
+
+		job, err := h.service.Retry(c.Request.Context(), c.GetString("userID"), uri.ID, input.Jwe)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, api.DefaultResponse[SendResponse]{
+			Message: "Reenvio enfileirado",
+			Data: SendResponse{
+				JobID:    job.ID,
+				Students: job.StudentCount,
+			},
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package message
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 
 	"github.com/ThalysSilva/unicast-backend/internal/student"
@@ -32,3 +34,25 @@ func TestFailedRecipientsOmitsContactData(t *testing.T) {
 		t.Fatalf("recipient = %+v", recipients[0])
 	}
 }
+
+func TestMessageInputReadsSnakeCaseRecipientTargets(t *testing.T) {
+	body := `{"smtp_id":"smtp-1","discipline_ids":["d1"],"program_ids":["p1"],"campus_ids":["c1"],"statuses":["ACTIVE"],"filter_id":"f1"}`
+
+	var input MessageInput
+	if err := json.Unmarshal([]byte(body), &input); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	want := student.RecipientFilter{
+		DisciplineIDs: []string{"d1"},
+		ProgramIDs:    []string{"p1"},
+		CampusIDs:     []string{"c1"},
+		Statuses:      []student.StudentStatus{student.StudentStatusActive},
+	}
+	if got := input.toMessage("user-1").RecipientFilter; !reflect.DeepEqual(got, want) {
+		t.Fatalf("filter = %+v, want %+v", got, want)
+	}
+	if input.FilterID != "f1" {
+		t.Fatalf("filter_id = %q", input.FilterID)
+	}
+}
@@ -0,0 +1,83 @@
+package message
+
+import "time"
+
+// HistoryFilter filtra o histórico de envios. Canal, período e disciplina filtram as entregas de cada envio;
+// Success filtra envios inteiros: false traz envios com alguma falha, true traz envios sem falhas.
+type HistoryFilter struct {
+	From         *time.Time
+	To           *time.Time
+	DisciplineID string
+	Channel      Channel
+	Success      *bool
+	Page         int
+	PageSize     int
+}
+
+type HistoryQuery struct {
+	// From e To (RFC 3339) delimitam a data das entregas: from inclusivo, to exclusivo.
+	From *time.Time `form:"from"`
+	To   *time.Time `form:"to"`
+	// DisciplineID restringe às entregas para alunos matriculados na disciplina.
+	DisciplineID string `form:"disciplineId" binding:"omitempty,uuid"`
+	Channel      string `form:"channel" binding:"omitempty,oneof=EMAIL WHATSAPP SMS"`
+	Success      *bool  `form:"success"`
+	Page         int    `form:"page" binding:"omitempty,min=1"`
+	PageSize     int    `form:"pageSize" binding:"omitempty,min=1,max=100"`
+}
+
+// DeliveryGroup resume um envio (delivery_group_id) a partir dos logs de entrega.
+type DeliveryGroup struct {
+	ID       string    `json:"id"`
+	Subject  *string   `json:"subject"`
+	Channels []Channel `json:"channels"`
+	Students int       `json:"students"`
+	Sent     int       `json:"sent"`
+	Failed   int       `json:"failed"`
+	// Delivered e Read contam os recibos do WhatsApp; Delivered inclui as mensagens lidas.
+	Delivered       int     `json:"delivered"`
+	Read            int     `json:"read"`
+	AttachmentNames *string `json:"attachmentNames"`
+	// RetryOf aponta para o envio original quando este envio reenviou as falhas dele.
+	RetryOf *string   `json:"retryOf,omitempty"`
+	FirstAt time.Time `json:"firstAt"`
+	LastAt  time.Time `json:"lastAt"`
+}
+
+type HistoryPage struct {
+	Items    []DeliveryGroup `json:"items"`
+	Total    int             `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"pageSize"`
+}
+
+// DeliveryRecipient é o resultado da entrega de um envio para um aluno em um canal.
+type DeliveryRecipient struct {
+	ID        string  `json:"id"`
+	StudentID string  `json:"studentId"`
+	Name      *string `json:"name"`
+	Channel   Channel `json:"channel"`
+	Success   bool    `json:"success"`
+	// Status segue os recibos do WhatsApp (SENT, DELIVERED, READ ou FAILED); email fica em SENT ou FAILED.
+	Status          DeliveryStatus `json:"status"`
+	StatusUpdatedAt *time.Time     `json:"statusUpdatedAt,omitempty"`
+	// FallbackFrom indica o canal que falhou antes de o aluno ser redirecionado para este.
+	FallbackFrom   *Channel  `json:"fallbackFrom,omitempty"`
+	ErrorText      *string   `json:"errorText"`
+	SenderType     *string   `json:"senderType"`
+	SenderProvider *string   `json:"senderProvider"`
+	SenderAddress  *string   `json:"senderAddress"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// DeliveryGroupDetail traz o conteúdo do envio e o resultado de cada destinatário.
+// JobStatus só existe para envios feitos pelo outbox; destinatários ainda na fila aparecem em /message/job/{id}.
+type DeliveryGroupDetail struct {
+	DeliveryGroup
+	Body            *string             `json:"body"`
+	AttachmentCount int                 `json:"attachmentCount"`
+	SmtpID          *string             `json:"smtp_id,omitempty"`
+	WhatsappID      *string             `json:"whatsapp_id,omitempty"`
+	JobStatus       *JobStatus          `json:"jobStatus,omitempty"`
+	Recipients      []DeliveryRecipient `json:"recipients"`
+}
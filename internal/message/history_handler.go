@@ -0,0 +1,91 @@
+package message
+
+import (
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+
+	"github.com/gin-gonic/gin"
+)
+
+type historyHandler struct {
+	service HistoryService
+}
+
+type HistoryHandler interface {
+	List() gin.HandlerFunc
+	Get() gin.HandlerFunc
+}
+
+func NewHistoryHandler(service HistoryService) HistoryHandler {
+	return &historyHandler{service: service}
+}
+
+type deliveryGroupURI struct {
+	ID string `uri:"deliveryGroupId" binding:"required,uuid"`
+}
+
+// @Summary Lista o histórico de envios
+// @Description Envios agrupados por delivery_group_id, do mais recente ao mais antigo. channel e from/to filtram as entregas e disciplineId filtra pela disciplina do envio; success=false traz envios com alguma falha e success=true envios sem falhas.
+// @OperationId listMessageHistory
+// @Tags message
+// @Produce json
+// @Param from query string false "Início do período (RFC 3339, inclusivo)"
+// @Param to query string false "Fim do período (RFC 3339, exclusivo)"
+// @Param disciplineId query string false "ID da disciplina"
+// @Param channel query string false "EMAIL, WHATSAPP ou SMS"
+// @Param success query bool false "Filtra envios com (false) ou sem (true) falhas"
+// @Param page query int false "Página (padrão 1)"
+// @Param pageSize query int false "Itens por página (padrão 20, máximo 100)"
+// @Success 200 {object} api.DefaultResponse[HistoryPage]
+// @Failure 400 {object} api.ErrorResponse
+// @Router /message/history [get]
+func (h *historyHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var query HistoryQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.Error(err)
+			return
+		}
+		page, err := h.service.List(c.Request.Context(), c.GetString("userID"), HistoryFilter{
+			From:         query.From,
+			To:           query.To,
+			DisciplineID: query.DisciplineID,
+			Channel:      Channel(query.Channel),
+			Success:      query.Success,
+			Page:         query.Page,
+			PageSize:     query.PageSize,
+		})
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[HistoryPage]{Message: "Histórico de envios listado com sucesso", Data: *page})
+	}
+}
+
+// @Summary Detalha um envio do histórico
+// @Description Retorna o conteúdo do envio e o resultado de cada destinatário por canal, com o texto de erro das falhas
+// @OperationId getMessageHistory
+// @Tags message
+// @Produce json
+// @Param deliveryGroupId path string true "ID do envio (delivery_group_id)"
+// @Success 200 {object} api.DefaultResponse[DeliveryGroupDetail]
+// @Failure 404 {object} api.ErrorResponse
+// @Router /message/history/{deliveryGroupId} [get]
+func (h *historyHandler) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var uri deliveryGroupURI
+		if err := c.ShouldBindUri(&uri); err != nil {
+			c.Error(err)
+			return
+		}
+		detail, err := h.service.Get(c.Request.Context(), c.GetString("userID"), uri.ID)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[DeliveryGroupDetail]{Message: "Envio encontrado", Data: *detail})
+	}
+}
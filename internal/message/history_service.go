@@ -0,0 +1,69 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+const (
+	defaultHistoryPageSize = 20
+	maxHistoryPageSize     = 100
+)
+
+var (
+	ErrDeliveryNotFound = customerror.Make("envio não encontrado no histórico", http.StatusNotFound, errors.New("ErrDeliveryNotFound"))
+	ErrInvalidPeriod    = customerror.Make("período inválido: from deve ser anterior a to", http.StatusBadRequest, errors.New("ErrInvalidPeriod"))
+)
+
+type HistoryService interface {
+	List(ctx context.Context, userID string, filter HistoryFilter) (*HistoryPage, error)
+	Get(ctx context.Context, userID, deliveryGroupID string) (*DeliveryGroupDetail, error)
+}
+
+type historyService struct {
+	logRepository LogRepository
+}
+
+func NewHistoryService(logRepository LogRepository) HistoryService {
+	return &historyService{logRepository: logRepository}
+}
+
+func (s *historyService) List(ctx context.Context, userID string, filter HistoryFilter) (*HistoryPage, error) {
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, customerror.Trace("ListHistory", ErrInvalidPeriod)
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = defaultHistoryPageSize
+	}
+	if filter.PageSize > maxHistoryPageSize {
+		filter.PageSize = maxHistoryPageSize
+	}
+
+	groups, total, err := s.logRepository.ListGroups(ctx, userID, filter)
+	if err != nil {
+		return nil, customerror.Trace("ListHistory", err)
+	}
+	return &HistoryPage{
+		Items:    groups,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	}, nil
+}
+
+func (s *historyService) Get(ctx context.Context, userID, deliveryGroupID string) (*DeliveryGroupDetail, error) {
+	detail, err := s.logRepository.FindGroup(ctx, userID, deliveryGroupID)
+	if err != nil {
+		return nil, customerror.Trace("GetHistory", err)
+	}
+	if detail == nil {
+		return nil, customerror.Trace("GetHistory", ErrDeliveryNotFound)
+	}
+	return detail, nil
+}
@@ -0,0 +1,96 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHistoryRepository struct {
+	LogRepository
+	gotFilter HistoryFilter
+	groups    map[string]*DeliveryGroupDetail
+}
+
+func (f *fakeHistoryRepository) ListGroups(ctx context.Context, userID string, filter HistoryFilter) ([]DeliveryGroup, int, error) {
+	f.gotFilter = filter
+	return []DeliveryGroup{{ID: "group-1"}}, 41, nil
+}
+
+func (f *fakeHistoryRepository) FindGroup(ctx context.Context, userID, groupID string) (*DeliveryGroupDetail, error) {
+	return f.groups[userID+"/"+groupID], nil
+}
+
+func TestHistoryListAppliesPaginationDefaultsAndLimits(t *testing.T) {
+	repo := &fakeHistoryRepository{}
+	svc := NewHistoryService(repo)
+
+	page, err := svc.List(context.Background(), "user-1", HistoryFilter{Channel: ChannelEmail})
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.gotFilter.Page)
+	assert.Equal(t, defaultHistoryPageSize, repo.gotFilter.PageSize)
+	assert.Equal(t, ChannelEmail, repo.gotFilter.Channel)
+	assert.Equal(t, 41, page.Total)
+	assert.Len(t, page.Items, 1)
+
+	_, err = svc.List(context.Background(), "user-1", HistoryFilter{Page: 3, PageSize: 500})
+	require.NoError(t, err)
+	assert.Equal(t, 3, repo.gotFilter.Page)
+	assert.Equal(t, maxHistoryPageSize, repo.gotFilter.PageSize)
+}
+
+func TestHistoryListRejectsInvertedPeriod(t *testing.T) {
+	from := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+
+	_, err := NewHistoryService(&fakeHistoryRepository{}).List(context.Background(), "user-1", HistoryFilter{From: &from, To: &to})
+
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+}
+
+func TestHistoryGetHidesOtherUsersDeliveries(t *testing.T) {
+	repo := &fakeHistoryRepository{groups: map[string]*DeliveryGroupDetail{
+		"user-1/group-1": {DeliveryGroup: DeliveryGroup{ID: "group-1"}},
+	}}
+	svc := NewHistoryService(repo)
+
+	detail, err := svc.Get(context.Background(), "user-1", "group-1")
+	require.NoError(t, err)
+	assert.Equal(t, "group-1", detail.ID)
+
+	_, err = svc.Get(context.Background(), "user-2", "group-1")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+}
+
+// recordingDB guarda a consulta montada pelo repositório; a falha interrompe a leitura das linhas.
+type recordingDB struct {
+	database.DB
+	query string
+	args  []any
+}
+
+func (d *recordingDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	d.query = query
+	d.args = args
+	return nil, errors.New("sem banco")
+}
+
+func TestListGroupsFiltersByTheDisciplineOfTheSend(t *testing.T) {
+	// Aluno matriculado em disc-a e disc-b: filtrar por disc-a não pode trazer os envios feitos para disc-b.
+	db := &recordingDB{}
+	repo := &logRepository{db: db}
+
+	_, _, err := repo.ListGroups(context.Background(), "user-1", HistoryFilter{DisciplineID: "disc-a", Page: 1, PageSize: 20})
+	require.Error(t, err)
+
+	assert.Contains(t, db.query, "LEFT JOIN message_jobs mj ON mj.id = ml.delivery_group_id")
+	assert.Contains(t, db.query, "mj.discipline_id = $2")
+	assert.NotContains(t, db.query, "enrollments")
+	assert.Equal(t, "disc-a", db.args[1])
+}
@@ -0,0 +1,124 @@
+package message
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+const (
+	// IdempotencyKeyHeader é o header com que o cliente identifica um envio para poder repeti-lo sem duplicar.
+	IdempotencyKeyHeader   = "Idempotency-Key"
+	maxIdempotencyKeyLen   = 255
+	idempotencyTTL         = 24 * time.Hour
+	idempotencyStaleWindow = 5 * time.Minute
+)
+
+var (
+	ErrInvalidIdempotencyKey    = customerror.Make("Idempotency-Key inválido: use até 255 caracteres visíveis", http.StatusBadRequest, errors.New("ErrInvalidIdempotencyKey"))
+	ErrIdempotencyKeyInProgress = customerror.Make("um envio com este Idempotency-Key ainda está em andamento", http.StatusConflict, errors.New("ErrIdempotencyKeyInProgress"))
+	ErrIdempotencyKeyReused     = customerror.Make("este Idempotency-Key já foi usado com outro conteúdo", http.StatusUnprocessableEntity, errors.New("ErrIdempotencyKeyReused"))
+)
+
+// IdempotencyRecord é o resultado guardado para um Idempotency-Key; JobID nulo indica envio em andamento.
+type IdempotencyRecord struct {
+	UserID       string
+	Key          string
+	RequestHash  string
+	JobID        *string
+	StudentCount int
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+type IdempotencyRepository interface {
+	database.Transactional
+	// Reserve grava a chave como em andamento. Quando ela já existe e ainda vale, devolve o registro existente e reserved=false.
+	// Chaves expiradas, ou em andamento há mais de staleAfter, são substituídas.
+	Reserve(ctx context.Context, record *IdempotencyRecord, staleAfter time.Duration) (existing *IdempotencyRecord, reserved bool, err error)
+	Complete(ctx context.Context, userID, key, jobID string, studentCount int) error
+	Release(ctx context.Context, userID, key string) error
+}
+
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return newIdempotencyRepository(db)
+}
+
+func validateIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLen {
+		return ErrInvalidIdempotencyKey
+	}
+	for _, r := range key {
+		if r < 0x21 || r > 0x7e {
+			return ErrInvalidIdempotencyKey
+		}
+	}
+	return nil
+}
+
+// requestHash identifica o conteúdo do envio. O JWE fica de fora: ele muda a cada login sem mudar o envio.
+func requestHash(message *Message) (string, error) {
+	payload := *message
+	payload.Jwe = ""
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// sendOnce executa send uma única vez por Idempotency-Key. Repetições com o mesmo conteúdo devolvem o job do primeiro pedido;
+// se o primeiro falhar, a chave é liberada para que o cliente possa corrigir e tentar de novo.
+func (s *service) sendOnce(ctx context.Context, message *Message, send func() (*Job, error)) (*Job, error) {
+	if err := validateIdempotencyKey(message.IdempotencyKey); err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	hash, err := requestHash(message)
+	if err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+
+	now := time.Now()
+	existing, reserved, err := s.idempotencyRepository.Reserve(ctx, &IdempotencyRecord{
+		UserID:      message.UserID,
+		Key:         message.IdempotencyKey,
+		RequestHash: hash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(idempotencyTTL),
+	}, idempotencyStaleWindow)
+	if err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if !reserved {
+		switch {
+		case existing.RequestHash != hash:
+			return nil, customerror.Trace("Send", ErrIdempotencyKeyReused)
+		case existing.JobID == nil:
+			return nil, customerror.Trace("Send", ErrIdempotencyKeyInProgress)
+		}
+		return &Job{ID: *existing.JobID, UserID: message.UserID, StudentCount: existing.StudentCount, Replayed: true}, nil
+	}
+
+	job, err := send()
+	if err != nil {
+		if releaseErr := s.idempotencyRepository.Release(context.WithoutCancel(ctx), message.UserID, message.IdempotencyKey); releaseErr != nil {
+			log.Printf("falha ao liberar Idempotency-Key do usuário %s: %v", message.UserID, releaseErr)
+		}
+		return nil, err
+	}
+	// O job já está no outbox: uma falha aqui só deixa a chave em andamento até ser considerada abandonada.
+	if err := s.idempotencyRepository.Complete(context.WithoutCancel(ctx), message.UserID, message.IdempotencyKey, job.ID, job.StudentCount); err != nil {
+		log.Printf("falha ao concluir Idempotency-Key do usuário %s: %v", message.UserID, err)
+	}
+	return job, nil
+}
@@ -0,0 +1,116 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIdempotencyRepository struct {
+	IdempotencyRepository
+	records map[string]*IdempotencyRecord
+}
+
+func (f *fakeIdempotencyRepository) Reserve(ctx context.Context, record *IdempotencyRecord, staleAfter time.Duration) (*IdempotencyRecord, bool, error) {
+	key := record.UserID + "/" + record.Key
+	if existing, ok := f.records[key]; ok {
+		return existing, false, nil
+	}
+	stored := *record
+	f.records[key] = &stored
+	return nil, true, nil
+}
+
+func (f *fakeIdempotencyRepository) Complete(ctx context.Context, userID, key, jobID string, studentCount int) error {
+	record := f.records[userID+"/"+key]
+	record.JobID = &jobID
+	record.StudentCount = studentCount
+	return nil
+}
+
+func (f *fakeIdempotencyRepository) Release(ctx context.Context, userID, key string) error {
+	delete(f.records, userID+"/"+key)
+	return nil
+}
+
+func newIdempotentService() *service {
+	return &service{idempotencyRepository: &fakeIdempotencyRepository{records: map[string]*IdempotencyRecord{}}}
+}
+
+func idempotentMessage() *Message {
+	return &Message{UserID: "user-1", Jwe: "jwe-1", Subject: "Aviso", Body: "Olá", To: []string{"s1"}, SmtpId: "smtp-1", IdempotencyKey: "envio-123"}
+}
+
+func TestSendOnceReplaysTheFirstJob(t *testing.T) {
+	svc := newIdempotentService()
+	calls := 0
+	send := func() (*Job, error) {
+		calls++
+		return &Job{ID: "job-1", StudentCount: 30}, nil
+	}
+
+	first, err := svc.sendOnce(context.Background(), idempotentMessage(), send)
+	require.NoError(t, err)
+	retry := idempotentMessage()
+	retry.Jwe = "jwe-de-outro-login"
+	again, err := svc.sendOnce(context.Background(), retry, send)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, calls)
+	assert.False(t, first.Replayed)
+	assert.Equal(t, &Job{ID: "job-1", UserID: "user-1", StudentCount: 30, Replayed: true}, again)
+}
+
+func TestSendOnceRejectsReuseAndConcurrentRequests(t *testing.T) {
+	svc := newIdempotentService()
+	repo := svc.idempotencyRepository.(*fakeIdempotencyRepository)
+
+	_, err := svc.sendOnce(context.Background(), idempotentMessage(), func() (*Job, error) {
+		// Enquanto o primeiro pedido roda, a chave está reservada sem job.
+		_, err := svc.sendOnce(context.Background(), idempotentMessage(), func() (*Job, error) {
+			t.Fatal("o pedido repetido não deveria enviar")
+			return nil, nil
+		})
+		assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+		return &Job{ID: "job-1"}, nil
+	})
+	require.NoError(t, err)
+	require.NotNil(t, repo.records["user-1/envio-123"].JobID)
+
+	changed := idempotentMessage()
+	changed.Body = "Outro texto"
+	_, err = svc.sendOnce(context.Background(), changed, func() (*Job, error) {
+		t.Fatal("conteúdo diferente não deveria enviar")
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+}
+
+func TestSendOnceReleasesTheKeyWhenTheSendFails(t *testing.T) {
+	svc := newIdempotentService()
+
+	_, err := svc.sendOnce(context.Background(), idempotentMessage(), func() (*Job, error) {
+		return nil, errors.New("smtp fora do ar")
+	})
+	require.Error(t, err)
+
+	job, err := svc.sendOnce(context.Background(), idempotentMessage(), func() (*Job, error) {
+		return &Job{ID: "job-2"}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "job-2", job.ID)
+}
+
+func TestSendOnceValidatesTheKey(t *testing.T) {
+	svc := newIdempotentService()
+	message := idempotentMessage()
+	message.IdempotencyKey = "chave com espaço"
+
+	_, err := svc.sendOnce(context.Background(), message, func() (*Job, error) { return &Job{ID: "job-1"}, nil })
+
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+}
@@ -0,0 +1,92 @@
+package message
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"html/template"
+	"regexp"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/ThalysSilva/unicast-backend/pkg/markdown"
+)
+
+const defaultLayoutAccentColor = "#1d4ed8"
+
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// EmailLayout é o layout institucional do professor: cabeçalho e rodapé (em Markdown) em volta de todo email enviado.
+type EmailLayout struct {
+	Header      string    `json:"header"`
+	Footer      string    `json:"footer"`
+	AccentColor string    `json:"accentColor"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	UserID      string    `json:"-"`
+}
+
+type EmailLayoutInput struct {
+	// Header e Footer aceitam Markdown; placeholders não são substituídos.
+	Header string `json:"header" binding:"max=5000"`
+	Footer string `json:"footer" binding:"max=5000"`
+	// AccentColor é a cor de fundo do cabeçalho, em hexadecimal (#1d4ed8).
+	AccentColor string `json:"accentColor" binding:"omitempty,hexcolor"`
+}
+
+type LayoutRepository interface {
+	database.Transactional
+	// FindByUserID devolve nil quando o professor não tem layout.
+	FindByUserID(ctx context.Context, userID string) (*EmailLayout, error)
+	Save(ctx context.Context, layout *EmailLayout) error
+	Delete(ctx context.Context, userID string) error
+}
+
+func NewLayoutRepository(db *sql.DB) LayoutRepository {
+	return newLayoutRepository(db)
+}
+
+// layoutTemplate usa tabelas e estilos inline, que é o que os clientes de email renderizam de forma consistente.
+var layoutTemplate = template.Must(template.New("layout").Parse(`<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Subject}}</title>
+</head>
+<body style="margin:0;padding:0;background-color:#f4f4f5;">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" style="background-color:#f4f4f5;">
+<tr><td align="center" style="padding:24px 12px;">
+<table role="presentation" width="600" cellpadding="0" cellspacing="0" style="max-width:600px;width:100%;background-color:#ffffff;font-family:Arial,Helvetica,sans-serif;font-size:15px;line-height:1.5;color:#1f2937;">
+{{- if .Header}}
+<tr><td style="background-color:{{.AccentColor}};color:#ffffff;padding:20px 24px;">{{.Header}}</td></tr>
+{{- end}}
+<tr><td style="padding:24px;">{{.Content}}</td></tr>
+{{- if .Footer}}
+<tr><td style="border-top:1px solid #e5e7eb;padding:16px 24px;font-size:12px;color:#6b7280;">{{.Footer}}</td></tr>
+{{- end}}
+</table>
+</td></tr>
+</table>
+</body>
+</html>`))
+
+// wrap coloca o fragmento HTML já sanitizado dentro do layout.
+func (l *EmailLayout) wrap(subject, content string) string {
+	accent := l.AccentColor
+	if !hexColorPattern.MatchString(accent) {
+		accent = defaultLayoutAccentColor
+	}
+	var buf bytes.Buffer
+	err := layoutTemplate.Execute(&buf, map[string]any{
+		"Subject":     subject,
+		"AccentColor": template.CSS(accent),
+		"Header":      template.HTML(markdown.ToHTML(l.Header)),
+		"Footer":      template.HTML(markdown.ToHTML(l.Footer)),
+		"Content":     template.HTML(content),
+	})
+	if err != nil {
+		return content
+	}
+	return buf.String()
+}
@@ -0,0 +1,85 @@
+package message
+
+import (
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+
+	"github.com/gin-gonic/gin"
+)
+
+type layoutHandler struct {
+	service LayoutService
+}
+
+type LayoutHandler interface {
+	Get() gin.HandlerFunc
+	Save() gin.HandlerFunc
+	Delete() gin.HandlerFunc
+}
+
+func NewLayoutHandler(service LayoutService) LayoutHandler {
+	return &layoutHandler{service: service}
+}
+
+// @Summary Busca o layout institucional de email
+// @OperationId getEmailLayout
+// @Tags message
+// @Produce json
+// @Success 200 {object} api.DefaultResponse[EmailLayout]
+// @Failure 404 {object} api.ErrorResponse
+// @Router /message/layout [get]
+func (h *layoutHandler) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		layout, err := h.service.Get(c.Request.Context(), c.GetString("userID"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[EmailLayout]{Message: "Layout encontrado", Data: *layout})
+	}
+}
+
+// @Summary Define o layout institucional de email
+// @Description Cabeçalho e rodapé (Markdown) envolvem todos os emails do usuário, que passam a sair em HTML com alternativa em texto puro.
+// @OperationId saveEmailLayout
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param body body EmailLayoutInput true "Dados do layout"
+// @Success 200 {object} api.DefaultResponse[EmailLayout]
+// @Failure 400 {object} api.ErrorResponse
+// @Router /message/layout [put]
+func (h *layoutHandler) Save() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input EmailLayoutInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		layout, err := h.service.Save(c.Request.Context(), c.GetString("userID"), input)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[EmailLayout]{Message: "Layout salvo com sucesso", Data: *layout})
+	}
+}
+
+// @Summary Remove o layout institucional de email
+// @OperationId deleteEmailLayout
+// @Tags message
+// @Produce json
+// @Success 200 {object} api.MessageResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Router /message/layout [delete]
+func (h *layoutHandler) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.service.Delete(c.Request.Context(), c.GetString("userID")); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.MessageResponse{Message: "Layout removido com sucesso"})
+	}
+}
@@ -0,0 +1,66 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var (
+	ErrLayoutNotFound = customerror.Make("layout de email não encontrado", http.StatusNotFound, errors.New("ErrLayoutNotFound"))
+	ErrEmptyLayout    = customerror.Make("informe cabeçalho ou rodapé do layout", http.StatusBadRequest, errors.New("ErrEmptyLayout"))
+)
+
+type LayoutService interface {
+	Get(ctx context.Context, userID string) (*EmailLayout, error)
+	Save(ctx context.Context, userID string, input EmailLayoutInput) (*EmailLayout, error)
+	Delete(ctx context.Context, userID string) error
+}
+
+type layoutService struct {
+	layoutRepository LayoutRepository
+}
+
+func NewLayoutService(layoutRepository LayoutRepository) LayoutService {
+	return &layoutService{layoutRepository: layoutRepository}
+}
+
+func (s *layoutService) Get(ctx context.Context, userID string) (*EmailLayout, error) {
+	layout, err := s.layoutRepository.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, customerror.Trace("GetLayout", err)
+	}
+	if layout == nil {
+		return nil, customerror.Trace("GetLayout", ErrLayoutNotFound)
+	}
+	return layout, nil
+}
+
+func (s *layoutService) Save(ctx context.Context, userID string, input EmailLayoutInput) (*EmailLayout, error) {
+	layout := &EmailLayout{
+		UserID:      userID,
+		Header:      strings.TrimSpace(input.Header),
+		Footer:      strings.TrimSpace(input.Footer),
+		AccentColor: input.AccentColor,
+	}
+	if layout.Header == "" && layout.Footer == "" {
+		return nil, customerror.Trace("SaveLayout", ErrEmptyLayout)
+	}
+	if err := s.layoutRepository.Save(ctx, layout); err != nil {
+		return nil, customerror.Trace("SaveLayout", err)
+	}
+	return layout, nil
+}
+
+func (s *layoutService) Delete(ctx context.Context, userID string) error {
+	if _, err := s.Get(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.layoutRepository.Delete(ctx, userID); err != nil {
+		return customerror.Trace("DeleteLayout", err)
+	}
+	return nil
+}
@@ -22,21 +22,51 @@ type Log struct {
 	SenderAddress      *string
 	SMTPID             *string
 	WhatsAppInstanceID *string
+	SMSInstanceID      *string
 	AttachmentNames    *string
 	AttachmentCount    int
-	CreatedAt          time.Time
+	// RetryOf liga o log ao envio original quando a entrega é um reenvio de falhas.
+	RetryOf *string
+	// ProviderMessageID é o ID da mensagem na Evolution; os recibos do webhook avançam DeliveryStatus por ele.
+	ProviderMessageID *string
+	DeliveryStatus    DeliveryStatus
+	// FallbackFrom é o canal que falhou antes desta entrega, quando o envio usa política de fallback.
+	FallbackFrom *Channel
+	CreatedAt    time.Time
 }
 
+// DeliveryStatus acompanha a entrega depois do envio: SENT -> DELIVERED -> READ, ou FAILED.
+// Email não tem recibos e fica em SENT ou FAILED.
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSent      DeliveryStatus = "SENT"
+	DeliveryStatusDelivered DeliveryStatus = "DELIVERED"
+	DeliveryStatusRead      DeliveryStatus = "READ"
+	DeliveryStatusFailed    DeliveryStatus = "FAILED"
+)
+
 type Channel string
 
 const (
 	ChannelEmail    Channel = "EMAIL"
 	ChannelWhatsApp Channel = "WHATSAPP"
+	ChannelSMS      Channel = "SMS"
 )
 
 type LogRepository interface {
 	database.Transactional
 	Save(ctx context.Context, log *Log) error
+	// ListGroups agrupa os logs dos alunos do usuário por delivery_group_id, do envio mais recente ao mais antigo.
+	ListGroups(ctx context.Context, userID string, filter HistoryFilter) ([]DeliveryGroup, int, error)
+	// FindGroup retorna o envio com o resultado de cada destinatário; nil se não houver logs do usuário.
+	FindGroup(ctx context.Context, userID, groupID string) (*DeliveryGroupDetail, error)
+	// FindFailedRecipients lista, por canal, os alunos que falharam no envio e ainda não receberam por um reenvio
+	// nem pelo canal de fallback.
+	FindFailedRecipients(ctx context.Context, userID, groupID string) (map[Channel][]string, error)
+	// UpdateDeliveryStatus aplica um recibo do WhatsApp ao log da mensagem, sem nunca voltar o status.
+	// Retorna false quando nenhum log foi alterado (mensagem desconhecida ou recibo atrasado).
+	UpdateDeliveryStatus(ctx context.Context, instanceName, providerMessageID string, status DeliveryStatus) (bool, error)
 }
 
 func NewLogRepository(db *sql.DB) LogRepository {
@@ -0,0 +1,153 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "PENDING"
+	JobStatusCompleted JobStatus = "COMPLETED"
+)
+
+type RecipientStatus string
+
+const (
+	RecipientStatusPending    RecipientStatus = "PENDING"
+	RecipientStatusProcessing RecipientStatus = "PROCESSING"
+	RecipientStatusSent       RecipientStatus = "SENT"
+	RecipientStatusFailed     RecipientStatus = "FAILED"
+)
+
+// Job é um disparo persistido no outbox. O ID do job também é o delivery_group_id dos logs.
+// Subject e Body ficam com os placeholders; a renderização por aluno acontece na entrega.
+type Job struct {
+	ID                 string
+	UserID             string
+	SmtpID             *string
+	WhatsAppInstanceID *string
+	SmsInstanceID      *string
+	TemplateID         *string
+	DisciplineID       *string
+	// RetryOf é o delivery_group_id do envio original quando o job reenvia apenas as falhas dele.
+	RetryOf *string
+	// Fallback é a política de canal alternativo usada pelo worker quando um canal falha.
+	Fallback    *FallbackPolicy
+	From        string
+	Subject     string
+	Body        string
+	Format      BodyFormat
+	Jwe         *string
+	Status      JobStatus
+	Attachments []Attachment
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	// StudentCount é preenchido apenas no enfileiramento; não é persistido.
+	StudentCount int
+	// Replayed indica que o job veio de um Idempotency-Key já usado; não é persistido.
+	Replayed bool
+	// JweReentryRequired marca jobs cujo JWE foi descartado por uma redefinição de senha pelo admin.
+	JweReentryRequired bool
+}
+
+// toMessage reconstrói a mensagem original a partir do job persistido.
+func (j *Job) toMessage() *Message {
+	message := &Message{
+		UserID:  j.UserID,
+		From:    j.From,
+		Subject: j.Subject,
+		Body:    j.Body,
+		Format:  j.Format,
+	}
+	if j.SmtpID != nil {
+		message.SmtpId = *j.SmtpID
+	}
+	if j.WhatsAppInstanceID != nil {
+		message.WhatsappId = *j.WhatsAppInstanceID
+	}
+	if j.SmsInstanceID != nil {
+		message.SmsId = *j.SmsInstanceID
+	}
+	if j.Jwe != nil {
+		message.Jwe = *j.Jwe
+	}
+	message.JweReentryRequired = j.JweReentryRequired
+	if j.DisciplineID != nil {
+		message.DisciplineID = *j.DisciplineID
+	}
+	if j.Fallback != nil {
+		message.Fallback = *j.Fallback
+	}
+	if len(j.Attachments) > 0 {
+		attachments := append([]Attachment(nil), j.Attachments...)
+		message.Attachments = &attachments
+	}
+	return message
+}
+
+// JobRecipient é a unidade de trabalho do outbox: um aluno em um canal.
+type JobRecipient struct {
+	ID        string
+	JobID     string
+	StudentID string
+	Channel   Channel
+	Status    RecipientStatus
+	Attempts  int
+	LastError *string
+	// FallbackFrom é o canal que falhou antes de o aluno ser redirecionado para Channel.
+	FallbackFrom *Channel
+}
+
+// JobSummary resume o andamento de um job para consulta do professor.
+type JobSummary struct {
+	Job            *Job
+	Counts         map[RecipientStatus]int
+	EmailsFailed   []student.Student
+	WhatsappFailed []student.Student
+	SmsFailed      []student.Student
+}
+
+func (s *JobSummary) counts() JobCounts {
+	return JobCounts{
+		Pending:    s.Counts[RecipientStatusPending],
+		Processing: s.Counts[RecipientStatusProcessing],
+		Sent:       s.Counts[RecipientStatusSent],
+		Failed:     s.Counts[RecipientStatusFailed],
+	}
+}
+
+type OutboxRepository interface {
+	database.Transactional
+	CreateJob(ctx context.Context, job *Job) error
+	AddAttachment(ctx context.Context, jobID string, position int, attachment Attachment) error
+	AddRecipients(ctx context.Context, jobID string, channel Channel, studentIDs []string) error
+	FindJob(ctx context.Context, id string) (*Job, error)
+	GetJobSummary(ctx context.Context, id, userID string) (*JobSummary, error)
+	// HasPendingRetry indica se já existe um reenvio em andamento para o envio informado.
+	HasPendingRetry(ctx context.Context, deliveryGroupID string) (bool, error)
+	// AddFallbackRecipient redireciona o aluno para outro canal do mesmo job; ignora se ele já estiver nesse canal.
+	AddFallbackRecipient(ctx context.Context, jobID, studentID string, channel, from Channel) error
+	// ClaimRecipients reserva itens pendentes (ou com lease expirado) para um worker.
+	ClaimRecipients(ctx context.Context, limit int, lease time.Duration) ([]*JobRecipient, error)
+	MarkRecipientSent(ctx context.Context, id string) error
+	MarkRecipientFailed(ctx context.Context, id, errText string) error
+	RescheduleRecipient(ctx context.Context, id, errText string, nextAttemptAt time.Time) error
+	// ListRecipients devolve todos os itens do job, em qualquer status.
+	ListRecipients(ctx context.Context, jobID string) ([]*JobRecipient, error)
+	// ReplaceJwe troca o JWE guardado nos jobs pendentes do usuário, depois de uma troca de senha.
+	// Com jwe nil, o JWE é descartado e os jobs ficam marcados para falhar com ErrSmtpNeedsPassword.
+	ReplaceJwe(ctx context.Context, userID string, jwe *string) error
+	// CompleteJobIfDone finaliza o job quando não há mais itens pendentes e descarta o JWE guardado.
+	// Devolve true apenas para a chamada que finalizou o job.
+	CompleteJobIfDone(ctx context.Context, jobID string) (bool, error)
+}
+
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return newOutboxRepository(db)
+}
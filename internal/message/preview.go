@@ -0,0 +1,159 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+// Preview executa as validações de Send e prevê o resultado por aluno, sem enfileirar nem entregar.
+// Erros que impedem montar o envio (sem canal, sem destinatários, instância inexistente) são retornados como em Send.
+func (s *service) Preview(ctx context.Context, message *Message) (*PreviewResponse, error) {
+	plan, err := s.plan(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &PreviewResponse{
+		Problems:    []string{},
+		Students:    len(plan.students),
+		Attachments: previewAttachments(message.Attachments),
+		Recipients:  make([]RecipientPreview, 0, len(plan.students)),
+	}
+	for _, attachment := range preview.Attachments {
+		if attachment.Error != "" {
+			preview.Problems = append(preview.Problems, fmt.Sprintf("anexo %s: %s", attachment.FileName, attachment.Error))
+		}
+	}
+	if len(preview.Problems) == 0 {
+		if _, _, err := buildWhatsAppAttachments(message); err != nil {
+			preview.Problems = append(preview.Problems, publicErrorText(err))
+		}
+	}
+	if err := checkPlaceholders(message, plan.students, plan.renderContext); err != nil {
+		preview.Problems = append(preview.Problems, publicErrorText(err))
+	}
+	if err := checkCredentials(ctx, message, plan.channels); err != nil {
+		preview.Problems = append(preview.Problems, publicErrorText(err))
+	}
+
+	for _, sender := range plan.channels {
+		if checker, ok := sender.(destinationChecker); ok {
+			checker.CheckDestinations(ctx, message, plan.students)
+		}
+	}
+
+	for _, stud := range plan.students {
+		recipient := RecipientPreview{ID: stud.ID, StudentID: stud.StudentID, Name: stud.Name}
+		for _, sender := range plan.channels {
+			role := channelUse(message.Fallback, stud, sender.Channel())
+			if role == channelUnused {
+				continue
+			}
+			content, to, err := sender.Preview(message, stud, plan.renderContext)
+			channel := channelPreview(sender.Channel(), content, err)
+			channel.Fallback = role == channelFallback
+			channel.To = to
+			if err == nil && !channel.Fallback {
+				preview.count(sender.Channel())
+			}
+			recipient.set(sender.Channel(), channel)
+		}
+		preview.Recipients = append(preview.Recipients, recipient)
+	}
+
+	preview.Ready = len(preview.Problems) == 0
+	return preview, nil
+}
+
+type channelRole int
+
+const (
+	channelUnused channelRole = iota
+	channelPrimary
+	channelFallback
+)
+
+// channelUse diz como o canal participa do envio para o aluno; canais fora da política são sempre principais.
+func channelUse(policy FallbackPolicy, stud *student.Student, channel Channel) channelRole {
+	if !policy.routes(channel) {
+		return channelPrimary
+	}
+	primary := policy.primaryChannel(stud)
+	if channel == primary {
+		return channelPrimary
+	}
+	if fallback, ok := policy.fallbackFor(primary); ok && fallback == channel {
+		return channelFallback
+	}
+	return channelUnused
+}
+
+func (p *PreviewResponse) count(channel Channel) {
+	switch channel {
+	case ChannelEmail:
+		p.EmailCount++
+	case ChannelWhatsApp:
+		p.WhatsAppCount++
+	case ChannelSMS:
+		p.SMSCount++
+	}
+}
+
+func (r *RecipientPreview) set(channel Channel, preview *ChannelPreview) {
+	switch channel {
+	case ChannelEmail:
+		r.Email = preview
+	case ChannelWhatsApp:
+		r.WhatsApp = preview
+	case ChannelSMS:
+		r.SMS = preview
+	}
+}
+
+func channelPreview(channel Channel, content *renderedMessage, err error) *ChannelPreview {
+	preview := &ChannelPreview{WillSend: err == nil}
+	if content != nil {
+		preview.Subject = content.Subject
+		preview.Body = content.Body
+		preview.HTML = content.HTML
+	}
+	if err != nil {
+		preview.Reason = deliveryErrorText(channel, err)
+	}
+	return preview
+}
+
+// previewAttachments valida cada anexo individualmente; anexos por URL só são baixados no envio.
+func previewAttachments(attachments *[]Attachment) []AttachmentPreview {
+	previews := []AttachmentPreview{}
+	if attachments == nil {
+		return previews
+	}
+	for _, attachment := range *attachments {
+		preview := AttachmentPreview{FileName: attachment.FileName}
+		err := validateAttachmentMetadata(attachment.FileName)
+		if err == nil && len(attachment.Data) > 0 {
+			err = validateAttachmentData(attachment.FileName, attachment.Data, maxAttachmentBytes)
+		}
+		if err == nil {
+			err = validateAttachmentSources(&[]Attachment{attachment})
+		}
+		if err != nil {
+			preview.Error = publicErrorText(err)
+		}
+		previews = append(previews, preview)
+	}
+	return previews
+}
+
+func publicErrorText(err error) string {
+	customErr := &customerror.CustomError{}
+	if errors.As(err, &customErr) {
+		return customErr.PublicMessage()
+	}
+	return "falha ao validar o envio"
+}
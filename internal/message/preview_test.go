@@ -0,0 +1,359 @@
+package message
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/attachment"
+	"github.com/ThalysSilva/unicast-backend/internal/sms"
+	"github.com/ThalysSilva/unicast-backend/internal/smtp"
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/internal/user"
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSmtpRepository struct {
+	smtp.Repository
+	instance *smtp.Instance
+}
+
+func (f *fakeSmtpRepository) FindByID(ctx context.Context, id string) (*smtp.Instance, error) {
+	return f.instance, nil
+}
+
+type fakeWhatsAppRepository struct {
+	whatsapp.Repository
+	instance *whatsapp.Instance
+	recorded []string
+}
+
+func (f *fakeWhatsAppRepository) RecordConnectionState(ctx context.Context, id, state, source string, at time.Time) (string, bool, error) {
+	f.recorded = append(f.recorded, source+":"+state)
+	return f.instance.ConnectionStatus, f.instance.ConnectionStatus != state, nil
+}
+
+func (f *fakeWhatsAppRepository) FindByID(ctx context.Context, id string) (*whatsapp.Instance, error) {
+	return f.instance, nil
+}
+
+type fakeSmsRepository struct {
+	sms.Repository
+	instance *sms.Instance
+}
+
+func (f *fakeSmsRepository) FindByID(ctx context.Context, id string) (*sms.Instance, error) {
+	return f.instance, nil
+}
+
+type fakeLayoutRepository struct {
+	LayoutRepository
+	layout *EmailLayout
+}
+
+func (f *fakeLayoutRepository) FindByUserID(ctx context.Context, userID string) (*EmailLayout, error) {
+	return f.layout, nil
+}
+
+type fakeAttachmentService struct {
+	attachment.Service
+	stored map[string]*attachment.Attachment
+	data   map[string][]byte
+}
+
+func (f *fakeAttachmentService) Load(ctx context.Context, userID, id string) (*attachment.Attachment, []byte, error) {
+	stored, ok := f.stored[id]
+	if !ok || stored.UserID != userID {
+		return nil, nil, attachment.ErrAttachmentNotFound
+	}
+	return stored, f.data[id], nil
+}
+
+type fakeUserRepository struct {
+	user.Repository
+}
+
+func (f *fakeUserRepository) FindByID(ctx context.Context, id string) (*user.User, error) {
+	return &user.User{ID: id, Name: "Prof. Ana"}, nil
+}
+
+// fakeEvolutionClient responde só à consulta de números; sem checks, nenhum número é verificado.
+type fakeEvolutionClient struct {
+	checks   map[string]whatsapp.NumberCheck
+	lookedUp []string
+	state    string
+}
+
+func (f *fakeEvolutionClient) CreateInstance(ctx context.Context, phone, instanceName string, qrCode bool) (string, string, error) {
+	return instanceName, "", nil
+}
+
+func (f *fakeEvolutionClient) DeleteInstance(ctx context.Context, instanceName string) error {
+	return nil
+}
+
+func (f *fakeEvolutionClient) ConnectInstance(ctx context.Context, instanceName, number string) (*whatsapp.ConnectResponse, error) {
+	return &whatsapp.ConnectResponse{}, nil
+}
+
+func (f *fakeEvolutionClient) LogoutInstance(ctx context.Context, instanceName string) error {
+	return nil
+}
+
+func (f *fakeEvolutionClient) RestartInstance(ctx context.Context, instanceName string) error {
+	return nil
+}
+
+func (f *fakeEvolutionClient) SendText(ctx context.Context, instanceName, number, text string) (string, error) {
+	return "msg-1", nil
+}
+
+func (f *fakeEvolutionClient) SendMedia(ctx context.Context, instanceName, number, fileName string, data []byte, caption string) (*whatsapp.SendMediaResponse, error) {
+	return &whatsapp.SendMediaResponse{}, nil
+}
+
+func (f *fakeEvolutionClient) ConnectionState(ctx context.Context, instanceName string) (string, error) {
+	return f.state, nil
+}
+
+func (f *fakeEvolutionClient) OwnerNumber(ctx context.Context, instanceName string) (string, error) {
+	return "", nil
+}
+
+func (f *fakeEvolutionClient) CheckNumbers(ctx context.Context, instanceName string, numbers []string) (map[string]whatsapp.NumberCheck, error) {
+	f.lookedUp = numbers
+	return f.checks, nil
+}
+
+func newPreviewService(students ...*student.Student) *service {
+	byID := map[string]*student.Student{}
+	for _, stud := range students {
+		byID[stud.ID] = stud
+	}
+	return &service{
+		studentRepository:  &fakeStudentRepository{students: byID},
+		smtpRepository:     &fakeSmtpRepository{instance: &smtp.Instance{ID: "smtp-1", UserID: "user-1", Email: "prof@example.com", AuthMode: smtp.AuthModeOAuth}},
+		whatsAppRepository: &fakeWhatsAppRepository{instance: &whatsapp.Instance{ID: "wa-1", UserID: "user-1", InstanceName: "prof"}},
+		smsRepository:      &fakeSmsRepository{instance: &sms.Instance{ID: "sms-1", UserID: "user-1", Name: "gateway", GatewayURL: "https://sms.example.com/send"}},
+		userRepository:     &fakeUserRepository{},
+		layoutRepository:   &fakeLayoutRepository{},
+		attachmentService:  &fakeAttachmentService{},
+		evolution:          &fakeEvolutionClient{},
+		defaultCountryCode: "55",
+	}
+}
+
+func TestPreviewPredictsEachChannelPerStudent(t *testing.T) {
+	svc := newPreviewService(
+		&student.Student{ID: "s1", StudentID: "2026001", UserOwnerID: "user-1", Name: strPtr("Maria Souza"), Email: strPtr("maria@example.com"), Phone: strPtr("(11) 98888-7777")},
+		&student.Student{ID: "s2", StudentID: "2026002", UserOwnerID: "user-1", Name: strPtr("João"), Phone: strPtr("123")},
+	)
+
+	preview, err := svc.Preview(context.Background(), &Message{
+		UserID:     "user-1",
+		SmtpId:     "smtp-1",
+		WhatsappId: "wa-1",
+		Subject:    "Aviso",
+		Body:       "Olá {{firstName}}",
+		To:         []string{"s1", "s2"},
+	})
+
+	require.NoError(t, err)
+	assert.True(t, preview.Ready)
+	assert.Equal(t, 2, preview.Students)
+	assert.Equal(t, 1, preview.EmailCount)
+	assert.Equal(t, 1, preview.WhatsAppCount)
+	require.Len(t, preview.Recipients, 2)
+
+	maria := preview.Recipients[0]
+	assert.Equal(t, &ChannelPreview{WillSend: true, To: "maria@example.com", Subject: "Aviso", Body: "Olá Maria"}, maria.Email)
+	assert.Equal(t, &ChannelPreview{WillSend: true, To: "5511988887777", Body: "*Aviso*\n\nOlá Maria"}, maria.WhatsApp)
+
+	joao := preview.Recipients[1]
+	assert.False(t, joao.Email.WillSend)
+	assert.Equal(t, "estudante sem email configurado", joao.Email.Reason)
+	assert.False(t, joao.WhatsApp.WillSend)
+	assert.Equal(t, "telefone inválido para WhatsApp", joao.WhatsApp.Reason)
+	assert.Equal(t, "*Aviso*\n\nOlá João", joao.WhatsApp.Body)
+}
+
+func TestPreviewReportsProblemsThatWouldBlockTheSend(t *testing.T) {
+	svc := newPreviewService(&student.Student{ID: "s1", StudentID: "2026001", UserOwnerID: "user-1", Email: strPtr("aluno@example.com")})
+
+	preview, err := svc.Preview(context.Background(), &Message{
+		UserID:  "user-1",
+		SmtpId:  "smtp-1",
+		Subject: "Aviso",
+		Body:    "Olá {{name}}",
+		To:      []string{"s1"},
+		Attachments: &[]Attachment{
+			{FileName: "malware.exe", Data: []byte("MZ")},
+			{FileName: "plano.pdf", URL: "https://example.com/plano.pdf"},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.False(t, preview.Ready)
+	assert.Equal(t, []AttachmentPreview{
+		{FileName: "malware.exe", Error: "tipo de arquivo não permitido"},
+		{FileName: "plano.pdf"},
+	}, preview.Attachments)
+	assert.Equal(t, []string{
+		"anexo malware.exe: tipo de arquivo não permitido",
+		"placeholders sem valor: {{name}} (1 aluno(s))",
+	}, preview.Problems)
+	assert.Equal(t, "placeholders sem valor: {{name}} (1 aluno(s))", preview.Recipients[0].Email.Reason)
+	assert.Nil(t, preview.Recipients[0].WhatsApp)
+}
+
+func TestPreviewIncludesSMSAlongsideFallbackPolicy(t *testing.T) {
+	svc := newPreviewService(
+		&student.Student{ID: "s1", StudentID: "2026001", UserOwnerID: "user-1", Name: strPtr("Maria"), Email: strPtr("maria@example.com"), Phone: strPtr("(11) 98888-7777")},
+		&student.Student{ID: "s2", StudentID: "2026002", UserOwnerID: "user-1", Name: strPtr("João"), Email: strPtr("joao@example.com")},
+	)
+
+	preview, err := svc.Preview(context.Background(), &Message{
+		UserID:     "user-1",
+		SmtpId:     "smtp-1",
+		WhatsappId: "wa-1",
+		SmsId:      "sms-1",
+		Fallback:   FallbackWhatsAppFirst,
+		Subject:    "Aviso",
+		Body:       "Olá {{firstName}}",
+		To:         []string{"s1", "s2"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, preview.SMSCount)
+	assert.Equal(t, &ChannelPreview{WillSend: true, To: "+5511988887777", Body: "Aviso\n\nOlá Maria"}, preview.Recipients[0].SMS)
+	assert.False(t, preview.Recipients[1].SMS.WillSend)
+}
+
+func TestPreviewRendersMarkdownPerChannelWithLayout(t *testing.T) {
+	svc := newPreviewService(&student.Student{ID: "s1", StudentID: "2026001", UserOwnerID: "user-1", Name: strPtr("*Maria*"), Email: strPtr("maria@example.com"), Phone: strPtr("(11) 98888-7777")})
+	svc.layoutRepository = &fakeLayoutRepository{layout: &EmailLayout{Header: "**Colégio Exemplo**", Footer: "Secretaria"}}
+
+	preview, err := svc.Preview(context.Background(), &Message{
+		UserID:     "user-1",
+		SmtpId:     "smtp-1",
+		WhatsappId: "wa-1",
+		SmsId:      "sms-1",
+		Format:     BodyFormatMarkdown,
+		Subject:    "Aviso",
+		Body:       "Olá {{name}}, a prova é **sexta**.",
+		To:         []string{"s1"},
+	})
+
+	require.NoError(t, err)
+	email := preview.Recipients[0].Email
+	assert.Equal(t, "Olá \\*Maria\\*, a prova é **sexta**.", email.Body)
+	assert.Contains(t, email.HTML, "<p>Olá *Maria*, a prova é <strong>sexta</strong>.</p>")
+	assert.Contains(t, email.HTML, "<strong>Colégio Exemplo</strong>")
+	assert.Contains(t, email.HTML, "Secretaria")
+	assert.Equal(t, "*Aviso*\n\nOlá Maria, a prova é *sexta*.", preview.Recipients[0].WhatsApp.Body)
+	assert.Equal(t, "Aviso\n\nOlá *Maria*, a prova é sexta.", preview.Recipients[0].SMS.Body)
+}
+
+func TestPreviewKeepsPlainTextEmailWithoutLayout(t *testing.T) {
+	svc := newPreviewService(&student.Student{ID: "s1", StudentID: "2026001", UserOwnerID: "user-1", Email: strPtr("aluno@example.com")})
+
+	preview, err := svc.Preview(context.Background(), &Message{
+		UserID:  "user-1",
+		SmtpId:  "smtp-1",
+		Subject: "Aviso",
+		Body:    "Texto **literal**",
+		To:      []string{"s1"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Texto **literal**", preview.Recipients[0].Email.Body)
+	assert.Empty(t, preview.Recipients[0].Email.HTML)
+}
+
+func TestPreviewResolvesAttachmentsStoredByID(t *testing.T) {
+	svc := newPreviewService(&student.Student{ID: "s1", StudentID: "2026001", UserOwnerID: "user-1", Email: strPtr("aluno@example.com")})
+	svc.attachmentService = &fakeAttachmentService{
+		stored: map[string]*attachment.Attachment{
+			"att-1": {ID: "att-1", UserID: "user-1", FileName: "plano.pdf"},
+			"att-2": {ID: "att-2", UserID: "user-2", FileName: "outro.pdf"},
+		},
+		data: map[string][]byte{"att-1": []byte("%PDF-1.7\n")},
+	}
+	message := &Message{
+		UserID:      "user-1",
+		SmtpId:      "smtp-1",
+		Subject:     "Aviso",
+		Body:        "Segue o plano",
+		To:          []string{"s1"},
+		Attachments: &[]Attachment{{ID: "att-1"}},
+	}
+
+	preview, err := svc.Preview(context.Background(), message)
+
+	require.NoError(t, err)
+	assert.True(t, preview.Ready)
+	assert.Equal(t, []AttachmentPreview{{FileName: "plano.pdf"}}, preview.Attachments)
+	assert.Equal(t, []byte("%PDF-1.7\n"), (*message.Attachments)[0].Data)
+
+	_, err = svc.Preview(context.Background(), &Message{
+		UserID:      "user-1",
+		SmtpId:      "smtp-1",
+		Subject:     "Aviso",
+		Body:        "Segue o plano",
+		To:          []string{"s1"},
+		Attachments: &[]Attachment{{ID: "att-2"}},
+	})
+	assert.ErrorIs(t, err, attachment.ErrAttachmentNotFound)
+}
+
+func TestPreviewChecksWhatsAppNumbersBeforeSending(t *testing.T) {
+	svc := newPreviewService(
+		&student.Student{ID: "s1", StudentID: "2026001", UserOwnerID: "user-1", Name: strPtr("Maria"), Phone: strPtr("(11) 8888-7777")},
+		&student.Student{ID: "s2", StudentID: "2026002", UserOwnerID: "user-1", Name: strPtr("João"), Phone: strPtr("(11) 97777-6666")},
+	)
+	evolution := &fakeEvolutionClient{checks: map[string]whatsapp.NumberCheck{
+		"551188887777":  {Number: "551188887777", Exists: true, SendTo: "5511988887777"},
+		"5511977776666": {Number: "5511977776666"},
+	}}
+	svc.evolution = evolution
+
+	preview, err := svc.Preview(context.Background(), &Message{
+		UserID:     "user-1",
+		WhatsappId: "wa-1",
+		Subject:    "Aviso",
+		Body:       "Olá {{firstName}}",
+		To:         []string{"s1", "s2"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"551188887777", "5511977776666"}, evolution.lookedUp, "a consulta é feita uma vez para a turma")
+	assert.Equal(t, 1, preview.WhatsAppCount)
+	assert.Equal(t, "5511988887777", preview.Recipients[0].WhatsApp.To)
+	assert.False(t, preview.Recipients[1].WhatsApp.WillSend)
+	assert.Equal(t, "número sem conta no WhatsApp", preview.Recipients[1].WhatsApp.Reason)
+}
+
+func TestPreviewRejectsWhatsAppInstanceStillDisconnected(t *testing.T) {
+	svc := newPreviewService(&student.Student{ID: "s1", StudentID: "2026001", UserOwnerID: "user-1", Name: strPtr("Maria"), Phone: strPtr("(11) 98888-7777")})
+	repo := &fakeWhatsAppRepository{instance: &whatsapp.Instance{ID: "wa-1", UserID: "user-1", InstanceName: "prof", ConnectionStatus: whatsapp.ConnectionClose}}
+	svc.whatsAppRepository = repo
+	svc.evolution = &fakeEvolutionClient{state: whatsapp.ConnectionClose}
+	message := &Message{UserID: "user-1", WhatsappId: "wa-1", Subject: "Aviso", Body: "Olá", To: []string{"s1"}}
+
+	preview, err := svc.Preview(context.Background(), message)
+
+	require.NoError(t, err)
+	assert.False(t, preview.Ready)
+	assert.Contains(t, preview.Problems, "o WhatsApp desta instância está desconectado; leia o QR code novamente para reconectar")
+	assert.Equal(t, []string{"send:close"}, repo.recorded)
+
+	svc.evolution = &fakeEvolutionClient{state: whatsapp.ConnectionOpen}
+	repo.instance.ConnectionStatus = whatsapp.ConnectionClose
+	preview, err = svc.Preview(context.Background(), message)
+
+	require.NoError(t, err)
+	assert.True(t, preview.Ready, "instância reconectada depois da última consulta não é recusada")
+	assert.Equal(t, []string{"send:close", "send:open"}, repo.recorded)
+}
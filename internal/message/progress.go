@@ -0,0 +1,191 @@
+package message
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+// ProgressEventType é o tipo de um evento de andamento de envio.
+type ProgressEventType string
+
+const (
+	// ProgressQueued indica que o aluno aguarda a entrega no canal (também após um redirecionamento de fallback).
+	ProgressQueued ProgressEventType = "queued"
+	// ProgressSent indica que o canal entregou a mensagem ao provedor.
+	ProgressSent ProgressEventType = "sent"
+	// ProgressFailed indica que a tentativa falhou; se ela for repetida, um evento retrying vem em seguida.
+	ProgressFailed ProgressEventType = "failed"
+	// ProgressRetrying indica que o worker vai tentar de novo em NextAttemptAt.
+	ProgressRetrying ProgressEventType = "retrying"
+	// ProgressSummary encerra o stream com a contagem final do envio.
+	ProgressSummary ProgressEventType = "summary"
+)
+
+// progressBuffer é quantos eventos um assinante lento pode acumular antes de começar a perder eventos.
+const progressBuffer = 256
+
+// ProgressEvent é o andamento de um aluno em um canal, ou o resumo final quando Type é summary.
+type ProgressEvent struct {
+	Type          ProgressEventType `json:"type"`
+	StudentID     string            `json:"studentId,omitempty"`
+	Channel       Channel           `json:"channel,omitempty"`
+	Reason        string            `json:"reason,omitempty"`
+	NextAttemptAt *time.Time        `json:"nextAttemptAt,omitempty"`
+	Counts        *JobCounts        `json:"counts,omitempty"`
+	At            time.Time         `json:"at"`
+}
+
+// ProgressBroker distribui os eventos de andamento entre os streams abertos, em memória.
+// O worker e a API precisam rodar no mesmo processo; um broker nil descarta os eventos.
+type ProgressBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan ProgressEvent]struct{}
+}
+
+func NewProgressBroker() *ProgressBroker {
+	return &ProgressBroker{subscribers: map[string]map[chan ProgressEvent]struct{}{}}
+}
+
+// Subscribe passa a receber os eventos do envio até a função devolvida ser chamada.
+func (b *ProgressBroker) Subscribe(deliveryGroupID string) (<-chan ProgressEvent, func()) {
+	events := make(chan ProgressEvent, progressBuffer)
+	if b == nil {
+		return events, func() {}
+	}
+
+	b.mu.Lock()
+	if b.subscribers[deliveryGroupID] == nil {
+		b.subscribers[deliveryGroupID] = map[chan ProgressEvent]struct{}{}
+	}
+	b.subscribers[deliveryGroupID][events] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[deliveryGroupID], events)
+			if len(b.subscribers[deliveryGroupID]) == 0 {
+				delete(b.subscribers, deliveryGroupID)
+			}
+		})
+	}
+}
+
+// Publish nunca bloqueia a entrega: um assinante com o buffer cheio perde o evento.
+func (b *ProgressBroker) Publish(deliveryGroupID string, event ProgressEvent) {
+	if b == nil {
+		return
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for events := range b.subscribers[deliveryGroupID] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// ProgressStream é o andamento de um envio para o stream SSE: Initial traz o estado atual de cada aluno.
+// Quando o envio já terminou, Initial termina com o resumo e Events é nil.
+type ProgressStream struct {
+	Initial []ProgressEvent
+	Events  <-chan ProgressEvent
+	Close   func()
+}
+
+// Progress abre o andamento de um envio do usuário. A assinatura vem antes da leitura do estado atual
+// para que nenhum evento se perca entre as duas.
+func (s *service) Progress(ctx context.Context, userID, deliveryGroupID string) (*ProgressStream, error) {
+	summary, err := s.outboxRepository.GetJobSummary(ctx, deliveryGroupID, userID)
+	if err != nil {
+		return nil, customerror.Trace("Progress", err)
+	}
+	if summary == nil {
+		return nil, customerror.Trace("Progress", ErrJobNotFound)
+	}
+
+	events, closeEvents := s.progress.Subscribe(deliveryGroupID)
+	recipients, err := s.outboxRepository.ListRecipients(ctx, deliveryGroupID)
+	if err != nil {
+		closeEvents()
+		return nil, customerror.Trace("Progress", err)
+	}
+
+	stream := &ProgressStream{Initial: make([]ProgressEvent, 0, len(recipients)+1), Events: events, Close: closeEvents}
+	counts := JobCounts{}
+	now := time.Now()
+	for _, recipient := range recipients {
+		event := ProgressEvent{StudentID: recipient.StudentID, Channel: recipient.Channel, At: now}
+		switch recipient.Status {
+		case RecipientStatusSent:
+			event.Type = ProgressSent
+			counts.Sent++
+		case RecipientStatusFailed:
+			event.Type = ProgressFailed
+			counts.Failed++
+		case RecipientStatusProcessing:
+			event.Type = ProgressQueued
+			counts.Processing++
+		default:
+			event.Type = ProgressQueued
+			if recipient.Attempts > 0 {
+				event.Type = ProgressRetrying
+			}
+			counts.Pending++
+		}
+		stream.Initial = append(stream.Initial, event)
+	}
+	if counts.Pending == 0 && counts.Processing == 0 {
+		closeEvents()
+		stream.Initial = append(stream.Initial, ProgressEvent{Type: ProgressSummary, Counts: &counts, At: now})
+		stream.Events = nil
+		stream.Close = func() {}
+	}
+	return stream, nil
+}
+
+// deliveryProgress publica o resultado de cada aluno assim que o canal termina a tentativa.
+// Os canais chamam report; finish cobre quem falhou antes de chegar ao canal.
+type deliveryProgress struct {
+	broker          *ProgressBroker
+	deliveryGroupID string
+	channel         Channel
+	mu              sync.Mutex
+	reported        map[string]struct{}
+}
+
+func (s *service) deliveryProgress(deliveryGroupID string, channel Channel) *deliveryProgress {
+	return &deliveryProgress{broker: s.progress, deliveryGroupID: deliveryGroupID, channel: channel, reported: map[string]struct{}{}}
+}
+
+func (p *deliveryProgress) report(studentID string, err error) {
+	p.mu.Lock()
+	p.reported[studentID] = struct{}{}
+	p.mu.Unlock()
+
+	event := ProgressEvent{Type: ProgressSent, StudentID: studentID, Channel: p.channel}
+	if err != nil {
+		event.Type = ProgressFailed
+		event.Reason = deliveryErrorText(p.channel, err)
+	}
+	p.broker.Publish(p.deliveryGroupID, event)
+}
+
+func (p *deliveryProgress) finish(studentID string, err error) {
+	p.mu.Lock()
+	_, done := p.reported[studentID]
+	p.mu.Unlock()
+	if !done {
+		p.report(studentID, err)
+	}
+}
@@ -0,0 +1,70 @@
+package message
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// progressHeartbeat mantém a conexão aberta em proxies que encerram streams ociosos.
+const progressHeartbeat = 15 * time.Second
+
+// @Summary Acompanha um envio em tempo real
+// @Description Stream Server-Sent Events com o andamento de cada aluno (queued, sent, failed, retrying) e um evento summary final, após o qual a conexão é encerrada.
+// @Description Os primeiros eventos refletem o estado atual do envio; os motivos de falhas anteriores à conexão ficam em /message/history/{deliveryGroupId}.
+// @OperationId streamMessageDeliveryEvents
+// @Tags message
+// @Produce text/event-stream
+// @Param deliveryGroupId path string true "ID do envio (delivery_group_id)"
+// @Success 200 {object} ProgressEvent
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Router /message/delivery/{deliveryGroupId}/events [get]
+func (h *handler) Events() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var uri deliveryGroupURI
+		if err := c.ShouldBindUri(&uri); err != nil {
+			c.Error(err)
+			return
+		}
+		stream, err := h.service.Progress(c.Request.Context(), c.GetString("userID"), uri.ID)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		defer stream.Close()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		for _, event := range stream.Initial {
+			c.SSEvent(string(event.Type), event)
+		}
+		c.Writer.Flush()
+		if stream.Events == nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(progressHeartbeat)
+		defer heartbeat.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-heartbeat.C:
+				_, err := io.WriteString(w, ": heartbeat\n\n")
+				return err == nil
+			case event := <-stream.Events:
+				c.SSEvent(string(event.Type), event)
+				return event.Type != ProgressSummary
+			}
+		})
+	}
+}
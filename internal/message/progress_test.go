@@ -0,0 +1,134 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProgressOutbox(userID string, recipients ...*JobRecipient) *fakeOutbox {
+	job := &Job{ID: "job-1", UserID: userID}
+	outbox := newFakeOutbox(job)
+	outbox.recipients = recipients
+	outbox.summary = &JobSummary{Job: job}
+	return outbox
+}
+
+func receiveProgress(t *testing.T, events <-chan ProgressEvent) ProgressEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("nenhum evento de andamento recebido")
+		return ProgressEvent{}
+	}
+}
+
+func TestProgressOfFinishedDeliveryEndsWithSummary(t *testing.T) {
+	lastError := "smtp: timeout"
+	svc := &service{progress: NewProgressBroker(), outboxRepository: newProgressOutbox("user-1",
+		&JobRecipient{StudentID: "s1", Channel: ChannelEmail, Status: RecipientStatusSent, Attempts: 1},
+		&JobRecipient{StudentID: "s2", Channel: ChannelEmail, Status: RecipientStatusFailed, Attempts: 3, LastError: &lastError},
+	)}
+
+	stream, err := svc.Progress(context.Background(), "user-1", "job-1")
+
+	require.NoError(t, err)
+	assert.Nil(t, stream.Events)
+	require.Len(t, stream.Initial, 3)
+	assert.Equal(t, ProgressSent, stream.Initial[0].Type)
+	assert.Equal(t, ProgressFailed, stream.Initial[1].Type)
+	assert.Empty(t, stream.Initial[1].Reason, "o texto interno do erro não deve ir para o stream")
+	assert.Equal(t, ProgressSummary, stream.Initial[2].Type)
+	assert.Equal(t, &JobCounts{Sent: 1, Failed: 1}, stream.Initial[2].Counts)
+}
+
+func TestProgressOnlyShowsDeliveriesOfTheCaller(t *testing.T) {
+	svc := &service{progress: NewProgressBroker(), outboxRepository: newProgressOutbox("user-1")}
+
+	_, err := svc.Progress(context.Background(), "user-2", "job-1")
+
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestProgressStreamsRecipientsAsTheyFinish(t *testing.T) {
+	broker := NewProgressBroker()
+	svc := &service{progress: broker, outboxRepository: newProgressOutbox("user-1",
+		&JobRecipient{StudentID: "s1", Channel: ChannelWhatsApp, Status: RecipientStatusPending},
+		&JobRecipient{StudentID: "s2", Channel: ChannelWhatsApp, Status: RecipientStatusProcessing},
+	)}
+
+	stream, err := svc.Progress(context.Background(), "user-1", "job-1")
+	require.NoError(t, err)
+	require.NotNil(t, stream.Events)
+	assert.Len(t, stream.Initial, 2)
+
+	progress := svc.deliveryProgress("job-1", ChannelWhatsApp)
+	progress.report("s1", nil)
+	progress.report("s2", ErrPhoneInvalid)
+	progress.finish("s2", ErrPhoneInvalid)
+
+	sent := receiveProgress(t, stream.Events)
+	assert.Equal(t, ProgressSent, sent.Type)
+	assert.Equal(t, "s1", sent.StudentID)
+	failed := receiveProgress(t, stream.Events)
+	assert.Equal(t, ProgressFailed, failed.Type)
+	assert.Equal(t, "telefone inválido para WhatsApp", failed.Reason)
+	select {
+	case event := <-stream.Events:
+		t.Fatalf("finish não deveria repetir um aluno já reportado: %+v", event)
+	default:
+	}
+
+	stream.Close()
+	broker.Publish("job-1", ProgressEvent{Type: ProgressSent})
+	assert.Empty(t, broker.subscribers)
+}
+
+func TestWorkerPublishesRetryAndSummary(t *testing.T) {
+	job := &Job{ID: "job-1", UserID: "user-1"}
+	outbox := newFakeOutbox(job, &JobRecipient{ID: "r1", JobID: "job-1", StudentID: "s1", Channel: ChannelEmail, Attempts: 1})
+	outbox.summary = &JobSummary{Job: job, Counts: map[RecipientStatus]int{RecipientStatusPending: 1}}
+	svc := &fakeDeliveryService{results: map[string]DeliveryResult{
+		"s1": {StudentID: "s1", Err: errors.New("timeout")},
+	}}
+	broker := NewProgressBroker()
+	events, cancel := broker.Subscribe("job-1")
+	defer cancel()
+	worker := newTestWorker(svc, outbox, &fakeLogRepository{})
+	worker.progress = broker
+
+	_, err := worker.ProcessBatch(context.Background())
+	require.NoError(t, err)
+
+	retrying := receiveProgress(t, events)
+	assert.Equal(t, ProgressRetrying, retrying.Type)
+	assert.Equal(t, "failed to send email", retrying.Reason)
+	require.NotNil(t, retrying.NextAttemptAt)
+	assert.Equal(t, time.Date(2026, 1, 1, 10, 1, 0, 0, time.UTC), *retrying.NextAttemptAt)
+	summary := receiveProgress(t, events)
+	assert.Equal(t, ProgressSummary, summary.Type)
+	assert.Equal(t, &JobCounts{Pending: 1}, summary.Counts)
+}
+
+func TestProgressEventsRejectsInvalidDeliveryGroupID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &service{outboxRepository: newProgressOutbox("user-1"), progress: NewProgressBroker()}
+	router := gin.New()
+	router.Use(middleware.ValidationErrorHandler())
+	router.GET("/message/delivery/:deliveryGroupId/events", NewHandler(svc).Events())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/message/delivery/nao-e-uuid/events", nil))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,202 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/internal/discipline"
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/internal/user"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z]+)\s*\}\}`)
+
+// errMissingPlaceholder identifica, via errors.Is, falhas de renderização por falta de dado do aluno.
+var errMissingPlaceholder = errors.New("ErrMissingPlaceholder")
+
+// RenderContext reúne os dados disponíveis para os placeholders de uma mensagem.
+type RenderContext struct {
+	Student    *student.Student
+	Teacher    *user.User
+	Discipline *discipline.Discipline
+	// Layout é o layout institucional aplicado ao email; nil quando o professor não tem um.
+	Layout *EmailLayout
+}
+
+// placeholders lista os placeholders suportados e como obter o valor de cada um.
+var placeholders = map[string]func(RenderContext) string{
+	"name": func(rc RenderContext) string {
+		return valueOf(studentField(rc, func(s *student.Student) *string { return s.Name }))
+	},
+	"firstName": func(rc RenderContext) string {
+		fields := strings.Fields(valueOf(studentField(rc, func(s *student.Student) *string { return s.Name })))
+		if len(fields) == 0 {
+			return ""
+		}
+		return fields[0]
+	},
+	"studentId": func(rc RenderContext) string {
+		if rc.Student == nil {
+			return ""
+		}
+		return rc.Student.StudentID
+	},
+	"email": func(rc RenderContext) string {
+		return valueOf(studentField(rc, func(s *student.Student) *string { return s.Email }))
+	},
+	"phone": func(rc RenderContext) string {
+		return valueOf(studentField(rc, func(s *student.Student) *string { return s.Phone }))
+	},
+	"discipline": func(rc RenderContext) string {
+		if rc.Discipline == nil {
+			return ""
+		}
+		return rc.Discipline.Name
+	},
+	"teacherName": func(rc RenderContext) string {
+		if rc.Teacher == nil {
+			return ""
+		}
+		return rc.Teacher.Name
+	},
+	"teacherEmail": func(rc RenderContext) string {
+		if rc.Teacher == nil {
+			return ""
+		}
+		return rc.Teacher.Email
+	},
+}
+
+func studentField(rc RenderContext, field func(*student.Student) *string) *string {
+	if rc.Student == nil {
+		return nil
+	}
+	return field(rc.Student)
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(*value)
+}
+
+// PlaceholdersIn devolve os placeholders usados nos textos, sem repetição e em ordem alfabética.
+func PlaceholdersIn(texts ...string) []string {
+	seen := map[string]struct{}{}
+	names := []string{}
+	for _, text := range texts {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			if _, ok := seen[match[1]]; ok {
+				continue
+			}
+			seen[match[1]] = struct{}{}
+			names = append(names, match[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidatePlaceholders rejeita placeholders que não existem no catálogo.
+func ValidatePlaceholders(texts ...string) error {
+	unknown := []string{}
+	for _, name := range PlaceholdersIn(texts...) {
+		if _, ok := placeholders[name]; !ok {
+			unknown = append(unknown, "{{"+name+"}}")
+		}
+	}
+	if len(unknown) > 0 {
+		return customerror.Make(
+			fmt.Sprintf("placeholders desconhecidos: %s", strings.Join(unknown, ", ")),
+			http.StatusBadRequest,
+			errors.New("ErrUnknownPlaceholder"),
+		)
+	}
+	return nil
+}
+
+// renderText substitui os placeholders e devolve os que ficaram sem valor.
+// escape adapta os valores ao canal (ex.: WhatsApp não pode receber marcação vinda do cadastro do aluno).
+func renderText(text string, rc RenderContext, escape func(string) string) (string, []string) {
+	missing := []string{}
+	rendered := placeholderPattern.ReplaceAllStringFunc(text, func(token string) string {
+		name := placeholderPattern.FindStringSubmatch(token)[1]
+		resolve, ok := placeholders[name]
+		if !ok {
+			missing = append(missing, name)
+			return token
+		}
+		value := resolve(rc)
+		if value == "" {
+			missing = append(missing, name)
+			return token
+		}
+		if escape != nil {
+			value = escape(value)
+		}
+		return value
+	})
+	return rendered, missing
+}
+
+// renderedMessage é o assunto e o corpo já preenchidos para um aluno.
+// HTML só é preenchido no email, quando ele sai em HTML; Body continua sendo o texto escrito pelo professor.
+type renderedMessage struct {
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// renderEmail renderiza assunto e corpo do email (e do SMS) de um aluno.
+func renderEmail(subject, body string, format BodyFormat, rc RenderContext) (renderedMessage, []string) {
+	renderedSubject, missingSubject := renderText(subject, rc, singleLine)
+	renderedBody, missingBody := renderText(body, rc, format.escapeBody(nil))
+	return renderedMessage{Subject: renderedSubject, Body: renderedBody}, append(missingSubject, missingBody...)
+}
+
+// renderWhatsApp renderiza assunto e corpo do WhatsApp de um aluno; o envio ainda passa por formatWhatsAppBody.
+func renderWhatsApp(subject, body string, format BodyFormat, rc RenderContext) (renderedMessage, []string) {
+	renderedSubject, missingSubject := renderText(subject, rc, escapeWhatsApp)
+	renderedBody, missingBody := renderText(body, rc, format.escapeBody(escapeWhatsApp))
+	return renderedMessage{Subject: renderedSubject, Body: renderedBody}, append(missingSubject, missingBody...)
+}
+
+func singleLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// whatsAppMarkup remove marcadores de negrito/tachado/monoespaçado vindos dos dados do aluno.
+// "_" é mantido porque aparece com frequência em emails.
+var whatsAppMarkup = strings.NewReplacer("*", "", "~", "", "`", "")
+
+func escapeWhatsApp(value string) string {
+	return whatsAppMarkup.Replace(singleLine(value))
+}
+
+// missingPlaceholdersError descreve quais placeholders ficariam vazios e para quantos alunos.
+func missingPlaceholdersError(missing map[string]int) error {
+	if len(missing) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	details := make([]string, 0, len(names))
+	for _, name := range names {
+		details = append(details, fmt.Sprintf("{{%s}} (%d aluno(s))", name, missing[name]))
+	}
+	return customerror.Make(
+		fmt.Sprintf("placeholders sem valor: %s", strings.Join(details, ", ")),
+		http.StatusBadRequest,
+		errMissingPlaceholder,
+	)
+}
@@ -0,0 +1,106 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ThalysSilva/unicast-backend/internal/discipline"
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/internal/user"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(value string) *string {
+	return &value
+}
+
+func TestPlaceholdersInListsUniqueNames(t *testing.T) {
+	got := PlaceholdersIn("Olá {{ name }}", "{{name}}, matrícula {{studentId}} em {{discipline}}")
+
+	assert.Equal(t, []string{"discipline", "name", "studentId"}, got)
+}
+
+func TestValidatePlaceholdersRejectsUnknownNames(t *testing.T) {
+	err := ValidatePlaceholders("Olá {{nome}}", "{{name}} {{cpf}}")
+
+	require.Error(t, err)
+	customErr := &customerror.CustomError{}
+	require.True(t, errors.As(err, &customErr))
+	assert.Equal(t, "placeholders desconhecidos: {{cpf}}, {{nome}}", customErr.PublicMessage())
+}
+
+func TestRenderEmailFillsStudentTeacherAndDiscipline(t *testing.T) {
+	rc := RenderContext{
+		Student:    &student.Student{StudentID: "2026001", Name: strPtr("Maria Souza")},
+		Teacher:    &user.User{Name: "Prof. Ana"},
+		Discipline: &discipline.Discipline{Name: "Cálculo I"},
+	}
+
+	got, missing := renderEmail("Aviso para {{firstName}}", "Olá {{name}}, sua matrícula {{studentId}} em {{discipline}}.\n{{teacherName}}", BodyFormatText, rc)
+
+	assert.Empty(t, missing)
+	assert.Equal(t, "Aviso para Maria", got.Subject)
+	assert.Equal(t, "Olá Maria Souza, sua matrícula 2026001 em Cálculo I.\nProf. Ana", got.Body)
+}
+
+func TestRenderWhatsAppStripsMarkupFromValues(t *testing.T) {
+	rc := RenderContext{Student: &student.Student{Name: strPtr("*Maria*\n~Souza~"), Email: strPtr("maria_souza@example.com")}}
+
+	got, missing := renderWhatsApp("Aviso", "Olá {{name}} ({{email}})", BodyFormatText, rc)
+
+	assert.Empty(t, missing)
+	assert.Equal(t, "Olá Maria Souza (maria_souza@example.com)", got.Body)
+	assert.Equal(t, "*Aviso*\n\nOlá Maria Souza (maria_souza@example.com)", formatWhatsAppBody(got.Subject, got.Body, BodyFormatText))
+}
+
+func TestCheckPlaceholdersReportsMissingValuesBeforeSending(t *testing.T) {
+	students := []*student.Student{
+		{ID: "1", StudentID: "2026001", Name: strPtr("Maria")},
+		{ID: "2", StudentID: "2026002"},
+		{ID: "3", StudentID: "2026003", Name: strPtr("  ")},
+	}
+
+	err := checkPlaceholders(&Message{Subject: "Aviso", Body: "Olá {{name}}, {{name}} - {{discipline}}"}, students, RenderContext{})
+
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errMissingPlaceholder))
+	customErr := &customerror.CustomError{}
+	require.True(t, errors.As(err, &customErr))
+	assert.Equal(t, "placeholders sem valor: {{discipline}} (3 aluno(s)), {{name}} (2 aluno(s))", customErr.PublicMessage())
+	assert.True(t, isPermanentDeliveryError(err))
+}
+
+func TestCheckPlaceholdersIgnoresPlainMessages(t *testing.T) {
+	err := checkPlaceholders(&Message{Subject: "Aviso", Body: "Sem placeholders"}, []*student.Student{{ID: "1"}}, RenderContext{})
+
+	assert.NoError(t, err)
+}
+
+type fakeTemplateRepository struct {
+	TemplateRepository
+	templates map[string]*Template
+}
+
+func (f *fakeTemplateRepository) FindByID(ctx context.Context, id string) (*Template, error) {
+	return f.templates[id], nil
+}
+
+func TestResolveTemplateFillsOnlyMissingFields(t *testing.T) {
+	svc := &service{templateRepository: &fakeTemplateRepository{templates: map[string]*Template{
+		"tpl-1": {ID: "tpl-1", UserID: "user-1", Subject: "Olá {{firstName}}", Body: "Corpo do template"},
+	}}}
+
+	message := &Message{UserID: "user-1", TemplateID: "tpl-1", Body: "Corpo próprio"}
+	require.NoError(t, svc.resolveTemplate(context.Background(), message))
+	assert.Equal(t, "Olá {{firstName}}", message.Subject)
+	assert.Equal(t, "Corpo próprio", message.Body)
+
+	err := svc.resolveTemplate(context.Background(), &Message{UserID: "user-2", TemplateID: "tpl-1"})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	err = svc.resolveTemplate(context.Background(), &Message{UserID: "user-1", Subject: "Sem corpo"})
+	assert.ErrorIs(t, err, ErrEmptyMessage)
+}
@@ -0,0 +1,157 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var (
+	ErrNothingToRetry              = customerror.Make("o envio não possui falhas para reenviar", http.StatusConflict, errors.New("ErrNothingToRetry"))
+	ErrRetryInProgress             = customerror.Make("já existe um reenvio em andamento para este envio", http.StatusConflict, errors.New("ErrRetryInProgress"))
+	ErrDeliveryInProgress          = customerror.Make("o envio ainda está em andamento; aguarde a conclusão para reenviar as falhas", http.StatusConflict, errors.New("ErrDeliveryInProgress"))
+	ErrRetryAttachmentsUnavailable = customerror.Make("os anexos deste envio não foram armazenados; envie a mensagem novamente", http.StatusConflict, errors.New("ErrRetryAttachmentsUnavailable"))
+)
+
+// RetryInput traz o JWE para abrir a senha SMTP; o do envio original é descartado ao final dele.
+type RetryInput struct {
+	Jwe string `json:"jwe"`
+}
+
+// Retry reenfileira o conteúdo de um envio apenas para as falhas dele: cada aluno só no canal em que falhou.
+// Os logs do reenvio apontam para o envio original em retry_of.
+func (s *service) Retry(ctx context.Context, userID, deliveryGroupID, jwe string) (*Job, error) {
+	detail, err := s.logRepository.FindGroup(ctx, userID, deliveryGroupID)
+	if err != nil {
+		return nil, customerror.Trace("Retry", err)
+	}
+	if detail == nil {
+		return nil, customerror.Trace("Retry", ErrDeliveryNotFound)
+	}
+
+	failed, err := s.logRepository.FindFailedRecipients(ctx, userID, deliveryGroupID)
+	if err != nil {
+		return nil, customerror.Trace("Retry", err)
+	}
+	failedIDs := []string{}
+	for _, ids := range failed {
+		failedIDs = append(failedIDs, ids...)
+	}
+	if len(failedIDs) == 0 {
+		return nil, customerror.Trace("Retry", ErrNothingToRetry)
+	}
+
+	pending, err := s.outboxRepository.HasPendingRetry(ctx, deliveryGroupID)
+	if err != nil {
+		return nil, customerror.Trace("Retry", err)
+	}
+	if pending {
+		return nil, customerror.Trace("Retry", ErrRetryInProgress)
+	}
+
+	message, err := s.originalMessage(ctx, userID, detail)
+	if err != nil {
+		return nil, customerror.Trace("Retry", err)
+	}
+	message.Jwe = jwe
+	// O reenvio repete cada falha só no canal em que ela ocorreu, sem redirecionar para o outro canal.
+	message.Fallback = ""
+	if len(failed[ChannelEmail]) == 0 {
+		message.SmtpId = ""
+	}
+	if len(failed[ChannelWhatsApp]) == 0 {
+		message.WhatsappId = ""
+	}
+	if len(failed[ChannelSMS]) == 0 {
+		message.SmsId = ""
+	}
+
+	students, err := s.studentRepository.FindByIDs(ctx, userID, uniqueIDs(failedIDs))
+	if err != nil {
+		return nil, customerror.Trace("Retry", err)
+	}
+	if len(students) == 0 {
+		return nil, customerror.Trace("Retry", ErrStudentsNotFound)
+	}
+
+	channels, senders, err := s.selectedSenders(ctx, message)
+	if err != nil {
+		return nil, customerror.Trace("Retry", err)
+	}
+	renderContext, err := s.loadRenderContext(ctx, message)
+	if err != nil {
+		return nil, customerror.Trace("Retry", err)
+	}
+	if err := checkPlaceholders(message, students, renderContext); err != nil {
+		return nil, customerror.Trace("Retry", err)
+	}
+	if err := checkCredentials(ctx, message, channels); err != nil {
+		return nil, err
+	}
+
+	job := buildJob(message, &sendPlan{students: students, channels: channels, senders: senders, renderContext: renderContext})
+	job.RetryOf = &deliveryGroupID
+
+	// Alunos removidos desde o envio original ficam de fora, assim como canais sem remetente (logs antigos).
+	found := make(map[string]struct{}, len(students))
+	for _, stud := range students {
+		found[stud.ID] = struct{}{}
+	}
+	recipients := map[Channel][]string{}
+	for channel, ids := range failed {
+		if _, ok := senders[channel]; !ok {
+			continue
+		}
+		for _, id := range ids {
+			if _, ok := found[id]; ok {
+				recipients[channel] = append(recipients[channel], id)
+			}
+		}
+	}
+	if err := s.enqueue(ctx, job, recipients); err != nil {
+		return nil, customerror.Trace("Retry", err)
+	}
+
+	job.StudentCount = len(students)
+	return job, nil
+}
+
+// originalMessage recupera o conteúdo do envio pelo job do outbox.
+// Envios anteriores ao outbox só têm os logs, que guardam o nome dos anexos mas não o conteúdo.
+// Enquanto o job original não terminar, as falhas ainda podem ser tentadas de novo pelo worker.
+func (s *service) originalMessage(ctx context.Context, userID string, detail *DeliveryGroupDetail) (*Message, error) {
+	job, err := s.outboxRepository.FindJob(ctx, detail.ID)
+	if err != nil {
+		return nil, err
+	}
+	if job != nil && job.UserID == userID {
+		if job.Status != JobStatusCompleted {
+			return nil, ErrDeliveryInProgress
+		}
+		message := job.toMessage()
+		if job.TemplateID != nil {
+			message.TemplateID = *job.TemplateID
+		}
+		return message, nil
+	}
+
+	if detail.AttachmentCount > 0 {
+		return nil, ErrRetryAttachmentsUnavailable
+	}
+	message := &Message{UserID: userID}
+	if detail.Subject != nil {
+		message.Subject = *detail.Subject
+	}
+	if detail.Body != nil {
+		message.Body = *detail.Body
+	}
+	if detail.SmtpID != nil {
+		message.SmtpId = *detail.SmtpID
+	}
+	if detail.WhatsappID != nil {
+		message.WhatsappId = *detail.WhatsappID
+	}
+	return message, nil
+}
@@ -0,0 +1,101 @@
+package message
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRetryLogRepository struct {
+	fakeHistoryRepository
+	failed map[Channel][]string
+}
+
+func (f *fakeRetryLogRepository) FindFailedRecipients(ctx context.Context, userID, groupID string) (map[Channel][]string, error) {
+	return f.failed, nil
+}
+
+type fakeRetryOutbox struct {
+	fakeOutbox
+	pendingRetry bool
+}
+
+func (f *fakeRetryOutbox) HasPendingRetry(ctx context.Context, deliveryGroupID string) (bool, error) {
+	return f.pendingRetry, nil
+}
+
+func newRetryService(detail *DeliveryGroupDetail, failed map[Channel][]string, outbox *fakeRetryOutbox) *service {
+	svc := newPreviewService()
+	svc.logRepository = &fakeRetryLogRepository{
+		fakeHistoryRepository: fakeHistoryRepository{groups: map[string]*DeliveryGroupDetail{"user-1/group-1": detail}},
+		failed:                failed,
+	}
+	svc.outboxRepository = outbox
+	return svc
+}
+
+func TestRetryRejectsUnknownDeliveriesAndDeliveriesWithoutFailures(t *testing.T) {
+	outbox := &fakeRetryOutbox{fakeOutbox: fakeOutbox{jobs: map[string]*Job{}}}
+	svc := newRetryService(&DeliveryGroupDetail{DeliveryGroup: DeliveryGroup{ID: "group-1"}}, map[Channel][]string{}, outbox)
+
+	_, err := svc.Retry(context.Background(), "user-2", "group-1", "")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+
+	_, err = svc.Retry(context.Background(), "user-1", "group-1", "")
+	assert.ErrorIs(t, err, ErrNothingToRetry)
+}
+
+func TestRetryRejectsWhileAnotherRetryIsPending(t *testing.T) {
+	outbox := &fakeRetryOutbox{fakeOutbox: fakeOutbox{jobs: map[string]*Job{}}, pendingRetry: true}
+	svc := newRetryService(&DeliveryGroupDetail{DeliveryGroup: DeliveryGroup{ID: "group-1"}}, map[Channel][]string{ChannelEmail: {"s1"}}, outbox)
+
+	_, err := svc.Retry(context.Background(), "user-1", "group-1", "")
+
+	assert.ErrorIs(t, err, ErrRetryInProgress)
+}
+
+func TestRetryRejectsLegacyDeliveriesWithAttachments(t *testing.T) {
+	outbox := &fakeRetryOutbox{fakeOutbox: fakeOutbox{jobs: map[string]*Job{}}}
+	detail := &DeliveryGroupDetail{DeliveryGroup: DeliveryGroup{ID: "group-1", Subject: strPtr("Aviso")}, Body: strPtr("Corpo"), AttachmentCount: 1}
+	svc := newRetryService(detail, map[Channel][]string{ChannelWhatsApp: {"s1"}}, outbox)
+
+	_, err := svc.Retry(context.Background(), "user-1", "group-1", "")
+
+	assert.ErrorIs(t, err, ErrRetryAttachmentsUnavailable)
+}
+
+func TestRetryOriginalMessageComesFromTheStoredJob(t *testing.T) {
+	job := &Job{
+		ID:          "group-1",
+		UserID:      "user-1",
+		Status:      JobStatusCompleted,
+		SmtpID:      strPtr("smtp-1"),
+		TemplateID:  strPtr("tpl-1"),
+		Subject:     "Olá {{firstName}}",
+		Body:        "Segue o material",
+		Attachments: []Attachment{{FileName: "aula.pdf", Data: []byte("%PDF-1.4")}},
+	}
+	outbox := &fakeRetryOutbox{fakeOutbox: fakeOutbox{jobs: map[string]*Job{job.ID: job}}}
+	svc := newRetryService(nil, nil, outbox)
+
+	message, err := svc.originalMessage(context.Background(), "user-1", &DeliveryGroupDetail{DeliveryGroup: DeliveryGroup{ID: "group-1"}, AttachmentCount: 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Olá {{firstName}}", message.Subject)
+	assert.Equal(t, "smtp-1", message.SmtpId)
+	assert.Equal(t, "tpl-1", message.TemplateID)
+	require.NotNil(t, message.Attachments)
+	assert.Equal(t, "aula.pdf", (*message.Attachments)[0].FileName)
+}
+
+func TestRetryRejectsWhileTheOriginalJobIsUnfinished(t *testing.T) {
+	job := &Job{ID: "group-1", UserID: "user-1", Status: JobStatusPending, SmtpID: strPtr("smtp-1"), Subject: "Aviso", Body: "Corpo"}
+	outbox := &fakeRetryOutbox{fakeOutbox: fakeOutbox{jobs: map[string]*Job{job.ID: job}}}
+	svc := newRetryService(&DeliveryGroupDetail{DeliveryGroup: DeliveryGroup{ID: "group-1"}}, map[Channel][]string{ChannelEmail: {"s1"}}, outbox)
+
+	_, err := svc.Retry(context.Background(), "user-1", "group-1", "")
+
+	assert.ErrorIs(t, err, ErrDeliveryInProgress)
+}
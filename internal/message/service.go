@@ -2,155 +2,550 @@ package message
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
-	"log"
-	"mime"
 	"net/http"
-	"path/filepath"
 	"strings"
-	"time"
 
-	"github.com/ThalysSilva/unicast-backend/internal/auth"
-	"github.com/ThalysSilva/unicast-backend/internal/config/env"
-	"github.com/ThalysSilva/unicast-backend/internal/encryption"
+	"github.com/ThalysSilva/unicast-backend/internal/attachment"
+	"github.com/ThalysSilva/unicast-backend/internal/discipline"
+	"github.com/ThalysSilva/unicast-backend/internal/sms"
 	"github.com/ThalysSilva/unicast-backend/internal/smtp"
 	"github.com/ThalysSilva/unicast-backend/internal/student"
 	"github.com/ThalysSilva/unicast-backend/internal/user"
 	"github.com/ThalysSilva/unicast-backend/internal/whatsapp"
 	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/ThalysSilva/unicast-backend/pkg/fetcher"
 	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
 )
 
 type Service interface {
-	Send(ctx context.Context, message *Message) (emailsFails, whatsappFails *[]student.Student, err error)
+	// Send valida o disparo e o grava no outbox; a entrega acontece em segundo plano pelo Worker.
+	Send(ctx context.Context, message *Message) (*Job, error)
+	// Preview percorre as mesmas validações de Send e prevê o resultado por aluno, sem entregar nada.
+	Preview(ctx context.Context, message *Message) (*PreviewResponse, error)
+	// Validate confere template, destinatários e posse das instâncias como Send, sem checar credenciais nem enfileirar.
+	Validate(ctx context.Context, message *Message) error
+	GetJob(ctx context.Context, userID, jobID string) (*JobSummary, error)
+	// Retry reenfileira um envio do histórico apenas para os alunos e canais que falharam.
+	Retry(ctx context.Context, userID, deliveryGroupID, jwe string) (*Job, error)
+	// Deliver envia o job para os alunos informados em um canal e devolve o resultado de cada aluno.
+	Deliver(ctx context.Context, job *Job, channel Channel, studentIDs []string) []DeliveryResult
+	// Progress abre o andamento em tempo real de um envio do usuário.
+	Progress(ctx context.Context, userID, deliveryGroupID string) (*ProgressStream, error)
+}
+
+// DeliveryResult é o resultado da entrega de um job para um aluno em um canal.
+type DeliveryResult struct {
+	StudentID string
+	Err       error
+	// Permanent indica que uma nova tentativa não mudaria o resultado (ex.: aluno sem email).
+	Permanent bool
+	// Log é o registro a persistir em message_logs quando o resultado for definitivo.
+	Log *Log
 }
 
 type service struct {
-	whatsAppRepository whatsapp.Repository
-	smtpService        smtp.Service
-	smtpRepository     smtp.Repository
-	userRepository     user.Repository
-	studentRepository  student.Repository
-	logRepository      LogRepository
-	jweSecret          []byte
-	defaultCountryCode string
+	whatsAppRepository    whatsapp.Repository
+	evolution             whatsapp.EvolutionClient
+	smtpService           smtp.Service
+	smtpRepository        smtp.Repository
+	smsService            sms.Service
+	smsRepository         sms.Repository
+	userRepository        user.Repository
+	studentRepository     student.Repository
+	filterRepository      student.FilterRepository
+	logRepository         LogRepository
+	outboxRepository      OutboxRepository
+	templateRepository    TemplateRepository
+	layoutRepository      LayoutRepository
+	idempotencyRepository IdempotencyRepository
+	attachmentService     attachment.Service
+	disciplineRepository  discipline.Repository
+	jweSecret             []byte
+	defaultCountryCode    string
+	progress              *ProgressBroker
 }
 
 var (
-	ErrSmtpNotFound      = customerror.Make("smtp não encontrado.", 404, errors.New("ErrSmtpNotFound"))
-	ErrWhatsAppNotFound  = customerror.Make("whatsapp não encontrado.", 404, errors.New("ErrWhatsAppNotFound"))
-	ErrStudentsNotFound  = customerror.Make("estudantes não encontrado.", 404, errors.New("ErrStudentsNotFound"))
-	ErrNoChannelSelected = customerror.Make("selecione ao menos um canal de envio", 400, errors.New("ErrNoChannelSelected"))
-	ErrPhoneMissing      = customerror.Make("estudante sem telefone configurado", 400, errors.New("ErrPhoneMissing"))
-	ErrPhoneInvalid      = customerror.Make("telefone inválido para WhatsApp", 400, errors.New("ErrPhoneInvalid"))
-	ErrInvalidAttachment = customerror.Make("anexo inválido", 400, errors.New("ErrInvalidAttachment"))
-	httpClient           = http.DefaultClient
+	ErrSmtpNotFound       = customerror.Make("smtp não encontrado.", 404, errors.New("ErrSmtpNotFound"))
+	ErrWhatsAppNotFound   = customerror.Make("whatsapp não encontrado.", 404, errors.New("ErrWhatsAppNotFound"))
+	ErrStudentsNotFound   = customerror.Make("estudantes não encontrado.", 404, errors.New("ErrStudentsNotFound"))
+	ErrJobNotFound        = customerror.Make("envio não encontrado.", 404, errors.New("ErrJobNotFound"))
+	ErrNoChannelSelected  = customerror.Make("selecione ao menos um canal de envio", 400, errors.New("ErrNoChannelSelected"))
+	ErrEmailMissing       = customerror.Make("estudante sem email configurado", 400, errors.New("ErrEmailMissing"))
+	ErrPhoneMissing       = customerror.Make("estudante sem telefone configurado", 400, errors.New("ErrPhoneMissing"))
+	ErrPhoneInvalid       = customerror.Make("telefone inválido para WhatsApp", 400, errors.New("ErrPhoneInvalid"))
+	ErrNotOnWhatsApp      = customerror.Make("número sem conta no WhatsApp", 400, errors.New("ErrNotOnWhatsApp"))
+	ErrWhatsAppOffline    = customerror.Make("o WhatsApp desta instância está desconectado; leia o QR code novamente para reconectar", 409, errors.New("ErrWhatsAppOffline"))
+	ErrInvalidAttachment  = customerror.Make("anexo inválido", 400, errors.New("ErrInvalidAttachment"))
+	ErrSmtpCredentials    = customerror.Make("não foi possível abrir as credenciais SMTP", 400, errors.New("ErrSmtpCredentials"))
+	ErrSmtpNeedsPassword  = customerror.Make("a senha desta conta SMTP precisa ser informada novamente após a redefinição da sua senha", 409, errors.New("ErrSmtpNeedsPassword"))
+	ErrDisciplineNotFound = customerror.Make("disciplina não encontrada.", 404, errors.New("ErrDisciplineNotFound"))
+	ErrEmptyMessage       = customerror.Make("informe assunto e corpo ou um template", 400, errors.New("ErrEmptyMessage"))
+	ErrNoRecipients       = customerror.Make("informe ao menos um destinatário", 400, errors.New("ErrNoRecipients"))
 )
 
+// attachmentFetcher baixa os anexos por URL bloqueando endereços internos; email e WhatsApp passam por ele.
+var attachmentFetcher = fetcher.New(fetcher.Options{MaxBytes: maxAttachmentBytes})
+
+// permanentDeliveryErrors não se resolvem com nova tentativa do worker.
+var permanentDeliveryErrors = []error{
+	ErrSmtpNotFound,
+	ErrWhatsAppNotFound,
+	ErrSmsNotFound,
+	ErrStudentsNotFound,
+	ErrEmailMissing,
+	ErrPhoneMissing,
+	ErrPhoneInvalid,
+	ErrNotOnWhatsApp,
+	ErrWhatsAppOffline,
+	ErrInvalidAttachment,
+	ErrSmtpCredentials,
+	ErrSmtpNeedsPassword,
+	ErrDisciplineNotFound,
+	ErrTemplateNotFound,
+	attachment.ErrAttachmentNotFound,
+	errMissingPlaceholder,
+	fetcher.ErrSchemeNotAllowed,
+	fetcher.ErrBlockedAddress,
+	fetcher.ErrTooLarge,
+	fetcher.ErrTooManyRedirects,
+	mailer.ErrRecipientRejected,
+	sms.ErrMessageRejected,
+	sms.ErrTokenUnavailable,
+}
+
 const (
-	maxAttachmentCount     = 5
-	maxAttachmentBytes     = 10 * 1024 * 1024
-	maxEmailTotalBytes     = 25 * 1024 * 1024
-	maxWhatsAppTotalBytes  = 15 * 1024 * 1024
+	maxAttachmentCount    = 5
+	maxAttachmentBytes    = attachment.MaxBytes
+	maxEmailTotalBytes    = 25 * 1024 * 1024
+	maxWhatsAppTotalBytes = 15 * 1024 * 1024
 )
 
-var blockedAttachmentExtensions = map[string]struct{}{
-	".apk": {}, ".app": {}, ".bat": {}, ".cmd": {}, ".com": {}, ".dll": {}, ".dmg": {},
-	".exe": {}, ".hta": {}, ".iso": {}, ".jar": {}, ".js": {}, ".msi": {}, ".ps1": {},
-	".scr": {}, ".sh": {}, ".vbs": {}, ".wsf": {},
+// ServiceDeps reúne as dependências do serviço de mensagens, montadas em cmd/main.
+type ServiceDeps struct {
+	WhatsAppRepository    whatsapp.Repository
+	Evolution             whatsapp.EvolutionClient
+	SmtpService           smtp.Service
+	SmtpRepository        smtp.Repository
+	SmsService            sms.Service
+	SmsRepository         sms.Repository
+	UserRepository        user.Repository
+	StudentRepository     student.Repository
+	FilterRepository      student.FilterRepository
+	LogRepository         LogRepository
+	OutboxRepository      OutboxRepository
+	TemplateRepository    TemplateRepository
+	LayoutRepository      LayoutRepository
+	IdempotencyRepository IdempotencyRepository
+	AttachmentService     attachment.Service
+	DisciplineRepository  discipline.Repository
+	JweSecret             []byte
+	Progress              *ProgressBroker
+}
+
+// NewMessageService monta o serviço; defaultCountryCode completa telefones de alunos sem DDI.
+func NewMessageService(deps ServiceDeps, defaultCountryCode string) Service {
+	return &service{
+		whatsAppRepository:    deps.WhatsAppRepository,
+		evolution:             deps.Evolution,
+		smtpService:           deps.SmtpService,
+		smtpRepository:        deps.SmtpRepository,
+		smsService:            deps.SmsService,
+		smsRepository:         deps.SmsRepository,
+		userRepository:        deps.UserRepository,
+		studentRepository:     deps.StudentRepository,
+		filterRepository:      deps.FilterRepository,
+		logRepository:         deps.LogRepository,
+		outboxRepository:      deps.OutboxRepository,
+		templateRepository:    deps.TemplateRepository,
+		layoutRepository:      deps.LayoutRepository,
+		idempotencyRepository: deps.IdempotencyRepository,
+		attachmentService:     deps.AttachmentService,
+		disciplineRepository:  deps.DisciplineRepository,
+		jweSecret:             deps.JweSecret,
+		defaultCountryCode:    defaultCountryCode,
+		progress:              deps.Progress,
+	}
 }
 
-var allowedAttachmentExtensions = map[string]struct{}{
-	".csv": {}, ".doc": {}, ".docx": {}, ".jpeg": {}, ".jpg": {}, ".mp3": {}, ".mp4": {},
-	".ogg": {}, ".pdf": {}, ".png": {}, ".ppt": {}, ".pptx": {}, ".txt": {}, ".webp": {},
-	".xls": {}, ".xlsx": {},
+// sendPlan reúne o que Send resolve antes de enfileirar; Preview percorre o mesmo caminho.
+type sendPlan struct {
+	students []*student.Student
+	// channels são os canais escolhidos na mensagem; senders guarda o remetente de cada um.
+	channels      []channelSender
+	senders       map[Channel]senderDetails
+	renderContext RenderContext
 }
 
-func NewMessageService(whatsAppRepository whatsapp.Repository, smtpService smtp.Service, smtpRepository smtp.Repository, userRepository user.Repository, studentRepository student.Repository, logRepository LogRepository, jweSecret []byte) Service {
-	cfg, _ := env.Load()
-	defaultCountry := "55"
-	if cfg != nil && cfg.Defaults.CountryCode != "" {
-		defaultCountry = cfg.Defaults.CountryCode
+func (s *service) plan(ctx context.Context, message *Message) (*sendPlan, error) {
+	if err := s.resolveTemplate(ctx, message); err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if err := ValidatePlaceholders(message.Subject, message.Body); err != nil {
+		return nil, customerror.Trace("Send", err)
 	}
 
-	return &service{
-		whatsAppRepository: whatsAppRepository,
-		smtpService:        smtpService,
-		smtpRepository:     smtpRepository,
-		userRepository:     userRepository,
-		studentRepository:  studentRepository,
-		logRepository:      logRepository,
-		jweSecret:          jweSecret,
-		defaultCountryCode: defaultCountry,
-	}
-}
-
-func extractEmailFailedStudents(err error, students []*student.Student) ([]student.Student, error) {
-	var emailErr *mailer.EmailSentError
-	if errors.As(err, &emailErr) {
-		emailToStudent := make(map[string]*student.Student, len(students))
-		for _, student := range students {
-			if student.Email != nil {
-				emailToStudent[*student.Email] = student
-			}
+	students, err := s.resolveRecipients(ctx, message)
+	if err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if err := validateAttachmentCount(message.Attachments); err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if err := s.loadStoredAttachments(ctx, message); err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+
+	channels, senders, err := s.selectedSenders(ctx, message)
+	if err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if err := validateFallback(message.Fallback, senders); err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+
+	renderContext, err := s.loadRenderContext(ctx, message)
+	if err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	return &sendPlan{
+		students:      students,
+		channels:      channels,
+		senders:       senders,
+		renderContext: renderContext,
+	}, nil
+}
+
+func (s *service) Validate(ctx context.Context, message *Message) error {
+	if _, err := s.plan(ctx, message); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkCredentials confirma as credenciais de cada canal escolhido antes de enfileirar.
+func checkCredentials(ctx context.Context, message *Message, channels []channelSender) error {
+	for _, sender := range channels {
+		if err := sender.CheckCredentials(ctx, message); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+func (s *service) Send(ctx context.Context, message *Message) (*Job, error) {
+	if message.IdempotencyKey != "" {
+		return s.sendOnce(ctx, message, func() (*Job, error) { return s.send(ctx, message) })
+	}
+	return s.send(ctx, message)
+}
+
+func (s *service) send(ctx context.Context, message *Message) (*Job, error) {
+	plan, err := s.plan(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	students, renderContext := plan.students, plan.renderContext
+
+	if err := validateAttachmentSources(message.Attachments); err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if _, _, err := buildWhatsAppAttachments(message); err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if err := checkPlaceholders(message, students, renderContext); err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+	if err := checkCredentials(ctx, message, plan.channels); err != nil {
+		return nil, err
+	}
+
+	job := buildJob(message, plan)
 
-		var failedStudents []student.Student
-		for _, failedEmail := range emailErr.To {
-			if student, exists := emailToStudent[failedEmail]; exists {
-				failedStudents = append(failedStudents, *student)
+	recipients := routeRecipients(message.Fallback, students, channelsOf(plan.channels))
+	if err := s.enqueue(ctx, job, recipients); err != nil {
+		return nil, customerror.Trace("Send", err)
+	}
+
+	job.StudentCount = len(students)
+	return job, nil
+}
+
+// buildJob monta o job do outbox com a mensagem ainda sem renderização; o JWE só é guardado para SMTP com senha.
+func buildJob(message *Message, plan *sendPlan) *Job {
+	job := &Job{
+		UserID:  message.UserID,
+		From:    message.From,
+		Subject: message.Subject,
+		Body:    message.Body,
+		Format:  message.Format,
+	}
+	if message.Attachments != nil {
+		job.Attachments = *message.Attachments
+	}
+	if message.TemplateID != "" {
+		job.TemplateID = &message.TemplateID
+	}
+	if message.Fallback != "" {
+		job.Fallback = &message.Fallback
+	}
+	if plan.renderContext.Discipline != nil {
+		job.DisciplineID = &plan.renderContext.Discipline.ID
+	}
+	for _, sender := range plan.senders {
+		if sender.UsesJwe {
+			job.Jwe = &message.Jwe
+		}
+	}
+	job.SmtpID = plan.senders[ChannelEmail].SMTPID
+	job.WhatsAppInstanceID = plan.senders[ChannelWhatsApp].WhatsAppInstanceID
+	job.SmsInstanceID = plan.senders[ChannelSMS].SMSInstanceID
+	return job
+}
+
+// enqueue grava o job, os anexos e os destinatários de cada canal em uma única transação.
+func (s *service) enqueue(ctx context.Context, job *Job, recipients map[Channel][]string) error {
+	_, err := database.MakeTransaction(ctx, []database.Transactional{s.outboxRepository}, func(txRepos []database.Transactional) (any, error) {
+		outboxRepo := txRepos[0].(OutboxRepository)
+		if err := outboxRepo.CreateJob(ctx, job); err != nil {
+			return nil, err
+		}
+		for i, attachment := range job.Attachments {
+			if err := outboxRepo.AddAttachment(ctx, job.ID, i, attachment); err != nil {
+				return nil, err
+			}
+		}
+		for _, sender := range s.senders() {
+			channel := sender.Channel()
+			if err := outboxRepo.AddRecipients(ctx, job.ID, channel, recipients[channel]); err != nil {
+				return nil, err
 			}
 		}
-		return failedStudents, err
+		return nil, nil
+	})
+	return err
+}
+
+func (s *service) GetJob(ctx context.Context, userID, jobID string) (*JobSummary, error) {
+	summary, err := s.outboxRepository.GetJobSummary(ctx, jobID, userID)
+	if err != nil {
+		return nil, customerror.Trace("GetJob", err)
+	}
+	if summary == nil {
+		return nil, customerror.Trace("GetJob", ErrJobNotFound)
 	}
-	return nil, err
+	return summary, nil
 }
 
-func (s *service) Send(ctx context.Context, message *Message) (emailsFails, whatsappFails *[]student.Student, err error) {
-	message.To = uniqueIDs(message.To)
-	students, err := s.studentRepository.FindByIDs(ctx, message.UserID, message.To)
+func (s *service) Deliver(ctx context.Context, job *Job, channel Channel, studentIDs []string) []DeliveryResult {
+	results := make([]DeliveryResult, 0, len(studentIDs))
+	progress := s.deliveryProgress(job.ID, channel)
+
+	students, err := s.studentRepository.FindByIDs(ctx, job.UserID, studentIDs)
 	if err != nil {
-		return nil, nil, customerror.Trace("Send", err)
+		for _, id := range studentIDs {
+			results = append(results, DeliveryResult{StudentID: id, Err: customerror.Trace("Deliver", err)})
+			progress.finish(id, err)
+		}
+		return results
+	}
+
+	found := make(map[string]struct{}, len(students))
+	for _, stud := range students {
+		found[stud.ID] = struct{}{}
+	}
+	for _, id := range studentIDs {
+		if _, ok := found[id]; !ok {
+			results = append(results, DeliveryResult{StudentID: id, Err: customerror.Trace("Deliver", ErrStudentsNotFound), Permanent: true})
+			progress.finish(id, ErrStudentsNotFound)
+		}
 	}
 	if len(students) == 0 {
-		return nil, nil, customerror.Trace("Send", ErrStudentsNotFound)
+		return results
 	}
-	if err := validateAttachmentCount(message.Attachments); err != nil {
-		return nil, nil, customerror.Trace("Send", err)
+
+	message := job.toMessage()
+	var delivery channelDelivery
+	sender, known := s.sender(channel)
+	renderContext, err := s.loadRenderContext(ctx, message)
+	if err == nil && known && sender.SendsAttachments() {
+		err = s.loadStoredAttachments(ctx, message)
+	}
+	switch {
+	case err != nil:
+		delivery = failAllDelivery(students, senderDetails{}, customerror.Trace("Deliver", err))
+	case !known:
+		delivery = failAllDelivery(students, senderDetails{}, fmt.Errorf("canal desconhecido: %s", channel))
+	default:
+		delivery = sender.Deliver(ctx, message, students, renderContext, progress.report)
 	}
 
-	smtpInstance, waInstance, err := s.loadSenders(ctx, message.UserID, message.SmtpId, message.WhatsappId)
-	if err != nil {
-		return nil, nil, err
+	attachmentNames, attachmentCount := "", 0
+	if known && sender.SendsAttachments() {
+		attachmentNames, attachmentCount = joinAttachmentNames(job.Attachments), len(job.Attachments)
+	}
+	for _, stud := range students {
+		err := delivery.failures[stud.ID]
+		progress.finish(stud.ID, err)
+		content, ok := delivery.rendered[stud.ID]
+		if !ok {
+			content = renderedMessage{Subject: job.Subject, Body: job.Body}
+		}
+		results = append(results, DeliveryResult{
+			StudentID: stud.ID,
+			Err:       err,
+			Permanent: isPermanentDeliveryError(err),
+			Log:       buildDeliveryLog(job, channel, stud.ID, content, delivery.sender, delivery.providerIDs[stud.ID], attachmentNames, attachmentCount, err),
+		})
+	}
+	return results
+}
+
+// resolveRecipients expande disciplinas, cursos, campi e o filtro salvo em alunos do usuário,
+// soma os alunos de To e aplica o filtro de status a todos eles.
+func (s *service) resolveRecipients(ctx context.Context, message *Message) ([]*student.Student, error) {
+	filter := message.RecipientFilter
+	if message.FilterID != "" {
+		saved, err := s.filterRepository.FindByID(ctx, message.FilterID)
+		if err != nil {
+			return nil, err
+		}
+		if saved == nil || saved.UserID != message.UserID {
+			return nil, student.ErrFilterNotFound
+		}
+		filter = saved.RecipientFilter.Merge(filter)
+	}
+	if len(message.To) == 0 && !filter.HasTargets() {
+		return nil, ErrNoRecipients
 	}
 
-	rawAttachments, attachmentNamesStr, err := buildWhatsAppAttachments(message)
+	ids := message.To
+	if filter.HasTargets() {
+		expanded, err := s.studentRepository.FindIDsByRecipientFilter(ctx, message.UserID, filter)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(append([]string{}, ids...), expanded...)
+	}
+	message.To = uniqueIDs(ids)
+
+	found, err := s.studentRepository.FindByIDs(ctx, message.UserID, message.To)
 	if err != nil {
-		return nil, nil, customerror.Trace("Send", err)
+		return nil, err
 	}
+	students := make([]*student.Student, 0, len(found))
+	for _, stud := range found {
+		if filter.AcceptsStatus(stud.Status) {
+			students = append(students, stud)
+		}
+	}
+	if len(students) == 0 {
+		return nil, ErrStudentsNotFound
+	}
+	return students, nil
+}
 
-	emailFailedSlice := []student.Student{}
-	var emailErr error
-	if smtpInstance != nil {
-		mailerAttachments, err := buildEmailAttachments(ctx, message)
+// resolveTemplate preenche assunto e corpo não informados com os do template escolhido.
+func (s *service) resolveTemplate(ctx context.Context, message *Message) error {
+	if message.TemplateID != "" {
+		template, err := s.templateRepository.FindByID(ctx, message.TemplateID)
 		if err != nil {
-			return nil, nil, customerror.Trace("Send", err)
+			return err
+		}
+		if template == nil || template.UserID != message.UserID {
+			return ErrTemplateNotFound
+		}
+		if strings.TrimSpace(message.Subject) == "" {
+			message.Subject = template.Subject
+		}
+		if strings.TrimSpace(message.Body) == "" {
+			message.Body = template.Body
+		}
+	}
+	if strings.TrimSpace(message.Subject) == "" || strings.TrimSpace(message.Body) == "" {
+		return ErrEmptyMessage
+	}
+	return nil
+}
+
+// loadRenderContext carrega professor e disciplina usados pelos placeholders e, se houver email, o layout do professor.
+// O aluno é preenchido por destinatário.
+func (s *service) loadRenderContext(ctx context.Context, message *Message) (RenderContext, error) {
+	renderContext := RenderContext{}
+	if message.SmtpId != "" {
+		layout, err := s.layoutRepository.FindByUserID(ctx, message.UserID)
+		if err != nil {
+			return renderContext, err
+		}
+		renderContext.Layout = layout
+	}
+	if message.DisciplineID != "" {
+		found, err := s.disciplineRepository.FindByIDWithUserOwnerID(ctx, message.DisciplineID)
+		if err != nil {
+			return renderContext, err
+		}
+		if found == nil || found.UserOwnerID != message.UserID {
+			return renderContext, ErrDisciplineNotFound
+		}
+		renderContext.Discipline = &found.Discipline
+	}
+	if len(PlaceholdersIn(message.Subject, message.Body)) == 0 {
+		return renderContext, nil
+	}
+	teacher, err := s.userRepository.FindByID(ctx, message.UserID)
+	if err != nil {
+		return renderContext, err
+	}
+	renderContext.Teacher = teacher
+	return renderContext, nil
+}
+
+// checkPlaceholders garante, antes de enfileirar, que nenhum aluno receberia um placeholder vazio.
+func checkPlaceholders(message *Message, students []*student.Student, base RenderContext) error {
+	if len(PlaceholdersIn(message.Subject, message.Body)) == 0 {
+		return nil
+	}
+	missing := map[string]int{}
+	for _, stud := range students {
+		renderContext := base
+		renderContext.Student = stud
+		_, names := renderEmail(message.Subject, message.Body, message.Format, renderContext)
+		for name := range countPlaceholders(names) {
+			missing[name]++
 		}
-		emailFailedSlice, emailErr = s.sendEmails(ctx, message, smtpInstance, mailerAttachments, students)
 	}
+	return missingPlaceholdersError(missing)
+}
 
-	whatsappFailedSlice := []student.Student{}
-	if waInstance != nil {
-		whatsappFailedSlice = s.sendWhats(ctx, waInstance, students, formatWhatsAppBody(message.Subject, message.Body), rawAttachments)
+func countPlaceholders(names []string) map[string]int {
+	counts := make(map[string]int, len(names))
+	for _, name := range names {
+		counts[name] = 1
 	}
+	return counts
+}
 
-	s.logResults(ctx, students, emailFailedSlice, whatsappFailedSlice, message, attachmentNamesStr, smtpInstance, waInstance)
+func failAll(students []*student.Student, err error) map[string]error {
+	failures := make(map[string]error, len(students))
+	for _, stud := range students {
+		failures[stud.ID] = err
+	}
+	return failures
+}
 
-	return &emailFailedSlice, &whatsappFailedSlice, emailErr
+func isPermanentDeliveryError(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, permanent := range permanentDeliveryErrors {
+		if errors.Is(err, permanent) {
+			return true
+		}
+	}
+	return false
 }
 
 func uniqueIDs(ids []string) []string {
@@ -173,45 +568,6 @@ func uniqueIDs(ids []string) []string {
 	return unique
 }
 
-func (s *service) loadSenders(ctx context.Context, userID, smtpID, whatsappID string) (*smtp.Instance, *whatsapp.Instance, error) {
-	if smtpID == "" && whatsappID == "" {
-		return nil, nil, customerror.Trace("Send", ErrNoChannelSelected)
-	}
-
-	var smtpInstance *smtp.Instance
-	var waInstance *whatsapp.Instance
-
-	if smtpID != "" {
-		instance, err := s.smtpRepository.FindByID(ctx, smtpID)
-		if err != nil {
-			return nil, nil, customerror.Trace("Send", err)
-		}
-		if instance == nil {
-			return nil, nil, customerror.Trace("Send", ErrSmtpNotFound)
-		}
-		if instance.UserID != userID {
-			return nil, nil, customerror.Trace("Send", ErrSmtpNotFound)
-		}
-		smtpInstance = instance
-	}
-
-	if whatsappID != "" {
-		instance, err := s.whatsAppRepository.FindByID(ctx, whatsappID)
-		if err != nil {
-			return nil, nil, customerror.Trace("Send", err)
-		}
-		if instance == nil {
-			return nil, nil, customerror.Trace("Send", ErrWhatsAppNotFound)
-		}
-		if instance.UserID != userID {
-			return nil, nil, customerror.Trace("Send", ErrWhatsAppNotFound)
-		}
-		waInstance = instance
-	}
-
-	return smtpInstance, waInstance, nil
-}
-
 func buildWhatsAppAttachments(message *Message) ([]Attachment, string, error) {
 	raw := []Attachment{}
 	var names []string
@@ -240,6 +596,30 @@ func buildWhatsAppAttachments(message *Message) ([]Attachment, string, error) {
 	return raw, strings.Join(names, ","), nil
 }
 
+// downloadWhatsAppAttachments baixa os anexos por URL para enviá-los em base64: a Evolution nunca recebe a URL do usuário.
+func downloadWhatsAppAttachments(ctx context.Context, attachments []Attachment) ([]Attachment, error) {
+	downloaded := make([]Attachment, 0, len(attachments))
+	totalBytes := 0
+	for _, attachment := range attachments {
+		if len(attachment.Data) == 0 && attachment.URL != "" {
+			data, err := attachmentFetcher.Fetch(ctx, attachment.URL)
+			if err != nil {
+				return nil, err
+			}
+			if err := validateAttachmentData(attachment.FileName, data, maxAttachmentBytes); err != nil {
+				return nil, err
+			}
+			attachment = Attachment{FileName: attachment.FileName, Data: data}
+		}
+		totalBytes += len(attachment.Data)
+		if totalBytes > maxWhatsAppTotalBytes {
+			return nil, customerror.Make("anexos excedem o limite total do WhatsApp", http.StatusBadRequest, errors.New("whatsapp attachment total too large"))
+		}
+		downloaded = append(downloaded, attachment)
+	}
+	return downloaded, nil
+}
+
 func buildEmailAttachments(ctx context.Context, message *Message) ([]mailer.Attachment, error) {
 	attachments := []mailer.Attachment{}
 	totalBytes := 0
@@ -265,9 +645,9 @@ func buildEmailAttachments(ctx context.Context, message *Message) ([]mailer.Atta
 				Data:     attachment.Data,
 			})
 		case attachment.URL != "":
-			data, err := fetchAttachmentData(ctx, attachment.URL)
+			data, err := attachmentFetcher.Fetch(ctx, attachment.URL)
 			if err != nil {
-				return nil, customerror.Make("falha ao baixar anexo para email", http.StatusBadRequest, err)
+				return nil, err
 			}
 			if err := validateAttachmentData(attachment.FileName, data, maxAttachmentBytes); err != nil {
 				return nil, err
@@ -288,28 +668,28 @@ func buildEmailAttachments(ctx context.Context, message *Message) ([]mailer.Atta
 	return attachments, nil
 }
 
-func fetchAttachmentData(ctx context.Context, rawURL string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("attachment download failed with status %d", resp.StatusCode)
+// loadStoredAttachments carrega o conteúdo dos anexos referenciados por ID no armazenamento de anexos.
+// O job guarda só a referência, então o worker lê os mesmos bytes a cada tentativa.
+func (s *service) loadStoredAttachments(ctx context.Context, message *Message) error {
+	if message.Attachments == nil {
+		return nil
 	}
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	for i := range *message.Attachments {
+		current := &(*message.Attachments)[i]
+		if current.ID == "" || len(current.Data) > 0 {
+			continue
+		}
+		stored, data, err := s.attachmentService.Load(ctx, message.UserID, current.ID)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(current.FileName) == "" {
+			current.FileName = stored.FileName
+		}
+		current.Data = data
+		current.URL = ""
 	}
-
-	return data, nil
+	return nil
 }
 
 func validateAttachmentCount(attachments *[]Attachment) error {
@@ -322,310 +702,86 @@ func validateAttachmentCount(attachments *[]Attachment) error {
 	return nil
 }
 
-func validateAttachmentMetadata(fileName string) error {
-	fileName = strings.TrimSpace(fileName)
-	if fileName == "" {
-		return customerror.Make("anexo deve ter um nome de arquivo", http.StatusBadRequest, errors.New("attachment filename required"))
-	}
-
-	ext := strings.ToLower(filepath.Ext(fileName))
-	if ext == "" {
-		return customerror.Make("anexo deve ter uma extensão permitida", http.StatusBadRequest, errors.New("attachment extension required"))
-	}
-	if _, blocked := blockedAttachmentExtensions[ext]; blocked {
-		return customerror.Make("tipo de arquivo não permitido", http.StatusBadRequest, errors.New("blocked attachment extension"))
-	}
-	if _, allowed := allowedAttachmentExtensions[ext]; !allowed {
-		return customerror.Make("tipo de arquivo não permitido", http.StatusBadRequest, errors.New("attachment extension not allowed"))
-	}
-
-	return nil
-}
-
-func validateAttachmentData(fileName string, data []byte, maxBytes int) error {
-	if len(data) == 0 {
-		return customerror.Make("anexo sem conteúdo", http.StatusBadRequest, errors.New("empty attachment data"))
-	}
-	if len(data) > maxBytes {
-		return customerror.Make("anexo excede o tamanho máximo permitido", http.StatusBadRequest, errors.New("attachment too large"))
-	}
-	if err := validateAttachmentDetectedType(fileName, data); err != nil {
-		return err
-	}
-	return nil
-}
-
-func validateAttachmentDetectedType(fileName string, data []byte) error {
-	ext := strings.ToLower(filepath.Ext(fileName))
-	detected := http.DetectContentType(data)
-	if idx := strings.Index(detected, ";"); idx >= 0 {
-		detected = detected[:idx]
-	}
-
-	expected := mime.TypeByExtension(ext)
-	if idx := strings.Index(expected, ";"); idx >= 0 {
-		expected = expected[:idx]
-	}
-
-	if detected == "application/octet-stream" || detected == "text/plain" {
+func validateAttachmentSources(attachments *[]Attachment) error {
+	if attachments == nil {
 		return nil
 	}
-	if expected != "" && detected != expected {
-		return customerror.Make("conteúdo do anexo não corresponde ao tipo permitido", http.StatusBadRequest, errors.New("attachment mime mismatch"))
-	}
-
-	return nil
-}
-
-func (s *service) sendEmails(ctx context.Context, message *Message, smtpInstance *smtp.Instance, attachments []mailer.Attachment, students []*student.Student) ([]student.Student, error) {
-	from := smtpInstance.Email
-	if message.From != "" {
-		from = message.From
-	}
-
-	recipients := make([]string, 0, len(students))
-	emailFailedStudents := make([]student.Student, 0)
-	for _, stud := range students {
-		if stud.Email == nil || *stud.Email == "" {
-			emailFailedStudents = append(emailFailedStudents, *stud)
-			continue
-		}
-		recipients = append(recipients, *stud.Email)
-	}
-
-	if len(recipients) == 0 {
-		return emailFailedStudents, nil
-	}
-
-	mailData := &mailer.MailerData{
-		From:        from,
-		To:          recipients,
-		Subject:     message.Subject,
-		Body:        message.Body,
-		Attachments: &attachments,
-		ContentType: mailer.TextPlain,
-	}
-
-	if smtpInstance.AuthMode == smtp.AuthModeOAuth {
-		if err := s.sendOAuthEmail(ctx, smtpInstance, mailData); err != nil {
-			emailFailedStudents = studentsToValues(students)
-			return emailFailedStudents, err
+	for _, attachment := range *attachments {
+		if len(attachment.Data) == 0 && attachment.URL == "" && attachment.ID == "" {
+			return customerror.Make("anexo deve conter data, url ou id", http.StatusBadRequest, errors.New("attachment missing data, url and id"))
 		}
-		return emailFailedStudents, nil
-	}
-
-	decryptedJwe, err := auth.DecryptJWE[auth.JwePayload](message.Jwe, s.jweSecret)
-	if err != nil {
-		return nil, customerror.Trace("Send", err)
-	}
-	smtpKey, err := base64.StdEncoding.DecodeString(decryptedJwe.SmtpKeyEncoded)
-	if err != nil {
-		return nil, customerror.Trace("Send", err)
-	}
-
-	decryptedSmtpPassword, err := encryption.DecryptSmtpPassword(smtpInstance.Password, smtpKey, smtpInstance.IV)
-	if err != nil {
-		return nil, customerror.Trace("Send", err)
-	}
-	sender := mailer.NewEmailSender(mailer.SmtpAuthentication{
-		Host:     smtpInstance.Host,
-		Port:     smtpInstance.Port,
-		Username: smtpInstance.Email,
-		Password: decryptedSmtpPassword,
-	})
-
-	if err := sender.SetData(mailData); err != nil {
-		return nil, customerror.Trace("Send", err)
-	}
-
-	emailSendErr := sender.SendEmails(4, 4, 10, 5*time.Second)
-	if emailSendErr != nil {
-		failedStudents, e := extractEmailFailedStudents(emailSendErr, students)
-		if e != nil {
-			return nil, customerror.Trace("Send", e)
-		}
-		if len(failedStudents) > 0 {
-			emailFailedStudents = failedStudents
-		}
-		return emailFailedStudents, emailSendErr
-	}
-	return emailFailedStudents, emailSendErr
-}
-
-func (s *service) sendOAuthEmail(ctx context.Context, smtpInstance *smtp.Instance, data *mailer.MailerData) error {
-	accessToken, err := s.smtpService.RefreshOAuthAccessToken(ctx, smtpInstance)
-	if err != nil {
-		return customerror.Trace("Send", err)
-	}
-
-	switch smtpInstance.Provider {
-	case smtp.ProviderGoogle:
-		if err := mailer.SendWithGmailAPI(accessToken, data); err != nil {
-			return customerror.Trace("Send", err)
+		if len(attachment.Data) == 0 && attachment.URL != "" {
+			if err := fetcher.ValidateURL(attachment.URL); err != nil {
+				return err
+			}
 		}
-		return nil
-	default:
-		return customerror.Trace("Send", customerror.Make("provedor OAuth de email inválido", 400, errors.New("invalidOAuthProvider")))
 	}
+	return nil
 }
 
-func studentsToValues(students []*student.Student) []student.Student {
-	out := make([]student.Student, 0, len(students))
-	for _, stud := range students {
-		if stud != nil {
-			out = append(out, *stud)
-		}
-	}
-	return out
+func validateAttachmentMetadata(fileName string) error {
+	return attachment.ValidateFileName(fileName)
 }
 
-func formatWhatsAppBody(subject, body string) string {
-	subject = strings.TrimSpace(strings.ReplaceAll(subject, "\n", " "))
-	if subject == "" {
-		return body
-	}
-	return fmt.Sprintf("*%s*\n\n%s", subject, body)
+func validateAttachmentData(fileName string, data []byte, maxBytes int) error {
+	return attachment.ValidateData(fileName, data, maxBytes)
 }
 
-func (s *service) sendWhats(ctx context.Context, waInstance *whatsapp.Instance, students []*student.Student, body string, attachments []Attachment) []student.Student {
-	var failed []student.Student
-
-	for _, stud := range students {
-		if stud.Phone == nil || *stud.Phone == "" {
-			failed = append(failed, *stud)
-			continue
-		}
-
-		normalized, err := whatsapp.NormalizeNumber(*stud.Phone, s.defaultCountryCode)
-		if err != nil {
-			failed = append(failed, *stud)
-			continue
-		}
-
-		if err := sendWhatsAppWithRetry(waInstance.InstanceName, normalized, body, 3, 1*time.Second); err != nil {
-			log.Printf("falha ao enviar whatsapp para %s: %v", *stud.Phone, err)
-			failed = append(failed, *stud)
-			continue
-		}
-
-		for _, att := range attachments {
-			if len(att.Data) > 0 {
-				if _, err := whatsapp.SendMedia(waInstance.InstanceName, normalized, att.FileName, att.Data, ""); err != nil {
-					log.Printf("falha ao enviar anexo via whatsapp para %s: %v", *stud.Phone, err)
-					failed = append(failed, *stud)
-					break
-				}
-				continue
-			}
-			if att.URL != "" {
-				if _, err := whatsapp.SendMediaURL(waInstance.InstanceName, normalized, att.URL, att.FileName, ""); err != nil {
-					log.Printf("falha ao enviar anexo via url whatsapp para %s: %v", *stud.Phone, err)
-					failed = append(failed, *stud)
-					break
-				}
-				continue
-			}
-			// Se não há data nem URL, ignora o attachment
-			log.Printf("falha ao enviar anexo via whatsapp para %s: anexo sem data e sem URL", *stud.Phone)
-			failed = append(failed, *stud)
-			break
-		}
+func buildDeliveryLog(job *Job, channel Channel, studentID string, content renderedMessage, sender senderDetails, providerMessageID string, attachmentNames string, attachmentCount int, err error) *Log {
+	errText := ""
+	status := DeliveryStatusSent
+	if err != nil {
+		errText = deliveryErrorText(channel, err)
+		status = DeliveryStatusFailed
+	}
+	return &Log{
+		DeliveryGroupID:    job.ID,
+		StudentID:          studentID,
+		Channel:            channel,
+		Success:            err == nil,
+		ErrorText:          nullableString(errText, err != nil),
+		Subject:            &content.Subject,
+		Body:               &content.Body,
+		SenderType:         nullableString(sender.Type, sender.Type != ""),
+		SenderProvider:     nullableString(sender.Provider, sender.Provider != ""),
+		SenderAddress:      nullableString(sender.Address, sender.Address != ""),
+		SMTPID:             sender.SMTPID,
+		WhatsAppInstanceID: sender.WhatsAppInstanceID,
+		SMSInstanceID:      sender.SMSInstanceID,
+		AttachmentNames:    nullableString(attachmentNames, attachmentCount > 0),
+		AttachmentCount:    attachmentCount,
+		RetryOf:            job.RetryOf,
+		ProviderMessageID:  nullableString(providerMessageID, providerMessageID != ""),
+		DeliveryStatus:     status,
 	}
-
-	return failed
 }
 
-func (s *service) logResults(ctx context.Context, students []*student.Student, emailFailed, whatsappFailed []student.Student, message *Message, attachmentNames string, smtpInstance *smtp.Instance, waInstance *whatsapp.Instance) {
-	attachmentCount := 0
-	if attachmentNames != "" {
-		attachmentCount = len(strings.Split(attachmentNames, ","))
+// deliveryErrorText devolve um texto seguro para exibir ao professor; detalhes internos ficam no outbox.
+func deliveryErrorText(channel Channel, err error) string {
+	customErr := &customerror.CustomError{}
+	if errors.As(err, &customErr) {
+		return customErr.PublicMessage()
 	}
-	deliveryGroupID := newDeliveryGroupID()
-
-	if message.SmtpId != "" {
-		emailFailedSet := make(map[string]string)
-		for _, s := range emailFailed {
-			emailFailedSet[s.ID] = "failed to send email"
-		}
-		senderType := "EMAIL_SMTP"
-		senderProvider := ""
-		senderAddress := ""
-		if smtpInstance != nil {
-			senderAddress = smtpInstance.Email
-			if smtpInstance.AuthMode == smtp.AuthModeOAuth {
-				senderType = "EMAIL_OAUTH"
-				senderProvider = smtpInstance.Provider
-			}
-		}
-		for _, stud := range students {
-			errText, failed := emailFailedSet[stud.ID]
-			if err := s.logRepository.Save(ctx, &Log{
-				DeliveryGroupID: deliveryGroupID,
-				StudentID:       stud.ID,
-				Channel:         ChannelEmail,
-				Success:         !failed,
-				ErrorText:       nullableString(errText, failed),
-				Subject:         &message.Subject,
-				Body:            &message.Body,
-				SenderType:      nullableString(senderType, senderType != ""),
-				SenderProvider:  nullableString(senderProvider, senderProvider != ""),
-				SenderAddress:   nullableString(senderAddress, senderAddress != ""),
-				SMTPID:          &message.SmtpId,
-				AttachmentNames: nullableString(attachmentNames, attachmentCount > 0),
-				AttachmentCount: attachmentCount,
-			}); err != nil {
-				log.Printf("falha ao salvar log email student %s: %v", stud.ID, err)
-			}
-		}
+	// A resposta do servidor SMTP do próprio professor explica a recusa melhor que um texto genérico.
+	recipientErr := &mailer.RecipientError{}
+	if errors.As(err, &recipientErr) {
+		return recipientErr.Error()
 	}
-
-	if message.WhatsappId != "" {
-		whatsFailedSet := make(map[string]string)
-		for _, s := range whatsappFailed {
-			whatsFailedSet[s.ID] = "failed to send whatsapp"
-		}
-		senderType := "WHATSAPP"
-		senderProvider := "evolution"
-		senderAddress := ""
-		if waInstance != nil {
-			senderAddress = waInstance.Phone
-		}
-		for _, stud := range students {
-			errText, failed := whatsFailedSet[stud.ID]
-			if err := s.logRepository.Save(ctx, &Log{
-				DeliveryGroupID:    deliveryGroupID,
-				StudentID:          stud.ID,
-				Channel:            ChannelWhatsApp,
-				Success:            !failed,
-				ErrorText:          nullableString(errText, failed),
-				Subject:            &message.Subject,
-				Body:               &message.Body,
-				SenderType:         nullableString(senderType, senderType != ""),
-				SenderProvider:     nullableString(senderProvider, senderProvider != ""),
-				SenderAddress:      nullableString(senderAddress, senderAddress != ""),
-				WhatsAppInstanceID: &message.WhatsappId,
-				AttachmentNames:    nullableString(attachmentNames, attachmentCount > 0),
-				AttachmentCount:    attachmentCount,
-			}); err != nil {
-				log.Printf("falha ao salvar log whatsapp student %s: %v", stud.ID, err)
-			}
-		}
+	switch channel {
+	case ChannelWhatsApp:
+		return "failed to send whatsapp"
+	case ChannelSMS:
+		return "failed to send sms"
 	}
+	return "failed to send email"
 }
 
-// sendWhatsAppWithRetry encapsula retentativa simples para envio de WhatsApp.
-func sendWhatsAppWithRetry(instanceID, number, body string, attempts int, delay time.Duration) error {
-	var lastErr error
-	for i := 0; i < attempts; i++ {
-		lastErr = whatsapp.SendText(instanceID, number, body)
-		if lastErr == nil {
-			return nil
-		}
-		if i < attempts-1 {
-			time.Sleep(delay)
-		}
+func joinAttachmentNames(attachments []Attachment) string {
+	names := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		names = append(names, attachment.FileName)
 	}
-	return lastErr
+	return strings.Join(names, ",")
 }
 
 func nullableString(val string, set bool) *string {
@@ -634,23 +790,3 @@ func nullableString(val string, set bool) *string {
 	}
 	return &val
 }
-
-func newDeliveryGroupID() string {
-	buf := make([]byte, 16)
-	if _, err := rand.Read(buf); err != nil {
-		now := time.Now().UnixNano()
-		return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", uint32(now>>32), uint16(now>>16), uint16(now), uint16(now>>8), uint64(now)&0xffffffffffff)
-	}
-
-	buf[6] = (buf[6] & 0x0f) | 0x40
-	buf[8] = (buf[8] & 0x3f) | 0x80
-
-	return fmt.Sprintf(
-		"%08x-%04x-%04x-%04x-%012x",
-		uint32(buf[0])<<24|uint32(buf[1])<<16|uint32(buf[2])<<8|uint32(buf[3]),
-		uint16(buf[4])<<8|uint16(buf[5]),
-		uint16(buf[6])<<8|uint16(buf[7]),
-		uint16(buf[8])<<8|uint16(buf[9]),
-		uint64(buf[10])<<40|uint64(buf[11])<<32|uint64(buf[12])<<24|uint64(buf[13])<<16|uint64(buf[14])<<8|uint64(buf[15]),
-	)
-}
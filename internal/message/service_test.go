@@ -6,30 +6,41 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/ThalysSilva/unicast-backend/internal/smtp"
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/pkg/fetcher"
 	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestFormatWhatsAppBodyUsesSubjectAsBoldTitle(t *testing.T) {
-	got := formatWhatsAppBody("Aviso importante", "A aula foi remarcada.")
+	got := formatWhatsAppBody("Aviso importante", "A aula foi remarcada.", BodyFormatText)
 
 	assert.Equal(t, "*Aviso importante*\n\nA aula foi remarcada.", got)
 }
 
 func TestFormatWhatsAppBodyKeepsBodyWhenSubjectIsBlank(t *testing.T) {
-	got := formatWhatsAppBody("  ", "Mensagem sem assunto.")
+	got := formatWhatsAppBody("  ", "Mensagem sem assunto.", BodyFormatText)
 
 	assert.Equal(t, "Mensagem sem assunto.", got)
 }
 
 func TestFormatWhatsAppBodyNormalizesSubjectNewlines(t *testing.T) {
-	got := formatWhatsAppBody("Aviso\nurgente", "Verifique o portal.")
+	got := formatWhatsAppBody("Aviso\nurgente", "Verifique o portal.", BodyFormatText)
 
 	assert.Equal(t, "*Aviso urgente*\n\nVerifique o portal.", got)
 }
 
+// allowLocalAttachmentURLs libera o httptest, que escuta em loopback, para os testes de anexos por URL.
+func allowLocalAttachmentURLs(t *testing.T) {
+	previous := attachmentFetcher
+	attachmentFetcher = fetcher.New(fetcher.Options{MaxBytes: maxAttachmentBytes, AllowPrivateNetworks: true})
+	t.Cleanup(func() { attachmentFetcher = previous })
+}
+
 func TestBuildEmailAttachmentsSupportsBase64DataAndURL(t *testing.T) {
+	allowLocalAttachmentURLs(t)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/arquivo.pdf", r.URL.Path)
 		_, _ = w.Write([]byte("arquivo remoto"))
@@ -63,6 +74,7 @@ func TestBuildEmailAttachmentsSupportsBase64DataAndURL(t *testing.T) {
 }
 
 func TestBuildEmailAttachmentsReturnsErrorWhenURLDownloadFails(t *testing.T) {
+	allowLocalAttachmentURLs(t)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "erro", http.StatusBadGateway)
 	}))
@@ -79,7 +91,45 @@ func TestBuildEmailAttachmentsReturnsErrorWhenURLDownloadFails(t *testing.T) {
 
 	assert.Nil(t, attachments)
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "falha ao baixar anexo para email")
+	assert.ErrorIs(t, err, fetcher.ErrDownloadFailed)
+	assert.False(t, isPermanentDeliveryError(err))
+}
+
+func TestURLAttachmentsToInternalAddressesAreBlocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("o servidor interno não deveria ser acessado")
+	}))
+	defer server.Close()
+	attachments := []Attachment{{FileName: "arquivo.pdf", URL: server.URL + "/arquivo.pdf"}}
+
+	_, err := buildEmailAttachments(context.Background(), &Message{Attachments: &attachments})
+	assert.ErrorIs(t, err, fetcher.ErrBlockedAddress)
+	assert.True(t, isPermanentDeliveryError(err))
+
+	_, err = downloadWhatsAppAttachments(context.Background(), attachments)
+	assert.ErrorIs(t, err, fetcher.ErrBlockedAddress)
+
+	err = validateAttachmentSources(&[]Attachment{{FileName: "arquivo.pdf", URL: "file:///etc/passwd"}})
+	assert.ErrorIs(t, err, fetcher.ErrSchemeNotAllowed)
+}
+
+func TestDownloadWhatsAppAttachmentsSendsURLContentAsData(t *testing.T) {
+	allowLocalAttachmentURLs(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("%PDF-1.7\n"))
+	}))
+	defer server.Close()
+
+	attachments, err := downloadWhatsAppAttachments(context.Background(), []Attachment{
+		{FileName: "local.txt", Data: []byte("arquivo local")},
+		{FileName: "remoto.pdf", URL: server.URL + "/remoto.pdf"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []Attachment{
+		{FileName: "local.txt", Data: []byte("arquivo local")},
+		{FileName: "remoto.pdf", Data: []byte("%PDF-1.7\n")},
+	}, attachments)
 }
 
 func TestBuildEmailAttachmentsRejectsBlockedExtension(t *testing.T) {
@@ -126,3 +176,116 @@ func TestBuildWhatsAppAttachmentsRejectsPayloadAboveLimit(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "anexos excedem o limite total do WhatsApp")
 }
+
+func TestSmtpRejectionIsLoggedWithServerReply(t *testing.T) {
+	rejected := &mailer.RecipientError{Address: "aluno@example.com", Code: 550, Message: "5.1.1 mailbox unavailable"}
+	entry := buildDeliveryLog(&Job{ID: "job-1"}, ChannelEmail, "s1", renderedMessage{}, senderDetails{}, "", "", 0, rejected)
+
+	require.NotNil(t, entry.ErrorText)
+	assert.Equal(t, "servidor SMTP recusou o email (550): 5.1.1 mailbox unavailable", *entry.ErrorText)
+	assert.True(t, isPermanentDeliveryError(rejected))
+
+	deferred := &mailer.RecipientError{Address: "aluno@example.com", Code: 451, Message: "4.7.1 try again later"}
+	assert.False(t, isPermanentDeliveryError(deferred), "recusas temporárias devem voltar para o worker")
+}
+
+type fakeStudentRepository struct {
+	student.Repository
+	students  map[string]*student.Student
+	targeted  []string
+	gotFilter student.RecipientFilter
+}
+
+func (f *fakeStudentRepository) FindIDsByRecipientFilter(ctx context.Context, userOwnerID string, filter student.RecipientFilter) ([]string, error) {
+	f.gotFilter = filter
+	return f.targeted, nil
+}
+
+func (f *fakeStudentRepository) FindByIDs(ctx context.Context, userOwnerID string, ids []string) ([]*student.Student, error) {
+	found := []*student.Student{}
+	for _, id := range ids {
+		if stud, ok := f.students[id]; ok && stud.UserOwnerID == userOwnerID {
+			found = append(found, stud)
+		}
+	}
+	return found, nil
+}
+
+type fakeFilterRepository struct {
+	student.FilterRepository
+	filters map[string]*student.SavedFilter
+}
+
+func (f *fakeFilterRepository) FindByID(ctx context.Context, id string) (*student.SavedFilter, error) {
+	return f.filters[id], nil
+}
+
+func TestResolveRecipientsMergesTargetsAndAppliesStatusFilter(t *testing.T) {
+	students := &fakeStudentRepository{
+		students: map[string]*student.Student{
+			"s1": {ID: "s1", UserOwnerID: "user-1", Status: student.StudentStatusActive},
+			"s2": {ID: "s2", UserOwnerID: "user-1", Status: student.StudentStatusPending},
+			"s3": {ID: "s3", UserOwnerID: "user-1", Status: student.StudentStatusActive},
+		},
+		targeted: []string{"s2", "s3"},
+	}
+	filters := &fakeFilterRepository{filters: map[string]*student.SavedFilter{
+		"filter-1": {ID: "filter-1", UserID: "user-1", RecipientFilter: student.RecipientFilter{ProgramIDs: []string{"program-1"}}},
+	}}
+	svc := &service{studentRepository: students, filterRepository: filters}
+
+	message := &Message{
+		UserID:          "user-1",
+		To:              []string{"s1", "s3"},
+		FilterID:        "filter-1",
+		RecipientFilter: student.RecipientFilter{DisciplineIDs: []string{"discipline-1"}, Statuses: []student.StudentStatus{student.StudentStatusActive}},
+	}
+	got, err := svc.resolveRecipients(context.Background(), message)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"discipline-1"}, students.gotFilter.DisciplineIDs)
+	assert.Equal(t, []string{"program-1"}, students.gotFilter.ProgramIDs)
+	assert.Equal(t, []string{"s1", "s3", "s2"}, message.To)
+	ids := []string{}
+	for _, stud := range got {
+		ids = append(ids, stud.ID)
+	}
+	assert.Equal(t, []string{"s1", "s3"}, ids)
+}
+
+func TestResolveRecipientsRejectsForeignFilterAndEmptyTargets(t *testing.T) {
+	filters := &fakeFilterRepository{filters: map[string]*student.SavedFilter{
+		"filter-1": {ID: "filter-1", UserID: "user-2", RecipientFilter: student.RecipientFilter{CampusIDs: []string{"campus-1"}}},
+	}}
+	svc := &service{studentRepository: &fakeStudentRepository{}, filterRepository: filters}
+
+	_, err := svc.resolveRecipients(context.Background(), &Message{UserID: "user-1", FilterID: "filter-1"})
+	assert.ErrorIs(t, err, student.ErrFilterNotFound)
+
+	_, err = svc.resolveRecipients(context.Background(), &Message{UserID: "user-1", RecipientFilter: student.RecipientFilter{Statuses: []student.StudentStatus{student.StudentStatusActive}}})
+	assert.ErrorIs(t, err, ErrNoRecipients)
+
+	_, err = svc.resolveRecipients(context.Background(), &Message{UserID: "user-1", RecipientFilter: student.RecipientFilter{CampusIDs: []string{"campus-1"}}})
+	assert.ErrorIs(t, err, ErrStudentsNotFound)
+}
+
+func TestSmtpInstanceAwaitingPasswordIsRejectedWithClearError(t *testing.T) {
+	svc := &service{smtpRepository: &fakeSmtpRepository{instance: &smtp.Instance{
+		ID: "smtp-1", UserID: "user-1", AuthMode: smtp.AuthModePassword, AuthMechanism: mailer.AuthPlain, PasswordReentryRequired: true,
+	}}}
+
+	err := (&emailSender{service: svc}).CheckCredentials(context.Background(), &Message{UserID: "user-1", SmtpId: "smtp-1", Jwe: "jwe"})
+
+	assert.ErrorIs(t, err, ErrSmtpNeedsPassword)
+	assert.True(t, isPermanentDeliveryError(err))
+}
+
+func TestMessageWithDiscardedJweIsRejectedWithClearError(t *testing.T) {
+	svc := &service{smtpRepository: &fakeSmtpRepository{instance: &smtp.Instance{
+		ID: "smtp-1", UserID: "user-1", AuthMode: smtp.AuthModePassword, AuthMechanism: mailer.AuthPlain,
+	}}}
+
+	err := (&emailSender{service: svc}).CheckCredentials(context.Background(), &Message{UserID: "user-1", SmtpId: "smtp-1", JweReentryRequired: true})
+
+	assert.ErrorIs(t, err, ErrSmtpNeedsPassword)
+}
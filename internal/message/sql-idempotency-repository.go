@@ -0,0 +1,107 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+type idempotencyRepository struct {
+	db    database.DB
+	sqlDB *sql.DB
+}
+
+func newIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &idempotencyRepository{
+		db:    database.NewSQLTx(db).DB,
+		sqlDB: db,
+	}
+}
+
+func (r *idempotencyRepository) WithTransaction(tx any) any {
+	return &idempotencyRepository{
+		db:    database.NewSQLTx(nil).WithSQLTransaction(tx).DB,
+		sqlDB: r.sqlDB,
+	}
+}
+
+func (r *idempotencyRepository) TransactionBackend() any {
+	return r.sqlDB
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *IdempotencyRecord, staleAfter time.Duration) (*IdempotencyRecord, bool, error) {
+	// O UPDATE condicional só assume a chave quando ela expirou ou ficou presa em andamento;
+	// caso contrário o RETURNING volta vazio e o registro atual é lido em seguida.
+	query := `
+		INSERT INTO message_idempotency_keys (user_id, idempotency_key, request_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+		    job_id = NULL,
+		    student_count = 0,
+		    created_at = EXCLUDED.created_at,
+		    expires_at = EXCLUDED.expires_at
+		WHERE message_idempotency_keys.expires_at <= NOW()
+		   OR (message_idempotency_keys.job_id IS NULL AND message_idempotency_keys.created_at <= NOW() - $6 * INTERVAL '1 second')
+		RETURNING user_id
+	`
+	var userID string
+	err := r.db.QueryRowContext(ctx, query,
+		record.UserID,
+		record.Key,
+		record.RequestHash,
+		record.CreatedAt,
+		record.ExpiresAt,
+		int(staleAfter.Seconds()),
+	).Scan(&userID)
+	if err == nil {
+		return nil, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("falha ao reservar Idempotency-Key: %w", err)
+	}
+
+	existing := &IdempotencyRecord{}
+	var jobID sql.NullString
+	err = r.db.QueryRowContext(ctx, `
+		SELECT user_id, idempotency_key, request_hash, job_id, student_count, created_at, expires_at
+		FROM message_idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+	`, record.UserID, record.Key).Scan(
+		&existing.UserID,
+		&existing.Key,
+		&existing.RequestHash,
+		&jobID,
+		&existing.StudentCount,
+		&existing.CreatedAt,
+		&existing.ExpiresAt,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("falha ao buscar Idempotency-Key: %w", err)
+	}
+	existing.JobID = nullStringPtr(jobID)
+	return existing, false, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, userID, key, jobID string, studentCount int) error {
+	query := `
+		UPDATE message_idempotency_keys
+		SET job_id = $3, student_count = $4
+		WHERE user_id = $1 AND idempotency_key = $2
+	`
+	if _, err := r.db.ExecContext(ctx, query, userID, key, jobID, studentCount); err != nil {
+		return fmt.Errorf("falha ao concluir Idempotency-Key: %w", err)
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, userID, key string) error {
+	query := `DELETE FROM message_idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND job_id IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, userID, key); err != nil {
+		return fmt.Errorf("falha ao liberar Idempotency-Key: %w", err)
+	}
+	return nil
+}
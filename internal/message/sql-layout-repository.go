@@ -0,0 +1,86 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+type layoutRepository struct {
+	db    database.DB
+	sqlDB *sql.DB
+}
+
+func newLayoutRepository(db *sql.DB) LayoutRepository {
+	return &layoutRepository{
+		db:    database.NewSQLTx(db).DB,
+		sqlDB: db,
+	}
+}
+
+func (r *layoutRepository) WithTransaction(tx any) any {
+	return &layoutRepository{
+		db:    database.NewSQLTx(nil).WithSQLTransaction(tx).DB,
+		sqlDB: r.sqlDB,
+	}
+}
+
+func (r *layoutRepository) TransactionBackend() any {
+	return r.sqlDB
+}
+
+func (r *layoutRepository) FindByUserID(ctx context.Context, userID string) (*EmailLayout, error) {
+	query := `
+		SELECT user_id, header, footer, accent_color, created_at, updated_at
+		FROM email_layouts
+		WHERE user_id = $1
+	`
+	layout := &EmailLayout{}
+	var accentColor sql.NullString
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&layout.UserID,
+		&layout.Header,
+		&layout.Footer,
+		&accentColor,
+		&layout.CreatedAt,
+		&layout.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("falha ao buscar layout de email: %w", err)
+	}
+	layout.AccentColor = accentColor.String
+	return layout, nil
+}
+
+// Save cria ou substitui o layout do usuário.
+func (r *layoutRepository) Save(ctx context.Context, layout *EmailLayout) error {
+	query := `
+		INSERT INTO email_layouts (user_id, header, footer, accent_color)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET header = EXCLUDED.header, footer = EXCLUDED.footer, accent_color = EXCLUDED.accent_color
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		layout.UserID,
+		layout.Header,
+		layout.Footer,
+		nullableString(layout.AccentColor, layout.AccentColor != ""),
+	).Scan(&layout.CreatedAt, &layout.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao salvar layout de email: %w", err)
+	}
+	return nil
+}
+
+func (r *layoutRepository) Delete(ctx context.Context, userID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM email_layouts WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("falha ao remover layout de email: %w", err)
+	}
+	return nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/lib/pq"
 )
 
 type logRepository struct {
@@ -48,11 +50,24 @@ func (r *logRepository) Save(ctx context.Context, log *Log) error {
 			smtp_id,
 			whatsapp_instance_id,
 			attachment_names,
-			attachment_count
+			attachment_count,
+			retry_of,
+			provider_message_id,
+			delivery_status,
+			fallback_from,
+			sms_instance_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
 
+	status := log.DeliveryStatus
+	if status == "" {
+		status = DeliveryStatusSent
+		if !log.Success {
+			status = DeliveryStatusFailed
+		}
+	}
+
 	_, err := r.db.ExecContext(ctx, query,
 		log.DeliveryGroupID,
 		log.StudentID,
@@ -68,9 +83,307 @@ func (r *logRepository) Save(ctx context.Context, log *Log) error {
 		log.WhatsAppInstanceID,
 		log.AttachmentNames,
 		log.AttachmentCount,
+		log.RetryOf,
+		log.ProviderMessageID,
+		string(status),
+		log.FallbackFrom,
+		log.SMSInstanceID,
 	)
 	if err != nil {
 		return fmt.Errorf("falha ao salvar log de mensagem: %w", err)
 	}
 	return nil
 }
+
+// Logs anteriores ao agrupamento por disparo não têm delivery_group_id; cada um vira um envio próprio.
+const logGroupID = `COALESCE(ml.delivery_group_id, ml.id)`
+
+func (r *logRepository) ListGroups(ctx context.Context, userID string, filter HistoryFilter) ([]DeliveryGroup, int, error) {
+	conditions := []string{"s.user_owner_id = $1"}
+	args := []any{userID}
+	addCondition := func(format string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+	if filter.From != nil {
+		addCondition("ml.created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("ml.created_at < $%d", *filter.To)
+	}
+	if filter.Channel != "" {
+		addCondition("ml.channel = $%d", string(filter.Channel))
+	}
+	if filter.DisciplineID != "" {
+		// A disciplina é a do envio, não a das matrículas: um aluno em duas disciplinas não traz os envios da outra.
+		addCondition("mj.discipline_id = $%d", filter.DisciplineID)
+	}
+
+	having := ""
+	if filter.Success != nil {
+		if *filter.Success {
+			having = "HAVING COUNT(*) FILTER (WHERE NOT ml.success) = 0"
+		} else {
+			having = "HAVING COUNT(*) FILTER (WHERE NOT ml.success) > 0"
+		}
+	}
+
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	query := fmt.Sprintf(`
+		SELECT `+logGroupID+` AS group_id,
+		       (ARRAY_AGG(ml.subject ORDER BY ml.created_at))[1],
+		       ARRAY_AGG(DISTINCT ml.channel),
+		       COUNT(DISTINCT ml.student_id),
+		       COUNT(*) FILTER (WHERE ml.success),
+		       COUNT(*) FILTER (WHERE NOT ml.success),
+		       COUNT(*) FILTER (WHERE ml.delivery_status IN ('DELIVERED', 'READ')),
+		       COUNT(*) FILTER (WHERE ml.delivery_status = 'READ'),
+		       (ARRAY_AGG(ml.attachment_names ORDER BY ml.created_at))[1],
+		       (ARRAY_AGG(ml.retry_of ORDER BY ml.created_at))[1],
+		       MIN(ml.created_at),
+		       MAX(ml.created_at),
+		       COUNT(*) OVER ()
+		FROM message_logs ml
+		JOIN students s ON s.id = ml.student_id
+		LEFT JOIN message_jobs mj ON mj.id = ml.delivery_group_id
+		WHERE %s
+		GROUP BY group_id
+		%s
+		ORDER BY MIN(ml.created_at) DESC
+		LIMIT $%d OFFSET $%d
+	`, strings.Join(conditions, " AND "), having, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("falha ao buscar histórico de envios: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []DeliveryGroup{}
+	total := 0
+	for rows.Next() {
+		var group DeliveryGroup
+		var channels pq.StringArray
+		if err := rows.Scan(
+			&group.ID,
+			&group.Subject,
+			&channels,
+			&group.Students,
+			&group.Sent,
+			&group.Failed,
+			&group.Delivered,
+			&group.Read,
+			&group.AttachmentNames,
+			&group.RetryOf,
+			&group.FirstAt,
+			&group.LastAt,
+			&total,
+		); err != nil {
+			return nil, 0, fmt.Errorf("falha ao ler envio do histórico: %w", err)
+		}
+		group.Channels = toChannels(channels)
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("erro ao iterar histórico de envios: %w", err)
+	}
+	return groups, total, nil
+}
+
+func (r *logRepository) FindGroup(ctx context.Context, userID, groupID string) (*DeliveryGroupDetail, error) {
+	query := `
+		SELECT s.id, s.student_id, s.name, ml.channel, ml.success, ml.delivery_status, ml.status_updated_at, ml.fallback_from, ml.error_text,
+		       ml.sender_type, ml.sender_provider, ml.sender_address, ml.created_at,
+		       ml.subject, ml.body, ml.attachment_names, ml.attachment_count, ml.retry_of,
+		       ml.smtp_id, ml.whatsapp_instance_id
+		FROM message_logs ml
+		JOIN students s ON s.id = ml.student_id
+		WHERE ` + logGroupID + ` = $1 AND s.user_owner_id = $2
+		ORDER BY ml.created_at, s.student_id, ml.channel
+	`
+	rows, err := r.db.QueryContext(ctx, query, groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar envio %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	detail := &DeliveryGroupDetail{
+		DeliveryGroup: DeliveryGroup{ID: groupID, Channels: []Channel{}},
+		Recipients:    []DeliveryRecipient{},
+	}
+	students := map[string]struct{}{}
+	channels := map[Channel]struct{}{}
+	for rows.Next() {
+		var recipient DeliveryRecipient
+		var subject, body, attachmentNames, retryOf, smtpID, whatsappID sql.NullString
+		var attachmentCount sql.NullInt64
+		if err := rows.Scan(
+			&recipient.ID,
+			&recipient.StudentID,
+			&recipient.Name,
+			&recipient.Channel,
+			&recipient.Success,
+			&recipient.Status,
+			&recipient.StatusUpdatedAt,
+			&recipient.FallbackFrom,
+			&recipient.ErrorText,
+			&recipient.SenderType,
+			&recipient.SenderProvider,
+			&recipient.SenderAddress,
+			&recipient.CreatedAt,
+			&subject,
+			&body,
+			&attachmentNames,
+			&attachmentCount,
+			&retryOf,
+			&smtpID,
+			&whatsappID,
+		); err != nil {
+			return nil, fmt.Errorf("falha ao ler destinatário do envio %s: %w", groupID, err)
+		}
+		if len(detail.Recipients) == 0 {
+			// Assunto e corpo vêm do primeiro log; com placeholders, cada aluno recebeu a própria versão.
+			detail.Subject = nullStringPtr(subject)
+			detail.Body = nullStringPtr(body)
+			detail.AttachmentNames = nullStringPtr(attachmentNames)
+			detail.AttachmentCount = int(attachmentCount.Int64)
+			detail.RetryOf = nullStringPtr(retryOf)
+			detail.FirstAt = recipient.CreatedAt
+		}
+		if detail.SmtpID == nil {
+			detail.SmtpID = nullStringPtr(smtpID)
+		}
+		if detail.WhatsappID == nil {
+			detail.WhatsappID = nullStringPtr(whatsappID)
+		}
+		detail.LastAt = recipient.CreatedAt
+		if recipient.Success {
+			detail.Sent++
+		} else {
+			detail.Failed++
+		}
+		switch recipient.Status {
+		case DeliveryStatusRead:
+			detail.Read++
+			detail.Delivered++
+		case DeliveryStatusDelivered:
+			detail.Delivered++
+		}
+		students[recipient.ID] = struct{}{}
+		if _, ok := channels[recipient.Channel]; !ok {
+			channels[recipient.Channel] = struct{}{}
+			detail.Channels = append(detail.Channels, recipient.Channel)
+		}
+		detail.Recipients = append(detail.Recipients, recipient)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar destinatários do envio %s: %w", groupID, err)
+	}
+	if len(detail.Recipients) == 0 {
+		return nil, nil
+	}
+	detail.Students = len(students)
+
+	var status string
+	err = r.db.QueryRowContext(ctx, `SELECT status FROM message_jobs WHERE id = $1 AND user_id = $2`, groupID, userID).Scan(&status)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("falha ao buscar job do envio %s: %w", groupID, err)
+	}
+	if err == nil {
+		jobStatus := JobStatus(status)
+		detail.JobStatus = &jobStatus
+	}
+	return detail, nil
+}
+
+func (r *logRepository) FindFailedRecipients(ctx context.Context, userID, groupID string) (map[Channel][]string, error) {
+	query := `
+		SELECT DISTINCT ml.channel, ml.student_id
+		FROM message_logs ml
+		JOIN students s ON s.id = ml.student_id
+		WHERE ` + logGroupID + ` = $1
+		  AND s.user_owner_id = $2
+		  AND NOT ml.success
+		  AND NOT EXISTS (
+		    SELECT 1 FROM message_logs retry
+		    WHERE retry.retry_of = $1
+		      AND retry.student_id = ml.student_id
+		      AND retry.channel = ml.channel
+		      AND retry.success
+		  )
+		  AND NOT EXISTS (
+		    SELECT 1 FROM message_logs fallback
+		    WHERE fallback.delivery_group_id = ml.delivery_group_id
+		      AND fallback.student_id = ml.student_id
+		      AND fallback.fallback_from = ml.channel
+		      AND fallback.success
+		  )
+		ORDER BY ml.channel, ml.student_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar falhas do envio %s: %w", groupID, err)
+	}
+	defer rows.Close()
+
+	failed := map[Channel][]string{}
+	for rows.Next() {
+		var channel Channel
+		var studentID string
+		if err := rows.Scan(&channel, &studentID); err != nil {
+			return nil, fmt.Errorf("falha ao ler falha do envio %s: %w", groupID, err)
+		}
+		failed[channel] = append(failed[channel], studentID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar falhas do envio %s: %w", groupID, err)
+	}
+	return failed, nil
+}
+
+// deliveryStatusRank ordena os status para que recibos atrasados ou repetidos não voltem o status.
+// FAILED só substitui SENT: depois de entregue, a mensagem não falha mais.
+const deliveryStatusRank = `CASE %s WHEN 'SENT' THEN 1 WHEN 'DELIVERED' THEN 2 WHEN 'FAILED' THEN 2 WHEN 'READ' THEN 3 ELSE 0 END`
+
+// whatsAppDeliveryFailedText é o erro gravado quando o WhatsApp reporta falha depois do envio aceito.
+const whatsAppDeliveryFailedText = "o WhatsApp não conseguiu entregar a mensagem"
+
+func (r *logRepository) UpdateDeliveryStatus(ctx context.Context, instanceName, providerMessageID string, status DeliveryStatus) (bool, error) {
+	query := `
+		UPDATE message_logs ml
+		SET delivery_status = $3,
+		    success = $3 <> 'FAILED',
+		    error_text = CASE WHEN $3 = 'FAILED' THEN $4 ELSE ml.error_text END,
+		    status_updated_at = NOW()
+		FROM whatsapp_instances wi
+		WHERE wi.id = ml.whatsapp_instance_id
+		  AND wi.instance_name = $1
+		  AND ml.provider_message_id = $2
+		  AND ml.channel = 'WHATSAPP'
+		  AND ` + fmt.Sprintf(deliveryStatusRank, "$3::varchar") + ` > ` + fmt.Sprintf(deliveryStatusRank, "ml.delivery_status") + `
+	`
+	result, err := r.db.ExecContext(ctx, query, instanceName, providerMessageID, string(status), whatsAppDeliveryFailedText)
+	if err != nil {
+		return false, fmt.Errorf("falha ao atualizar status da mensagem %s: %w", providerMessageID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("falha ao atualizar status da mensagem %s: %w", providerMessageID, err)
+	}
+	return affected > 0, nil
+}
+
+func toChannels(values []string) []Channel {
+	channels := make([]Channel, 0, len(values))
+	for _, value := range values {
+		channels = append(channels, Channel(value))
+	}
+	return channels
+}
+
+func nullStringPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
@@ -0,0 +1,454 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/lib/pq"
+)
+
+type outboxRepository struct {
+	db    database.DB
+	sqlDB *sql.DB
+}
+
+func newOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{
+		db:    database.NewSQLTx(db).DB,
+		sqlDB: db,
+	}
+}
+
+func (r *outboxRepository) WithTransaction(tx any) any {
+	return &outboxRepository{
+		db:    database.NewSQLTx(nil).WithSQLTransaction(tx).DB,
+		sqlDB: r.sqlDB,
+	}
+}
+
+func (r *outboxRepository) TransactionBackend() any {
+	return r.sqlDB
+}
+
+func (r *outboxRepository) CreateJob(ctx context.Context, job *Job) error {
+	query := `
+		INSERT INTO message_jobs (user_id, smtp_id, whatsapp_instance_id, sender_from, subject, body, jwe, status, template_id, discipline_id, retry_of, fallback, sms_instance_id, body_format)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		job.UserID,
+		job.SmtpID,
+		job.WhatsAppInstanceID,
+		nullableString(job.From, job.From != ""),
+		job.Subject,
+		job.Body,
+		job.Jwe,
+		string(JobStatusPending),
+		job.TemplateID,
+		job.DisciplineID,
+		job.RetryOf,
+		job.Fallback,
+		job.SmsInstanceID,
+		nullableString(string(job.Format), job.Format != ""),
+	).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao criar job de mensagem: %w", err)
+	}
+	job.Status = JobStatusPending
+	return nil
+}
+
+func (r *outboxRepository) AddAttachment(ctx context.Context, jobID string, position int, attachment Attachment) error {
+	query := `
+		INSERT INTO message_job_attachments (job_id, position, file_name, data, url, attachment_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	// Anexos do armazenamento guardam só a referência; o conteúdo é lido de novo na entrega.
+	var data []byte
+	if len(attachment.Data) > 0 && attachment.ID == "" {
+		data = attachment.Data
+	}
+	_, err := r.db.ExecContext(ctx, query, jobID, position, attachment.FileName, data, nullableString(attachment.URL, attachment.URL != ""), nullableString(attachment.ID, attachment.ID != ""))
+	if err != nil {
+		return fmt.Errorf("falha ao salvar anexo do job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) AddRecipients(ctx context.Context, jobID string, channel Channel, studentIDs []string) error {
+	if len(studentIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO message_job_recipients (job_id, student_id, channel)
+		SELECT $1, unnest($2::uuid[]), $3
+	`
+	_, err := r.db.ExecContext(ctx, query, jobID, pq.Array(studentIDs), string(channel))
+	if err != nil {
+		return fmt.Errorf("falha ao enfileirar destinatários do job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) FindJob(ctx context.Context, id string) (*Job, error) {
+	query := `
+		SELECT id, user_id, smtp_id, whatsapp_instance_id, sender_from, subject, body, jwe, status, completed_at, created_at, template_id, discipline_id, retry_of, fallback, sms_instance_id, body_format, jwe_reentry_required
+		FROM message_jobs
+		WHERE id = $1
+	`
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("falha ao buscar job %s: %w", id, err)
+	}
+
+	attachments, err := r.findAttachments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	job.Attachments = attachments
+	return job, nil
+}
+
+func (r *outboxRepository) HasPendingRetry(ctx context.Context, deliveryGroupID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM message_jobs WHERE retry_of = $1 AND status = $2)`
+	var pending bool
+	if err := r.db.QueryRowContext(ctx, query, deliveryGroupID, string(JobStatusPending)).Scan(&pending); err != nil {
+		return false, fmt.Errorf("falha ao verificar reenvios do envio %s: %w", deliveryGroupID, err)
+	}
+	return pending, nil
+}
+
+func (r *outboxRepository) findAttachments(ctx context.Context, jobID string) ([]Attachment, error) {
+	query := `
+		SELECT file_name, data, url, attachment_id
+		FROM message_job_attachments
+		WHERE job_id = $1
+		ORDER BY position
+	`
+	rows, err := r.db.QueryContext(ctx, query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar anexos do job %s: %w", jobID, err)
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		var attachment Attachment
+		var url, attachmentID sql.NullString
+		if err := rows.Scan(&attachment.FileName, &attachment.Data, &url, &attachmentID); err != nil {
+			return nil, fmt.Errorf("falha ao ler anexo do job %s: %w", jobID, err)
+		}
+		attachment.URL = url.String
+		attachment.ID = attachmentID.String
+		attachments = append(attachments, attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar anexos do job %s: %w", jobID, err)
+	}
+	return attachments, nil
+}
+
+func (r *outboxRepository) GetJobSummary(ctx context.Context, id, userID string) (*JobSummary, error) {
+	query := `
+		SELECT id, user_id, smtp_id, whatsapp_instance_id, sender_from, subject, body, jwe, status, completed_at, created_at, template_id, discipline_id, retry_of, fallback, sms_instance_id, body_format, jwe_reentry_required
+		FROM message_jobs
+		WHERE id = $1 AND user_id = $2
+	`
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("falha ao buscar job %s: %w", id, err)
+	}
+
+	recipientsQuery := `
+		SELECT r.channel, r.status, s.id, s.student_id
+		FROM message_job_recipients r
+		JOIN students s ON s.id = r.student_id
+		WHERE r.job_id = $1
+		ORDER BY s.student_id
+	`
+	rows, err := r.db.QueryContext(ctx, recipientsQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar destinatários do job %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	summary := &JobSummary{
+		Job:            job,
+		Counts:         map[RecipientStatus]int{},
+		EmailsFailed:   []student.Student{},
+		WhatsappFailed: []student.Student{},
+		SmsFailed:      []student.Student{},
+	}
+	for rows.Next() {
+		var channel Channel
+		var status RecipientStatus
+		var stud student.Student
+		if err := rows.Scan(&channel, &status, &stud.ID, &stud.StudentID); err != nil {
+			return nil, fmt.Errorf("falha ao ler destinatário do job %s: %w", id, err)
+		}
+		summary.Counts[status]++
+		if status != RecipientStatusFailed {
+			continue
+		}
+		switch channel {
+		case ChannelEmail:
+			summary.EmailsFailed = append(summary.EmailsFailed, stud)
+		case ChannelWhatsApp:
+			summary.WhatsappFailed = append(summary.WhatsappFailed, stud)
+		case ChannelSMS:
+			summary.SmsFailed = append(summary.SmsFailed, stud)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar destinatários do job %s: %w", id, err)
+	}
+	return summary, nil
+}
+
+func (r *outboxRepository) AddFallbackRecipient(ctx context.Context, jobID, studentID string, channel, from Channel) error {
+	query := `
+		INSERT INTO message_job_recipients (job_id, student_id, channel, fallback_from)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (job_id, student_id, channel) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, query, jobID, studentID, string(channel), string(from)); err != nil {
+		return fmt.Errorf("falha ao redirecionar destinatário %s do job %s: %w", studentID, jobID, err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) ClaimRecipients(ctx context.Context, limit int, lease time.Duration) ([]*JobRecipient, error) {
+	query := `
+		WITH claimed AS (
+			SELECT id
+			FROM message_job_recipients
+			WHERE (status = 'PENDING' AND next_attempt_at <= NOW())
+			   OR (status = 'PROCESSING' AND locked_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE message_job_recipients r
+		SET status = 'PROCESSING',
+			attempts = r.attempts + 1,
+			locked_until = NOW() + make_interval(secs => $2)
+		FROM claimed
+		WHERE r.id = claimed.id
+		RETURNING r.id, r.job_id, r.student_id, r.channel, r.status, r.attempts, r.last_error, r.fallback_from
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("falha ao reservar destinatários do outbox: %w", err)
+	}
+	defer rows.Close()
+
+	recipients, err := scanRecipients(rows)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler destinatários reservados: %w", err)
+	}
+	return recipients, nil
+}
+
+func (r *outboxRepository) ListRecipients(ctx context.Context, jobID string) ([]*JobRecipient, error) {
+	query := `
+		SELECT id, job_id, student_id, channel, status, attempts, last_error, fallback_from
+		FROM message_job_recipients
+		WHERE job_id = $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar destinatários do job %s: %w", jobID, err)
+	}
+	defer rows.Close()
+
+	recipients, err := scanRecipients(rows)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler destinatários do job %s: %w", jobID, err)
+	}
+	return recipients, nil
+}
+
+func scanRecipients(rows *sql.Rows) ([]*JobRecipient, error) {
+	recipients := []*JobRecipient{}
+	for rows.Next() {
+		recipient := &JobRecipient{}
+		var lastError, fallbackFrom sql.NullString
+		if err := rows.Scan(
+			&recipient.ID,
+			&recipient.JobID,
+			&recipient.StudentID,
+			&recipient.Channel,
+			&recipient.Status,
+			&recipient.Attempts,
+			&lastError,
+			&fallbackFrom,
+		); err != nil {
+			return nil, err
+		}
+		if lastError.Valid {
+			recipient.LastError = &lastError.String
+		}
+		if fallbackFrom.Valid {
+			from := Channel(fallbackFrom.String)
+			recipient.FallbackFrom = &from
+		}
+		recipients = append(recipients, recipient)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+func (r *outboxRepository) MarkRecipientSent(ctx context.Context, id string) error {
+	query := `
+		UPDATE message_job_recipients
+		SET status = 'SENT', last_error = NULL, locked_until = NULL
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("falha ao marcar destinatário %s como enviado: %w", id, err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkRecipientFailed(ctx context.Context, id, errText string) error {
+	query := `
+		UPDATE message_job_recipients
+		SET status = 'FAILED', last_error = $2, locked_until = NULL
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, id, errText); err != nil {
+		return fmt.Errorf("falha ao marcar destinatário %s como falho: %w", id, err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) RescheduleRecipient(ctx context.Context, id, errText string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE message_job_recipients
+		SET status = 'PENDING', last_error = $2, next_attempt_at = $3, locked_until = NULL
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, id, errText, nextAttemptAt); err != nil {
+		return fmt.Errorf("falha ao reagendar destinatário %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) ReplaceJwe(ctx context.Context, userID string, jwe *string) error {
+	query := `
+		UPDATE message_jobs
+		SET jwe = $2, jwe_reentry_required = $2::text IS NULL
+		WHERE user_id = $1 AND status = 'PENDING' AND jwe IS NOT NULL
+	`
+	if _, err := r.db.ExecContext(ctx, query, userID, jwe); err != nil {
+		return fmt.Errorf("falha ao atualizar o JWE dos jobs do usuário %s: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) CompleteJobIfDone(ctx context.Context, jobID string) (bool, error) {
+	query := `
+		UPDATE message_jobs
+		SET status = 'COMPLETED', jwe = NULL, completed_at = NOW()
+		WHERE id = $1
+		  AND status <> 'COMPLETED'
+		  AND NOT EXISTS (
+			SELECT 1 FROM message_job_recipients
+			WHERE job_id = $1 AND status IN ('PENDING', 'PROCESSING')
+		  )
+	`
+	result, err := r.db.ExecContext(ctx, query, jobID)
+	if err != nil {
+		return false, fmt.Errorf("falha ao finalizar job %s: %w", jobID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("falha ao finalizar job %s: %w", jobID, err)
+	}
+	return affected > 0, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(scanner rowScanner) (*Job, error) {
+	job := &Job{}
+	var smtpID, whatsappID, from, jwe, templateID, disciplineID, retryOf, fallback, smsID, format sql.NullString
+	var completedAt sql.NullTime
+
+	err := scanner.Scan(
+		&job.ID,
+		&job.UserID,
+		&smtpID,
+		&whatsappID,
+		&from,
+		&job.Subject,
+		&job.Body,
+		&jwe,
+		&job.Status,
+		&completedAt,
+		&job.CreatedAt,
+		&templateID,
+		&disciplineID,
+		&retryOf,
+		&fallback,
+		&smsID,
+		&format,
+		&job.JweReentryRequired,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if smtpID.Valid {
+		job.SmtpID = &smtpID.String
+	}
+	if whatsappID.Valid {
+		job.WhatsAppInstanceID = &whatsappID.String
+	}
+	if smsID.Valid {
+		job.SmsInstanceID = &smsID.String
+	}
+	job.From = from.String
+	if jwe.Valid {
+		job.Jwe = &jwe.String
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	if templateID.Valid {
+		job.TemplateID = &templateID.String
+	}
+	if retryOf.Valid {
+		job.RetryOf = &retryOf.String
+	}
+	if disciplineID.Valid {
+		job.DisciplineID = &disciplineID.String
+	}
+	if fallback.Valid {
+		policy := FallbackPolicy(fallback.String)
+		job.Fallback = &policy
+	}
+	job.Format = BodyFormat(format.String)
+	return job, nil
+}
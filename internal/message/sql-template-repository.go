@@ -0,0 +1,141 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+type templateRepository struct {
+	db    database.DB
+	sqlDB *sql.DB
+}
+
+func newTemplateRepository(db *sql.DB) TemplateRepository {
+	return &templateRepository{
+		db:    database.NewSQLTx(db).DB,
+		sqlDB: db,
+	}
+}
+
+func (r *templateRepository) WithTransaction(tx any) any {
+	return &templateRepository{
+		db:    database.NewSQLTx(nil).WithSQLTransaction(tx).DB,
+		sqlDB: r.sqlDB,
+	}
+}
+
+func (r *templateRepository) TransactionBackend() any {
+	return r.sqlDB
+}
+
+func (r *templateRepository) Create(ctx context.Context, template *Template) error {
+	query := `
+		INSERT INTO message_templates (user_id, name, subject, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, template.UserID, template.Name, template.Subject, template.Body).
+		Scan(&template.ID, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao criar template: %w", err)
+	}
+	return nil
+}
+
+func (r *templateRepository) FindByID(ctx context.Context, id string) (*Template, error) {
+	query := `
+		SELECT id, user_id, name, subject, body, created_at, updated_at
+		FROM message_templates
+		WHERE id = $1
+	`
+	return r.findOne(ctx, query, id)
+}
+
+func (r *templateRepository) FindByNameAndUserID(ctx context.Context, name, userID string) (*Template, error) {
+	query := `
+		SELECT id, user_id, name, subject, body, created_at, updated_at
+		FROM message_templates
+		WHERE name = $1 AND user_id = $2
+	`
+	return r.findOne(ctx, query, name, userID)
+}
+
+func (r *templateRepository) FindByUserID(ctx context.Context, userID string) ([]*Template, error) {
+	query := `
+		SELECT id, user_id, name, subject, body, created_at, updated_at
+		FROM message_templates
+		WHERE user_id = $1
+		ORDER BY name
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []*Template{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao ler template: %w", err)
+		}
+		templates = append(templates, template)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (r *templateRepository) Update(ctx context.Context, template *Template) error {
+	query := `
+		UPDATE message_templates
+		SET name = $2, subject = $3, body = $4
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, template.ID, template.Name, template.Subject, template.Body).Scan(&template.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar template %s: %w", template.ID, err)
+	}
+	return nil
+}
+
+func (r *templateRepository) Delete(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM message_templates WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("falha ao remover template %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *templateRepository) findOne(ctx context.Context, query string, args ...any) (*Template, error) {
+	template, err := scanTemplate(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("falha ao buscar template: %w", err)
+	}
+	return template, nil
+}
+
+func scanTemplate(scanner rowScanner) (*Template, error) {
+	template := &Template{}
+	err := scanner.Scan(
+		&template.ID,
+		&template.UserID,
+		&template.Name,
+		&template.Subject,
+		&template.Body,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	template.Placeholders = PlaceholdersIn(template.Subject, template.Body)
+	return template, nil
+}
@@ -0,0 +1,41 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+// Template é um modelo de mensagem do professor; assunto e corpo aceitam placeholders como {{name}}.
+type Template struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Subject      string    `json:"subject"`
+	Body         string    `json:"body"`
+	Placeholders []string  `json:"placeholders"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	UserID       string    `json:"-"`
+}
+
+type TemplateInput struct {
+	Name    string `json:"name" binding:"required"`
+	Subject string `json:"subject" binding:"required"`
+	Body    string `json:"body" binding:"required"`
+}
+
+type TemplateRepository interface {
+	database.Transactional
+	Create(ctx context.Context, template *Template) error
+	FindByID(ctx context.Context, id string) (*Template, error)
+	FindByNameAndUserID(ctx context.Context, name, userID string) (*Template, error)
+	FindByUserID(ctx context.Context, userID string) ([]*Template, error)
+	Update(ctx context.Context, template *Template) error
+	Delete(ctx context.Context, id string) error
+}
+
+func NewTemplateRepository(db *sql.DB) TemplateRepository {
+	return newTemplateRepository(db)
+}
@@ -0,0 +1,139 @@
+package message
+
+import (
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+
+	"github.com/gin-gonic/gin"
+)
+
+type templateHandler struct {
+	service TemplateService
+}
+
+type TemplateHandler interface {
+	Create() gin.HandlerFunc
+	List() gin.HandlerFunc
+	Get() gin.HandlerFunc
+	Update() gin.HandlerFunc
+	Delete() gin.HandlerFunc
+}
+
+func NewTemplateHandler(service TemplateService) TemplateHandler {
+	return &templateHandler{service: service}
+}
+
+// @Summary Cria um template de mensagem
+// @Description Assunto e corpo aceitam placeholders: {{name}}, {{firstName}}, {{studentId}}, {{email}}, {{phone}}, {{discipline}}, {{teacherName}} e {{teacherEmail}}
+// @OperationId createMessageTemplate
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param body body TemplateInput true "Dados do template"
+// @Success 201 {object} api.DefaultResponse[Template]
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /message/template [post]
+func (h *templateHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input TemplateInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		template, err := h.service.Create(c.Request.Context(), c.GetString("userID"), input)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, api.DefaultResponse[Template]{Message: "Template criado com sucesso", Data: *template})
+	}
+}
+
+// @Summary Lista templates de mensagem
+// @OperationId listMessageTemplates
+// @Tags message
+// @Produce json
+// @Success 200 {object} api.DefaultResponse[[]Template]
+// @Router /message/template [get]
+func (h *templateHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		templates, err := h.service.List(c.Request.Context(), c.GetString("userID"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		items := make([]Template, 0, len(templates))
+		for _, template := range templates {
+			items = append(items, *template)
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[[]Template]{Message: "Templates listados com sucesso", Data: items})
+	}
+}
+
+// @Summary Busca um template de mensagem
+// @OperationId getMessageTemplate
+// @Tags message
+// @Produce json
+// @Param id path string true "ID do template"
+// @Success 200 {object} api.DefaultResponse[Template]
+// @Failure 404 {object} api.ErrorResponse
+// @Router /message/template/{id} [get]
+func (h *templateHandler) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		template, err := h.service.Get(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[Template]{Message: "Template encontrado", Data: *template})
+	}
+}
+
+// @Summary Atualiza um template de mensagem
+// @OperationId updateMessageTemplate
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param id path string true "ID do template"
+// @Param body body TemplateInput true "Dados do template"
+// @Success 200 {object} api.DefaultResponse[Template]
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /message/template/{id} [put]
+func (h *templateHandler) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input TemplateInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		template, err := h.service.Update(c.Request.Context(), c.GetString("userID"), c.Param("id"), input)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[Template]{Message: "Template atualizado com sucesso", Data: *template})
+	}
+}
+
+// @Summary Remove um template de mensagem
+// @OperationId deleteMessageTemplate
+// @Tags message
+// @Produce json
+// @Param id path string true "ID do template"
+// @Success 200 {object} api.MessageResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Router /message/template/{id} [delete]
+func (h *templateHandler) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.service.Delete(c.Request.Context(), c.GetString("userID"), c.Param("id")); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.MessageResponse{Message: "Template removido com sucesso"})
+	}
+}
@@ -0,0 +1,116 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var (
+	ErrTemplateNotFound      = customerror.Make("template não encontrado", http.StatusNotFound, errors.New("ErrTemplateNotFound"))
+	ErrTemplateAlreadyExists = customerror.Make("já existe um template com esse nome", http.StatusConflict, errors.New("ErrTemplateAlreadyExists"))
+)
+
+type TemplateService interface {
+	Create(ctx context.Context, userID string, input TemplateInput) (*Template, error)
+	List(ctx context.Context, userID string) ([]*Template, error)
+	Get(ctx context.Context, userID, id string) (*Template, error)
+	Update(ctx context.Context, userID, id string, input TemplateInput) (*Template, error)
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type templateService struct {
+	templateRepository TemplateRepository
+}
+
+func NewTemplateService(templateRepository TemplateRepository) TemplateService {
+	return &templateService{templateRepository: templateRepository}
+}
+
+func (s *templateService) Create(ctx context.Context, userID string, input TemplateInput) (*Template, error) {
+	name := strings.TrimSpace(input.Name)
+	if err := ValidatePlaceholders(input.Subject, input.Body); err != nil {
+		return nil, customerror.Trace("CreateTemplate", err)
+	}
+	existing, err := s.templateRepository.FindByNameAndUserID(ctx, name, userID)
+	if err != nil {
+		return nil, customerror.Trace("CreateTemplate", err)
+	}
+	if existing != nil {
+		return nil, customerror.Trace("CreateTemplate", ErrTemplateAlreadyExists)
+	}
+
+	template := &Template{
+		UserID:       userID,
+		Name:         name,
+		Subject:      input.Subject,
+		Body:         input.Body,
+		Placeholders: PlaceholdersIn(input.Subject, input.Body),
+	}
+	if err := s.templateRepository.Create(ctx, template); err != nil {
+		return nil, customerror.Trace("CreateTemplate", err)
+	}
+	return template, nil
+}
+
+func (s *templateService) List(ctx context.Context, userID string) ([]*Template, error) {
+	templates, err := s.templateRepository.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, customerror.Trace("ListTemplates", err)
+	}
+	return templates, nil
+}
+
+func (s *templateService) Get(ctx context.Context, userID, id string) (*Template, error) {
+	template, err := s.templateRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, customerror.Trace("GetTemplate", err)
+	}
+	if template == nil || template.UserID != userID {
+		return nil, customerror.Trace("GetTemplate", ErrTemplateNotFound)
+	}
+	return template, nil
+}
+
+func (s *templateService) Update(ctx context.Context, userID, id string, input TemplateInput) (*Template, error) {
+	template, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidatePlaceholders(input.Subject, input.Body); err != nil {
+		return nil, customerror.Trace("UpdateTemplate", err)
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name != template.Name {
+		existing, err := s.templateRepository.FindByNameAndUserID(ctx, name, userID)
+		if err != nil {
+			return nil, customerror.Trace("UpdateTemplate", err)
+		}
+		if existing != nil {
+			return nil, customerror.Trace("UpdateTemplate", ErrTemplateAlreadyExists)
+		}
+	}
+
+	template.Name = name
+	template.Subject = input.Subject
+	template.Body = input.Body
+	template.Placeholders = PlaceholdersIn(input.Subject, input.Body)
+	if err := s.templateRepository.Update(ctx, template); err != nil {
+		return nil, customerror.Trace("UpdateTemplate", err)
+	}
+	return template, nil
+}
+
+func (s *templateService) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := s.templateRepository.Delete(ctx, id); err != nil {
+		return customerror.Trace("DeleteTemplate", err)
+	}
+	return nil
+}
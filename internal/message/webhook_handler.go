@@ -0,0 +1,61 @@
+package message
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBodyBytes limita o corpo aceito; eventos MESSAGES_UPDATE e MESSAGES_UPSERT têm poucos KB.
+const maxWebhookBodyBytes = 1 << 20
+
+type webhookHandler struct {
+	service WebhookService
+}
+
+type WebhookHandler interface {
+	Evolution() gin.HandlerFunc
+}
+
+func NewWebhookHandler(service WebhookService) WebhookHandler {
+	return &webhookHandler{service: service}
+}
+
+// @Summary Recebe recibos de entrega e respostas do WhatsApp
+// @Description Webhook da Evolution para eventos MESSAGES_UPDATE e MESSAGES_UPSERT. MESSAGES_UPDATE move cada log de WhatsApp de SENT para DELIVERED, READ ou FAILED.
+// @Description MESSAGES_UPSERT grava na caixa de entrada (/inbox) as mensagens recebidas de números de alunos do dono da instância.
+// @Description O segredo (EVOLUTION_WEBHOOK_SECRET) vai no header X-Webhook-Secret. O parâmetro token existe só para o webhook global da Evolution, que não envia headers próprios; o valor é mascarado no log de requisições. Outros eventos são ignorados.
+// @OperationId evolutionWebhook
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param X-Webhook-Secret header string false "Segredo do webhook"
+// @Param token query string false "Segredo do webhook, para o webhook global da Evolution"
+// @Success 200 {object} api.MessageResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 401 {object} api.ErrorResponse
+// @Router /webhook/evolution [post]
+func (h *webhookHandler) Evolution() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader("X-Webhook-Secret")
+		if secret == "" {
+			// O webhook global da Evolution não manda headers próprios; o RequestLogger mascara o token.
+			secret = c.Query("token")
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes))
+		if err != nil {
+			customerror.HandleResponse(c, ErrInvalidWebhookBody)
+			return
+		}
+
+		if _, err := h.service.HandleEvolution(c.Request.Context(), secret, body); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.MessageResponse{Message: "Evento recebido"})
+	}
+}
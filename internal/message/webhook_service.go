@@ -0,0 +1,75 @@
+package message
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var (
+	ErrInvalidWebhookSecret = customerror.Make("segredo do webhook inválido", http.StatusUnauthorized, errors.New("ErrInvalidWebhookSecret"))
+	ErrInvalidWebhookBody   = customerror.Make("corpo do webhook inválido", http.StatusBadRequest, errors.New("ErrInvalidWebhookBody"))
+)
+
+type WebhookService interface {
+	// HandleEvolution aplica os recibos de um evento MESSAGES_UPDATE ou repassa as mensagens recebidas de um
+	// MESSAGES_UPSERT; devolve quantos logs mudaram de status ou quantas mensagens foram gravadas.
+	HandleEvolution(ctx context.Context, secret string, body []byte) (int, error)
+}
+
+// InboundReceiver grava as mensagens recebidas pelas instâncias (MESSAGES_UPSERT), que chegam pelo mesmo webhook.
+type InboundReceiver interface {
+	ReceiveWhatsApp(ctx context.Context, event *whatsapp.MessagesUpsertEvent) (int, error)
+}
+
+type webhookService struct {
+	logRepository LogRepository
+	inbound       InboundReceiver
+	secret        string
+}
+
+// NewWebhookService recebe o segredo compartilhado com a Evolution; sem segredo, todo webhook é recusado.
+// Com inbound nil, eventos MESSAGES_UPSERT são ignorados.
+func NewWebhookService(logRepository LogRepository, inbound InboundReceiver, secret string) WebhookService {
+	return &webhookService{logRepository: logRepository, inbound: inbound, secret: secret}
+}
+
+func (s *webhookService) HandleEvolution(ctx context.Context, secret string, body []byte) (int, error) {
+	if s.secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(s.secret)) != 1 {
+		return 0, customerror.Trace("HandleEvolutionWebhook", ErrInvalidWebhookSecret)
+	}
+
+	event, err := whatsapp.ParseMessagesUpdate(body)
+	if err != nil {
+		return 0, customerror.Trace("HandleEvolutionWebhook", ErrInvalidWebhookBody)
+	}
+	if s.inbound != nil {
+		upsert, err := whatsapp.ParseMessagesUpsert(body)
+		if err != nil {
+			return 0, customerror.Trace("HandleEvolutionWebhook", ErrInvalidWebhookBody)
+		}
+		if len(upsert.Messages) > 0 {
+			stored, err := s.inbound.ReceiveWhatsApp(ctx, upsert)
+			if err != nil {
+				return stored, customerror.Trace("HandleEvolutionWebhook", err)
+			}
+			return stored, nil
+		}
+	}
+
+	updated := 0
+	for _, update := range event.Updates {
+		changed, err := s.logRepository.UpdateDeliveryStatus(ctx, event.Instance, update.MessageID, DeliveryStatus(update.Status))
+		if err != nil {
+			return updated, customerror.Trace("HandleEvolutionWebhook", err)
+		}
+		if changed {
+			updated++
+		}
+	}
+	return updated, nil
+}
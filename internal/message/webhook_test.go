@@ -0,0 +1,75 @@
+package message
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReceiptLogRepository struct {
+	LogRepository
+	statuses map[string]DeliveryStatus
+}
+
+func (f *fakeReceiptLogRepository) UpdateDeliveryStatus(ctx context.Context, instanceName, providerMessageID string, status DeliveryStatus) (bool, error) {
+	key := instanceName + "/" + providerMessageID
+	if _, ok := f.statuses[key]; !ok {
+		return false, nil
+	}
+	f.statuses[key] = status
+	return true, nil
+}
+
+func TestWebhookAppliesReceiptsToKnownMessages(t *testing.T) {
+	repo := &fakeReceiptLogRepository{statuses: map[string]DeliveryStatus{"prof/A1": DeliveryStatusSent}}
+	svc := NewWebhookService(repo, nil, "segredo")
+	body := []byte(`{"event":"messages.update","instance":"prof","data":[
+		{"keyId":"A1","fromMe":true,"status":"READ"},
+		{"keyId":"B2","fromMe":true,"status":"DELIVERY_ACK"}
+	]}`)
+
+	updated, err := svc.HandleEvolution(context.Background(), "segredo", body)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+	assert.Equal(t, DeliveryStatusRead, repo.statuses["prof/A1"])
+}
+
+type fakeInboundReceiver struct {
+	events []*whatsapp.MessagesUpsertEvent
+}
+
+func (f *fakeInboundReceiver) ReceiveWhatsApp(ctx context.Context, event *whatsapp.MessagesUpsertEvent) (int, error) {
+	f.events = append(f.events, event)
+	return len(event.Messages), nil
+}
+
+func TestWebhookForwardsRepliesToInboundReceiver(t *testing.T) {
+	inbound := &fakeInboundReceiver{}
+	svc := NewWebhookService(&fakeReceiptLogRepository{}, inbound, "segredo")
+	reply := []byte(`{"event":"messages.upsert","instance":"prof","data":{"key":{"remoteJid":"5500000000001@s.whatsapp.net","fromMe":false,"id":"R1"},"message":{"conversation":"Recebi"}}}`)
+	receipt := []byte(`{"event":"messages.update","instance":"prof","data":{"keyId":"A1","fromMe":true,"status":"READ"}}`)
+
+	stored, err := svc.HandleEvolution(context.Background(), "segredo", reply)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored)
+	_, err = svc.HandleEvolution(context.Background(), "segredo", receipt)
+	require.NoError(t, err)
+
+	require.Len(t, inbound.events, 1)
+	assert.Equal(t, "prof", inbound.events[0].Instance)
+	assert.Equal(t, "Recebi", inbound.events[0].Messages[0].Text)
+}
+
+func TestWebhookRejectsWrongOrMissingSecret(t *testing.T) {
+	body := []byte(`{"event":"messages.update","instance":"prof","data":{"keyId":"A1","status":"READ"}}`)
+
+	_, err := NewWebhookService(&fakeReceiptLogRepository{}, nil, "segredo").HandleEvolution(context.Background(), "outro", body)
+	assert.ErrorIs(t, err, ErrInvalidWebhookSecret)
+
+	_, err = NewWebhookService(&fakeReceiptLogRepository{}, nil, "").HandleEvolution(context.Background(), "", body)
+	assert.ErrorIs(t, err, ErrInvalidWebhookSecret)
+}
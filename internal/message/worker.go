@@ -0,0 +1,266 @@
+package message
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// WorkerOptions configura o pool de workers que consome o outbox.
+type WorkerOptions struct {
+	// Workers é a quantidade de goroutines consumindo o outbox em paralelo.
+	Workers int
+	// BatchSize é o máximo de itens reservados por consulta.
+	BatchSize int
+	// PollInterval é a espera entre consultas quando o outbox está vazio.
+	PollInterval time.Duration
+	// Lease é o tempo que um item fica reservado; após isso outro worker pode retomá-lo.
+	Lease time.Duration
+	// MaxAttempts é o número de tentativas antes de marcar o item como falho.
+	MaxAttempts int
+	// RetryDelay é a espera base entre tentativas; dobra a cada nova falha.
+	RetryDelay time.Duration
+}
+
+func (o WorkerOptions) withDefaults() WorkerOptions {
+	if o.Workers <= 0 {
+		o.Workers = 2
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 20
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = 2 * time.Second
+	}
+	if o.Lease <= 0 {
+		o.Lease = 10 * time.Minute
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 3
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = 30 * time.Second
+	}
+	return o
+}
+
+// Worker entrega os itens do outbox em segundo plano.
+type Worker struct {
+	service       Service
+	outbox        OutboxRepository
+	logRepository LogRepository
+	progress      *ProgressBroker
+	opts          WorkerOptions
+	now           func() time.Time
+}
+
+func NewWorker(service Service, outbox OutboxRepository, logRepository LogRepository, progress *ProgressBroker, opts WorkerOptions) *Worker {
+	return &Worker{
+		service:       service,
+		outbox:        outbox,
+		logRepository: logRepository,
+		progress:      progress,
+		opts:          opts.withDefaults(),
+		now:           time.Now,
+	}
+}
+
+// Run bloqueia até o contexto ser cancelado. Lotes já reservados são concluídos antes de retornar.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range w.opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		processed, err := w.ProcessBatch(ctx)
+		if err != nil {
+			log.Printf("worker de mensagens: %v", err)
+		}
+		if processed > 0 && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.opts.PollInterval):
+		}
+	}
+}
+
+// ProcessBatch reserva e entrega um lote do outbox, devolvendo quantos itens foram processados.
+func (w *Worker) ProcessBatch(ctx context.Context) (int, error) {
+	if ctx.Err() != nil {
+		return 0, nil
+	}
+	recipients, err := w.outbox.ClaimRecipients(ctx, w.opts.BatchSize, w.opts.Lease)
+	if err != nil {
+		return 0, err
+	}
+	if len(recipients) == 0 {
+		return 0, nil
+	}
+
+	// Itens já reservados são entregues mesmo durante o desligamento para não esperar o lease expirar.
+	workCtx := context.WithoutCancel(ctx)
+	for _, group := range groupRecipients(recipients) {
+		w.processGroup(workCtx, group)
+	}
+	return len(recipients), nil
+}
+
+type recipientGroup struct {
+	jobID      string
+	channel    Channel
+	recipients []*JobRecipient
+}
+
+func groupRecipients(recipients []*JobRecipient) []*recipientGroup {
+	groups := []*recipientGroup{}
+	index := map[string]*recipientGroup{}
+	for _, recipient := range recipients {
+		key := recipient.JobID + "|" + string(recipient.Channel)
+		group, ok := index[key]
+		if !ok {
+			group = &recipientGroup{jobID: recipient.JobID, channel: recipient.Channel}
+			index[key] = group
+			groups = append(groups, group)
+		}
+		group.recipients = append(group.recipients, recipient)
+	}
+	return groups
+}
+
+func (w *Worker) processGroup(ctx context.Context, group *recipientGroup) {
+	job, err := w.outbox.FindJob(ctx, group.jobID)
+	if err != nil {
+		log.Printf("worker de mensagens: %v", err)
+		return
+	}
+	if job == nil {
+		for _, recipient := range group.recipients {
+			w.markFailed(ctx, recipient, "job não encontrado")
+		}
+		return
+	}
+
+	studentIDs := make([]string, 0, len(group.recipients))
+	byStudent := make(map[string]*JobRecipient, len(group.recipients))
+	for _, recipient := range group.recipients {
+		studentIDs = append(studentIDs, recipient.StudentID)
+		byStudent[recipient.StudentID] = recipient
+	}
+
+	for _, result := range w.service.Deliver(ctx, job, group.channel, studentIDs) {
+		recipient, ok := byStudent[result.StudentID]
+		if !ok {
+			continue
+		}
+		w.handleResult(ctx, job, recipient, result)
+	}
+
+	completed, err := w.outbox.CompleteJobIfDone(ctx, job.ID)
+	if err != nil {
+		log.Printf("worker de mensagens: %v", err)
+		return
+	}
+	if completed {
+		w.publishSummary(ctx, job)
+	}
+}
+
+// publishSummary encerra os streams de andamento do job com a contagem final.
+func (w *Worker) publishSummary(ctx context.Context, job *Job) {
+	if w.progress == nil {
+		return
+	}
+	summary, err := w.outbox.GetJobSummary(ctx, job.ID, job.UserID)
+	if err != nil || summary == nil {
+		log.Printf("worker de mensagens: resumo do job %s indisponível: %v", job.ID, err)
+		return
+	}
+	counts := summary.counts()
+	w.progress.Publish(job.ID, ProgressEvent{Type: ProgressSummary, Counts: &counts})
+}
+
+func (w *Worker) handleResult(ctx context.Context, job *Job, recipient *JobRecipient, result DeliveryResult) {
+	if result.Log != nil {
+		result.Log.FallbackFrom = recipient.FallbackFrom
+	}
+	if result.Err == nil {
+		if err := w.outbox.MarkRecipientSent(ctx, recipient.ID); err != nil {
+			log.Printf("worker de mensagens: %v", err)
+		}
+		w.saveLog(ctx, result.Log)
+		return
+	}
+
+	errText := result.Err.Error()
+	if result.Permanent || recipient.Attempts >= w.opts.MaxAttempts {
+		w.markFailed(ctx, recipient, errText)
+		w.saveLog(ctx, result.Log)
+		w.fallback(ctx, job, recipient)
+		return
+	}
+
+	nextAttemptAt := w.now().Add(w.backoff(recipient.Attempts))
+	if err := w.outbox.RescheduleRecipient(ctx, recipient.ID, errText, nextAttemptAt); err != nil {
+		log.Printf("worker de mensagens: %v", err)
+		return
+	}
+	w.progress.Publish(job.ID, ProgressEvent{
+		Type:          ProgressRetrying,
+		StudentID:     recipient.StudentID,
+		Channel:       recipient.Channel,
+		Reason:        deliveryErrorText(recipient.Channel, result.Err),
+		NextAttemptAt: &nextAttemptAt,
+	})
+}
+
+// fallback redireciona o aluno para o outro canal quando a política do job prevê; o novo item entra no
+// mesmo job antes de CompleteJobIfDone, então o job só termina depois dele.
+func (w *Worker) fallback(ctx context.Context, job *Job, recipient *JobRecipient) {
+	if job.Fallback == nil || recipient.FallbackFrom != nil {
+		return
+	}
+	channel, ok := job.Fallback.fallbackFor(recipient.Channel)
+	if !ok {
+		return
+	}
+	if err := w.outbox.AddFallbackRecipient(ctx, job.ID, recipient.StudentID, channel, recipient.Channel); err != nil {
+		log.Printf("worker de mensagens: %v", err)
+		return
+	}
+	w.progress.Publish(job.ID, ProgressEvent{Type: ProgressQueued, StudentID: recipient.StudentID, Channel: channel})
+}
+
+func (w *Worker) markFailed(ctx context.Context, recipient *JobRecipient, errText string) {
+	if err := w.outbox.MarkRecipientFailed(ctx, recipient.ID, errText); err != nil {
+		log.Printf("worker de mensagens: %v", err)
+	}
+}
+
+func (w *Worker) saveLog(ctx context.Context, entry *Log) {
+	if entry == nil {
+		return
+	}
+	if err := w.logRepository.Save(ctx, entry); err != nil {
+		log.Printf("falha ao salvar log de envio: %v", err)
+	}
+}
+
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.opts.RetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+	}
+	return delay
+}
@@ -0,0 +1,230 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutbox struct {
+	OutboxRepository
+	jobs        map[string]*Job
+	claimed     []*JobRecipient
+	sent        []string
+	failed      map[string]string
+	rescheduled map[string]time.Time
+	completed   []string
+	fallbacks   []*JobRecipient
+	recipients  []*JobRecipient
+	summary     *JobSummary
+}
+
+func newFakeOutbox(job *Job, recipients ...*JobRecipient) *fakeOutbox {
+	return &fakeOutbox{
+		jobs:        map[string]*Job{job.ID: job},
+		claimed:     recipients,
+		failed:      map[string]string{},
+		rescheduled: map[string]time.Time{},
+	}
+}
+
+func (f *fakeOutbox) ClaimRecipients(ctx context.Context, limit int, lease time.Duration) ([]*JobRecipient, error) {
+	claimed := f.claimed
+	f.claimed = nil
+	return claimed, nil
+}
+
+func (f *fakeOutbox) FindJob(ctx context.Context, id string) (*Job, error) {
+	return f.jobs[id], nil
+}
+
+func (f *fakeOutbox) MarkRecipientSent(ctx context.Context, id string) error {
+	f.sent = append(f.sent, id)
+	return nil
+}
+
+func (f *fakeOutbox) MarkRecipientFailed(ctx context.Context, id, errText string) error {
+	f.failed[id] = errText
+	return nil
+}
+
+func (f *fakeOutbox) RescheduleRecipient(ctx context.Context, id, errText string, nextAttemptAt time.Time) error {
+	f.rescheduled[id] = nextAttemptAt
+	return nil
+}
+
+func (f *fakeOutbox) AddFallbackRecipient(ctx context.Context, jobID, studentID string, channel, from Channel) error {
+	f.fallbacks = append(f.fallbacks, &JobRecipient{JobID: jobID, StudentID: studentID, Channel: channel, FallbackFrom: &from})
+	return nil
+}
+
+func (f *fakeOutbox) CompleteJobIfDone(ctx context.Context, jobID string) (bool, error) {
+	f.completed = append(f.completed, jobID)
+	return true, nil
+}
+
+func (f *fakeOutbox) GetJobSummary(ctx context.Context, id, userID string) (*JobSummary, error) {
+	if f.summary == nil || f.summary.Job.ID != id || f.summary.Job.UserID != userID {
+		return nil, nil
+	}
+	return f.summary, nil
+}
+
+func (f *fakeOutbox) ListRecipients(ctx context.Context, jobID string) ([]*JobRecipient, error) {
+	return f.recipients, nil
+}
+
+type fakeDeliveryService struct {
+	Service
+	results map[string]DeliveryResult
+	calls   int
+}
+
+func (f *fakeDeliveryService) Deliver(ctx context.Context, job *Job, channel Channel, studentIDs []string) []DeliveryResult {
+	f.calls++
+	results := make([]DeliveryResult, 0, len(studentIDs))
+	for _, id := range studentIDs {
+		results = append(results, f.results[id])
+	}
+	return results
+}
+
+type fakeLogRepository struct {
+	LogRepository
+	saved []*Log
+}
+
+func (f *fakeLogRepository) Save(ctx context.Context, log *Log) error {
+	f.saved = append(f.saved, log)
+	return nil
+}
+
+func newTestWorker(svc Service, outbox OutboxRepository, logs LogRepository) *Worker {
+	worker := NewWorker(svc, outbox, logs, nil, WorkerOptions{MaxAttempts: 3, RetryDelay: time.Minute})
+	worker.now = func() time.Time { return time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC) }
+	return worker
+}
+
+func TestWorkerMarksSentAndSavesLog(t *testing.T) {
+	job := &Job{ID: "job-1"}
+	outbox := newFakeOutbox(job, &JobRecipient{ID: "r1", JobID: "job-1", StudentID: "s1", Channel: ChannelEmail, Attempts: 1})
+	svc := &fakeDeliveryService{results: map[string]DeliveryResult{
+		"s1": {StudentID: "s1", Log: &Log{StudentID: "s1", Success: true}},
+	}}
+	logs := &fakeLogRepository{}
+
+	processed, err := newTestWorker(svc, outbox, logs).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	assert.Equal(t, []string{"r1"}, outbox.sent)
+	assert.Len(t, logs.saved, 1)
+	assert.Equal(t, []string{"job-1"}, outbox.completed)
+}
+
+func TestWorkerReschedulesTransientFailureWithBackoff(t *testing.T) {
+	job := &Job{ID: "job-1"}
+	outbox := newFakeOutbox(job, &JobRecipient{ID: "r1", JobID: "job-1", StudentID: "s1", Channel: ChannelWhatsApp, Attempts: 2})
+	svc := &fakeDeliveryService{results: map[string]DeliveryResult{
+		"s1": {StudentID: "s1", Err: errors.New("timeout"), Log: &Log{StudentID: "s1"}},
+	}}
+	logs := &fakeLogRepository{}
+
+	_, err := newTestWorker(svc, outbox, logs).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, outbox.failed)
+	assert.Empty(t, logs.saved, "tentativas intermediárias não devem gerar log")
+	assert.Equal(t, time.Date(2026, 1, 1, 10, 2, 0, 0, time.UTC), outbox.rescheduled["r1"])
+}
+
+func TestWorkerFailsPermanentErrorWithoutRetry(t *testing.T) {
+	job := &Job{ID: "job-1"}
+	outbox := newFakeOutbox(job, &JobRecipient{ID: "r1", JobID: "job-1", StudentID: "s1", Channel: ChannelEmail, Attempts: 1})
+	svc := &fakeDeliveryService{results: map[string]DeliveryResult{
+		"s1": {StudentID: "s1", Err: ErrEmailMissing, Permanent: true, Log: &Log{StudentID: "s1"}},
+	}}
+	logs := &fakeLogRepository{}
+
+	_, err := newTestWorker(svc, outbox, logs).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Contains(t, outbox.failed, "r1")
+	assert.Empty(t, outbox.rescheduled)
+	assert.Len(t, logs.saved, 1)
+}
+
+func TestWorkerFailsAfterMaxAttempts(t *testing.T) {
+	job := &Job{ID: "job-1"}
+	outbox := newFakeOutbox(job, &JobRecipient{ID: "r1", JobID: "job-1", StudentID: "s1", Channel: ChannelEmail, Attempts: 3})
+	svc := &fakeDeliveryService{results: map[string]DeliveryResult{
+		"s1": {StudentID: "s1", Err: errors.New("smtp indisponível"), Log: &Log{StudentID: "s1"}},
+	}}
+	logs := &fakeLogRepository{}
+
+	_, err := newTestWorker(svc, outbox, logs).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "smtp indisponível", outbox.failed["r1"])
+	assert.Empty(t, outbox.rescheduled)
+	assert.Len(t, logs.saved, 1)
+}
+
+func TestWorkerGroupsRecipientsByJobAndChannel(t *testing.T) {
+	job := &Job{ID: "job-1"}
+	outbox := newFakeOutbox(job,
+		&JobRecipient{ID: "r1", JobID: "job-1", StudentID: "s1", Channel: ChannelEmail, Attempts: 1},
+		&JobRecipient{ID: "r2", JobID: "job-1", StudentID: "s2", Channel: ChannelEmail, Attempts: 1},
+		&JobRecipient{ID: "r3", JobID: "job-1", StudentID: "s1", Channel: ChannelWhatsApp, Attempts: 1},
+	)
+	svc := &fakeDeliveryService{results: map[string]DeliveryResult{
+		"s1": {StudentID: "s1"},
+		"s2": {StudentID: "s2"},
+	}}
+
+	processed, err := newTestWorker(svc, outbox, &fakeLogRepository{}).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, processed)
+	assert.Equal(t, 2, svc.calls)
+	assert.ElementsMatch(t, []string{"r1", "r2", "r3"}, outbox.sent)
+}
+
+func TestWorkerFallsBackToEmailWhenWhatsAppFailsPermanently(t *testing.T) {
+	policy := FallbackWhatsAppFirst
+	job := &Job{ID: "job-1", Fallback: &policy}
+	outbox := newFakeOutbox(job, &JobRecipient{ID: "r1", JobID: "job-1", StudentID: "s1", Channel: ChannelWhatsApp, Attempts: 1})
+	svc := &fakeDeliveryService{results: map[string]DeliveryResult{
+		"s1": {StudentID: "s1", Err: ErrPhoneMissing, Permanent: true, Log: &Log{StudentID: "s1"}},
+	}}
+
+	_, err := newTestWorker(svc, outbox, &fakeLogRepository{}).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, outbox.fallbacks, 1)
+	assert.Equal(t, "s1", outbox.fallbacks[0].StudentID)
+	assert.Equal(t, ChannelEmail, outbox.fallbacks[0].Channel)
+	assert.Equal(t, ChannelWhatsApp, *outbox.fallbacks[0].FallbackFrom)
+}
+
+func TestWorkerDoesNotChainFallbacks(t *testing.T) {
+	policy := FallbackWhatsAppFirst
+	from := ChannelWhatsApp
+	job := &Job{ID: "job-1", Fallback: &policy}
+	outbox := newFakeOutbox(job, &JobRecipient{ID: "r1", JobID: "job-1", StudentID: "s1", Channel: ChannelEmail, Attempts: 3, FallbackFrom: &from})
+	svc := &fakeDeliveryService{results: map[string]DeliveryResult{
+		"s1": {StudentID: "s1", Err: errors.New("smtp indisponível"), Log: &Log{StudentID: "s1"}},
+	}}
+	logs := &fakeLogRepository{}
+
+	_, err := newTestWorker(svc, outbox, logs).ProcessBatch(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, outbox.fallbacks)
+	require.Len(t, logs.saved, 1)
+	assert.Equal(t, ChannelWhatsApp, *logs.saved[0].FallbackFrom)
+}
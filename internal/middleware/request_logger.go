@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedQueryParams são parâmetros que carregam segredos: o webhook global da Evolution só consegue
+// mandar o segredo em ?token=, e o callback OAuth recebe code e state.
+var redactedQueryParams = []string{"token", "code", "state"}
+
+// RequestLogger é o log de requisições do gin com os segredos da query string mascarados.
+func RequestLogger(out io.Writer) gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Output: out,
+		Formatter: func(param gin.LogFormatterParams) string {
+			return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				param.StatusCode,
+				param.Latency,
+				param.ClientIP,
+				param.Method,
+				redactQuery(param.Path),
+				param.ErrorMessage,
+			)
+		},
+	})
+}
+
+func redactQuery(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?REDACTED"
+	}
+	for _, key := range redactedQueryParams {
+		if values.Has(key) {
+			values.Set(key, "REDACTED")
+		}
+	}
+	return base + "?" + values.Encode()
+}
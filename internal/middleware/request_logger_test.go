@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestLoggerRedactsSecretsInQueryString(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(RequestLogger(&out))
+	router.POST("/webhook/evolution", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/webhook/evolution?token=super-secret&page=2", nil))
+
+	logged := out.String()
+	if strings.Contains(logged, "super-secret") {
+		t.Fatalf("log expõe o segredo: %s", logged)
+	}
+	if !strings.Contains(logged, "/webhook/evolution?page=2&token=REDACTED") {
+		t.Fatalf("log sem a rota mascarada: %s", logged)
+	}
+}
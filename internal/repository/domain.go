@@ -3,11 +3,13 @@ package repository
 import (
 	"database/sql"
 
+	"github.com/ThalysSilva/unicast-backend/internal/attachment"
 	"github.com/ThalysSilva/unicast-backend/internal/campus"
 	"github.com/ThalysSilva/unicast-backend/internal/discipline"
 	"github.com/ThalysSilva/unicast-backend/internal/enrollment"
 	"github.com/ThalysSilva/unicast-backend/internal/invite"
 	"github.com/ThalysSilva/unicast-backend/internal/program"
+	"github.com/ThalysSilva/unicast-backend/internal/sms"
 	"github.com/ThalysSilva/unicast-backend/internal/smtp"
 	"github.com/ThalysSilva/unicast-backend/internal/student"
 	"github.com/ThalysSilva/unicast-backend/internal/user"
@@ -16,10 +18,12 @@ import (
 
 type Repositories struct {
 	User             user.Repository
+	Attachment       attachment.Repository
 	Discipline       discipline.Repository
 	Enrollment       enrollment.Repository
 	Invite           invite.Repository
 	SmtpInstance     smtp.Repository
+	SmsInstance      sms.Repository
 	WhatsAppInstance whatsapp.Repository
 	Campus           campus.Repository
 	Program          program.Repository
@@ -29,10 +33,12 @@ type Repositories struct {
 func NewRepositories(dbSQL *sql.DB) *Repositories {
 	return &Repositories{
 		User:             user.NewRepository(dbSQL),
+		Attachment:       attachment.NewRepository(dbSQL),
 		Discipline:       discipline.NewRepository(dbSQL),
 		Enrollment:       enrollment.NewRepository(dbSQL),
 		Invite:           invite.NewRepository(dbSQL),
 		SmtpInstance:     smtp.NewRepository(dbSQL),
+		SmsInstance:      sms.NewRepository(dbSQL),
 		WhatsAppInstance: whatsapp.NewRepository(dbSQL),
 		Campus:           campus.NewRepository(dbSQL),
 		Program:          program.NewRepository(dbSQL),
@@ -0,0 +1,163 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/message"
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+type Status string
+
+const (
+	StatusActive    Status = "ACTIVE"
+	StatusPaused    Status = "PAUSED"
+	StatusCanceled  Status = "CANCELED"
+	StatusCompleted Status = "COMPLETED"
+)
+
+const defaultTimezone = "America/Sao_Paulo"
+
+// Schedule é uma mensagem agendada para um horário (send_at) ou recorrente (recurrence a partir do send_at).
+type Schedule struct {
+	ID                 string
+	UserID             string
+	SmtpID             *string
+	WhatsAppInstanceID *string
+	SmsInstanceID      *string
+	TemplateID         *string
+	DisciplineID       *string
+	From               string
+	Subject            string
+	Body               string
+	StudentIDs         []string
+	// Targets e FilterID são expandidos a cada execução, acompanhando novas matrículas.
+	Targets     student.RecipientFilter
+	FilterID    *string
+	Fallback    message.FallbackPolicy
+	Format      message.BodyFormat
+	Attachments []message.Attachment
+	Jwe         *string
+	SendAt      time.Time
+	Recurrence  *string
+	Timezone    string
+	Status      Status
+	NextRunAt   *time.Time
+	LastRunAt   *time.Time
+	LastJobID   *string
+	LastError   *string
+	RunCount    int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	// JweReentryRequired marca agendamentos cujo JWE foi descartado por uma redefinição de senha pelo admin;
+	// os envios por SMTP com senha falham até o agendamento ser editado com um JWE novo.
+	JweReentryRequired bool
+}
+
+// toMessage monta a mensagem enviada pelo fluxo normal de /message/send a cada execução.
+func (s *Schedule) toMessage() *message.Message {
+	msg := &message.Message{
+		UserID:  s.UserID,
+		To:      append([]string(nil), s.StudentIDs...),
+		From:    s.From,
+		Subject: s.Subject,
+		Body:    s.Body,
+
+		RecipientFilter: s.Targets,
+		Fallback:        s.Fallback,
+		Format:          s.Format,
+	}
+	if s.FilterID != nil {
+		msg.FilterID = *s.FilterID
+	}
+	if s.SmtpID != nil {
+		msg.SmtpId = *s.SmtpID
+	}
+	if s.WhatsAppInstanceID != nil {
+		msg.WhatsappId = *s.WhatsAppInstanceID
+	}
+	if s.SmsInstanceID != nil {
+		msg.SmsId = *s.SmsInstanceID
+	}
+	if s.Jwe != nil {
+		msg.Jwe = *s.Jwe
+	}
+	msg.JweReentryRequired = s.JweReentryRequired
+	if s.TemplateID != nil {
+		msg.TemplateID = *s.TemplateID
+	}
+	if s.DisciplineID != nil {
+		msg.DisciplineID = *s.DisciplineID
+	}
+	if len(s.Attachments) > 0 {
+		attachments := append([]message.Attachment(nil), s.Attachments...)
+		msg.Attachments = &attachments
+	}
+	return msg
+}
+
+type ScheduleInput struct {
+	message.MessageInput
+	// SendAt é o primeiro envio (RFC 3339). Em recorrências também define o horário local das execuções.
+	SendAt time.Time `json:"sendAt" binding:"required"`
+	// Recurrence aceita RRULE com FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY, COUNT e UNTIL.
+	Recurrence string `json:"recurrence"`
+	Timezone   string `json:"timezone"`
+}
+
+type ScheduleResponse struct {
+	ID           string   `json:"id"`
+	SmtpID       *string  `json:"smtp_id,omitempty"`
+	WhatsappID   *string  `json:"whatsapp_id,omitempty"`
+	SmsID        *string  `json:"sms_id,omitempty"`
+	TemplateID   *string  `json:"template_id,omitempty"`
+	DisciplineID *string  `json:"discipline_id,omitempty"`
+	From         string   `json:"from,omitempty"`
+	Subject      string   `json:"subject"`
+	Body         string   `json:"body"`
+	To           []string `json:"to"`
+	// Os alvos usam os mesmos nomes do corpo de criação.
+	DisciplineIDs   []string                `json:"discipline_ids"`
+	ProgramIDs      []string                `json:"program_ids"`
+	CampusIDs       []string                `json:"campus_ids"`
+	Statuses        []student.StudentStatus `json:"statuses"`
+	FilterID        *string                 `json:"filter_id,omitempty"`
+	Fallback        message.FallbackPolicy  `json:"fallback,omitempty"`
+	Format          message.BodyFormat      `json:"format,omitempty"`
+	AttachmentNames []string                `json:"attachmentNames"`
+	SendAt          time.Time               `json:"sendAt"`
+	Recurrence      *string                 `json:"recurrence,omitempty"`
+	Timezone        string                  `json:"timezone"`
+	Status          Status                  `json:"status"`
+	NextRunAt       *time.Time              `json:"nextRunAt,omitempty"`
+	LastRunAt       *time.Time              `json:"lastRunAt,omitempty"`
+	LastJobID       *string                 `json:"lastJobId,omitempty"`
+	LastError       *string                 `json:"lastError,omitempty"`
+	RunCount        int                     `json:"runCount"`
+	CreatedAt       time.Time               `json:"createdAt"`
+}
+
+type Repository interface {
+	database.Transactional
+	Create(ctx context.Context, schedule *Schedule) error
+	FindByID(ctx context.Context, id string) (*Schedule, error)
+	FindByUserID(ctx context.Context, userID string) ([]*Schedule, error)
+	// Update regrava os dados editáveis e o estado (status/next_run_at) do agendamento.
+	Update(ctx context.Context, schedule *Schedule) error
+	// LockDue reserva agendamentos ativos vencidos; deve ser chamado dentro de transação.
+	LockDue(ctx context.Context, now time.Time, limit int) ([]*Schedule, error)
+	// Advance registra uma execução e define o próximo horário (nil encerra o agendamento).
+	Advance(ctx context.Context, id string, ranAt time.Time, nextRunAt *time.Time, status Status) error
+	// ReplaceJwe troca o JWE dos agendamentos em aberto do usuário, depois de uma troca de senha.
+	// Com jwe nil, o JWE é descartado e os agendamentos ficam marcados para falhar com ErrSmtpNeedsPassword.
+	ReplaceJwe(ctx context.Context, userID string, jwe *string) error
+	// RecordRun guarda o job gerado pela execução ou o erro; agendamentos encerrados descartam o JWE.
+	RecordRun(ctx context.Context, id string, jobID *string, errText *string) error
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return newSQLRepository(db)
+}
@@ -0,0 +1,243 @@
+package schedule
+
+import (
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+
+	"github.com/gin-gonic/gin"
+)
+
+type handler struct {
+	service Service
+}
+
+type Handler interface {
+	Create() gin.HandlerFunc
+	List() gin.HandlerFunc
+	Get() gin.HandlerFunc
+	Update() gin.HandlerFunc
+	Pause() gin.HandlerFunc
+	Resume() gin.HandlerFunc
+	Cancel() gin.HandlerFunc
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Agenda uma mensagem
+// @Description Agenda uma mensagem para um horário (sendAt) ou de forma recorrente (recurrence em formato RRULE a partir do sendAt). Template, destinatários e instâncias passam pelas mesmas validações do envio imediato.
+// @OperationId createScheduledMessage
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param body body ScheduleInput true "Mensagem e agendamento"
+// @Success 201 {object} api.DefaultResponse[ScheduleResponse]
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Router /message/scheduled [post]
+func (h *handler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input ScheduleInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		schedule, err := h.service.Create(c.Request.Context(), c.GetString("userID"), input)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, api.DefaultResponse[ScheduleResponse]{
+			Message: "Mensagem agendada com sucesso",
+			Data:    toResponse(schedule),
+		})
+	}
+}
+
+// @Summary Lista mensagens agendadas
+// @OperationId listScheduledMessages
+// @Tags message
+// @Produce json
+// @Success 200 {object} api.DefaultResponse[[]ScheduleResponse]
+// @Router /message/scheduled [get]
+func (h *handler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedules, err := h.service.List(c.Request.Context(), c.GetString("userID"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		items := make([]ScheduleResponse, 0, len(schedules))
+		for _, schedule := range schedules {
+			items = append(items, toResponse(schedule))
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[[]ScheduleResponse]{
+			Message: "Agendamentos listados com sucesso",
+			Data:    items,
+		})
+	}
+}
+
+// @Summary Busca uma mensagem agendada
+// @OperationId getScheduledMessage
+// @Tags message
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Success 200 {object} api.DefaultResponse[ScheduleResponse]
+// @Failure 404 {object} api.ErrorResponse
+// @Router /message/scheduled/{id} [get]
+func (h *handler) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedule, err := h.service.Get(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[ScheduleResponse]{
+			Message: "Agendamento encontrado",
+			Data:    toResponse(schedule),
+		})
+	}
+}
+
+// @Summary Edita uma mensagem agendada
+// @Description Substitui a mensagem, o horário e a recorrência. Se o jwe não for enviado, o anterior é mantido. As validações são as mesmas da criação.
+// @OperationId updateScheduledMessage
+// @Tags message
+// @Accept json
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Param body body ScheduleInput true "Mensagem e agendamento"
+// @Success 200 {object} api.DefaultResponse[ScheduleResponse]
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /message/scheduled/{id} [put]
+func (h *handler) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input ScheduleInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		schedule, err := h.service.Update(c.Request.Context(), c.GetString("userID"), c.Param("id"), input)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[ScheduleResponse]{
+			Message: "Agendamento atualizado com sucesso",
+			Data:    toResponse(schedule),
+		})
+	}
+}
+
+// @Summary Pausa uma mensagem agendada
+// @OperationId pauseScheduledMessage
+// @Tags message
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Success 200 {object} api.DefaultResponse[ScheduleResponse]
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /message/scheduled/{id}/pause [post]
+func (h *handler) Pause() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedule, err := h.service.Pause(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[ScheduleResponse]{
+			Message: "Agendamento pausado com sucesso",
+			Data:    toResponse(schedule),
+		})
+	}
+}
+
+// @Summary Retoma uma mensagem agendada
+// @Description Execuções que venceram durante a pausa não são enviadas
+// @OperationId resumeScheduledMessage
+// @Tags message
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Success 200 {object} api.DefaultResponse[ScheduleResponse]
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /message/scheduled/{id}/resume [post]
+func (h *handler) Resume() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedule, err := h.service.Resume(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[ScheduleResponse]{
+			Message: "Agendamento retomado com sucesso",
+			Data:    toResponse(schedule),
+		})
+	}
+}
+
+// @Summary Cancela uma mensagem agendada
+// @OperationId cancelScheduledMessage
+// @Tags message
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Success 200 {object} api.MessageResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /message/scheduled/{id} [delete]
+func (h *handler) Cancel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.service.Cancel(c.Request.Context(), c.GetString("userID"), c.Param("id")); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.MessageResponse{Message: "Agendamento cancelado com sucesso"})
+	}
+}
+
+func toResponse(schedule *Schedule) ScheduleResponse {
+	names := make([]string, 0, len(schedule.Attachments))
+	for _, attachment := range schedule.Attachments {
+		name := attachment.FileName
+		if name == "" {
+			name = attachment.ID
+		}
+		names = append(names, name)
+	}
+	return ScheduleResponse{
+		ID:              schedule.ID,
+		SmtpID:          schedule.SmtpID,
+		WhatsappID:      schedule.WhatsAppInstanceID,
+		SmsID:           schedule.SmsInstanceID,
+		TemplateID:      schedule.TemplateID,
+		DisciplineID:    schedule.DisciplineID,
+		From:            schedule.From,
+		Subject:         schedule.Subject,
+		Body:            schedule.Body,
+		To:              schedule.StudentIDs,
+		DisciplineIDs:   schedule.Targets.DisciplineIDs,
+		ProgramIDs:      schedule.Targets.ProgramIDs,
+		CampusIDs:       schedule.Targets.CampusIDs,
+		Statuses:        schedule.Targets.Statuses,
+		FilterID:        schedule.FilterID,
+		Fallback:        schedule.Fallback,
+		Format:          schedule.Format,
+		AttachmentNames: names,
+		SendAt:          schedule.SendAt,
+		Recurrence:      schedule.Recurrence,
+		Timezone:        schedule.Timezone,
+		Status:          schedule.Status,
+		NextRunAt:       schedule.NextRunAt,
+		LastRunAt:       schedule.LastRunAt,
+		LastJobID:       schedule.LastJobID,
+		LastError:       schedule.LastError,
+		RunCount:        schedule.RunCount,
+		CreatedAt:       schedule.CreatedAt,
+	}
+}
@@ -0,0 +1,197 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+)
+
+// maxOccurrenceScan limita a busca da próxima ocorrência para regras muito antigas ou muito densas.
+const maxOccurrenceScan = 100000
+
+// Rule é o subconjunto de RRULE (RFC 5545) suportado pelos agendamentos.
+// A data/hora de início (DTSTART) é o send_at do agendamento.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    *time.Time
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRule interpreta uma regra no formato "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,WE;COUNT=10".
+// O prefixo "RRULE:" é opcional. UNTIL aceita "20261231T235959Z" ou "20261231".
+func ParseRule(value string) (*Rule, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.ToUpper(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("regra de recorrência vazia")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("parte inválida na recorrência: %q", part)
+		}
+		switch key {
+		case "FREQ":
+			switch Frequency(val) {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+				rule.Freq = Frequency(val)
+			default:
+				return nil, fmt.Errorf("FREQ não suportado: %s", val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("INTERVAL inválido: %s", val)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("COUNT inválido: %s", val)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return nil, fmt.Errorf("BYDAY inválido: %s", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		default:
+			return nil, fmt.Errorf("parâmetro de recorrência não suportado: %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ é obrigatório na recorrência")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != FrequencyWeekly {
+		return nil, fmt.Errorf("BYDAY só é suportado com FREQ=WEEKLY")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT e UNTIL não podem ser usados juntos")
+	}
+	sort.Slice(rule.ByDay, func(i, j int) bool {
+		return mondayIndex(rule.ByDay[i]) < mondayIndex(rule.ByDay[j])
+	})
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if until, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// Data sem hora inclui o dia inteiro.
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			return until, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL inválido: %s", value)
+}
+
+// Next devolve a primeira ocorrência estritamente depois de after.
+// dtstart deve estar no fuso do agendamento para que o horário local seja preservado.
+func (r *Rule) Next(dtstart, after time.Time) (time.Time, bool) {
+	next := r.iterator(dtstart)
+	for n := 1; n <= maxOccurrenceScan; n++ {
+		occurrence, ok := next()
+		if !ok {
+			return time.Time{}, false
+		}
+		if r.Count > 0 && n > r.Count {
+			return time.Time{}, false
+		}
+		if r.Until != nil && occurrence.After(*r.Until) {
+			return time.Time{}, false
+		}
+		if occurrence.After(after) {
+			return occurrence, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (r *Rule) iterator(dtstart time.Time) func() (time.Time, bool) {
+	switch r.Freq {
+	case FrequencyDaily:
+		step := 0
+		return func() (time.Time, bool) {
+			occurrence := dtstart.AddDate(0, 0, step*r.Interval)
+			step++
+			return occurrence, true
+		}
+	case FrequencyMonthly:
+		step := 0
+		return func() (time.Time, bool) {
+			// Meses sem o dia de início (ex.: dia 31) são pulados, como no RFC 5545.
+			for i := 0; i < 48; i++ {
+				occurrence := dtstart.AddDate(0, step*r.Interval, 0)
+				step++
+				if occurrence.Day() == dtstart.Day() {
+					return occurrence, true
+				}
+			}
+			return time.Time{}, false
+		}
+	default:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{dtstart.Weekday()}
+		}
+		weekStart := dtstart.AddDate(0, 0, -mondayIndex(dtstart.Weekday()))
+		week, index := 0, 0
+		return func() (time.Time, bool) {
+			for {
+				if index == len(days) {
+					week++
+					index = 0
+				}
+				occurrence := weekStart.AddDate(0, 0, week*7*r.Interval+mondayIndex(days[index]))
+				index++
+				if !occurrence.Before(dtstart) {
+					return occurrence, true
+				}
+			}
+		}
+	}
+}
+
+// mondayIndex numera os dias com a semana começando na segunda (WKST=MO).
+func mondayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
@@ -0,0 +1,96 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var saoPaulo = time.FixedZone("BRT", -3*60*60)
+
+func TestParseRuleRejectsUnsupportedParts(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;COUNT=2;UNTIL=20261231",
+		"FREQ=WEEKLY;BYHOUR=7",
+	} {
+		_, err := ParseRule(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRuleNextWeeklyByDayKeepsLocalTime(t *testing.T) {
+	rule, err := ParseRule("RRULE:FREQ=WEEKLY;BYDAY=WE,MO")
+	require.NoError(t, err)
+
+	// Domingo, 07:00
+	dtstart := time.Date(2026, 3, 1, 7, 0, 0, 0, saoPaulo)
+
+	first, ok := rule.Next(dtstart, dtstart.Add(-time.Second))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 2, 7, 0, 0, 0, saoPaulo), first)
+
+	second, ok := rule.Next(dtstart, first)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 4, 7, 0, 0, 0, saoPaulo), second)
+
+	third, ok := rule.Next(dtstart, second)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 9, 7, 0, 0, 0, saoPaulo), third)
+}
+
+func TestRuleNextHonorsIntervalAndCount(t *testing.T) {
+	rule, err := ParseRule("FREQ=WEEKLY;INTERVAL=2;COUNT=2")
+	require.NoError(t, err)
+
+	dtstart := time.Date(2026, 3, 2, 7, 0, 0, 0, saoPaulo)
+
+	next, ok := rule.Next(dtstart, dtstart)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 16, 7, 0, 0, 0, saoPaulo), next)
+
+	_, ok = rule.Next(dtstart, next)
+	assert.False(t, ok)
+}
+
+func TestRuleNextStopsAtUntil(t *testing.T) {
+	rule, err := ParseRule("FREQ=DAILY;UNTIL=20260303")
+	require.NoError(t, err)
+
+	dtstart := time.Date(2026, 3, 1, 7, 0, 0, 0, saoPaulo)
+
+	next, ok := rule.Next(dtstart, time.Date(2026, 3, 2, 8, 0, 0, 0, saoPaulo))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 3, 7, 0, 0, 0, saoPaulo), next)
+
+	_, ok = rule.Next(dtstart, next)
+	assert.False(t, ok)
+}
+
+func TestRuleNextMonthlySkipsShortMonths(t *testing.T) {
+	rule, err := ParseRule("FREQ=MONTHLY")
+	require.NoError(t, err)
+
+	dtstart := time.Date(2026, 1, 31, 7, 0, 0, 0, saoPaulo)
+
+	next, ok := rule.Next(dtstart, dtstart)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 31, 7, 0, 0, 0, saoPaulo), next)
+}
+
+func TestRuleNextSkipsMissedOccurrences(t *testing.T) {
+	rule, err := ParseRule("FREQ=DAILY")
+	require.NoError(t, err)
+
+	dtstart := time.Date(2026, 3, 1, 7, 0, 0, 0, saoPaulo)
+
+	next, ok := rule.Next(dtstart, time.Date(2026, 3, 10, 9, 0, 0, 0, saoPaulo))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2026, 3, 11, 7, 0, 0, 0, saoPaulo), next)
+}
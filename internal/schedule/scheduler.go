@@ -0,0 +1,123 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/message"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+const schedulerBatchSize = 20
+
+// Scheduler dispara os agendamentos vencidos pelo mesmo caminho de POST /message/send.
+type Scheduler struct {
+	repository     Repository
+	messageService message.Service
+	interval       time.Duration
+	now            func() time.Time
+}
+
+func NewScheduler(repository Repository, messageService message.Service, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &Scheduler{
+		repository:     repository,
+		messageService: messageService,
+		interval:       interval,
+		now:            time.Now,
+	}
+}
+
+// Run bloqueia até o contexto ser cancelado.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		for {
+			processed, err := s.RunDue(ctx)
+			if err != nil {
+				log.Printf("scheduler de mensagens: %v", err)
+			}
+			if err != nil || processed < schedulerBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue executa um lote de agendamentos vencidos e devolve quantos foram disparados.
+//
+// A execução é avançada antes do envio: se o processo cair entre os dois passos, a execução é
+// perdida em vez de duplicada, e o próximo horário continua valendo.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	if ctx.Err() != nil {
+		return 0, nil
+	}
+	now := s.now()
+
+	due, err := database.MakeTransaction(ctx, []database.Transactional{s.repository}, func(txRepos []database.Transactional) ([]*Schedule, error) {
+		repo := txRepos[0].(Repository)
+		due, err := repo.LockDue(ctx, now, schedulerBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, schedule := range due {
+			next, err := nextRun(schedule, now)
+			if err != nil {
+				log.Printf("scheduler de mensagens: agendamento %s: %v", schedule.ID, err)
+			}
+			schedule.Status = StatusActive
+			if next == nil {
+				schedule.Status = StatusCompleted
+			}
+			schedule.NextRunAt = next
+			if err := repo.Advance(ctx, schedule.ID, now, next, schedule.Status); err != nil {
+				return nil, err
+			}
+		}
+		return due, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	workCtx := context.WithoutCancel(ctx)
+	for _, schedule := range due {
+		s.fire(workCtx, schedule)
+	}
+	return len(due), nil
+}
+
+func (s *Scheduler) fire(ctx context.Context, schedule *Schedule) {
+	var jobID, errText *string
+	job, err := s.messageService.Send(ctx, schedule.toMessage())
+	if err != nil {
+		log.Printf("scheduler de mensagens: falha ao enviar agendamento %s: %v", schedule.ID, err)
+		text := publicErrorText(err)
+		errText = &text
+	} else {
+		jobID = &job.ID
+	}
+	if err := s.repository.RecordRun(ctx, schedule.ID, jobID, errText); err != nil {
+		log.Printf("scheduler de mensagens: %v", err)
+	}
+}
+
+// publicErrorText evita expor detalhes internos do erro na listagem de agendamentos.
+func publicErrorText(err error) string {
+	customErr := &customerror.CustomError{}
+	if errors.As(err, &customErr) {
+		return customErr.PublicMessage()
+	}
+	return "falha ao enviar mensagem agendada"
+}
@@ -0,0 +1,274 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	_ "time/tzdata" // a imagem alpine não traz o banco de fusos
+
+	"github.com/ThalysSilva/unicast-backend/internal/message"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var (
+	ErrScheduleNotFound  = customerror.Make("agendamento não encontrado", http.StatusNotFound, errors.New("ErrScheduleNotFound"))
+	ErrScheduleFinished  = customerror.Make("agendamento já foi concluído ou cancelado", http.StatusConflict, errors.New("ErrScheduleFinished"))
+	ErrNoChannelSelected = customerror.Make("selecione ao menos um canal de envio", http.StatusBadRequest, errors.New("ErrNoChannelSelected"))
+	ErrNoRecipients      = customerror.Make("informe ao menos um destinatário", http.StatusBadRequest, errors.New("ErrNoRecipients"))
+	ErrEmptyMessage      = customerror.Make("informe assunto e corpo ou um template", http.StatusBadRequest, errors.New("ErrEmptyMessage"))
+	ErrInvalidTimezone   = customerror.Make("fuso horário inválido", http.StatusBadRequest, errors.New("ErrInvalidTimezone"))
+	ErrInvalidRecurrence = customerror.Make("recorrência inválida", http.StatusBadRequest, errors.New("ErrInvalidRecurrence"))
+	ErrSendAtInPast      = customerror.Make("a data de envio deve estar no futuro", http.StatusBadRequest, errors.New("ErrSendAtInPast"))
+	ErrNoUpcomingRun     = customerror.Make("a recorrência não possui próximas execuções", http.StatusBadRequest, errors.New("ErrNoUpcomingRun"))
+	ErrScheduleNotPaused = customerror.Make("o agendamento não está pausado", http.StatusConflict, errors.New("ErrScheduleNotPaused"))
+	ErrScheduleNotActive = customerror.Make("o agendamento não está ativo", http.StatusConflict, errors.New("ErrScheduleNotActive"))
+)
+
+type Service interface {
+	Create(ctx context.Context, userID string, input ScheduleInput) (*Schedule, error)
+	List(ctx context.Context, userID string) ([]*Schedule, error)
+	Get(ctx context.Context, userID, id string) (*Schedule, error)
+	// Update substitui a mensagem, o horário e a recorrência; o próximo envio é recalculado.
+	Update(ctx context.Context, userID, id string, input ScheduleInput) (*Schedule, error)
+	Pause(ctx context.Context, userID, id string) (*Schedule, error)
+	Resume(ctx context.Context, userID, id string) (*Schedule, error)
+	Cancel(ctx context.Context, userID, id string) error
+}
+
+type service struct {
+	repository     Repository
+	messageService message.Service
+	now            func() time.Time
+}
+
+func NewService(repository Repository, messageService message.Service) Service {
+	return &service{repository: repository, messageService: messageService, now: time.Now}
+}
+
+func (s *service) Create(ctx context.Context, userID string, input ScheduleInput) (*Schedule, error) {
+	schedule := &Schedule{UserID: userID, Status: StatusActive}
+	if err := applyInput(schedule, input); err != nil {
+		return nil, customerror.Trace("CreateSchedule", err)
+	}
+	if err := s.messageService.Validate(ctx, schedule.toMessage()); err != nil {
+		return nil, customerror.Trace("CreateSchedule", err)
+	}
+	if err := s.scheduleNextRun(schedule); err != nil {
+		return nil, customerror.Trace("CreateSchedule", err)
+	}
+	if err := s.repository.Create(ctx, schedule); err != nil {
+		return nil, customerror.Trace("CreateSchedule", err)
+	}
+	return schedule, nil
+}
+
+func (s *service) List(ctx context.Context, userID string) ([]*Schedule, error) {
+	schedules, err := s.repository.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, customerror.Trace("ListSchedules", err)
+	}
+	return schedules, nil
+}
+
+func (s *service) Get(ctx context.Context, userID, id string) (*Schedule, error) {
+	schedule, err := s.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, customerror.Trace("GetSchedule", err)
+	}
+	if schedule == nil || schedule.UserID != userID {
+		return nil, customerror.Trace("GetSchedule", ErrScheduleNotFound)
+	}
+	return schedule, nil
+}
+
+func (s *service) Update(ctx context.Context, userID, id string, input ScheduleInput) (*Schedule, error) {
+	schedule, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if isFinished(schedule.Status) {
+		return nil, customerror.Trace("UpdateSchedule", ErrScheduleFinished)
+	}
+	if err := applyInput(schedule, input); err != nil {
+		return nil, customerror.Trace("UpdateSchedule", err)
+	}
+	if err := s.messageService.Validate(ctx, schedule.toMessage()); err != nil {
+		return nil, customerror.Trace("UpdateSchedule", err)
+	}
+	if schedule.Status == StatusActive {
+		if err := s.scheduleNextRun(schedule); err != nil {
+			return nil, customerror.Trace("UpdateSchedule", err)
+		}
+	}
+	if err := s.repository.Update(ctx, schedule); err != nil {
+		return nil, customerror.Trace("UpdateSchedule", err)
+	}
+	return schedule, nil
+}
+
+func (s *service) Pause(ctx context.Context, userID, id string) (*Schedule, error) {
+	schedule, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Status != StatusActive {
+		return nil, customerror.Trace("PauseSchedule", ErrScheduleNotActive)
+	}
+	schedule.Status = StatusPaused
+	schedule.NextRunAt = nil
+	if err := s.repository.Update(ctx, schedule); err != nil {
+		return nil, customerror.Trace("PauseSchedule", err)
+	}
+	return schedule, nil
+}
+
+// Resume reativa o agendamento a partir de agora; execuções que venceram durante a pausa não são enviadas.
+func (s *service) Resume(ctx context.Context, userID, id string) (*Schedule, error) {
+	schedule, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Status != StatusPaused {
+		return nil, customerror.Trace("ResumeSchedule", ErrScheduleNotPaused)
+	}
+	schedule.Status = StatusActive
+	if err := s.scheduleNextRun(schedule); err != nil {
+		return nil, customerror.Trace("ResumeSchedule", err)
+	}
+	if err := s.repository.Update(ctx, schedule); err != nil {
+		return nil, customerror.Trace("ResumeSchedule", err)
+	}
+	return schedule, nil
+}
+
+func (s *service) Cancel(ctx context.Context, userID, id string) error {
+	schedule, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if isFinished(schedule.Status) {
+		return customerror.Trace("CancelSchedule", ErrScheduleFinished)
+	}
+	schedule.Status = StatusCanceled
+	schedule.NextRunAt = nil
+	schedule.Jwe = nil
+	if err := s.repository.Update(ctx, schedule); err != nil {
+		return customerror.Trace("CancelSchedule", err)
+	}
+	return nil
+}
+
+func (s *service) scheduleNextRun(schedule *Schedule) error {
+	now := s.now()
+	if schedule.Recurrence == nil && !schedule.SendAt.After(now) {
+		return ErrSendAtInPast
+	}
+	next, err := nextRun(schedule, now)
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		return ErrNoUpcomingRun
+	}
+	schedule.NextRunAt = next
+	return nil
+}
+
+func applyInput(schedule *Schedule, input ScheduleInput) error {
+	if input.SmtpId == "" && input.WhatsappId == "" && input.SmsId == "" {
+		return ErrNoChannelSelected
+	}
+	if len(input.To) == 0 && !input.RecipientFilter().HasTargets() && input.FilterID == "" {
+		return ErrNoRecipients
+	}
+	if input.Fallback != "" && (input.SmtpId == "" || input.WhatsappId == "") {
+		return message.ErrFallbackNeedsBothChannels
+	}
+	// Com template, assunto e corpo são resolvidos a cada execução para refletir edições no template.
+	if input.TemplateID == "" && (strings.TrimSpace(input.Subject) == "" || strings.TrimSpace(input.Body) == "") {
+		return ErrEmptyMessage
+	}
+	if err := message.ValidatePlaceholders(input.Subject, input.Body); err != nil {
+		return err
+	}
+
+	timezone := input.Timezone
+	if timezone == "" {
+		timezone = defaultTimezone
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidTimezone, err)
+	}
+
+	var recurrence *string
+	if input.Recurrence != "" {
+		if _, err := ParseRule(input.Recurrence); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidRecurrence, err)
+		}
+		recurrence = &input.Recurrence
+	}
+
+	schedule.SmtpID = nullableString(input.SmtpId)
+	schedule.WhatsAppInstanceID = nullableString(input.WhatsappId)
+	schedule.SmsInstanceID = nullableString(input.SmsId)
+	schedule.TemplateID = nullableString(input.TemplateID)
+	schedule.DisciplineID = nullableString(input.DisciplineID)
+	schedule.From = input.From
+	schedule.Subject = input.Subject
+	schedule.Body = input.Body
+	schedule.StudentIDs = input.To
+	schedule.Targets = input.RecipientFilter()
+	schedule.FilterID = nullableString(input.FilterID)
+	schedule.Fallback = input.Fallback
+	schedule.Format = input.Format
+	schedule.Attachments = []message.Attachment{}
+	if input.Attachments != nil {
+		schedule.Attachments = *input.Attachments
+	}
+	// O JWE só é substituído quando enviado; edições sem JWE mantêm o anterior.
+	if input.Jwe != "" {
+		schedule.Jwe = &input.Jwe
+		schedule.JweReentryRequired = false
+	}
+	schedule.SendAt = input.SendAt
+	schedule.Recurrence = recurrence
+	schedule.Timezone = timezone
+	return nil
+}
+
+// nextRun calcula a próxima execução estritamente depois de after; nil indica que não há mais execuções.
+func nextRun(schedule *Schedule, after time.Time) (*time.Time, error) {
+	if schedule.Recurrence == nil {
+		if schedule.SendAt.After(after) {
+			sendAt := schedule.SendAt
+			return &sendAt, nil
+		}
+		return nil, nil
+	}
+
+	rule, err := ParseRule(*schedule.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRecurrence, err)
+	}
+	location, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTimezone, err)
+	}
+
+	dtstart := schedule.SendAt.In(location)
+	if after.Before(dtstart) {
+		after = dtstart.Add(-time.Nanosecond)
+	}
+	next, ok := rule.Next(dtstart, after)
+	if !ok {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+func isFinished(status Status) bool {
+	return status == StatusCanceled || status == StatusCompleted
+}
@@ -0,0 +1,207 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/message"
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepository struct {
+	Repository
+	schedules map[string]*Schedule
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{schedules: map[string]*Schedule{}}
+}
+
+func (f *fakeRepository) Create(ctx context.Context, schedule *Schedule) error {
+	schedule.ID = "schedule-1"
+	f.schedules[schedule.ID] = schedule
+	return nil
+}
+
+func (f *fakeRepository) FindByID(ctx context.Context, id string) (*Schedule, error) {
+	return f.schedules[id], nil
+}
+
+func (f *fakeRepository) Update(ctx context.Context, schedule *Schedule) error {
+	f.schedules[schedule.ID] = schedule
+	return nil
+}
+
+// fakeMessageService simula as validações de envio; err é devolvido por Validate.
+type fakeMessageService struct {
+	message.Service
+	err       error
+	validated *message.Message
+}
+
+func (f *fakeMessageService) Validate(ctx context.Context, msg *message.Message) error {
+	f.validated = msg
+	return f.err
+}
+
+func newTestService(repo Repository, now time.Time) *service {
+	return &service{repository: repo, messageService: &fakeMessageService{}, now: func() time.Time { return now }}
+}
+
+func scheduleInput(sendAt time.Time, recurrence string) ScheduleInput {
+	return ScheduleInput{
+		MessageInput: message.MessageInput{
+			Jwe:     "jwe-original",
+			SmtpId:  "smtp-1",
+			Subject: "Entrega do trabalho",
+			Body:    "Lembrete semanal.",
+			To:      []string{"student-1"},
+		},
+		SendAt:     sendAt,
+		Recurrence: recurrence,
+		Timezone:   "America/Sao_Paulo",
+	}
+}
+
+func TestCreateRejectsOneOffInThePast(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	svc := newTestService(newFakeRepository(), now)
+
+	_, err := svc.Create(context.Background(), "user-1", scheduleInput(now.Add(-time.Minute), ""))
+
+	assert.ErrorIs(t, err, ErrSendAtInPast)
+}
+
+func TestCreateRejectsInvalidRecurrence(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	svc := newTestService(newFakeRepository(), now)
+
+	_, err := svc.Create(context.Background(), "user-1", scheduleInput(now, "FREQ=HOURLY"))
+
+	assert.ErrorIs(t, err, ErrInvalidRecurrence)
+}
+
+func TestCreateKeepsRecipientTargetsForEachRun(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	svc := newTestService(newFakeRepository(), now)
+
+	input := scheduleInput(now.Add(time.Hour), "")
+	input.To = nil
+	_, err := svc.Create(context.Background(), "user-1", input)
+	assert.ErrorIs(t, err, ErrNoRecipients)
+
+	input.DisciplineIDs = []string{"discipline-1"}
+	input.Statuses = []student.StudentStatus{student.StudentStatusActive}
+	input.FilterID = "filter-1"
+	schedule, err := svc.Create(context.Background(), "user-1", input)
+
+	require.NoError(t, err)
+	msg := schedule.toMessage()
+	assert.Empty(t, msg.To)
+	assert.Equal(t, []string{"discipline-1"}, msg.DisciplineIDs)
+	assert.Equal(t, []student.StudentStatus{student.StudentStatusActive}, msg.Statuses)
+	assert.Equal(t, "filter-1", msg.FilterID)
+}
+
+func TestCreateRecurringStartsFromNextOccurrence(t *testing.T) {
+	location, err := time.LoadLocation("America/Sao_Paulo")
+	require.NoError(t, err)
+	// Segunda às 07:00 em São Paulo, já passada no momento do cadastro.
+	sendAt := time.Date(2026, 3, 2, 7, 0, 0, 0, location)
+	now := sendAt.Add(2 * time.Hour)
+	svc := newTestService(newFakeRepository(), now)
+
+	schedule, err := svc.Create(context.Background(), "user-1", scheduleInput(sendAt, "FREQ=WEEKLY;BYDAY=MO"))
+
+	require.NoError(t, err)
+	require.NotNil(t, schedule.NextRunAt)
+	assert.True(t, time.Date(2026, 3, 9, 7, 0, 0, 0, location).Equal(*schedule.NextRunAt))
+	assert.Equal(t, StatusActive, schedule.Status)
+}
+
+func TestPauseAndResumeSkipsRunsMissedWhilePaused(t *testing.T) {
+	location, err := time.LoadLocation("America/Sao_Paulo")
+	require.NoError(t, err)
+	sendAt := time.Date(2026, 3, 2, 7, 0, 0, 0, location)
+	repo := newFakeRepository()
+	svc := newTestService(repo, sendAt.Add(-time.Hour))
+
+	_, err = svc.Create(context.Background(), "user-1", scheduleInput(sendAt, "FREQ=DAILY"))
+	require.NoError(t, err)
+
+	paused, err := svc.Pause(context.Background(), "user-1", "schedule-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusPaused, paused.Status)
+	assert.Nil(t, paused.NextRunAt)
+
+	svc.now = func() time.Time { return time.Date(2026, 3, 5, 9, 0, 0, 0, location) }
+	resumed, err := svc.Resume(context.Background(), "user-1", "schedule-1")
+	require.NoError(t, err)
+	assert.True(t, time.Date(2026, 3, 6, 7, 0, 0, 0, location).Equal(*resumed.NextRunAt))
+}
+
+func TestUpdateKeepsJweWhenOmittedAndHidesOtherUsersSchedules(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	repo := newFakeRepository()
+	svc := newTestService(repo, now)
+
+	_, err := svc.Create(context.Background(), "user-1", scheduleInput(now.Add(time.Hour), ""))
+	require.NoError(t, err)
+
+	input := scheduleInput(now.Add(2*time.Hour), "")
+	input.Jwe = ""
+	updated, err := svc.Update(context.Background(), "user-1", "schedule-1", input)
+	require.NoError(t, err)
+	require.NotNil(t, updated.Jwe)
+	assert.Equal(t, "jwe-original", *updated.Jwe)
+
+	_, err = svc.Update(context.Background(), "user-2", "schedule-1", input)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+}
+
+func TestCancelClearsJweAndBlocksFurtherEdits(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	repo := newFakeRepository()
+	svc := newTestService(repo, now)
+
+	_, err := svc.Create(context.Background(), "user-1", scheduleInput(now.Add(time.Hour), ""))
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Cancel(context.Background(), "user-1", "schedule-1"))
+	assert.Nil(t, repo.schedules["schedule-1"].Jwe)
+
+	_, err = svc.Update(context.Background(), "user-1", "schedule-1", scheduleInput(now.Add(time.Hour), ""))
+	assert.ErrorIs(t, err, ErrScheduleFinished)
+}
+
+func TestCreateRejectsScheduleThatFailsSendValidation(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	repo := newFakeRepository()
+	svc := newTestService(repo, now)
+	messageService := &fakeMessageService{err: message.ErrSmtpNotFound}
+	svc.messageService = messageService
+
+	_, err := svc.Create(context.Background(), "user-1", scheduleInput(now.Add(time.Hour), ""))
+
+	assert.ErrorIs(t, err, message.ErrSmtpNotFound)
+	assert.Empty(t, repo.schedules)
+	require.NotNil(t, messageService.validated)
+	assert.Equal(t, "user-1", messageService.validated.UserID)
+	assert.Equal(t, "smtp-1", messageService.validated.SmtpId)
+}
+
+func TestUpdateRejectsScheduleWithoutResolvedRecipients(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	repo := newFakeRepository()
+	svc := newTestService(repo, now)
+	created, err := svc.Create(context.Background(), "user-1", scheduleInput(now.Add(time.Hour), ""))
+	require.NoError(t, err)
+
+	svc.messageService = &fakeMessageService{err: message.ErrNoRecipients}
+	_, err = svc.Update(context.Background(), "user-1", created.ID, scheduleInput(now.Add(2*time.Hour), ""))
+
+	assert.ErrorIs(t, err, message.ErrNoRecipients)
+}
@@ -0,0 +1,339 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/message"
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/lib/pq"
+)
+
+type sqlRepository struct {
+	db    database.DB
+	sqlDB *sql.DB
+}
+
+func newSQLRepository(db *sql.DB) Repository {
+	newDb := database.NewSQLTx(db)
+	return &sqlRepository{
+		db:    newDb.DB,
+		sqlDB: db,
+	}
+}
+
+func (r *sqlRepository) WithTransaction(tx any) any {
+	return &sqlRepository{
+		db:    database.NewSQLTx(nil).WithSQLTransaction(tx).DB,
+		sqlDB: r.sqlDB,
+	}
+}
+
+func (r *sqlRepository) TransactionBackend() any {
+	return r.sqlDB
+}
+
+const scheduleColumns = `
+	id, user_id, smtp_id, whatsapp_instance_id, sender_from, subject, body, student_ids, attachments, jwe,
+	send_at, recurrence, timezone, status, next_run_at, last_run_at, last_job_id, last_error, run_count,
+	created_at, updated_at, template_id, discipline_id, recipient_filter, filter_id, fallback, sms_instance_id, body_format,
+	jwe_reentry_required
+`
+
+func (r *sqlRepository) Create(ctx context.Context, schedule *Schedule) error {
+	attachments, err := json.Marshal(schedule.Attachments)
+	if err != nil {
+		return fmt.Errorf("falha ao serializar anexos do agendamento: %w", err)
+	}
+	targets, err := json.Marshal(schedule.Targets)
+	if err != nil {
+		return fmt.Errorf("falha ao serializar destinatários do agendamento: %w", err)
+	}
+
+	query := `
+		INSERT INTO message_schedules (
+			user_id, smtp_id, whatsapp_instance_id, sender_from, subject, body, student_ids, attachments, jwe,
+			send_at, recurrence, timezone, status, next_run_at, template_id, discipline_id, recipient_filter, filter_id, fallback, sms_instance_id, body_format
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		RETURNING id, created_at, updated_at
+	`
+	err = r.db.QueryRowContext(ctx, query,
+		schedule.UserID,
+		schedule.SmtpID,
+		schedule.WhatsAppInstanceID,
+		nullableString(schedule.From),
+		schedule.Subject,
+		schedule.Body,
+		pq.Array(schedule.StudentIDs),
+		attachments,
+		schedule.Jwe,
+		schedule.SendAt,
+		schedule.Recurrence,
+		schedule.Timezone,
+		string(schedule.Status),
+		schedule.NextRunAt,
+		schedule.TemplateID,
+		schedule.DisciplineID,
+		targets,
+		schedule.FilterID,
+		nullableString(string(schedule.Fallback)),
+		schedule.SmsInstanceID,
+		nullableString(string(schedule.Format)),
+	).Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao criar agendamento: %w", err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) FindByID(ctx context.Context, id string) (*Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM message_schedules WHERE id = $1`
+
+	schedule, err := scanSchedule(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("falha ao buscar agendamento %s: %w", id, err)
+	}
+	return schedule, nil
+}
+
+func (r *sqlRepository) FindByUserID(ctx context.Context, userID string) ([]*Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM message_schedules WHERE user_id = $1 ORDER BY created_at DESC`
+	return r.query(ctx, query, userID)
+}
+
+func (r *sqlRepository) Update(ctx context.Context, schedule *Schedule) error {
+	attachments, err := json.Marshal(schedule.Attachments)
+	if err != nil {
+		return fmt.Errorf("falha ao serializar anexos do agendamento: %w", err)
+	}
+	targets, err := json.Marshal(schedule.Targets)
+	if err != nil {
+		return fmt.Errorf("falha ao serializar destinatários do agendamento: %w", err)
+	}
+
+	query := `
+		UPDATE message_schedules
+		SET smtp_id = $2,
+			whatsapp_instance_id = $3,
+			sender_from = $4,
+			subject = $5,
+			body = $6,
+			student_ids = $7,
+			attachments = $8,
+			jwe = $9,
+			send_at = $10,
+			recurrence = $11,
+			timezone = $12,
+			status = $13,
+			next_run_at = $14,
+			template_id = $15,
+			discipline_id = $16,
+			recipient_filter = $17,
+			filter_id = $18,
+			fallback = $19,
+			sms_instance_id = $20,
+			body_format = $21,
+			jwe_reentry_required = $22
+		WHERE id = $1
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		schedule.ID,
+		schedule.SmtpID,
+		schedule.WhatsAppInstanceID,
+		nullableString(schedule.From),
+		schedule.Subject,
+		schedule.Body,
+		pq.Array(schedule.StudentIDs),
+		attachments,
+		schedule.Jwe,
+		schedule.SendAt,
+		schedule.Recurrence,
+		schedule.Timezone,
+		string(schedule.Status),
+		schedule.NextRunAt,
+		schedule.TemplateID,
+		schedule.DisciplineID,
+		targets,
+		schedule.FilterID,
+		nullableString(string(schedule.Fallback)),
+		schedule.SmsInstanceID,
+		nullableString(string(schedule.Format)),
+		schedule.JweReentryRequired,
+	)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar agendamento %s: %w", schedule.ID, err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) LockDue(ctx context.Context, now time.Time, limit int) ([]*Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM message_schedules
+		WHERE status = 'ACTIVE' AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	return r.query(ctx, query, now, limit)
+}
+
+func (r *sqlRepository) Advance(ctx context.Context, id string, ranAt time.Time, nextRunAt *time.Time, status Status) error {
+	query := `
+		UPDATE message_schedules
+		SET last_run_at = $2, next_run_at = $3, status = $4, run_count = run_count + 1
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, id, ranAt, nextRunAt, string(status)); err != nil {
+		return fmt.Errorf("falha ao avançar agendamento %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) ReplaceJwe(ctx context.Context, userID string, jwe *string) error {
+	query := `
+		UPDATE message_schedules
+		SET jwe = $2, jwe_reentry_required = $2::text IS NULL
+		WHERE user_id = $1 AND status IN ('ACTIVE', 'PAUSED') AND jwe IS NOT NULL
+	`
+	if _, err := r.db.ExecContext(ctx, query, userID, jwe); err != nil {
+		return fmt.Errorf("falha ao atualizar o JWE dos agendamentos do usuário %s: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) RecordRun(ctx context.Context, id string, jobID *string, errText *string) error {
+	query := `
+		UPDATE message_schedules
+		SET last_job_id = $2,
+			last_error = $3,
+			jwe = CASE WHEN status IN ('COMPLETED', 'CANCELED') THEN NULL ELSE jwe END
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, id, jobID, errText); err != nil {
+		return fmt.Errorf("falha ao registrar execução do agendamento %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *sqlRepository) query(ctx context.Context, query string, args ...any) ([]*Schedule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar agendamentos: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []*Schedule{}
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao ler agendamento: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar agendamentos: %w", err)
+	}
+	return schedules, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSchedule(scanner rowScanner) (*Schedule, error) {
+	schedule := &Schedule{}
+	var smtpID, whatsappID, from, jwe, recurrence, lastJobID, lastError, templateID, disciplineID, filterID, fallback, smsID, format sql.NullString
+	var nextRunAt, lastRunAt sql.NullTime
+	var studentIDs pq.StringArray
+	var attachments, targets []byte
+
+	err := scanner.Scan(
+		&schedule.ID,
+		&schedule.UserID,
+		&smtpID,
+		&whatsappID,
+		&from,
+		&schedule.Subject,
+		&schedule.Body,
+		&studentIDs,
+		&attachments,
+		&jwe,
+		&schedule.SendAt,
+		&recurrence,
+		&schedule.Timezone,
+		&schedule.Status,
+		&nextRunAt,
+		&lastRunAt,
+		&lastJobID,
+		&lastError,
+		&schedule.RunCount,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+		&templateID,
+		&disciplineID,
+		&targets,
+		&filterID,
+		&fallback,
+		&smsID,
+		&format,
+		&schedule.JweReentryRequired,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.SmtpID = nullStringPtr(smtpID)
+	schedule.WhatsAppInstanceID = nullStringPtr(whatsappID)
+	schedule.From = from.String
+	schedule.Jwe = nullStringPtr(jwe)
+	schedule.Recurrence = nullStringPtr(recurrence)
+	schedule.LastJobID = nullStringPtr(lastJobID)
+	schedule.LastError = nullStringPtr(lastError)
+	schedule.TemplateID = nullStringPtr(templateID)
+	schedule.DisciplineID = nullStringPtr(disciplineID)
+	schedule.FilterID = nullStringPtr(filterID)
+	schedule.Fallback = message.FallbackPolicy(fallback.String)
+	schedule.SmsInstanceID = nullStringPtr(smsID)
+	schedule.Format = message.BodyFormat(format.String)
+	schedule.StudentIDs = []string(studentIDs)
+	if nextRunAt.Valid {
+		schedule.NextRunAt = &nextRunAt.Time
+	}
+	if lastRunAt.Valid {
+		schedule.LastRunAt = &lastRunAt.Time
+	}
+	schedule.Attachments = []message.Attachment{}
+	if len(attachments) > 0 {
+		if err := json.Unmarshal(attachments, &schedule.Attachments); err != nil {
+			return nil, fmt.Errorf("anexos do agendamento inválidos: %w", err)
+		}
+	}
+	if len(targets) > 0 {
+		if err := json.Unmarshal(targets, &schedule.Targets); err != nil {
+			return nil, fmt.Errorf("destinatários do agendamento inválidos: %w", err)
+		}
+	}
+	return schedule, nil
+}
+
+func nullStringPtr(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
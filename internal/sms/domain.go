@@ -0,0 +1,35 @@
+package sms
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+// Instance é um gateway HTTP de SMS configurado pelo usuário. Token autentica as chamadas ao gateway
+// e fica cifrado com a chave do servidor.
+type Instance struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	GatewayURL string    `json:"gatewayUrl"`
+	Sender     string    `json:"sender"`
+	Token      []byte    `json:"-"`
+	TokenIV    []byte    `json:"-"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+	UserID     string    `json:"-"`
+}
+
+type Repository interface {
+	database.Transactional
+	Create(ctx context.Context, userID, name, gatewayURL, sender string, token, tokenIV []byte) error
+	FindByID(ctx context.Context, id string) (*Instance, error)
+	GetInstances(ctx context.Context, userID string) ([]*Instance, error)
+	Delete(ctx context.Context, id string) error
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return newSQLRepository(db)
+}
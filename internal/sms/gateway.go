@@ -0,0 +1,96 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var (
+	// ErrMessageRejected indica que o gateway recusou destino ou conteúdo (4xx); repetir não muda o resultado.
+	ErrMessageRejected    = customerror.Make("gateway SMS recusou a mensagem", http.StatusBadRequest, errors.New("smsMessageRejected"))
+	ErrGatewayUnavailable = customerror.Make("gateway SMS indisponível", http.StatusBadGateway, errors.New("smsGatewayUnavailable"))
+)
+
+// maxGatewayResponseBytes limita a leitura da resposta; o gateway só precisa devolver o ID da mensagem.
+const maxGatewayResponseBytes = 64 * 1024
+
+// sendPayload é o corpo genérico enviado ao gateway. O token vai em Authorization: Bearer.
+type sendPayload struct {
+	To   string `json:"to"`
+	From string `json:"from,omitempty"`
+	Text string `json:"text"`
+}
+
+// sendResponse aceita o ID da mensagem em "id" ou "messageId"; gateways sem corpo também são aceitos.
+type sendResponse struct {
+	ID        string `json:"id"`
+	MessageID string `json:"messageId"`
+}
+
+// Gateway fala com gateways HTTP de SMS: um POST JSON por mensagem na URL configurada na instância.
+type Gateway struct {
+	client *http.Client
+}
+
+// NewGateway usa o client informado; nil usa um client com timeout de 15s.
+func NewGateway(client *http.Client) *Gateway {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &Gateway{client: client}
+}
+
+// Send envia text para o número to (E.164) e devolve o ID da mensagem no gateway, quando houver.
+// Respostas 4xx (exceto 429) viram ErrMessageRejected; falhas de rede, 429 e 5xx viram ErrGatewayUnavailable.
+func (g *Gateway) Send(ctx context.Context, gatewayURL, token, from, to, text string) (string, error) {
+	body, err := json.Marshal(sendPayload{To: to, From: from, Text: text})
+	if err != nil {
+		return "", customerror.Trace("SendSMS", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gatewayURL, bytes.NewReader(body))
+	if err != nil {
+		return "", customerror.Trace("SendSMS", fmt.Errorf("%w: %w", ErrMessageRejected, err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", customerror.Trace("SendSMS", fmt.Errorf("%w: %w", ErrGatewayUnavailable, err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxGatewayResponseBytes))
+	if err != nil {
+		return "", customerror.Trace("SendSMS", fmt.Errorf("%w: %w", ErrGatewayUnavailable, err))
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return "", customerror.Trace("SendSMS", fmt.Errorf("%w: status %d", ErrGatewayUnavailable, resp.StatusCode))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return "", customerror.Trace("SendSMS", fmt.Errorf("%w: status %d", ErrMessageRejected, resp.StatusCode))
+	}
+
+	var parsed sendResponse
+	if len(bytes.TrimSpace(respBody)) == 0 || json.Unmarshal(respBody, &parsed) != nil {
+		return "", nil
+	}
+	if parsed.ID != "" {
+		return strings.TrimSpace(parsed.ID), nil
+	}
+	return strings.TrimSpace(parsed.MessageID), nil
+}
@@ -0,0 +1,83 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewaySendPostsJSONWithBearerToken(t *testing.T) {
+	var gotAuth string
+	var gotPayload sendPayload
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		gotAuth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotPayload))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"messageId":"sms-123","status":"queued"}`))
+	}))
+	defer server.Close()
+
+	messageID, err := NewGateway(server.Client()).Send(context.Background(), server.URL+"/v1/messages", "secret-token", "UNICAST", "+5500000000001", "Aula remarcada")
+
+	require.NoError(t, err)
+	assert.Equal(t, "sms-123", messageID)
+	assert.Equal(t, "Bearer secret-token", gotAuth)
+	assert.Equal(t, sendPayload{To: "+5500000000001", From: "UNICAST", Text: "Aula remarcada"}, gotPayload)
+}
+
+func TestGatewaySendAcceptsEmptyResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	messageID, err := NewGateway(server.Client()).Send(context.Background(), server.URL, "", "", "+5500000000001", "Olá")
+
+	require.NoError(t, err)
+	assert.Empty(t, messageID)
+}
+
+func TestGatewaySendClassifiesFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   error
+	}{
+		{name: "rejected number", status: http.StatusUnprocessableEntity, want: ErrMessageRejected},
+		{name: "invalid token", status: http.StatusUnauthorized, want: ErrMessageRejected},
+		{name: "rate limited", status: http.StatusTooManyRequests, want: ErrGatewayUnavailable},
+		{name: "server error", status: http.StatusServiceUnavailable, want: ErrGatewayUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			_, err := NewGateway(server.Client()).Send(context.Background(), server.URL, "token", "", "+5500000000001", "Olá")
+
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestGatewaySendReportsUnreachableGateway(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	_, err := NewGateway(nil).Send(context.Background(), url, "token", "", "+5500000000001", "Olá")
+
+	assert.ErrorIs(t, err, ErrGatewayUnavailable)
+}
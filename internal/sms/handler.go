@@ -0,0 +1,106 @@
+package sms
+
+import (
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/gin-gonic/gin"
+)
+
+type handler struct {
+	service Service
+}
+
+type createInstanceInput struct {
+	Name       string `json:"name" binding:"required"`
+	GatewayURL string `json:"gatewayUrl" binding:"required,url"`
+	// Sender é o remetente enviado ao gateway em "from" (número ou alias); opcional.
+	Sender string `json:"sender"`
+	// Token vai em Authorization: Bearer em cada envio; opcional para gateways sem autenticação.
+	Token string `json:"token"`
+}
+
+type Handler interface {
+	Create() gin.HandlerFunc
+	GetInstances() gin.HandlerFunc
+	DeleteInstance() gin.HandlerFunc
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{
+		service: service,
+	}
+}
+
+// @Summary Cria uma instância SMS
+// @Description Cadastra um gateway HTTP de SMS. Cada envio faz POST JSON {"to","from","text"} na gatewayUrl com o token em Authorization: Bearer.
+// @Tags sms
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param body body createInstanceInput true "Dados do gateway SMS"
+// @Success 200 {object} api.MessageResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Router /sms/instance [post]
+func (h *handler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input createInstanceInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		userID := c.GetString("userID")
+		if err := h.service.Create(c.Request.Context(), userID, input.Name, input.GatewayURL, input.Sender, input.Token); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(200, api.MessageResponse{Message: "Instância SMS criada com sucesso"})
+	}
+}
+
+// @Summary Lista instâncias SMS do usuário
+// @Tags sms
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Success 200 {object} api.DefaultResponse[[]Instance]
+// @Router /sms/instance [get]
+func (h *handler) GetInstances() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		instances, err := h.service.GetInstances(c.Request.Context(), userID)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		items := make([]Instance, 0, len(instances))
+		for _, inst := range instances {
+			if inst != nil {
+				items = append(items, *inst)
+			}
+		}
+		c.JSON(200, api.DefaultResponse[[]Instance]{Message: "Instâncias listadas com sucesso", Data: items})
+	}
+}
+
+// @Summary Remove uma instância SMS
+// @Tags sms
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param id path string true "Instance ID"
+// @Success 200 {object} api.MessageResponse
+// @Failure 403 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Router /sms/instance/{id} [delete]
+func (h *handler) DeleteInstance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		instanceID := c.Param("id")
+		if err := h.service.DeleteInstance(c.Request.Context(), userID, instanceID); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(200, api.MessageResponse{Message: "Instância SMS removida com sucesso"})
+	}
+}
@@ -0,0 +1,99 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/internal/encryption"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+type smsService struct {
+	smsRepository Repository
+	secret        []byte
+	gateway       *Gateway
+}
+
+type Service interface {
+	Create(ctx context.Context, userID, name, gatewayURL, sender, token string) error
+	GetInstances(ctx context.Context, userID string) ([]*Instance, error)
+	DeleteInstance(ctx context.Context, userID, instanceID string) error
+	// Send envia um SMS pela instância e devolve o ID da mensagem no gateway (vazio se o gateway não informar).
+	Send(ctx context.Context, instance *Instance, to, text string) (string, error)
+}
+
+// NewService cifra o token dos gateways com secret, a mesma chave do servidor usada no OAuth de email.
+func NewService(smsRepository Repository, secret []byte, gateway *Gateway) Service {
+	return &smsService{smsRepository: smsRepository, secret: secret, gateway: gateway}
+}
+
+var (
+	InstanceNotFound     = customerror.Make("Instância SMS não encontrada", http.StatusNotFound, errors.New("smsInstanceNotFound"))
+	InstanceForbidden    = customerror.Make("Você não tem permissão para esta instância SMS", http.StatusForbidden, errors.New("smsInstanceForbidden"))
+	ErrInvalidGatewayURL = customerror.Make("URL do gateway SMS inválida", http.StatusBadRequest, errors.New("smsInvalidGatewayURL"))
+	ErrTokenUnavailable  = customerror.Make("não foi possível abrir o token do gateway SMS", http.StatusBadRequest, errors.New("smsTokenUnavailable"))
+)
+
+func (s *smsService) Create(ctx context.Context, userID, name, gatewayURL, sender, token string) error {
+	gatewayURL = strings.TrimSpace(gatewayURL)
+	if err := validateGatewayURL(gatewayURL); err != nil {
+		return customerror.Trace("Create", err)
+	}
+
+	var encryptedToken, iv []byte
+	if token != "" {
+		var err error
+		encryptedToken, iv, err = encryption.EncryptSmtpPassword(token, s.secret)
+		if err != nil {
+			return customerror.Trace("Create", err)
+		}
+	}
+
+	if err := s.smsRepository.Create(ctx, userID, strings.TrimSpace(name), gatewayURL, strings.TrimSpace(sender), encryptedToken, iv); err != nil {
+		return customerror.Trace("Create", err)
+	}
+	return nil
+}
+
+func (s *smsService) GetInstances(ctx context.Context, userID string) ([]*Instance, error) {
+	return s.smsRepository.GetInstances(ctx, userID)
+}
+
+func (s *smsService) DeleteInstance(ctx context.Context, userID, instanceID string) error {
+	instance, err := s.smsRepository.FindByID(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if instance == nil {
+		return InstanceNotFound
+	}
+	if instance.UserID != userID {
+		return InstanceForbidden
+	}
+	return s.smsRepository.Delete(ctx, instanceID)
+}
+
+func (s *smsService) Send(ctx context.Context, instance *Instance, to, text string) (string, error) {
+	token := ""
+	if len(instance.Token) > 0 {
+		decrypted, err := encryption.DecryptSmtpPassword(instance.Token, s.secret, instance.TokenIV)
+		if err != nil {
+			return "", customerror.Trace("SendSMS", fmt.Errorf("%w: %w", ErrTokenUnavailable, err))
+		}
+		token = decrypted
+	}
+	return s.gateway.Send(ctx, instance.GatewayURL, token, instance.Sender, to, text)
+}
+
+// validateGatewayURL aceita apenas URLs absolutas http(s); o token só deve trafegar em https fora de desenvolvimento.
+func validateGatewayURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return ErrInvalidGatewayURL
+	}
+	return nil
+}
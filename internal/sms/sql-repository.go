@@ -0,0 +1,108 @@
+package sms
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+type sqlRepository struct {
+	db    database.DB
+	sqlDB *sql.DB
+}
+
+func newSQLRepository(db *sql.DB) Repository {
+	return &sqlRepository{
+		db:    database.NewSQLTx(db).DB,
+		sqlDB: db,
+	}
+}
+
+func (r *sqlRepository) WithTransaction(tx any) any {
+	return &sqlRepository{
+		db:    database.NewSQLTx(nil).WithSQLTransaction(tx).DB,
+		sqlDB: r.sqlDB,
+	}
+}
+
+func (r *sqlRepository) TransactionBackend() any {
+	return r.sqlDB
+}
+
+const instanceColumns = `id, name, gateway_url, sender, token, token_iv, created_at, updated_at, user_id`
+
+func (r *sqlRepository) Create(ctx context.Context, userID, name, gatewayURL, sender string, token, tokenIV []byte) error {
+	query := `
+		INSERT INTO sms_instances (user_id, name, gateway_url, sender, token, token_iv)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	var senderValue *string
+	if sender != "" {
+		senderValue = &sender
+	}
+	_, err := r.db.ExecContext(ctx, query, userID, name, gatewayURL, senderValue, token, tokenIV)
+	return err
+}
+
+func (r *sqlRepository) FindByID(ctx context.Context, id string) (*Instance, error) {
+	query := `SELECT ` + instanceColumns + ` FROM sms_instances WHERE id = $1`
+	instance, err := scanInstance(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return instance, nil
+}
+
+func (r *sqlRepository) GetInstances(ctx context.Context, userID string) ([]*Instance, error) {
+	query := `SELECT ` + instanceColumns + ` FROM sms_instances WHERE user_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instances := []*Instance{}
+	for rows.Next() {
+		instance, err := scanInstance(rows)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	return instances, rows.Err()
+}
+
+func (r *sqlRepository) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM sms_instances WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanInstance(scanner rowScanner) (*Instance, error) {
+	instance := &Instance{}
+	var sender sql.NullString
+	err := scanner.Scan(
+		&instance.ID,
+		&instance.Name,
+		&instance.GatewayURL,
+		&sender,
+		&instance.Token,
+		&instance.TokenIV,
+		&instance.CreatedAt,
+		&instance.UpdatedAt,
+		&instance.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	instance.Sender = sender.String
+	return instance, nil
+}
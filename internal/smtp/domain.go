@@ -6,35 +6,62 @@ import (
 	"time"
 
 	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
 )
 
 type Instance struct {
-	ID            string     `json:"id"`
-	Host          string     `json:"host"`
-	Port          int        `json:"port"`
-	Email         string     `json:"email" validate:"required"`
-	AuthMode      string     `json:"authMode"`
-	Provider      string     `json:"provider"`
-	Password      []byte     `json:"-"`
-	IV            []byte     `json:"-"`
-	OAuthPayload  []byte     `json:"-"`
-	OAuthIV       []byte     `json:"-"`
-	TokenExpiresAt *time.Time `json:"tokenExpiresAt,omitempty"`
-	CreatedAt     time.Time  `json:"-"`
-	UpdatedAt     time.Time  `json:"-"`
-	UserID        string     `json:"-"`
+	ID             string               `json:"id"`
+	Host           string               `json:"host"`
+	Port           int                  `json:"port"`
+	Email          string               `json:"email" validate:"required"`
+	AuthMode       string               `json:"authMode"`
+	Provider       string               `json:"provider"`
+	TLSMode        mailer.TLSMode       `json:"tlsMode"`
+	AuthMechanism  mailer.AuthMechanism `json:"authMechanism"`
+	Password       []byte               `json:"-"`
+	IV             []byte               `json:"-"`
+	OAuthPayload   []byte               `json:"-"`
+	OAuthIV        []byte               `json:"-"`
+	TokenExpiresAt *time.Time           `json:"tokenExpiresAt,omitempty"`
+	CreatedAt      time.Time            `json:"-"`
+	UpdatedAt      time.Time            `json:"-"`
+	UserID         string               `json:"-"`
+	// PasswordReentryRequired indica que a senha foi cifrada com uma chave perdida no reset da senha do usuário.
+	PasswordReentryRequired bool `json:"passwordReentryRequired"`
 }
 
 type Repository interface {
 	database.Transactional
-	Create(ctx context.Context, userID, email, host string, port int, password, iv []byte) error
+	Create(ctx context.Context, userID, email, host string, port int, tlsMode mailer.TLSMode, authMechanism mailer.AuthMechanism, password, iv []byte) error
 	UpsertOAuth(ctx context.Context, userID, email, provider, host string, port int, oauthPayload, oauthIV []byte, tokenExpiresAt *time.Time) error
 	FindByID(ctx context.Context, id string) (*Instance, error)
 	UpdateOAuthTokens(ctx context.Context, id string, oauthPayload, oauthIV []byte, tokenExpiresAt *time.Time) error
+	// UpdatePassword grava a senha cifrada de novo e libera a instância marcada para reentrada.
+	UpdatePassword(ctx context.Context, id string, password, iv []byte) error
+	MarkPasswordReentry(ctx context.Context, id string) error
+	// MarkUserPasswordsReentry marca todas as instâncias com senha do usuário.
+	MarkUserPasswordsReentry(ctx context.Context, userID string) error
 	Delete(ctx context.Context, id string) error
 	GetInstances(ctx context.Context, userID string) ([]*Instance, error)
 }
 
+// UsesPassword indica se o envio precisa abrir a senha SMTP com o JWE do usuário.
+func (i *Instance) UsesPassword() bool {
+	return i.AuthMode != AuthModeOAuth && i.AuthMechanism != mailer.AuthNone
+}
+
+// Authentication monta a configuração do mailer para a instância; password é a senha já aberta.
+func (i *Instance) Authentication(password string) mailer.SmtpAuthentication {
+	return mailer.SmtpAuthentication{
+		Host:          i.Host,
+		Port:          i.Port,
+		Username:      i.Email,
+		Password:      password,
+		TLSMode:       i.TLSMode,
+		AuthMechanism: i.AuthMechanism,
+	}
+}
+
 func NewRepository(db *sql.DB) Repository {
 	return newSQLRepository(db)
 }
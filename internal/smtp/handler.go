@@ -5,6 +5,7 @@ import (
 
 	"github.com/ThalysSilva/unicast-backend/pkg/api"
 	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,19 +13,30 @@ type handler struct {
 	service Service
 }
 
+// createInstanceInput: password e jwe só podem faltar com authMechanism NONE.
+// tlsMode (starttls, tls, none) e authMechanism (PLAIN, LOGIN, CRAM-MD5, NONE) vazios usam starttls e PLAIN.
 type createInstanceInput struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
-	Host     string `json:"host" binding:"required"`
-	Port     int    `json:"port" binding:"required"`
-	Jwe      string `json:"jwe" binding:"required"`
+	Email         string               `json:"email" binding:"required,email"`
+	Password      string               `json:"password"`
+	Host          string               `json:"host" binding:"required"`
+	Port          int                  `json:"port" binding:"required"`
+	Jwe           string               `json:"jwe"`
+	TLSMode       mailer.TLSMode       `json:"tlsMode"`
+	AuthMechanism mailer.AuthMechanism `json:"authMechanism"`
 }
 
 type testConnectionInput struct {
-	Email    string `json:"email" binding:"required,email"`
+	Email         string               `json:"email" binding:"required,email"`
+	Password      string               `json:"password"`
+	Host          string               `json:"host" binding:"required"`
+	Port          int                  `json:"port" binding:"required"`
+	TLSMode       mailer.TLSMode       `json:"tlsMode"`
+	AuthMechanism mailer.AuthMechanism `json:"authMechanism"`
+}
+
+type updatePasswordInput struct {
 	Password string `json:"password" binding:"required"`
-	Host     string `json:"host" binding:"required"`
-	Port     int    `json:"port" binding:"required"`
+	Jwe      string `json:"jwe" binding:"required"`
 }
 
 type Handler interface {
@@ -34,6 +46,7 @@ type Handler interface {
 	TestConnection() gin.HandlerFunc
 	GetInstances() gin.HandlerFunc
 	DeleteInstance() gin.HandlerFunc
+	UpdatePassword() gin.HandlerFunc
 }
 
 func NewHandler(service Service) Handler {
@@ -63,7 +76,7 @@ func (h *handler) Create(jweSecret []byte) gin.HandlerFunc {
 			return
 		}
 		userID := c.GetString("userID")
-		err := h.service.Create(c.Request.Context(), jweSecret, userID, input.Jwe, input.Email, input.Password, input.Host, input.Port)
+		err := h.service.Create(c.Request.Context(), jweSecret, userID, input.Jwe, input.Email, input.Password, input.Host, input.Port, input.TLSMode, input.AuthMechanism)
 		if err != nil {
 			customerror.HandleResponse(c, err)
 			return
@@ -79,7 +92,7 @@ func (h *handler) Create(jweSecret []byte) gin.HandlerFunc {
 // @Produce json
 // @Param Authorization header string true "Bearer token"
 // @Security BearerAuth
-// @Param body body createInstanceInput true "Dados SMTP"
+// @Param body body testConnectionInput true "Dados SMTP"
 // @Success 200 {object} api.MessageResponse
 // @Failure 400 {object} api.ErrorResponse
 // @Router /smtp/instance/test [post]
@@ -90,7 +103,7 @@ func (h *handler) TestConnection() gin.HandlerFunc {
 			c.Error(err)
 			return
 		}
-		if err := h.service.TestConnection(c.Request.Context(), input.Email, input.Password, input.Host, input.Port); err != nil {
+		if err := h.service.TestConnection(c.Request.Context(), input.Email, input.Password, input.Host, input.Port, input.TLSMode, input.AuthMechanism); err != nil {
 			customerror.HandleResponse(c, err)
 			return
 		}
@@ -172,3 +185,30 @@ func (h *handler) DeleteInstance() gin.HandlerFunc {
 		c.JSON(200, api.MessageResponse{Message: "SMTP instance deleted successfully"})
 	}
 }
+
+// @Summary Informa novamente a senha de uma instância SMTP
+// @Description Cifra a senha com a chave do JWE atual. Use nas instâncias com passwordReentryRequired depois de um reset administrativo da senha da conta.
+// @Tags smtp
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param id path string true "Instance ID"
+// @Param body body updatePasswordInput true "Senha SMTP e JWE"
+// @Success 200 {object} api.MessageResponse
+// @Failure 400 {object} api.ErrorResponse
+// @Router /smtp/instance/{id}/password [put]
+func (h *handler) UpdatePassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input updatePasswordInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		if err := h.service.UpdatePassword(c.Request.Context(), c.GetString("userID"), c.Param("id"), input.Jwe, input.Password); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(200, api.MessageResponse{Message: "Senha SMTP atualizada com sucesso"})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 	configenv "github.com/ThalysSilva/unicast-backend/internal/config/env"
 	"github.com/ThalysSilva/unicast-backend/internal/encryption"
 	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
 )
 
 const (
@@ -22,6 +24,7 @@ const (
 	AuthModeOAuth      = "oauth"
 	ProviderCustomSMTP = "custom_smtp"
 	ProviderGoogle     = "google"
+	ProviderMicrosoft  = "microsoft"
 )
 
 type oauthState struct {
@@ -58,6 +61,8 @@ type oauthProviderConfig struct {
 	Scopes       []string
 	Host         string
 	Port         int
+	// APIURL é a raiz da API usada no envio; vazio quando o provedor usa um endereço fixo.
+	APIURL string
 }
 
 func buildOAuthProviderConfig(cfg configenv.OAuth, provider string) (*oauthProviderConfig, error) {
@@ -80,6 +85,29 @@ func buildOAuthProviderConfig(cfg configenv.OAuth, provider string) (*oauthProvi
 			Host: "smtp.gmail.com",
 			Port: 587,
 		}, nil
+	case ProviderMicrosoft:
+		if cfg.MicrosoftClientID == "" || cfg.MicrosoftClientSecret == "" || cfg.MicrosoftRedirectURL == "" {
+			return nil, customerror.Make("OAuth Microsoft não configurado", http.StatusBadRequest, fmt.Errorf("missing Microsoft OAuth env vars"))
+		}
+		authority := strings.TrimRight(cfg.MicrosoftAuthorityURL, "/") + "/" + url.PathEscape(cfg.MicrosoftTenant) + "/oauth2/v2.0"
+		return &oauthProviderConfig{
+			ClientID:     cfg.MicrosoftClientID,
+			ClientSecret: cfg.MicrosoftClientSecret,
+			RedirectURL:  cfg.MicrosoftRedirectURL,
+			AuthURL:      authority + "/authorize",
+			TokenURL:     authority + "/token",
+			// offline_access é o que faz a Microsoft devolver o refresh token.
+			Scopes: []string{
+				"openid",
+				"email",
+				"profile",
+				"offline_access",
+				"https://graph.microsoft.com/Mail.Send",
+			},
+			Host:   "smtp.office365.com",
+			Port:   587,
+			APIURL: cfg.MicrosoftGraphURL,
+		}, nil
 	default:
 		return nil, customerror.Make("provedor OAuth inválido", http.StatusBadRequest, fmt.Errorf("invalid provider"))
 	}
@@ -112,6 +140,10 @@ func (s *smtpService) StartOAuth(ctx context.Context, userID, provider string) (
 		params.Set("include_granted_scopes", "true")
 		params.Set("prompt", "consent")
 	}
+	if provider == ProviderMicrosoft {
+		params.Set("response_mode", "query")
+		params.Set("prompt", "select_account")
+	}
 
 	return providerCfg.AuthURL + "?" + params.Encode(), nil
 }
@@ -132,7 +164,7 @@ func (s *smtpService) HandleOAuthCallback(ctx context.Context, provider, code, s
 		return redirectBase, customerror.Trace("HandleOAuthCallback", err)
 	}
 
-	tokenResp, err := exchangeOAuthCode(ctx, provider, providerCfg, code)
+	tokenResp, err := exchangeOAuthCode(ctx, s.httpClient, provider, providerCfg, code)
 	if err != nil {
 		return redirectBase, customerror.Trace("HandleOAuthCallback", err)
 	}
@@ -187,7 +219,7 @@ func (s *smtpService) RefreshOAuthAccessToken(ctx context.Context, instance *Ins
 		return "", customerror.Trace("RefreshOAuthAccessToken", err)
 	}
 
-	tokenResp, err := refreshOAuthToken(ctx, instance.Provider, providerCfg, payload.RefreshToken)
+	tokenResp, err := refreshOAuthToken(ctx, s.httpClient, instance.Provider, providerCfg, payload.RefreshToken)
 	if err != nil {
 		return "", customerror.Trace("RefreshOAuthAccessToken", err)
 	}
@@ -216,35 +248,66 @@ func (s *smtpService) RefreshOAuthAccessToken(ctx context.Context, instance *Ins
 	return tokenResp.AccessToken, nil
 }
 
-func exchangeOAuthCode(ctx context.Context, provider string, cfg *oauthProviderConfig, code string) (*oauthTokenResponse, error) {
+// SendOAuthEmail renova o token se preciso e envia pela API de email do provedor da instância.
+func (s *smtpService) SendOAuthEmail(ctx context.Context, instance *Instance, data *mailer.MailerData) error {
+	accessToken, err := s.RefreshOAuthAccessToken(ctx, instance)
+	if err != nil {
+		return customerror.Trace("SendOAuthEmail", err)
+	}
+
+	switch instance.Provider {
+	case ProviderGoogle:
+		err = mailer.SendWithGmailAPI(ctx, s.httpClient, accessToken, data)
+	case ProviderMicrosoft:
+		providerCfg, cfgErr := buildOAuthProviderConfig(s.oauth, instance.Provider)
+		if cfgErr != nil {
+			return customerror.Trace("SendOAuthEmail", cfgErr)
+		}
+		err = mailer.SendWithMicrosoftGraph(ctx, s.httpClient, providerCfg.APIURL, accessToken, data)
+	default:
+		err = customerror.Make("provedor OAuth de email inválido", http.StatusBadRequest, errors.New("invalidOAuthProvider"))
+	}
+	if err != nil {
+		return customerror.Trace("SendOAuthEmail", err)
+	}
+	return nil
+}
+
+func exchangeOAuthCode(ctx context.Context, client *http.Client, provider string, cfg *oauthProviderConfig, code string) (*oauthTokenResponse, error) {
 	values := url.Values{}
 	values.Set("client_id", cfg.ClientID)
 	values.Set("client_secret", cfg.ClientSecret)
 	values.Set("code", code)
 	values.Set("redirect_uri", cfg.RedirectURL)
 	values.Set("grant_type", "authorization_code")
+	if provider == ProviderMicrosoft {
+		values.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
 
-	return doOAuthTokenRequest(ctx, cfg.TokenURL, values)
+	return doOAuthTokenRequest(ctx, client, cfg.TokenURL, values)
 }
 
-func refreshOAuthToken(ctx context.Context, provider string, cfg *oauthProviderConfig, refreshToken string) (*oauthTokenResponse, error) {
+func refreshOAuthToken(ctx context.Context, client *http.Client, provider string, cfg *oauthProviderConfig, refreshToken string) (*oauthTokenResponse, error) {
 	values := url.Values{}
 	values.Set("client_id", cfg.ClientID)
 	values.Set("client_secret", cfg.ClientSecret)
 	values.Set("refresh_token", refreshToken)
 	values.Set("grant_type", "refresh_token")
+	if provider == ProviderMicrosoft {
+		values.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
 
-	return doOAuthTokenRequest(ctx, cfg.TokenURL, values)
+	return doOAuthTokenRequest(ctx, client, cfg.TokenURL, values)
 }
 
-func doOAuthTokenRequest(ctx context.Context, tokenURL string, values url.Values) (*oauthTokenResponse, error) {
+func doOAuthTokenRequest(ctx context.Context, client *http.Client, tokenURL string, values url.Values) (*oauthTokenResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(values.Encode()))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,175 @@
+package smtp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	configenv "github.com/ThalysSilva/unicast-backend/internal/config/env"
+	"github.com/ThalysSilva/unicast-backend/internal/encryption"
+	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOAuthSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// microsoftStandIn imita os endpoints de token e o sendMail do Graph.
+type microsoftStandIn struct {
+	*httptest.Server
+	mu     sync.Mutex
+	tokens []url.Values
+	sent   []string
+	auth   string
+}
+
+func newMicrosoftStandIn(t *testing.T) *microsoftStandIn {
+	s := &microsoftStandIn{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/organizations/oauth2/v2.0/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		s.mu.Lock()
+		s.tokens = append(s.tokens, r.PostForm)
+		s.mu.Unlock()
+		claims, _ := json.Marshal(map[string]string{"preferred_username": "prof@escola.edu"})
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access-" + r.PostForm.Get("grant_type"),
+			"refresh_token": "refresh-1",
+			"expires_in":    3600,
+			"id_token":      "e30." + base64.RawURLEncoding.EncodeToString(claims) + ".sig",
+		})
+	})
+	mux.HandleFunc("/graph/me/sendMail", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mime, err := base64.StdEncoding.DecodeString(string(body))
+		require.NoError(t, err)
+		s.mu.Lock()
+		s.sent = append(s.sent, string(mime))
+		s.auth = r.Header.Get("Authorization")
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newMicrosoftService(standIn *microsoftStandIn, repo Repository) *smtpService {
+	return &smtpService{smtpRepository: repo, jweSecret: testOAuthSecret, oauth: configenv.OAuth{
+		FrontendBaseURL:       "http://front",
+		MicrosoftClientID:     "client",
+		MicrosoftClientSecret: "secret",
+		MicrosoftRedirectURL:  "http://api/smtp/oauth/microsoft/callback",
+		MicrosoftTenant:       "organizations",
+		MicrosoftAuthorityURL: standIn.URL,
+		MicrosoftGraphURL:     standIn.URL + "/graph",
+	}, httpClient: standIn.Client()}
+}
+
+func TestStartOAuthMicrosoftUsesConfiguredAuthority(t *testing.T) {
+	standIn := newMicrosoftStandIn(t)
+	svc := newMicrosoftService(standIn, &fakeRepository{})
+
+	authURL, err := svc.StartOAuth(context.Background(), "user-1", ProviderMicrosoft)
+
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, standIn.URL+"/organizations/oauth2/v2.0/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Contains(t, parsed.Query().Get("scope"), "offline_access")
+	assert.Contains(t, parsed.Query().Get("scope"), "https://graph.microsoft.com/Mail.Send")
+	assert.NotEmpty(t, parsed.Query().Get("state"))
+}
+
+func TestMicrosoftCallbackStoresAccountAndSendsThroughGraph(t *testing.T) {
+	standIn := newMicrosoftStandIn(t)
+	repo := &fakeRepository{}
+	svc := newMicrosoftService(standIn, repo)
+	authURL, err := svc.StartOAuth(context.Background(), "user-1", ProviderMicrosoft)
+	require.NoError(t, err)
+	parsed, _ := url.Parse(authURL)
+
+	redirect, err := svc.HandleOAuthCallback(context.Background(), ProviderMicrosoft, "code-1", parsed.Query().Get("state"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "http://front/integrations?oauth_status=success&oauth_provider=microsoft", redirect)
+	require.NotNil(t, repo.upserted)
+	assert.Equal(t, "user-1", repo.upserted.UserID)
+	assert.Equal(t, "prof@escola.edu", repo.upserted.Email)
+	assert.Equal(t, ProviderMicrosoft, repo.upserted.Provider)
+	require.Len(t, standIn.tokens, 1)
+	assert.Equal(t, "code-1", standIn.tokens[0].Get("code"))
+	assert.Contains(t, standIn.tokens[0].Get("scope"), "offline_access")
+
+	err = svc.SendOAuthEmail(context.Background(), repo.upserted, &mailer.MailerData{
+		From:        "prof@escola.edu",
+		To:          []string{"aluno@escola.edu"},
+		Subject:     "Prova",
+		Body:        "Amanhã às 10h",
+		ContentType: mailer.TextPlain,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer access-authorization_code", standIn.auth)
+	require.Len(t, standIn.sent, 1)
+	assert.Contains(t, standIn.sent[0], "Subject: Prova")
+	assert.Contains(t, standIn.sent[0], "aluno@escola.edu")
+}
+
+func TestRefreshOAuthAccessTokenRenewsExpiredMicrosoftToken(t *testing.T) {
+	standIn := newMicrosoftStandIn(t)
+	repo := &fakeRepository{}
+	svc := newMicrosoftService(standIn, repo)
+	payload, iv, err := encryption.EncryptSmtpPassword(`{"accessToken":"old","refreshToken":"refresh-0"}`, testOAuthSecret)
+	require.NoError(t, err)
+	expired := time.Now().Add(-time.Hour)
+
+	token, err := svc.RefreshOAuthAccessToken(context.Background(), &Instance{
+		ID: "smtp-1", AuthMode: AuthModeOAuth, Provider: ProviderMicrosoft,
+		OAuthPayload: payload, OAuthIV: iv, TokenExpiresAt: &expired,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "access-refresh_token", token)
+	require.Len(t, standIn.tokens, 1)
+	assert.Equal(t, "refresh-0", standIn.tokens[0].Get("refresh_token"))
+	assert.Contains(t, standIn.tokens[0].Get("scope"), "Mail.Send")
+	assert.NotEmpty(t, repo.updatedToken)
+}
+
+func TestSendWithMicrosoftGraphReportsGraphError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `{"error":{"code":"ErrorAccessDenied"}}`)
+	}))
+	defer server.Close()
+
+	err := mailer.SendWithMicrosoftGraph(context.Background(), server.Client(), server.URL, "token", &mailer.MailerData{
+		From: "prof@escola.edu", To: []string{"aluno@escola.edu"}, Subject: "x", Body: "y", ContentType: mailer.TextPlain,
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ErrorAccessDenied")
+}
+
+func TestOAuthTokenRequestGivesUpOnSlowEndpoint(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+
+	_, err := doOAuthTokenRequest(context.Background(), client, server.URL, url.Values{"grant_type": {"refresh_token"}})
+
+	require.Error(t, err)
+}
@@ -19,33 +19,79 @@ type smtpService struct {
 	smtpRepository Repository
 	jweSecret      []byte
 	oauth          configenv.OAuth
+	httpClient     *http.Client
 }
 
 type Service interface {
-	Create(ctx context.Context, jweSecret []byte, userId, jwe, email, password, host string, port int) error
+	Create(ctx context.Context, jweSecret []byte, userId, jwe, email, password, host string, port int, tlsMode mailer.TLSMode, authMechanism mailer.AuthMechanism) error
 	StartOAuth(ctx context.Context, userID, provider string) (string, error)
 	HandleOAuthCallback(ctx context.Context, provider, code, state string) (string, error)
-	TestConnection(ctx context.Context, email, password, host string, port int) error
+	TestConnection(ctx context.Context, email, password, host string, port int, tlsMode mailer.TLSMode, authMechanism mailer.AuthMechanism) error
 	GetInstances(ctx context.Context, userID string) ([]*Instance, error)
 	DeleteInstance(ctx context.Context, userID, instanceID string) error
 	RefreshOAuthAccessToken(ctx context.Context, instance *Instance) (string, error)
+	SendOAuthEmail(ctx context.Context, instance *Instance, data *mailer.MailerData) error
+	UpdatePassword(ctx context.Context, userID, instanceID, jwe, password string) error
 }
 
-func NewService(smtpRepository Repository, jweSecret []byte, oauth configenv.OAuth) Service {
-	return &smtpService{smtpRepository: smtpRepository, jweSecret: jweSecret, oauth: oauth}
+// oauthHTTPTimeout limita as chamadas aos endpoints de token e às APIs de email dos provedores OAuth.
+const oauthHTTPTimeout = 30 * time.Second
+
+// NewService usa httpClient nas chamadas OAuth; nil usa um client com timeout de oauthHTTPTimeout.
+func NewService(smtpRepository Repository, jweSecret []byte, oauth configenv.OAuth, httpClient *http.Client) Service {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: oauthHTTPTimeout}
+	}
+	return &smtpService{smtpRepository: smtpRepository, jweSecret: jweSecret, oauth: oauth, httpClient: httpClient}
 }
 
 var (
 	InstanceNotFound  = customerror.Make("Instância SMTP não encontrada", http.StatusNotFound, errors.New("smtpInstanceNotFound"))
 	InstanceForbidden = customerror.Make("Você não tem permissão para esta instância SMTP", http.StatusForbidden, errors.New("smtpInstanceForbidden"))
+	InvalidTLSMode    = customerror.Make("Modo TLS inválido: use starttls, tls ou none", http.StatusBadRequest, errors.New("smtpInvalidTLSMode"))
+	InvalidAuthMech   = customerror.Make("Mecanismo de autenticação inválido: use PLAIN, LOGIN, CRAM-MD5 ou NONE", http.StatusBadRequest, errors.New("smtpInvalidAuthMechanism"))
+	PasswordRequired  = customerror.Make("Informe a senha e o jwe para autenticar no servidor SMTP", http.StatusBadRequest, errors.New("smtpPasswordRequired"))
+	PlainTextPassword = customerror.Make("PLAIN e LOGIN enviam a senha sem criptografia; use TLS ou CRAM-MD5", http.StatusBadRequest, errors.New("smtpPlainTextPassword"))
+	PasswordNotUsed   = customerror.Make("Esta instância SMTP não usa senha", http.StatusBadRequest, errors.New("smtpPasswordNotUsed"))
 )
 
-func (s *smtpService) Create(ctx context.Context, jweSecret []byte, userId, jwe, email, password, host string, port int) error {
-	decryptedJwe, err := auth.DecryptJWE[auth.JwePayload](jwe, jweSecret)
+// resolveSecurity aplica os padrões (STARTTLS com PLAIN) e recusa combinações que exporiam a senha.
+func resolveSecurity(tlsMode mailer.TLSMode, authMechanism mailer.AuthMechanism) (mailer.TLSMode, mailer.AuthMechanism, error) {
+	if tlsMode == "" {
+		tlsMode = mailer.TLSStartTLS
+	}
+	if authMechanism == "" {
+		authMechanism = mailer.AuthPlain
+	}
+	if !tlsMode.IsValid() {
+		return "", "", InvalidTLSMode
+	}
+	if !authMechanism.IsValid() {
+		return "", "", InvalidAuthMech
+	}
+	if tlsMode == mailer.TLSNone && (authMechanism == mailer.AuthPlain || authMechanism == mailer.AuthLogin) {
+		return "", "", PlainTextPassword
+	}
+	return tlsMode, authMechanism, nil
+}
+
+func (s *smtpService) Create(ctx context.Context, jweSecret []byte, userId, jwe, email, password, host string, port int, tlsMode mailer.TLSMode, authMechanism mailer.AuthMechanism) error {
+	tlsMode, authMechanism, err := resolveSecurity(tlsMode, authMechanism)
 	if err != nil {
 		return customerror.Trace("Create", err)
 	}
-	smtpKey, err := base64.StdEncoding.DecodeString(decryptedJwe.SmtpKeyEncoded)
+	// Relays sem autenticação não guardam senha.
+	if authMechanism == mailer.AuthNone {
+		if err := s.smtpRepository.Create(ctx, userId, email, host, port, tlsMode, authMechanism, nil, nil); err != nil {
+			return customerror.Trace("Create", err)
+		}
+		return nil
+	}
+	if password == "" || jwe == "" {
+		return customerror.Trace("Create", PasswordRequired)
+	}
+
+	smtpKey, err := smtpKeyFromJwe(jwe, jweSecret)
 	if err != nil {
 		return customerror.Trace("Create", err)
 	}
@@ -55,18 +101,100 @@ func (s *smtpService) Create(ctx context.Context, jweSecret []byte, userId, jwe,
 		return customerror.Trace("Create", err)
 	}
 
-	if (s.smtpRepository.Create(ctx, userId, email, host, port, encryptedPassword, iv)) != nil {
+	if err := s.smtpRepository.Create(ctx, userId, email, host, port, tlsMode, authMechanism, encryptedPassword, iv); err != nil {
 		return customerror.Trace("Create", err)
 	}
 	return nil
 }
 
-func (s *smtpService) TestConnection(ctx context.Context, email, password, host string, port int) error {
-	err := mailer.TestSMTPConnection(mailer.SmtpAuthentication{
-		Host:     host,
-		Port:     port,
-		Username: email,
-		Password: password,
+// UpdatePassword regrava a senha de uma instância com a chave do JWE atual; é assim que o usuário
+// reativa instâncias marcadas depois de um reset administrativo da senha da conta.
+func (s *smtpService) UpdatePassword(ctx context.Context, userID, instanceID, jwe, password string) error {
+	instance, err := s.smtpRepository.FindByID(ctx, instanceID)
+	if err != nil {
+		return customerror.Trace("UpdatePassword", err)
+	}
+	if instance == nil {
+		return customerror.Trace("UpdatePassword", InstanceNotFound)
+	}
+	if instance.UserID != userID {
+		return customerror.Trace("UpdatePassword", InstanceForbidden)
+	}
+	if !instance.UsesPassword() {
+		return customerror.Trace("UpdatePassword", PasswordNotUsed)
+	}
+	if password == "" || jwe == "" {
+		return customerror.Trace("UpdatePassword", PasswordRequired)
+	}
+
+	smtpKey, err := smtpKeyFromJwe(jwe, s.jweSecret)
+	if err != nil {
+		return customerror.Trace("UpdatePassword", err)
+	}
+	encryptedPassword, iv, err := encryption.EncryptSmtpPassword(password, smtpKey)
+	if err != nil {
+		return customerror.Trace("UpdatePassword", err)
+	}
+	if err := s.smtpRepository.UpdatePassword(ctx, instance.ID, encryptedPassword, iv); err != nil {
+		return customerror.Trace("UpdatePassword", err)
+	}
+	return nil
+}
+
+// ReencryptPasswords troca a chave das senhas SMTP do usuário quando a senha da conta muda.
+// Deve rodar na mesma transação que grava o novo salt; senhas que a chave antiga já não abre
+// ficam marcadas para reentrada em vez de impedir a troca.
+func ReencryptPasswords(ctx context.Context, repo Repository, userID string, oldKey, newKey []byte) error {
+	instances, err := repo.GetInstances(ctx, userID)
+	if err != nil {
+		return customerror.Trace("ReencryptPasswords", err)
+	}
+	for _, instance := range instances {
+		if !instance.UsesPassword() || instance.PasswordReentryRequired {
+			continue
+		}
+		password, err := encryption.DecryptSmtpPassword(instance.Password, oldKey, instance.IV)
+		if err != nil {
+			if err := repo.MarkPasswordReentry(ctx, instance.ID); err != nil {
+				return customerror.Trace("ReencryptPasswords", err)
+			}
+			continue
+		}
+		encryptedPassword, iv, err := encryption.EncryptSmtpPassword(password, newKey)
+		if err != nil {
+			return customerror.Trace("ReencryptPasswords", err)
+		}
+		if err := repo.UpdatePassword(ctx, instance.ID, encryptedPassword, iv); err != nil {
+			return customerror.Trace("ReencryptPasswords", err)
+		}
+	}
+	return nil
+}
+
+func smtpKeyFromJwe(jwe string, jweSecret []byte) ([]byte, error) {
+	decryptedJwe, err := auth.DecryptJWE[auth.JwePayload](jwe, jweSecret)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(decryptedJwe.SmtpKeyEncoded)
+}
+
+func (s *smtpService) TestConnection(ctx context.Context, email, password, host string, port int, tlsMode mailer.TLSMode, authMechanism mailer.AuthMechanism) error {
+	tlsMode, authMechanism, err := resolveSecurity(tlsMode, authMechanism)
+	if err != nil {
+		return customerror.Trace("TestConnection", err)
+	}
+	if authMechanism != mailer.AuthNone && password == "" {
+		return customerror.Trace("TestConnection", PasswordRequired)
+	}
+
+	err = mailer.TestSMTPConnection(mailer.SmtpAuthentication{
+		Host:          host,
+		Port:          port,
+		Username:      email,
+		Password:      password,
+		TLSMode:       tlsMode,
+		AuthMechanism: authMechanism,
 	}, 8*time.Second)
 	if err == nil {
 		return nil
@@ -0,0 +1,106 @@
+package smtp
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/auth"
+	"github.com/ThalysSilva/unicast-backend/internal/encryption"
+	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepository struct {
+	Repository
+	instances    []*Instance
+	upserted     *Instance
+	updatedToken []byte
+	marked       []string
+}
+
+func (r *fakeRepository) UpsertOAuth(ctx context.Context, userID, email, provider, host string, port int, oauthPayload, oauthIV []byte, tokenExpiresAt *time.Time) error {
+	r.upserted = &Instance{UserID: userID, Email: email, Provider: provider, Host: host, Port: port, AuthMode: AuthModeOAuth, OAuthPayload: oauthPayload, OAuthIV: oauthIV, TokenExpiresAt: tokenExpiresAt}
+	return nil
+}
+
+func (r *fakeRepository) UpdateOAuthTokens(ctx context.Context, id string, oauthPayload, oauthIV []byte, tokenExpiresAt *time.Time) error {
+	r.updatedToken = oauthPayload
+	return nil
+}
+
+func (r *fakeRepository) FindByID(ctx context.Context, id string) (*Instance, error) {
+	for _, instance := range r.instances {
+		if instance.ID == id {
+			return instance, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) GetInstances(ctx context.Context, userID string) ([]*Instance, error) {
+	return r.instances, nil
+}
+
+func (r *fakeRepository) UpdatePassword(ctx context.Context, id string, password, iv []byte) error {
+	instance, _ := r.FindByID(ctx, id)
+	instance.Password, instance.IV, instance.PasswordReentryRequired = password, iv, false
+	return nil
+}
+
+func (r *fakeRepository) MarkPasswordReentry(ctx context.Context, id string) error {
+	r.marked = append(r.marked, id)
+	return nil
+}
+
+func passwordInstance(t *testing.T, id, password string, key []byte) *Instance {
+	t.Helper()
+	encrypted, iv, err := encryption.EncryptSmtpPassword(password, key)
+	require.NoError(t, err)
+	return &Instance{ID: id, UserID: "user-1", AuthMode: AuthModePassword, AuthMechanism: mailer.AuthPlain, Password: encrypted, IV: iv}
+}
+
+func smtpKey(t *testing.T, password string) []byte {
+	t.Helper()
+	key, err := encryption.GenerateSmtpKey(password, []byte("salt-de-teste"))
+	require.NoError(t, err)
+	return key
+}
+
+func TestReencryptPasswordsMovesCredentialsToTheNewKey(t *testing.T) {
+	oldKey, newKey := smtpKey(t, "antiga"), smtpKey(t, "nova")
+	repo := &fakeRepository{instances: []*Instance{
+		passwordInstance(t, "smtp-1", "segredo-1", oldKey),
+		passwordInstance(t, "smtp-2", "segredo-2", smtpKey(t, "outra")),
+		{ID: "relay", UserID: "user-1", AuthMode: AuthModePassword, AuthMechanism: mailer.AuthNone},
+		{ID: "oauth", UserID: "user-1", AuthMode: AuthModeOAuth, Provider: ProviderGoogle},
+	}}
+
+	err := ReencryptPasswords(context.Background(), repo, "user-1", oldKey, newKey)
+
+	require.NoError(t, err)
+	password, err := encryption.DecryptSmtpPassword(repo.instances[0].Password, newKey, repo.instances[0].IV)
+	require.NoError(t, err)
+	assert.Equal(t, "segredo-1", password)
+	assert.Equal(t, []string{"smtp-2"}, repo.marked, "senhas que a chave antiga não abre ficam para reentrada")
+}
+
+func TestUpdatePasswordClearsReentryFlag(t *testing.T) {
+	key := smtpKey(t, "nova")
+	instance := passwordInstance(t, "smtp-1", "perdida", smtpKey(t, "antiga"))
+	instance.PasswordReentryRequired = true
+	repo := &fakeRepository{instances: []*Instance{instance}}
+	svc := &smtpService{smtpRepository: repo, jweSecret: testOAuthSecret}
+	jwe, err := auth.GenerateJWE(auth.JwePayload{SmtpKeyEncoded: base64.StdEncoding.EncodeToString(key)}, testOAuthSecret)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, svc.UpdatePassword(context.Background(), "user-2", "smtp-1", jwe, "segredo"), InstanceForbidden)
+	require.NoError(t, svc.UpdatePassword(context.Background(), "user-1", "smtp-1", jwe, "segredo"))
+
+	assert.False(t, instance.PasswordReentryRequired)
+	password, err := encryption.DecryptSmtpPassword(instance.Password, key, instance.IV)
+	require.NoError(t, err)
+	assert.Equal(t, "segredo", password)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
 )
 
 type sqlRepository struct {
@@ -15,9 +16,9 @@ type sqlRepository struct {
 
 // Cria uma nova instância do repositório
 func newSQLRepository(db *sql.DB) Repository {
-	newDb := database.NewSQLTx(db)
 	return &sqlRepository{
-		db: newDb.DB,
+		db:    database.NewSQLTx(db).DB,
+		sqlDB: db,
 	}
 }
 func (r *sqlRepository) WithTransaction(tx any) any {
@@ -32,12 +33,12 @@ func (r *sqlRepository) TransactionBackend() any {
 }
 
 // Insere uma nova instância SMTP
-func (r *sqlRepository) Create(ctx context.Context, userID, email, host string, port int, password, iv []byte) error {
+func (r *sqlRepository) Create(ctx context.Context, userID, email, host string, port int, tlsMode mailer.TLSMode, authMechanism mailer.AuthMechanism, password, iv []byte) error {
 	query := `
-        INSERT INTO smtp_instances (host, port, email, password, iv, user_id, auth_mode, provider)
-        VALUES ($1, $2, $3, $4, $5, $6, 'password', 'custom_smtp')
+        INSERT INTO smtp_instances (host, port, email, password, iv, user_id, auth_mode, provider, tls_mode, auth_mechanism)
+        VALUES ($1, $2, $3, $4, $5, $6, 'password', 'custom_smtp', $7, $8)
     `
-	_, err := r.db.ExecContext(ctx, query, host, port, email, password, iv, userID)
+	_, err := r.db.ExecContext(ctx, query, host, port, email, password, iv, userID, tlsMode, authMechanism)
 	return err
 }
 
@@ -65,7 +66,7 @@ func (r *sqlRepository) UpsertOAuth(ctx context.Context, userID, email, provider
 // Busca uma instância SMTP pelo ID
 func (r *sqlRepository) FindByID(ctx context.Context, id string) (*Instance, error) {
 	query := `
-        SELECT id, host, port, email, auth_mode, provider, password, iv, oauth_payload, oauth_iv, token_expires_at, created_at, updated_at, user_id
+        SELECT id, host, port, email, auth_mode, provider, tls_mode, auth_mechanism, password, iv, oauth_payload, oauth_iv, token_expires_at, password_reentry_required, created_at, updated_at, user_id
         FROM smtp_instances
         WHERE id = $1
     `
@@ -80,11 +81,14 @@ func (r *sqlRepository) FindByID(ctx context.Context, id string) (*Instance, err
 		&instance.Email,
 		&instance.AuthMode,
 		&instance.Provider,
+		&instance.TLSMode,
+		&instance.AuthMechanism,
 		&instance.Password,
 		&instance.IV,
 		&instance.OAuthPayload,
 		&instance.OAuthIV,
 		&tokenExpiresAt,
+		&instance.PasswordReentryRequired,
 		&instance.CreatedAt,
 		&instance.UpdatedAt,
 		&instance.UserID,
@@ -104,7 +108,7 @@ func (r *sqlRepository) FindByID(ctx context.Context, id string) (*Instance, err
 func (r *sqlRepository) GetInstances(ctx context.Context, userID string) ([]*Instance, error) {
 
 	query := `
-				SELECT id, host, port, email, auth_mode, provider, password, iv, oauth_payload, oauth_iv, token_expires_at, created_at, updated_at, user_id
+				SELECT id, host, port, email, auth_mode, provider, tls_mode, auth_mechanism, password, iv, oauth_payload, oauth_iv, token_expires_at, password_reentry_required, created_at, updated_at, user_id
 				FROM smtp_instances
 				WHERE user_id = $1
 		`
@@ -126,11 +130,14 @@ func (r *sqlRepository) GetInstances(ctx context.Context, userID string) ([]*Ins
 			&instance.Email,
 			&instance.AuthMode,
 			&instance.Provider,
+			&instance.TLSMode,
+			&instance.AuthMechanism,
 			&instance.Password,
 			&instance.IV,
 			&instance.OAuthPayload,
 			&instance.OAuthIV,
 			&tokenExpiresAt,
+			&instance.PasswordReentryRequired,
 			&instance.CreatedAt,
 			&instance.UpdatedAt,
 			&instance.UserID,
@@ -159,6 +166,41 @@ func (r *sqlRepository) UpdateOAuthTokens(ctx context.Context, id string, oauthP
 	return err
 }
 
+func (r *sqlRepository) UpdatePassword(ctx context.Context, id string, password, iv []byte) error {
+	query := `
+		UPDATE smtp_instances
+		SET password = $2,
+			iv = $3,
+			password_reentry_required = FALSE,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, err := r.db.ExecContext(ctx, query, id, password, iv)
+	return err
+}
+
+func (r *sqlRepository) MarkPasswordReentry(ctx context.Context, id string) error {
+	query := `
+		UPDATE smtp_instances
+		SET password_reentry_required = TRUE,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *sqlRepository) MarkUserPasswordsReentry(ctx context.Context, userID string) error {
+	query := `
+		UPDATE smtp_instances
+		SET password_reentry_required = TRUE,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND auth_mode = 'password' AND auth_mechanism <> 'NONE'
+	`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
 // Remove uma instância SMTP pelo ID
 func (r *sqlRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM smtp_instances WHERE id = $1`
@@ -31,6 +31,7 @@ type Student struct {
 	Consent     bool          `json:"consent"`
 	EmailDeliveryIssue    bool      `json:"emailDeliveryIssue"`
 	WhatsAppDeliveryIssue bool      `json:"whatsappDeliveryIssue"`
+	SmsDeliveryIssue      bool      `json:"smsDeliveryIssue"`
 	CreatedAt   time.Time     `json:"-"`
 	UpdatedAt   time.Time     `json:"-"`
 	Status      StudentStatus `json:"status"`
@@ -40,6 +41,8 @@ type Student struct {
 type DeliverySnapshot struct {
 	Channel        string     `json:"channel"`
 	Success        bool       `json:"success"`
+	// Status é SENT, DELIVERED, READ ou FAILED; no WhatsApp acompanha os recibos de entrega e leitura.
+	Status         string     `json:"status"`
 	ErrorText      *string    `json:"errorText"`
 	SenderType     *string    `json:"senderType"`
 	SenderProvider *string    `json:"senderProvider"`
@@ -50,6 +53,7 @@ type DeliverySnapshot struct {
 type DeliverySummary struct {
 	Email    *DeliverySnapshot `json:"email"`
 	WhatsApp *DeliverySnapshot `json:"whatsapp"`
+	SMS      *DeliverySnapshot `json:"sms"`
 }
 
 func hasText(value *string) bool {
@@ -89,6 +93,47 @@ func DeriveContactAwareStatus(current, requested StudentStatus, statusProvided b
 	return StudentStatusPending
 }
 
+// RecipientFilter seleciona os alunos matriculados nas disciplinas informadas ou em disciplinas
+// dos cursos e campi informados. Statuses restringe o resultado; vazio aceita qualquer status.
+type RecipientFilter struct {
+	DisciplineIDs []string        `json:"disciplineIds" binding:"omitempty,dive,uuid"`
+	ProgramIDs    []string        `json:"programIds" binding:"omitempty,dive,uuid"`
+	CampusIDs     []string        `json:"campusIds" binding:"omitempty,dive,uuid"`
+	Statuses      []StudentStatus `json:"statuses" binding:"omitempty,dive,oneof=ACTIVE CANCELED GRADUATED LOCKED PENDING"`
+}
+
+// HasTargets indica se o filtro aponta para alguma disciplina, curso ou campus.
+func (f RecipientFilter) HasTargets() bool {
+	return len(f.DisciplineIDs) > 0 || len(f.ProgramIDs) > 0 || len(f.CampusIDs) > 0
+}
+
+// Merge soma os alvos dos dois filtros; os status de other prevalecem quando informados.
+func (f RecipientFilter) Merge(other RecipientFilter) RecipientFilter {
+	merged := RecipientFilter{
+		DisciplineIDs: append(append([]string{}, f.DisciplineIDs...), other.DisciplineIDs...),
+		ProgramIDs:    append(append([]string{}, f.ProgramIDs...), other.ProgramIDs...),
+		CampusIDs:     append(append([]string{}, f.CampusIDs...), other.CampusIDs...),
+		Statuses:      f.Statuses,
+	}
+	if len(other.Statuses) > 0 {
+		merged.Statuses = other.Statuses
+	}
+	return merged
+}
+
+// AcceptsStatus indica se um aluno com o status informado passa pelo filtro de status.
+func (f RecipientFilter) AcceptsStatus(status StudentStatus) bool {
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, accepted := range f.Statuses {
+		if accepted == status {
+			return true
+		}
+	}
+	return false
+}
+
 type Repository interface {
 	database.Transactional
 	Create(ctx context.Context, userOwnerID, studentID string, name, phone, email, annotation *string, noPhone bool, status StudentStatus) error
@@ -99,6 +144,8 @@ type Repository interface {
 	Update(ctx context.Context, id string, fields map[string]any) error
 	Delete(ctx context.Context, id string) error
 	FindByIDs(ctx context.Context, userOwnerID string, ids []string) ([]*Student, error)
+	// FindIDsByRecipientFilter expande o filtro pelas matrículas → disciplinas → cursos → campi do usuário.
+	FindIDsByRecipientFilter(ctx context.Context, userOwnerID string, filter RecipientFilter) ([]string, error)
 }
 
 func NewRepository(db *sql.DB) Repository {
@@ -0,0 +1,38 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+)
+
+// SavedFilter é um filtro de destinatários salvo pelo professor para reutilizar nos envios.
+type SavedFilter struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	RecipientFilter
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UserID    string    `json:"-"`
+}
+
+type SavedFilterInput struct {
+	Name string `json:"name" binding:"required"`
+	RecipientFilter
+}
+
+type FilterRepository interface {
+	database.Transactional
+	Create(ctx context.Context, filter *SavedFilter) error
+	FindByID(ctx context.Context, id string) (*SavedFilter, error)
+	FindByNameAndUserID(ctx context.Context, name, userID string) (*SavedFilter, error)
+	FindByUserID(ctx context.Context, userID string) ([]*SavedFilter, error)
+	Update(ctx context.Context, filter *SavedFilter) error
+	Delete(ctx context.Context, id string) error
+}
+
+func NewFilterRepository(db *sql.DB) FilterRepository {
+	return newFilterRepository(db)
+}
@@ -0,0 +1,144 @@
+package student
+
+import (
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/api"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+
+	"github.com/gin-gonic/gin"
+)
+
+type filterHandler struct {
+	service FilterService
+}
+
+type FilterHandler interface {
+	Create() gin.HandlerFunc
+	List() gin.HandlerFunc
+	Get() gin.HandlerFunc
+	Update() gin.HandlerFunc
+	Delete() gin.HandlerFunc
+}
+
+func NewFilterHandler(service FilterService) FilterHandler {
+	return &filterHandler{service: service}
+}
+
+// @Summary Salva um filtro de alunos
+// @Description Filtro reutilizável como destinatário de mensagens (filterId em /message/send)
+// @Tags student
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param body body SavedFilterInput true "Dados do filtro"
+// @Success 201 {object} api.DefaultResponse[SavedFilter]
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /student/filter [post]
+func (h *filterHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input SavedFilterInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		filter, err := h.service.Create(c.Request.Context(), c.GetString("userID"), input)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, api.DefaultResponse[SavedFilter]{Message: "Filtro criado com sucesso", Data: *filter})
+	}
+}
+
+// @Summary Lista os filtros de alunos salvos
+// @Tags student
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Success 200 {object} api.DefaultResponse[[]SavedFilter]
+// @Router /student/filter [get]
+func (h *filterHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filters, err := h.service.List(c.Request.Context(), c.GetString("userID"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		items := make([]SavedFilter, 0, len(filters))
+		for _, filter := range filters {
+			items = append(items, *filter)
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[[]SavedFilter]{Message: "Filtros listados com sucesso", Data: items})
+	}
+}
+
+// @Summary Busca um filtro de alunos salvo
+// @Tags student
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param id path string true "ID do filtro"
+// @Success 200 {object} api.DefaultResponse[SavedFilter]
+// @Failure 404 {object} api.ErrorResponse
+// @Router /student/filter/{id} [get]
+func (h *filterHandler) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := h.service.Get(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[SavedFilter]{Message: "Filtro encontrado", Data: *filter})
+	}
+}
+
+// @Summary Atualiza um filtro de alunos salvo
+// @Tags student
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param id path string true "ID do filtro"
+// @Param body body SavedFilterInput true "Dados do filtro"
+// @Success 200 {object} api.DefaultResponse[SavedFilter]
+// @Failure 400 {object} api.ErrorResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Failure 409 {object} api.ErrorResponse
+// @Router /student/filter/{id} [put]
+func (h *filterHandler) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input SavedFilterInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+		filter, err := h.service.Update(c.Request.Context(), c.GetString("userID"), c.Param("id"), input)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.DefaultResponse[SavedFilter]{Message: "Filtro atualizado com sucesso", Data: *filter})
+	}
+}
+
+// @Summary Remove um filtro de alunos salvo
+// @Tags student
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param id path string true "ID do filtro"
+// @Success 200 {object} api.MessageResponse
+// @Failure 404 {object} api.ErrorResponse
+// @Router /student/filter/{id} [delete]
+func (h *filterHandler) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.service.Delete(c.Request.Context(), c.GetString("userID"), c.Param("id")); err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, api.MessageResponse{Message: "Filtro removido com sucesso"})
+	}
+}
@@ -0,0 +1,113 @@
+package student
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var (
+	ErrFilterNotFound       = customerror.Make("filtro de alunos não encontrado", http.StatusNotFound, errors.New("ErrFilterNotFound"))
+	ErrFilterAlreadyExists  = customerror.Make("já existe um filtro de alunos com esse nome", http.StatusConflict, errors.New("ErrFilterAlreadyExists"))
+	ErrFilterWithoutTargets = customerror.Make("informe ao menos uma disciplina, curso ou campus", http.StatusBadRequest, errors.New("ErrFilterWithoutTargets"))
+)
+
+type FilterService interface {
+	Create(ctx context.Context, userID string, input SavedFilterInput) (*SavedFilter, error)
+	List(ctx context.Context, userID string) ([]*SavedFilter, error)
+	Get(ctx context.Context, userID, id string) (*SavedFilter, error)
+	Update(ctx context.Context, userID, id string, input SavedFilterInput) (*SavedFilter, error)
+	Delete(ctx context.Context, userID, id string) error
+}
+
+type filterService struct {
+	filterRepository FilterRepository
+}
+
+func NewFilterService(filterRepository FilterRepository) FilterService {
+	return &filterService{filterRepository: filterRepository}
+}
+
+func (s *filterService) Create(ctx context.Context, userID string, input SavedFilterInput) (*SavedFilter, error) {
+	if !input.HasTargets() {
+		return nil, customerror.Trace("CreateStudentFilter", ErrFilterWithoutTargets)
+	}
+	name := strings.TrimSpace(input.Name)
+	existing, err := s.filterRepository.FindByNameAndUserID(ctx, name, userID)
+	if err != nil {
+		return nil, customerror.Trace("CreateStudentFilter", err)
+	}
+	if existing != nil {
+		return nil, customerror.Trace("CreateStudentFilter", ErrFilterAlreadyExists)
+	}
+
+	filter := &SavedFilter{
+		UserID:          userID,
+		Name:            name,
+		RecipientFilter: input.RecipientFilter,
+	}
+	if err := s.filterRepository.Create(ctx, filter); err != nil {
+		return nil, customerror.Trace("CreateStudentFilter", err)
+	}
+	return filter, nil
+}
+
+func (s *filterService) List(ctx context.Context, userID string) ([]*SavedFilter, error) {
+	filters, err := s.filterRepository.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, customerror.Trace("ListStudentFilters", err)
+	}
+	return filters, nil
+}
+
+func (s *filterService) Get(ctx context.Context, userID, id string) (*SavedFilter, error) {
+	filter, err := s.filterRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, customerror.Trace("GetStudentFilter", err)
+	}
+	if filter == nil || filter.UserID != userID {
+		return nil, customerror.Trace("GetStudentFilter", ErrFilterNotFound)
+	}
+	return filter, nil
+}
+
+func (s *filterService) Update(ctx context.Context, userID, id string, input SavedFilterInput) (*SavedFilter, error) {
+	filter, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !input.HasTargets() {
+		return nil, customerror.Trace("UpdateStudentFilter", ErrFilterWithoutTargets)
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name != filter.Name {
+		existing, err := s.filterRepository.FindByNameAndUserID(ctx, name, userID)
+		if err != nil {
+			return nil, customerror.Trace("UpdateStudentFilter", err)
+		}
+		if existing != nil {
+			return nil, customerror.Trace("UpdateStudentFilter", ErrFilterAlreadyExists)
+		}
+	}
+
+	filter.Name = name
+	filter.RecipientFilter = input.RecipientFilter
+	if err := s.filterRepository.Update(ctx, filter); err != nil {
+		return nil, customerror.Trace("UpdateStudentFilter", err)
+	}
+	return filter, nil
+}
+
+func (s *filterService) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := s.filterRepository.Delete(ctx, id); err != nil {
+		return customerror.Trace("DeleteStudentFilter", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/lib/pq"
+)
+
+type filterRepository struct {
+	db    database.DB
+	sqlDB *sql.DB
+}
+
+func newFilterRepository(db *sql.DB) FilterRepository {
+	return &filterRepository{
+		db:    database.NewSQLTx(db).DB,
+		sqlDB: db,
+	}
+}
+
+func (r *filterRepository) WithTransaction(tx any) any {
+	return &filterRepository{
+		db:    database.NewSQLTx(nil).WithSQLTransaction(tx).DB,
+		sqlDB: r.sqlDB,
+	}
+}
+
+func (r *filterRepository) TransactionBackend() any {
+	return r.sqlDB
+}
+
+const filterColumns = `id, user_id, name, discipline_ids, program_ids, campus_ids, statuses, created_at, updated_at`
+
+func (r *filterRepository) Create(ctx context.Context, filter *SavedFilter) error {
+	query := `
+		INSERT INTO student_filters (user_id, name, discipline_ids, program_ids, campus_ids, statuses)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		filter.UserID,
+		filter.Name,
+		pq.Array(nonNilStrings(filter.DisciplineIDs)),
+		pq.Array(nonNilStrings(filter.ProgramIDs)),
+		pq.Array(nonNilStrings(filter.CampusIDs)),
+		pq.Array(statusStrings(filter.Statuses)),
+	).Scan(&filter.ID, &filter.CreatedAt, &filter.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao criar filtro de alunos: %w", err)
+	}
+	return nil
+}
+
+func (r *filterRepository) FindByID(ctx context.Context, id string) (*SavedFilter, error) {
+	query := `SELECT ` + filterColumns + ` FROM student_filters WHERE id = $1`
+	return r.findOne(ctx, query, id)
+}
+
+func (r *filterRepository) FindByNameAndUserID(ctx context.Context, name, userID string) (*SavedFilter, error) {
+	query := `SELECT ` + filterColumns + ` FROM student_filters WHERE name = $1 AND user_id = $2`
+	return r.findOne(ctx, query, name, userID)
+}
+
+func (r *filterRepository) FindByUserID(ctx context.Context, userID string) ([]*SavedFilter, error) {
+	query := `SELECT ` + filterColumns + ` FROM student_filters WHERE user_id = $1 ORDER BY name`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar filtros de alunos: %w", err)
+	}
+	defer rows.Close()
+
+	filters := []*SavedFilter{}
+	for rows.Next() {
+		filter, err := scanSavedFilter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao ler filtro de alunos: %w", err)
+		}
+		filters = append(filters, filter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar filtros de alunos: %w", err)
+	}
+	return filters, nil
+}
+
+func (r *filterRepository) Update(ctx context.Context, filter *SavedFilter) error {
+	query := `
+		UPDATE student_filters
+		SET name = $2, discipline_ids = $3, program_ids = $4, campus_ids = $5, statuses = $6
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		filter.ID,
+		filter.Name,
+		pq.Array(nonNilStrings(filter.DisciplineIDs)),
+		pq.Array(nonNilStrings(filter.ProgramIDs)),
+		pq.Array(nonNilStrings(filter.CampusIDs)),
+		pq.Array(statusStrings(filter.Statuses)),
+	).Scan(&filter.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar filtro de alunos %s: %w", filter.ID, err)
+	}
+	return nil
+}
+
+func (r *filterRepository) Delete(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM student_filters WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("falha ao remover filtro de alunos %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *filterRepository) findOne(ctx context.Context, query string, args ...any) (*SavedFilter, error) {
+	filter, err := scanSavedFilter(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("falha ao buscar filtro de alunos: %w", err)
+	}
+	return filter, nil
+}
+
+func scanSavedFilter(scanner rowScanner) (*SavedFilter, error) {
+	filter := &SavedFilter{}
+	var disciplineIDs, programIDs, campusIDs, statuses pq.StringArray
+	err := scanner.Scan(
+		&filter.ID,
+		&filter.UserID,
+		&filter.Name,
+		&disciplineIDs,
+		&programIDs,
+		&campusIDs,
+		&statuses,
+		&filter.CreatedAt,
+		&filter.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	filter.DisciplineIDs = []string(disciplineIDs)
+	filter.ProgramIDs = []string(programIDs)
+	filter.CampusIDs = []string(campusIDs)
+	filter.Statuses = make([]StudentStatus, 0, len(statuses))
+	for _, status := range statuses {
+		filter.Statuses = append(filter.Statuses, StudentStatus(status))
+	}
+	return filter, nil
+}
+
+func statusStrings(statuses []StudentStatus) []string {
+	values := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		values = append(values, string(status))
+	}
+	return values
+}
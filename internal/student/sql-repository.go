@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/ThalysSilva/unicast-backend/pkg/database"
+	"github.com/lib/pq"
 )
 
 // Gerencia operações de banco para Student
@@ -63,6 +64,14 @@ func (r *sqlRepository) FindByID(ctx context.Context, id, userOwnerID string) (*
                  SELECT MAX(created_at) FROM message_logs ml
                  WHERE ml.student_id = students.id AND ml.channel = 'WHATSAPP' AND ml.success = true
                ), '-infinity'::timestamptz) AS whatsapp_delivery_issue,
+               COALESCE((
+                 SELECT MAX(created_at) FROM message_logs ml
+                 WHERE ml.student_id = students.id AND ml.channel = 'SMS' AND ml.success = false
+               ), '-infinity'::timestamptz) >
+               COALESCE((
+                 SELECT MAX(created_at) FROM message_logs ml
+                 WHERE ml.student_id = students.id AND ml.channel = 'SMS' AND ml.success = true
+               ), '-infinity'::timestamptz) AS sms_delivery_issue,
                created_at, updated_at, status, user_owner_id
         FROM students
         WHERE id = $1 AND user_owner_id = $2
@@ -98,6 +107,14 @@ func (r *sqlRepository) FindByStudentID(ctx context.Context, studentID, userOwne
                  SELECT MAX(created_at) FROM message_logs ml
                  WHERE ml.student_id = students.id AND ml.channel = 'WHATSAPP' AND ml.success = true
                ), '-infinity'::timestamptz) AS whatsapp_delivery_issue,
+               COALESCE((
+                 SELECT MAX(created_at) FROM message_logs ml
+                 WHERE ml.student_id = students.id AND ml.channel = 'SMS' AND ml.success = false
+               ), '-infinity'::timestamptz) >
+               COALESCE((
+                 SELECT MAX(created_at) FROM message_logs ml
+                 WHERE ml.student_id = students.id AND ml.channel = 'SMS' AND ml.success = true
+               ), '-infinity'::timestamptz) AS sms_delivery_issue,
                created_at, updated_at, status, user_owner_id
         FROM students
         WHERE student_id = $1 AND user_owner_id = $2
@@ -146,9 +163,15 @@ func (r *sqlRepository) GetDeliverySummary(ctx context.Context, id, userOwnerID
 		return nil, err
 	}
 
+	sms, err := r.latestDeliveryByChannel(ctx, id, userOwnerID, "SMS")
+	if err != nil {
+		return nil, err
+	}
+
 	return &DeliverySummary{
 		Email:    email,
 		WhatsApp: whatsApp,
+		SMS:      sms,
 	}, nil
 }
 
@@ -171,6 +194,14 @@ func buildFilteredStudentsQuery(filters map[string]string) (string, []any) {
 		         SELECT MAX(created_at) FROM message_logs ml
 		         WHERE ml.student_id = s.id AND ml.channel = 'WHATSAPP' AND ml.success = true
 		       ), '-infinity'::timestamptz) AS whatsapp_delivery_issue,
+		       COALESCE((
+		         SELECT MAX(created_at) FROM message_logs ml
+		         WHERE ml.student_id = s.id AND ml.channel = 'SMS' AND ml.success = false
+		       ), '-infinity'::timestamptz) >
+		       COALESCE((
+		         SELECT MAX(created_at) FROM message_logs ml
+		         WHERE ml.student_id = s.id AND ml.channel = 'SMS' AND ml.success = true
+		       ), '-infinity'::timestamptz) AS sms_delivery_issue,
 		       s.created_at, s.updated_at, s.status, s.user_owner_id
 		FROM students s
 	`
@@ -225,6 +256,14 @@ func (r *sqlRepository) FindByIDs(ctx context.Context, userOwnerID string, stude
 			         SELECT MAX(created_at) FROM message_logs ml
 			         WHERE ml.student_id = students.id AND ml.channel = 'WHATSAPP' AND ml.success = true
 			       ), '-infinity'::timestamptz) AS whatsapp_delivery_issue,
+			       COALESCE((
+			         SELECT MAX(created_at) FROM message_logs ml
+			         WHERE ml.student_id = students.id AND ml.channel = 'SMS' AND ml.success = false
+			       ), '-infinity'::timestamptz) >
+			       COALESCE((
+			         SELECT MAX(created_at) FROM message_logs ml
+			         WHERE ml.student_id = students.id AND ml.channel = 'SMS' AND ml.success = true
+			       ), '-infinity'::timestamptz) AS sms_delivery_issue,
 			       created_at, updated_at, status, user_owner_id
 			FROM students
 			WHERE user_owner_id = $1 AND id IN (%s)
@@ -247,6 +286,59 @@ func (r *sqlRepository) FindByIDs(ctx context.Context, userOwnerID string, stude
 	return students, nil
 }
 
+// Busca os IDs dos estudantes alcançados pelo filtro de destinatários
+// Se o filtro não tiver disciplina, curso ou campus, retorna nil
+func (r *sqlRepository) FindIDsByRecipientFilter(ctx context.Context, userOwnerID string, filter RecipientFilter) ([]string, error) {
+	if !filter.HasTargets() {
+		return nil, nil
+	}
+
+	query := `
+		SELECT DISTINCT s.id
+		FROM students s
+		JOIN enrollments e ON e.student_id = s.id
+		JOIN disciplines d ON d.id = e.discipline_id
+		JOIN programs p ON p.id = d.program_id
+		JOIN campuses ca ON ca.id = p.campus_id
+		WHERE s.user_owner_id = $1
+		  AND ca.user_owner_id = $1
+		  AND (d.id = ANY($2::uuid[]) OR p.id = ANY($3::uuid[]) OR ca.id = ANY($4::uuid[]))
+		  AND (cardinality($5::varchar[]) = 0 OR s.status = ANY($5::varchar[]))
+	`
+	rows, err := r.db.QueryContext(ctx, query,
+		userOwnerID,
+		pq.Array(nonNilStrings(filter.DisciplineIDs)),
+		pq.Array(nonNilStrings(filter.ProgramIDs)),
+		pq.Array(nonNilStrings(filter.CampusIDs)),
+		pq.Array(statusStrings(filter.Statuses)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// pq.Array envia slices nil como NULL; as comparações com ANY precisam de arrays vazios.
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
 // Atualiza um estudante
 func (r *sqlRepository) Update(ctx context.Context, id string, fields map[string]any) error {
 	err := database.Update(ctx, r.db, "students", id, fields)
@@ -291,6 +383,7 @@ func (r *sqlRepository) latestDeliveryByChannel(ctx context.Context, id, userOwn
 		SELECT
 			ml.channel,
 			ml.success,
+			ml.delivery_status,
 			ml.error_text,
 			ml.sender_type,
 			ml.sender_provider,
@@ -314,6 +407,7 @@ func (r *sqlRepository) latestDeliveryByChannel(ctx context.Context, id, userOwn
 	err := r.db.QueryRowContext(ctx, query, id, userOwnerID, channel).Scan(
 		&snapshot.Channel,
 		&snapshot.Success,
+		&snapshot.Status,
 		&errorText,
 		&senderType,
 		&senderProvider,
@@ -369,6 +463,7 @@ func scanStudent(scanner rowScanner) (*Student, error) {
 		&student.Consent,
 		&student.EmailDeliveryIssue,
 		&student.WhatsAppDeliveryIssue,
+		&student.SmsDeliveryIssue,
 		&student.CreatedAt,
 		&student.UpdatedAt,
 		&student.Status,
@@ -0,0 +1,90 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// deliveryLogDriver responde à consulta da última entrega com uma linha por canal de logsByChannel.
+type deliveryLogDriver struct{}
+
+type deliveryLogConn struct{}
+
+type deliveryLogRows struct {
+	values []driver.Value
+	done   bool
+}
+
+var logsByChannel = map[string][]driver.Value{
+	"EMAIL": {"EMAIL", true, "SENT", nil, "SMTP", nil, "prof@example.com", time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)},
+	"SMS":   {"SMS", false, "FAILED", "número inválido", "SMS", "gateway", "+5511988887777", time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)},
+}
+
+func (deliveryLogDriver) Open(string) (driver.Conn, error) { return deliveryLogConn{}, nil }
+
+func (deliveryLogConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (deliveryLogConn) Close() error                        { return nil }
+func (deliveryLogConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (deliveryLogConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	channel, _ := args[2].Value.(string)
+	return &deliveryLogRows{values: logsByChannel[channel]}, nil
+}
+
+func (r *deliveryLogRows) Columns() []string {
+	return []string{"channel", "success", "delivery_status", "error_text", "sender_type", "sender_provider", "sender_address", "created_at"}
+}
+
+func (r *deliveryLogRows) Close() error { return nil }
+
+func (r *deliveryLogRows) Next(dest []driver.Value) error {
+	if r.done || r.values == nil {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.values)
+	return nil
+}
+
+func TestGetDeliverySummaryIncludesTheLatestSmsDelivery(t *testing.T) {
+	sql.Register("student-delivery-logs", deliveryLogDriver{})
+	db, err := sql.Open("student-delivery-logs", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repo := &sqlRepository{db: db}
+
+	summary, err := repo.GetDeliverySummary(context.Background(), "student-1", "user-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if summary.Email == nil || summary.Email.Status != "SENT" {
+		t.Fatalf("expected email snapshot, got %#v", summary.Email)
+	}
+	if summary.WhatsApp != nil {
+		t.Fatalf("expected no whatsapp snapshot, got %#v", summary.WhatsApp)
+	}
+	if summary.SMS == nil {
+		t.Fatal("expected sms snapshot")
+	}
+	if summary.SMS.Success || summary.SMS.Status != "FAILED" {
+		t.Fatalf("unexpected sms snapshot: %#v", summary.SMS)
+	}
+	if summary.SMS.ErrorText == nil || *summary.SMS.ErrorText != "número inválido" {
+		t.Fatalf("unexpected sms error text: %v", summary.SMS.ErrorText)
+	}
+}
+
+func TestFilteredStudentsQueryFlagsSmsDeliveryIssues(t *testing.T) {
+	query, _ := buildFilteredStudentsQuery(map[string]string{"user": "user-1"})
+
+	if !strings.Contains(query, "ml.channel = 'SMS' AND ml.success = false") || !strings.Contains(query, "AS sms_delivery_issue") {
+		t.Fatalf("expected sms delivery issue in query, got %s", query)
+	}
+}
@@ -0,0 +1,95 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ThalysSilva/unicast-backend/internal/smtp"
+	"github.com/ThalysSilva/unicast-backend/internal/user"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
+)
+
+var NoSmtpForAlert = customerror.Make("Nenhum SMTP disponível para enviar o aviso de queda: contas com senha só funcionam com o professor logado. Conecte uma conta Google/Microsoft ou um relay SMTP sem autenticação", http.StatusConflict, errors.New("noSmtpForAlert"))
+
+type emailNotifier struct {
+	userRepository user.Repository
+	smtpRepository smtp.Repository
+	smtpService    smtp.Service
+}
+
+// NewEmailNotifier avisa a queda por email, a partir de um SMTP do próprio professor.
+func NewEmailNotifier(userRepo user.Repository, smtpRepo smtp.Repository, smtpService smtp.Service) DisconnectNotifier {
+	return &emailNotifier{userRepository: userRepo, smtpRepository: smtpRepo, smtpService: smtpService}
+}
+
+func (n *emailNotifier) InstanceDisconnected(ctx context.Context, instance *Instance) error {
+	owner, err := n.userRepository.FindByID(ctx, instance.UserID)
+	if err != nil {
+		return customerror.Trace("InstanceDisconnected", err)
+	}
+	if owner == nil {
+		return customerror.Trace("InstanceDisconnected", UserNotFound)
+	}
+	instances, err := n.smtpRepository.GetInstances(ctx, instance.UserID)
+	if err != nil {
+		return customerror.Trace("InstanceDisconnected", err)
+	}
+	smtpInstance := alertSmtpInstance(instances)
+	if smtpInstance == nil {
+		return customerror.Trace("InstanceDisconnected", NoSmtpForAlert)
+	}
+
+	data := &mailer.MailerData{
+		From:        smtpInstance.Email,
+		To:          []string{owner.Email},
+		Subject:     fmt.Sprintf("WhatsApp desconectado: %s", instance.Phone),
+		Body:        disconnectAlertBody(owner.Name, instance.Phone),
+		ContentType: mailer.TextPlain,
+	}
+	if smtpInstance.AuthMode == smtp.AuthModeOAuth {
+		if err := n.smtpService.SendOAuthEmail(ctx, smtpInstance, data); err != nil {
+			return customerror.Trace("InstanceDisconnected", err)
+		}
+		return nil
+	}
+
+	sender := mailer.NewEmailSender(smtpInstance.Authentication(""))
+	if err := sender.SetData(data); err != nil {
+		return customerror.Trace("InstanceDisconnected", err)
+	}
+	result, err := sender.SendEmails(ctx)
+	if err != nil {
+		return customerror.Trace("InstanceDisconnected", err)
+	}
+	if recipient := result.Recipients[0]; recipient.Status == mailer.RecipientRejected {
+		return customerror.Trace("InstanceDisconnected", recipient.Err)
+	}
+	return nil
+}
+
+// alertSmtpInstance escolhe um SMTP que funciona em segundo plano: a senha das instâncias com senha só
+// abre com o JWE do professor, que o monitor não tem. OAuth vem primeiro.
+func alertSmtpInstance(instances []*smtp.Instance) *smtp.Instance {
+	var relay *smtp.Instance
+	for _, instance := range instances {
+		if instance.AuthMode == smtp.AuthModeOAuth {
+			return instance
+		}
+		if relay == nil && !instance.UsesPassword() {
+			relay = instance
+		}
+	}
+	return relay
+}
+
+func disconnectAlertBody(name, phone string) string {
+	return fmt.Sprintf(`Olá, %s.
+
+O WhatsApp do número %s foi desconectado do Unicast. Enquanto ele estiver desconectado, os envios por WhatsApp desta instância falham.
+
+Para reconectar, abra as integrações no Unicast e leia o novo QR code com o celular.
+`, name, phone)
+}
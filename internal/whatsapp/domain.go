@@ -3,7 +3,6 @@ package whatsapp
 import (
 	"context"
 	"database/sql"
-	"encoding/base64"
 	"errors"
 	"mime"
 	"net/http"
@@ -22,42 +21,26 @@ type Instance struct {
 	UpdatedAt        time.Time `json:"-"`
 	UserID           string    `json:"-"`
 	InstanceName     string    `json:"instanceName"`
+	// ConnectionStatusUpdatedAt é quando o estado mudou pela última vez; nil antes da primeira mudança registrada.
+	ConnectionStatusUpdatedAt *time.Time `json:"connectionStatusUpdatedAt"`
+	// DisconnectAlertError explica por que o último aviso de queda por email não foi enviado; nil quando foi
+	// enviado ou depois que a instância reconecta.
+	DisconnectAlertError *string `json:"disconnectAlertError"`
 }
 
-// SendText envia uma mensagem de texto via Evolution API usando a instância informada.
-func SendText(instanceName, number, text string) error {
-	return sendEvolutionText(instanceName, number, text)
-}
-
-// SendMedia envia um attachment via Evolution API (media pode ser URL ou base64).
-func SendMedia(instanceName, number string, fileName string, data []byte, caption string) (*sendMediaResponse, error) {
-	mime := detectMediaMIME(data, fileName)
-	mediaType := inferMediaType(mime)
-	encoded := base64.StdEncoding.EncodeToString(data)
-
-	return sendEvolutionMedia(instanceName, sendMediaPayload{
-		Number:    evolutionRecipientJID(number),
-		Media:     encoded,
-		MediaType: mediaType,
-		MimeType:  mime,
-		FileName:  fileName,
-		Caption:   caption,
-	})
-}
+// Estados de conexão reportados pela Evolution em connectionState.
+const (
+	ConnectionOpen       = "open"
+	ConnectionConnecting = "connecting"
+	ConnectionClose      = "close"
+)
 
-// SendMediaURL envia um attachment hospedado por URL via Evolution API.
-func SendMediaURL(instanceName, number string, mediaURL string, fileName string, caption string) (*sendMediaResponse, error) {
-	mime := detectMediaMIME(nil, fileName)
-
-	return sendEvolutionMedia(instanceName, sendMediaPayload{
-		Number:    evolutionRecipientJID(number),
-		Media:     mediaURL,
-		MediaType: inferMediaType(mime),
-		MimeType:  mime,
-		FileName:  fileName,
-		Caption:   caption,
-	})
-}
+// Origens de uma mudança de estado gravada em whatsapp_connection_state_changes.
+const (
+	StateSourceMonitor = "monitor"
+	StateSourceStatus  = "status"
+	StateSourceSend    = "send"
+)
 
 // NormalizeNumber sanitiza e retorna dígitos puros com DDI, no formato aceito pela Evolution.
 // Se o número for muito curto, retorna erro.
@@ -113,8 +96,17 @@ type Repository interface {
 	Create(ctx context.Context, phone, userID, instanceID string) error
 	FindByID(ctx context.Context, id string) (*Instance, error)
 	FindByPhoneAndUserId(ctx context.Context, phone, userId string) (*Instance, error)
+	// FindByInstanceName busca a instância pelo nome usado na Evolution, que chega nos webhooks.
+	FindByInstanceName(ctx context.Context, instanceName string) (*Instance, error)
 	FindAllByUserId(ctx context.Context, userId string) ([]*Instance, error)
+	// FindAll lista as instâncias de todos os usuários, para o monitor de conexão.
+	FindAll(ctx context.Context) ([]*Instance, error)
 	Update(ctx context.Context, id string, fields map[string]any) error
+	// RecordConnectionState grava o estado e, se ele mudou, a transição com o horário e a origem.
+	// Devolve o estado anterior e se houve mudança.
+	RecordConnectionState(ctx context.Context, id, state, source string, at time.Time) (previous string, changed bool, err error)
+	// RecordDisconnectAlert grava a falha do aviso de queda, para a tela de integrações mostrá-la; nil limpa.
+	RecordDisconnectAlert(ctx context.Context, id string, alertError *string) error
 	Delete(ctx context.Context, id string) error
 }
 
@@ -2,6 +2,8 @@ package whatsapp
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -86,7 +88,8 @@ type sendMediaPayload struct {
 	Caption   string `json:"caption,omitempty"`
 }
 
-type sendMediaResponse struct {
+// SendMediaResponse é a resposta da Evolution ao envio de mídia.
+type SendMediaResponse struct {
 	Status  string          `json:"status"`
 	Message json.RawMessage `json:"message"`
 	Key     struct {
@@ -97,7 +100,8 @@ type sendMediaResponse struct {
 	MessageTimestamp json.RawMessage `json:"messageTimestamp"`
 }
 
-type connectResponse struct {
+// ConnectResponse traz o QR code ou o código de pareamento devolvido ao conectar uma instância.
+type ConnectResponse struct {
 	Status      string `json:"status"`
 	Message     string `json:"message"`
 	PairingCode string `json:"pairingCode"`
@@ -117,23 +121,68 @@ type statusResponse struct {
 	} `json:"instance"`
 }
 
-var jsonFunc = json.Marshal
-var cachedConfig *env.Config
+// fetchedInstance cobre o fetchInstances da v2 (ownerJid na raiz) e da v1 (owner dentro de instance).
+type fetchedInstance struct {
+	OwnerJid string `json:"ownerJid"`
+	Instance struct {
+		Owner string `json:"owner"`
+	} `json:"instance"`
+}
 
-func httpClientEvolution[responseType any](method, uri string, payload *bytes.Buffer) (*responseType, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	if cachedConfig == nil {
-		cfg, err := env.Load()
-		if err != nil {
-			return nil, customerror.Trace("HTTPClientEvolution", err)
-		}
-		cachedConfig = cfg
+type whatsappNumbersPayload struct {
+	Numbers []string `json:"numbers"`
+}
+
+type whatsappNumberResponse struct {
+	Exists bool   `json:"exists"`
+	Jid    string `json:"jid"`
+	Number string `json:"number"`
+}
+
+// EvolutionClient reúne as chamadas à Evolution API usadas pelo backend. Os serviços recebem o cliente
+// pelo construtor; os testes e o cmd/fake-evolution apontam para o servidor falso de fakeevolution.
+type EvolutionClient interface {
+	CreateInstance(ctx context.Context, phone, instanceName string, qrCode bool) (createdName, qrCodeString string, err error)
+	DeleteInstance(ctx context.Context, instanceName string) error
+	ConnectInstance(ctx context.Context, instanceName, number string) (*ConnectResponse, error)
+	ConnectionState(ctx context.Context, instanceName string) (string, error)
+	// OwnerNumber devolve o número (só dígitos) do celular pareado; vazio se a instância não estiver pareada.
+	OwnerNumber(ctx context.Context, instanceName string) (string, error)
+	LogoutInstance(ctx context.Context, instanceName string) error
+	RestartInstance(ctx context.Context, instanceName string) error
+	// SendText devolve o ID da mensagem, usado para casar os recibos de entrega/leitura do webhook.
+	SendText(ctx context.Context, instanceName, number, text string) (string, error)
+	SendMedia(ctx context.Context, instanceName, number, fileName string, data []byte, caption string) (*SendMediaResponse, error)
+	// CheckNumbers confere quais números têm WhatsApp; veja numberCache.
+	CheckNumbers(ctx context.Context, instanceName string, numbers []string) (map[string]NumberCheck, error)
+}
+
+// evolutionTimeout limita cada chamada à Evolution quando o contexto não tem prazo menor.
+const evolutionTimeout = 30 * time.Second
+
+type evolutionClient struct {
+	config     env.Evolution
+	httpClient *http.Client
+	numbers    *numberCache
+}
+
+// NewEvolutionClient cria o cliente da Evolution. httpClient nil usa um cliente próprio, compartilhado
+// por todas as chamadas (e pelas conexões do transporte).
+func NewEvolutionClient(config env.Evolution, httpClient *http.Client) EvolutionClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: evolutionTimeout}
 	}
+	client := &evolutionClient{config: config, httpClient: httpClient}
+	client.numbers = newNumberCache(client.lookupNumbers)
+	return client
+}
+
+var jsonFunc = json.Marshal
+
+func httpClientEvolution[responseType any](ctx context.Context, c *evolutionClient, method, uri string, payload *bytes.Buffer) (*responseType, error) {
 	evolutionURL, err := buildEvolutionURL(
-		cachedConfig.Evolution.Host,
-		cachedConfig.Evolution.Port,
+		c.config.Host,
+		c.config.Port,
 		uri,
 	)
 	if err != nil {
@@ -141,15 +190,14 @@ func httpClientEvolution[responseType any](method, uri string, payload *bytes.Bu
 		return nil, customerror.Trace("HTTPClientEvolution", err)
 	}
 
-	evolutionApiKey := cachedConfig.Evolution.APIKey
-	req, err := http.NewRequest(method, evolutionURL, payload)
+	req, err := http.NewRequestWithContext(ctx, method, evolutionURL, payload)
 	if err != nil {
 		err := customerror.Make("Falha ao criar a requisição", http.StatusInternalServerError, err)
 		return nil, customerror.Trace("HTTPClientEvolution", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Add("apikey", evolutionApiKey)
-	resp, err := client.Do(req)
+	req.Header.Add("apikey", c.config.APIKey)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		err := customerror.Make("Falha ao fazer a requisição", http.StatusBadGateway, err)
 		return nil, customerror.Trace("HTTPClientEvolution", err)
@@ -216,7 +264,7 @@ func compactResponseBody(body []byte) string {
 	return text
 }
 
-func createEvolutionInstance(phone, instanceName string, qrCode bool) (createdName, qrCodeString string, err error) {
+func (c *evolutionClient) CreateInstance(ctx context.Context, phone, instanceName string, qrCode bool) (createdName, qrCodeString string, err error) {
 	jsonData, err := jsonFunc(newEvolutionPayload{
 		Phone:        phone,
 		InstanceName: instanceName,
@@ -227,7 +275,7 @@ func createEvolutionInstance(phone, instanceName string, qrCode bool) (createdNa
 		return "", "", customerror.Make("createEvolutionInstance: Falha ao codificar o payload", http.StatusInternalServerError, err)
 	}
 	payload := bytes.NewBuffer(jsonData)
-	resp, err := httpClientEvolution[newEvolutionInstanceReturn]("POST", "/instance/create", payload)
+	resp, err := httpClientEvolution[newEvolutionInstanceReturn](ctx, c, "POST", "/instance/create", payload)
 	if err != nil {
 		return "", "", customerror.Trace("createEvolutionInstance", err)
 	}
@@ -239,38 +287,46 @@ func createEvolutionInstance(phone, instanceName string, qrCode bool) (createdNa
 	return createdName, resp.Qrcode.Code, nil
 }
 
-// sendEvolutionText envia uma mensagem de texto simples usando a Evolution API.
-func sendEvolutionText(instanceName, number, text string) error {
+// SendText envia uma mensagem de texto simples usando a Evolution API e devolve o ID da mensagem.
+func (c *evolutionClient) SendText(ctx context.Context, instanceName, number, text string) (string, error) {
 	body, err := jsonFunc(sendTextPayload{
 		Number: evolutionRecipientJID(number),
 		Text:   text,
 	})
 	if err != nil {
-		return customerror.Trace("sendEvolutionText: marshal", err)
+		return "", customerror.Trace("sendEvolutionText: marshal", err)
 	}
 
 	payload := bytes.NewBuffer(body)
-	resp, err := httpClientEvolution[sendTextResponse]("POST", "/message/sendText/"+instanceName, payload)
+	resp, err := httpClientEvolution[sendTextResponse](ctx, c, "POST", "/message/sendText/"+instanceName, payload)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if resp == nil {
-		return customerror.Make("resposta vazia da Evolution API", http.StatusBadGateway, fmt.Errorf("empty response"))
+		return "", customerror.Make("resposta vazia da Evolution API", http.StatusBadGateway, fmt.Errorf("empty response"))
 	}
 
-	return nil
+	return resp.Key.ID, nil
 }
 
-// sendEvolutionMedia envia mídia/base64 ou URL via Evolution API.
-func sendEvolutionMedia(instanceName string, payload sendMediaPayload) (*sendMediaResponse, error) {
-	body, err := jsonFunc(payload)
+// SendMedia envia um attachment em base64 via Evolution API.
+func (c *evolutionClient) SendMedia(ctx context.Context, instanceName, number, fileName string, data []byte, caption string) (*SendMediaResponse, error) {
+	mime := detectMediaMIME(data, fileName)
+	body, err := jsonFunc(sendMediaPayload{
+		Number:    evolutionRecipientJID(number),
+		Media:     base64.StdEncoding.EncodeToString(data),
+		MediaType: inferMediaType(mime),
+		MimeType:  mime,
+		FileName:  fileName,
+		Caption:   caption,
+	})
 	if err != nil {
 		return nil, customerror.Trace("sendEvolutionMedia: marshal", err)
 	}
 
 	buf := bytes.NewBuffer(body)
-	resp, err := httpClientEvolution[sendMediaResponse]("POST", "/message/sendMedia/"+instanceName, buf)
+	resp, err := httpClientEvolution[SendMediaResponse](ctx, c, "POST", "/message/sendMedia/"+instanceName, buf)
 	if err != nil {
 		return nil, err
 	}
@@ -282,21 +338,44 @@ func sendEvolutionMedia(instanceName string, payload sendMediaPayload) (*sendMed
 	return resp, nil
 }
 
-// deleteEvolutionInstance remove uma instância na Evolution API.
-func deleteEvolutionInstance(instanceName string) error {
+func (c *evolutionClient) CheckNumbers(ctx context.Context, instanceName string, numbers []string) (map[string]NumberCheck, error) {
+	return c.numbers.check(ctx, instanceName, numbers)
+}
+
+// lookupNumbers consulta na Evolution quais números têm conta no WhatsApp.
+func (c *evolutionClient) lookupNumbers(ctx context.Context, instanceName string, numbers []string) ([]whatsappNumberResponse, error) {
+	body, err := jsonFunc(whatsappNumbersPayload{Numbers: numbers})
+	if err != nil {
+		return nil, customerror.Trace("checkEvolutionNumbers: marshal", err)
+	}
+
+	payload := bytes.NewBuffer(body)
+	encodedName := url.PathEscape(instanceName)
+	resp, err := httpClientEvolution[[]whatsappNumberResponse](ctx, c, "POST", fmt.Sprintf("/chat/whatsappNumbers/%s", encodedName), payload)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, customerror.Make("resposta vazia da Evolution API (whatsappNumbers)", http.StatusBadGateway, fmt.Errorf("empty response"))
+	}
+	return *resp, nil
+}
+
+// DeleteInstance remove uma instância na Evolution API.
+func (c *evolutionClient) DeleteInstance(ctx context.Context, instanceName string) error {
 	payload := bytes.NewBuffer(nil)
 	encodedName := url.PathEscape(instanceName)
-	_, err := httpClientEvolution[deleteInstanceResponse]("DELETE", fmt.Sprintf("/instance/delete/%s", encodedName), payload)
+	_, err := httpClientEvolution[deleteInstanceResponse](ctx, c, "DELETE", fmt.Sprintf("/instance/delete/%s", encodedName), payload)
 	return err
 }
 
-// connectEvolutionInstance dispara a conexão/pareamento (precisa do número).
-func connectEvolutionInstance(instanceName, number string) (*connectResponse, error) {
+// ConnectInstance dispara a conexão/pareamento (precisa do número).
+func (c *evolutionClient) ConnectInstance(ctx context.Context, instanceName, number string) (*ConnectResponse, error) {
 	payload := bytes.NewBuffer(nil)
 	encodedName := url.PathEscape(instanceName)
-	resp, err := httpClientEvolution[connectResponse]("GET", fmt.Sprintf("/instance/connect/%s", encodedName), payload)
+	resp, err := httpClientEvolution[ConnectResponse](ctx, c, "GET", fmt.Sprintf("/instance/connect/%s", encodedName), payload)
 	if err != nil && number != "" {
-		resp, err = httpClientEvolution[connectResponse]("GET", fmt.Sprintf("/instance/connect/%s?number=%s", encodedName, number), payload)
+		resp, err = httpClientEvolution[ConnectResponse](ctx, c, "GET", fmt.Sprintf("/instance/connect/%s?number=%s", encodedName, number), payload)
 		if err != nil {
 			return nil, err
 		}
@@ -319,11 +398,11 @@ func connectEvolutionInstance(instanceName, number string) (*connectResponse, er
 	return resp, nil
 }
 
-// connectionStateEvolution retorna o status da instância.
-func connectionStateEvolution(instanceName string) (string, error) {
+// ConnectionState retorna o status da instância.
+func (c *evolutionClient) ConnectionState(ctx context.Context, instanceName string) (string, error) {
 	payload := bytes.NewBuffer(nil)
 	encodedName := url.PathEscape(instanceName)
-	resp, err := httpClientEvolution[statusResponse]("GET", fmt.Sprintf("/instance/connectionState/%s", encodedName), payload)
+	resp, err := httpClientEvolution[statusResponse](ctx, c, "GET", fmt.Sprintf("/instance/connectionState/%s", encodedName), payload)
 	if err != nil {
 		return "", err
 	}
@@ -336,16 +415,34 @@ func connectionStateEvolution(instanceName string) (string, error) {
 	return resp.Instance.Status, nil
 }
 
-func logoutEvolutionInstance(instanceName string) error {
+// OwnerNumber consulta o dono da instância em fetchInstances; o connectionState não traz o número.
+func (c *evolutionClient) OwnerNumber(ctx context.Context, instanceName string) (string, error) {
+	payload := bytes.NewBuffer(nil)
+	uri := "/instance/fetchInstances?instanceName=" + url.QueryEscape(instanceName)
+	resp, err := httpClientEvolution[[]fetchedInstance](ctx, c, "GET", uri, payload)
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || len(*resp) == 0 {
+		return "", nil
+	}
+	owner := (*resp)[0].OwnerJid
+	if owner == "" {
+		owner = (*resp)[0].Instance.Owner
+	}
+	return digitsOnly(strings.SplitN(owner, "@", 2)[0]), nil
+}
+
+func (c *evolutionClient) LogoutInstance(ctx context.Context, instanceName string) error {
 	payload := bytes.NewBuffer(nil)
 	encodedName := url.PathEscape(instanceName)
-	_, err := httpClientEvolution[deleteInstanceResponse]("DELETE", fmt.Sprintf("/instance/logout/%s", encodedName), payload)
+	_, err := httpClientEvolution[deleteInstanceResponse](ctx, c, "DELETE", fmt.Sprintf("/instance/logout/%s", encodedName), payload)
 	return err
 }
 
-func restartEvolutionInstance(instanceName string) error {
+func (c *evolutionClient) RestartInstance(ctx context.Context, instanceName string) error {
 	payload := bytes.NewBuffer(nil)
 	encodedName := url.PathEscape(instanceName)
-	_, err := httpClientEvolution[deleteInstanceResponse]("POST", fmt.Sprintf("/instance/restart/%s", encodedName), payload)
+	_, err := httpClientEvolution[deleteInstanceResponse](ctx, c, "POST", fmt.Sprintf("/instance/restart/%s", encodedName), payload)
 	return err
 }
@@ -1,6 +1,7 @@
 package whatsapp
 
 import (
+	"context"
 	"encoding/json"
 	"net"
 	"net/http"
@@ -32,11 +33,12 @@ func TestSendEvolutionTextUsesInstancePathAndJIDPayload(t *testing.T) {
 	}))
 	defer server.Close()
 
-	setEvolutionTestConfig(t, server.URL, "test-api-key")
+	client := newTestEvolutionClient(t, server.URL, "test-api-key")
 
-	err := sendEvolutionText("professor@example.com:5500000000000", "+5500000000001", "enviando mensagem de teste")
+	messageID, err := client.SendText(context.Background(), "professor@example.com:5500000000000", "+5500000000001", "enviando mensagem de teste")
 
 	require.NoError(t, err)
+	assert.Equal(t, "3EB0313C9EA80A7ED95190", messageID)
 	assert.Equal(t, "/message/sendText/professor@example.com:5500000000000", gotPath)
 	assert.Equal(t, "test-api-key", gotAPIKey)
 	assert.Equal(t, sendTextPayload{
@@ -119,9 +121,10 @@ func TestSendMediaUsesEvolutionMediaContract(t *testing.T) {
 	}))
 	defer server.Close()
 
-	setEvolutionTestConfig(t, server.URL, "test-api-key")
+	client := newTestEvolutionClient(t, server.URL, "test-api-key")
 
-	resp, err := SendMedia(
+	resp, err := client.SendMedia(
+		context.Background(),
 		"professor@example.com:5500000000000",
 		"+5500000000001",
 		"3.webp",
@@ -162,9 +165,10 @@ func TestSendMediaUsesEvolutionVideoContract(t *testing.T) {
 	}))
 	defer server.Close()
 
-	setEvolutionTestConfig(t, server.URL, "test-api-key")
+	client := newTestEvolutionClient(t, server.URL, "test-api-key")
 
-	resp, err := SendMedia(
+	resp, err := client.SendMedia(
+		context.Background(),
 		"professor@example.com:5500000000000",
 		"+5500000000001",
 		"Clair_Obscure_Expedition_.mp4",
@@ -205,9 +209,10 @@ func TestSendMediaUsesEvolutionDocumentContract(t *testing.T) {
 	}))
 	defer server.Close()
 
-	setEvolutionTestConfig(t, server.URL, "test-api-key")
+	client := newTestEvolutionClient(t, server.URL, "test-api-key")
 
-	resp, err := SendMedia(
+	resp, err := client.SendMedia(
+		context.Background(),
 		"professor@example.com:5500000000000",
 		"+5500000000001",
 		"ML-INFORME-RENDIMENTOS-2025 (2).pdf",
@@ -236,34 +241,27 @@ func TestSendEvolutionTextIncludesEvolutionBodyOnHTTPError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	setEvolutionTestConfig(t, server.URL, "test-api-key")
+	client := newTestEvolutionClient(t, server.URL, "test-api-key")
 
-	err := sendEvolutionText("professor@example.com:5500000000000", "+5500000000001", "enviando mensagem de teste")
+	_, err := client.SendText(context.Background(), "professor@example.com:5500000000000", "+5500000000001", "enviando mensagem de teste")
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "Evolution API retornou status 403 em POST /message/sendText/professor@example.com:5500000000000")
 	assert.Contains(t, err.Error(), `body="{\"response\":{\"message\":[\"Unauthorized\"]}}"`)
 }
 
-func setEvolutionTestConfig(t *testing.T, rawURL, apiKey string) {
+func newTestEvolutionClient(t *testing.T, rawURL, apiKey string) EvolutionClient {
 	t.Helper()
 
-	previousConfig := cachedConfig
-	t.Cleanup(func() {
-		cachedConfig = previousConfig
-	})
-
 	parsed, err := url.Parse(rawURL)
 	require.NoError(t, err)
 
 	host, port, err := net.SplitHostPort(parsed.Host)
 	require.NoError(t, err)
 
-	cachedConfig = &env.Config{
-		Evolution: env.Evolution{
-			Host:   host,
-			Port:   port,
-			APIKey: apiKey,
-		},
-	}
+	return NewEvolutionClient(env.Evolution{
+		Host:   host,
+		Port:   port,
+		APIKey: apiKey,
+	}, nil)
 }
@@ -0,0 +1,611 @@
+// Package fakeevolution simula a Evolution API em memória para desenvolver e testar o fluxo de WhatsApp sem
+// rede: ciclo de vida das instâncias, QR codes, estados de conexão, envios, recibos e respostas pelo webhook.
+// Roda como servidor em cmd/fake-evolution e dentro dos testes com httptest.
+package fakeevolution
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Estados de conexão, com os mesmos nomes da Evolution.
+const (
+	StateConnecting = "connecting"
+	StateOpen       = "open"
+	StateClose      = "close"
+)
+
+type Options struct {
+	// APIKey é exigida no header apikey; vazia aceita qualquer chave.
+	APIKey string
+	// PairAfter conecta a instância sozinha, como se o QR fosse lido, depois de cada connect.
+	// Zero deixa a conexão para Pair ou POST /fake/pair/{instance}.
+	PairAfter time.Duration
+	// WebhookURL recebe um MESSAGES_UPDATE com DELIVERY_ACK para cada mensagem enviada e um MESSAGES_UPSERT
+	// para cada resposta simulada; vazia desativa.
+	WebhookURL string
+	// MissingNumbers são os números (só dígitos) sem conta no WhatsApp; os demais existem.
+	MissingNumbers []string
+}
+
+// SentMessage é uma mensagem aceita pelo servidor. Type é "text" ou o mediatype do envio de mídia.
+type SentMessage struct {
+	ID       string `json:"id"`
+	Instance string `json:"instance"`
+	Number   string `json:"number"`
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	FileName string `json:"fileName,omitempty"`
+}
+
+type instance struct {
+	name      string
+	id        string
+	phone     string
+	owner     string
+	state     string
+	qrCount   int
+	pairTimer *time.Timer
+}
+
+type Server struct {
+	opts       Options
+	mux        *http.ServeMux
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	instances map[string]*instance
+	missing   map[string]bool
+	sent      []SentMessage
+	seq       int
+}
+
+func New(opts Options) *Server {
+	s := &Server{
+		opts:       opts,
+		mux:        http.NewServeMux(),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		instances:  map[string]*instance{},
+		missing:    map[string]bool{},
+	}
+	for _, number := range opts.MissingNumbers {
+		s.missing[digitsOnly(number)] = true
+	}
+
+	s.mux.HandleFunc("POST /instance/create", s.createInstance)
+	s.mux.HandleFunc("GET /instance/connect/{instance}", s.connect)
+	s.mux.HandleFunc("GET /instance/connectionState/{instance}", s.connectionState)
+	s.mux.HandleFunc("GET /instance/fetchInstances", s.fetchInstances)
+	s.mux.HandleFunc("DELETE /instance/logout/{instance}", s.logout)
+	s.mux.HandleFunc("POST /instance/restart/{instance}", s.restart)
+	s.mux.HandleFunc("DELETE /instance/delete/{instance}", s.deleteInstance)
+	s.mux.HandleFunc("POST /message/sendText/{instance}", s.sendText)
+	s.mux.HandleFunc("POST /message/sendMedia/{instance}", s.sendMedia)
+	s.mux.HandleFunc("POST /chat/whatsappNumbers/{instance}", s.whatsappNumbers)
+	s.mux.HandleFunc("POST /fake/pair/{instance}", s.pairHandler)
+	s.mux.HandleFunc("POST /fake/disconnect/{instance}", s.disconnectHandler)
+	s.mux.HandleFunc("GET /fake/sent", s.sentHandler)
+	s.mux.HandleFunc("POST /fake/reply/{instance}", s.replyHandler)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.APIKey != "" && r.Header.Get("apikey") != s.opts.APIKey {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Pair simula a leitura do QR: a instância passa a open. Devolve false se a instância não existe.
+func (s *Server) Pair(name string) bool {
+	return s.PairAs(name, "")
+}
+
+// PairAs simula a leitura do QR pelo celular phone, que passa a ser o dono reportado em fetchInstances.
+// phone vazio usa o número informado na criação da instância.
+func (s *Server) PairAs(name, phone string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, ok := s.instances[name]
+	if !ok {
+		return false
+	}
+	inst.stopPairTimer()
+	inst.state = StateOpen
+	inst.owner = inst.phone
+	if phone != "" {
+		inst.owner = digitsOnly(phone)
+	}
+	return true
+}
+
+// Disconnect simula a queda do celular: a instância passa a close sem ser removida.
+func (s *Server) Disconnect(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, ok := s.instances[name]
+	if !ok {
+		return false
+	}
+	inst.stopPairTimer()
+	inst.state = StateClose
+	return true
+}
+
+// State devolve o estado da instância; vazio se ela não existe.
+func (s *Server) State(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inst, ok := s.instances[name]; ok {
+		return inst.state
+	}
+	return ""
+}
+
+// Sent devolve uma cópia das mensagens aceitas, na ordem de envio.
+func (s *Server) Sent() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentMessage{}, s.sent...)
+}
+
+// Reply simula uma mensagem recebida pela instância, de number, e a entrega ao webhook como MESSAGES_UPSERT
+// antes de retornar. Devolve o ID da mensagem, ou false se a instância não existe ou não está conectada.
+func (s *Server) Reply(name, number, pushName, text string) (string, bool) {
+	s.mu.Lock()
+	inst, ok := s.instances[name]
+	if !ok || inst.state != StateOpen {
+		s.mu.Unlock()
+		return "", false
+	}
+	s.seq++
+	id := fmt.Sprintf("FAKEIN%014X", s.seq)
+	s.mu.Unlock()
+
+	s.postWebhook(map[string]any{
+		"event":    "messages.upsert",
+		"instance": name,
+		"data": map[string]any{
+			"key":              map[string]any{"remoteJid": recipientJID(number), "fromMe": false, "id": id},
+			"pushName":         pushName,
+			"message":          map[string]string{"conversation": text},
+			"messageType":      "conversation",
+			"messageTimestamp": time.Now().Unix(),
+		},
+		"date_time": time.Now().UTC().Format(time.RFC3339),
+	})
+	return id, true
+}
+
+func (s *Server) createInstance(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		InstanceName string `json:"instanceName"`
+		Phone        string `json:"phone"`
+		QrCode       bool   `json:"qrcode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.InstanceName == "" {
+		writeError(w, http.StatusBadRequest, "instanceName is required")
+		return
+	}
+
+	s.mu.Lock()
+	if _, exists := s.instances[payload.InstanceName]; exists {
+		s.mu.Unlock()
+		writeError(w, http.StatusForbidden, fmt.Sprintf("This name %q is already in use.", payload.InstanceName))
+		return
+	}
+	s.seq++
+	inst := &instance{
+		name:  payload.InstanceName,
+		id:    fmt.Sprintf("fake-instance-%d", s.seq),
+		phone: digitsOnly(payload.Phone),
+		state: StateClose,
+	}
+	s.instances[inst.name] = inst
+	qr := qrCode{}
+	if payload.QrCode {
+		qr = s.startPairing(inst)
+	}
+	state := inst.state
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"instance": map[string]any{
+			"instanceName": inst.name,
+			"instanceId":   inst.id,
+			"integration":  "WHATSAPP-BAILEYS",
+			"status":       state,
+		},
+		"hash":   "fake-hash-" + inst.id,
+		"qrcode": qr,
+	})
+}
+
+func (s *Server) connect(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	inst, ok := s.instances[r.PathValue("instance")]
+	if !ok {
+		s.mu.Unlock()
+		writeInstanceNotFound(w, r.PathValue("instance"))
+		return
+	}
+	if inst.state == StateOpen {
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{"instance": map[string]string{"instanceName": inst.name, "state": inst.state}})
+		return
+	}
+	qr := s.startPairing(inst)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, qr)
+}
+
+func (s *Server) connectionState(w http.ResponseWriter, r *http.Request) {
+	state := s.State(r.PathValue("instance"))
+	if state == "" {
+		writeInstanceNotFound(w, r.PathValue("instance"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"instance": map[string]string{"instanceName": r.PathValue("instance"), "state": state}})
+}
+
+// fetchInstances responde no formato da v2: ownerJid só existe depois do pareamento.
+func (s *Server) fetchInstances(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("instanceName")
+	s.mu.Lock()
+	list := []map[string]any{}
+	for _, inst := range s.instances {
+		if name != "" && inst.name != name {
+			continue
+		}
+		item := map[string]any{"id": inst.id, "name": inst.name, "connectionStatus": inst.state, "ownerJid": nil}
+		if inst.owner != "" {
+			item["ownerJid"] = recipientJID(inst.owner)
+		}
+		list = append(list, item)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	inst, ok := s.instances[r.PathValue("instance")]
+	if ok && inst.state == StateClose {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("The %q instance is not connected", inst.name))
+		return
+	}
+	if ok {
+		inst.stopPairTimer()
+		inst.state = StateClose
+		inst.owner = ""
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeInstanceNotFound(w, r.PathValue("instance"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "SUCCESS", "error": false, "response": map[string]string{"message": "Instance logged out"}})
+}
+
+// restart reabre a conexão de uma instância já pareada; sem pareamento, volta a esperar o QR.
+func (s *Server) restart(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	inst, ok := s.instances[r.PathValue("instance")]
+	if ok && inst.state != StateOpen {
+		s.startPairing(inst)
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeInstanceNotFound(w, r.PathValue("instance"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"instance": map[string]string{"instanceName": inst.name, "state": s.State(inst.name)}})
+}
+
+func (s *Server) deleteInstance(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	inst, ok := s.instances[r.PathValue("instance")]
+	if ok {
+		inst.stopPairTimer()
+		delete(s.instances, inst.name)
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeInstanceNotFound(w, r.PathValue("instance"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "SUCCESS", "error": false, "response": map[string]string{"message": "Instance deleted"}})
+}
+
+func (s *Server) sendText(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Number string `json:"number"`
+		Text   string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Number == "" {
+		writeError(w, http.StatusBadRequest, "number is required")
+		return
+	}
+	sent, ok := s.accept(w, r.PathValue("instance"), SentMessage{Number: payload.Number, Type: "text", Text: payload.Text})
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"key":              map[string]any{"remoteJid": recipientJID(sent.Number), "fromMe": true, "id": sent.ID},
+		"status":           "PENDING",
+		"message":          map[string]string{"conversation": sent.Text},
+		"messageTimestamp": time.Now().Unix(),
+	})
+}
+
+func (s *Server) sendMedia(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Number    string `json:"number"`
+		Media     string `json:"media"`
+		MediaType string `json:"mediatype"`
+		MimeType  string `json:"mimetype"`
+		FileName  string `json:"fileName"`
+		Caption   string `json:"caption"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Number == "" || payload.Media == "" {
+		writeError(w, http.StatusBadRequest, "number and media are required")
+		return
+	}
+	sent, ok := s.accept(w, r.PathValue("instance"), SentMessage{Number: payload.Number, Type: payload.MediaType, Text: payload.Caption, FileName: payload.FileName})
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"key":              map[string]any{"remoteJid": recipientJID(sent.Number), "fromMe": true, "id": sent.ID},
+		"status":           "PENDING",
+		"message":          map[string]any{payload.MediaType + "Message": map[string]string{"mimetype": payload.MimeType, "fileName": payload.FileName, "caption": payload.Caption}},
+		"messageType":      payload.MediaType + "Message",
+		"messageTimestamp": time.Now().Unix(),
+	})
+}
+
+// accept registra o envio se a instância está conectada e o número tem WhatsApp; caso contrário, responde o erro.
+func (s *Server) accept(w http.ResponseWriter, name string, message SentMessage) (SentMessage, bool) {
+	number := digitsOnly(strings.SplitN(message.Number, "@", 2)[0])
+
+	s.mu.Lock()
+	inst, ok := s.instances[name]
+	switch {
+	case !ok:
+		s.mu.Unlock()
+		writeInstanceNotFound(w, name)
+		return SentMessage{}, false
+	case inst.state != StateOpen:
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "Connection Closed")
+		return SentMessage{}, false
+	case s.missing[number]:
+		s.mu.Unlock()
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"status": http.StatusBadRequest,
+			"error":  "Bad Request",
+			"response": map[string]any{"message": []map[string]any{
+				{"exists": false, "jid": recipientJID(number), "number": number},
+			}},
+		})
+		return SentMessage{}, false
+	}
+	s.seq++
+	message.ID = fmt.Sprintf("FAKE%016X", s.seq)
+	message.Instance = name
+	message.Number = number
+	s.sent = append(s.sent, message)
+	s.mu.Unlock()
+
+	go s.notifyDelivered(name, message)
+	return message, true
+}
+
+func (s *Server) whatsappNumbers(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Numbers []string `json:"numbers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "numbers is required")
+		return
+	}
+
+	s.mu.Lock()
+	inst, ok := s.instances[r.PathValue("instance")]
+	if ok && inst.state != StateOpen {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "Connection Closed")
+		return
+	}
+	responses := make([]map[string]any, 0, len(payload.Numbers))
+	for _, raw := range payload.Numbers {
+		number := digitsOnly(raw)
+		responses = append(responses, map[string]any{"exists": !s.missing[number], "jid": recipientJID(number), "number": number})
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeInstanceNotFound(w, r.PathValue("instance"))
+		return
+	}
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (s *Server) pairHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.Pair(r.PathValue("instance")) {
+		writeInstanceNotFound(w, r.PathValue("instance"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"instance": map[string]string{"instanceName": r.PathValue("instance"), "state": StateOpen}})
+}
+
+func (s *Server) disconnectHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.Disconnect(r.PathValue("instance")) {
+		writeInstanceNotFound(w, r.PathValue("instance"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"instance": map[string]string{"instanceName": r.PathValue("instance"), "state": StateClose}})
+}
+
+func (s *Server) sentHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Sent())
+}
+
+func (s *Server) replyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Number   string `json:"number"`
+		PushName string `json:"pushName"`
+		Text     string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Number == "" {
+		writeError(w, http.StatusBadRequest, "number is required")
+		return
+	}
+	id, ok := s.Reply(r.PathValue("instance"), payload.Number, payload.PushName, payload.Text)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Connection Closed")
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"key": map[string]any{"remoteJid": recipientJID(payload.Number), "fromMe": false, "id": id}})
+}
+
+type qrCode struct {
+	PairingCode *string `json:"pairingCode"`
+	Code        string  `json:"code"`
+	Base64      string  `json:"base64"`
+	Count       int     `json:"count"`
+}
+
+// startPairing gera um novo QR e, com PairAfter, agenda a conexão. Como no WhatsApp, um connect durante
+// o pareamento só troca o QR: a sessão (e o agendamento) continua a mesma. Chamado com s.mu travado.
+func (s *Server) startPairing(inst *instance) qrCode {
+	inst.qrCount++
+	code := fmt.Sprintf("2@fake-%s-%d,%s", inst.id, inst.qrCount, base64.StdEncoding.EncodeToString([]byte(inst.name)))
+	if inst.state == StateConnecting && inst.pairTimer != nil {
+		return qrCode{Code: code, Base64: qrImage(code), Count: inst.qrCount}
+	}
+	inst.stopPairTimer()
+	inst.state = StateConnecting
+	if s.opts.PairAfter > 0 {
+		name := inst.name
+		inst.pairTimer = time.AfterFunc(s.opts.PairAfter, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if current, ok := s.instances[name]; ok && current == inst && inst.state == StateConnecting {
+				inst.state = StateOpen
+				inst.owner = inst.phone
+			}
+		})
+	}
+	return qrCode{Code: code, Base64: qrImage(code), Count: inst.qrCount}
+}
+
+func (inst *instance) stopPairTimer() {
+	if inst.pairTimer != nil {
+		inst.pairTimer.Stop()
+		inst.pairTimer = nil
+	}
+}
+
+// notifyDelivered envia o recibo de entrega no formato v2 do webhook MESSAGES_UPDATE.
+func (s *Server) notifyDelivered(instanceName string, message SentMessage) {
+	s.postWebhook(map[string]any{
+		"event":    "messages.update",
+		"instance": instanceName,
+		"data": map[string]any{
+			"keyId":     message.ID,
+			"remoteJid": recipientJID(message.Number),
+			"fromMe":    true,
+			"status":    "DELIVERY_ACK",
+		},
+		"date_time": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *Server) postWebhook(event map[string]any) {
+	if s.opts.WebhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	resp, err := s.httpClient.Post(s.opts.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("fake-evolution: falha ao enviar webhook: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// qrImage desenha um PNG a partir do código. Não é um QR legível: só ocupa o lugar da imagem no front.
+func qrImage(code string) string {
+	const modules, scale = 25, 8
+	sum := sha256.Sum256([]byte(code))
+	img := image.NewGray(image.Rect(0, 0, modules*scale, modules*scale))
+	for y := 0; y < modules; y++ {
+		for x := 0; x < modules; x++ {
+			bit := (y*modules + x) % (len(sum) * 8)
+			shade := color.Gray{Y: 255}
+			if sum[bit/8]&(1<<(bit%8)) != 0 {
+				shade = color.Gray{Y: 0}
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray(x*scale+dx, y*scale+dy, shade)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return ""
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError responde no formato de erro da Evolution.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"status":   status,
+		"error":    http.StatusText(status),
+		"response": map[string]any{"message": []string{message}},
+	})
+}
+
+func writeInstanceNotFound(w http.ResponseWriter, name string) {
+	writeError(w, http.StatusNotFound, fmt.Sprintf("The %q instance does not exist", name))
+}
+
+func recipientJID(number string) string {
+	return digitsOnly(number) + "@s.whatsapp.net"
+}
+
+func digitsOnly(value string) string {
+	digits := make([]rune, 0, len(value))
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	return string(digits)
+}
@@ -0,0 +1,125 @@
+package whatsapp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp/fakeevolution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvolutionClientAgainstFakeEvolutionLifecycle(t *testing.T) {
+	fake := fakeevolution.New(fakeevolution.Options{APIKey: "test-api-key", MissingNumbers: []string{"5500000000009"}})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := newTestEvolutionClient(t, server.URL, "test-api-key")
+	ctx := context.Background()
+	name := "professor@example.com:5500000000000"
+
+	createdName, qr, err := client.CreateInstance(ctx, "5500000000000", name, true)
+	require.NoError(t, err)
+	assert.Equal(t, name, createdName)
+	assert.NotEmpty(t, qr)
+
+	connect, err := client.ConnectInstance(ctx, name, "5500000000000")
+	require.NoError(t, err)
+	assert.NotEmpty(t, connect.Qrcode.Code)
+	assert.Contains(t, connect.Base64, "data:image/png;base64,")
+	assert.Equal(t, 2, connect.Count, "cada connect gera um QR novo")
+
+	state, err := client.ConnectionState(ctx, name)
+	require.NoError(t, err)
+	assert.Equal(t, fakeevolution.StateConnecting, state)
+	_, err = client.SendText(ctx, name, "5500000000001", "antes do pareamento")
+	require.Error(t, err, "instância sem pareamento não envia")
+
+	require.True(t, fake.Pair(name))
+	state, err = client.ConnectionState(ctx, name)
+	require.NoError(t, err)
+	assert.Equal(t, fakeevolution.StateOpen, state)
+
+	messageID, err := client.SendText(ctx, name, "+5500000000001", "Olá")
+	require.NoError(t, err)
+	assert.NotEmpty(t, messageID)
+	_, err = client.SendMedia(ctx, name, "5500000000001", "prova.pdf", []byte("%PDF-1.7"), "")
+	require.NoError(t, err)
+	checks, err := client.CheckNumbers(ctx, name, []string{"5500000000001", "5500000000009"})
+	require.NoError(t, err)
+	assert.True(t, checks["5500000000001"].Exists)
+	assert.False(t, checks["5500000000009"].Exists)
+
+	sent := fake.Sent()
+	require.Len(t, sent, 2)
+	assert.Equal(t, fakeevolution.SentMessage{ID: messageID, Instance: name, Number: "5500000000001", Type: "text", Text: "Olá"}, sent[0])
+	assert.Equal(t, "document", sent[1].Type)
+
+	require.NoError(t, client.LogoutInstance(ctx, name))
+	assert.Equal(t, fakeevolution.StateClose, fake.State(name))
+	require.NoError(t, client.DeleteInstance(ctx, name))
+	_, err = client.ConnectionState(ctx, name)
+	require.Error(t, err)
+}
+
+func TestFakeEvolutionSendsDeliveryReceiptToWebhook(t *testing.T) {
+	received := make(chan []byte, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer webhook.Close()
+	fake := fakeevolution.New(fakeevolution.Options{PairAfter: time.Millisecond, WebhookURL: webhook.URL})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := newTestEvolutionClient(t, server.URL, "any-key")
+	ctx := context.Background()
+
+	_, _, err := client.CreateInstance(ctx, "5500000000000", "prof", true)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return fake.State("prof") == fakeevolution.StateOpen }, time.Second, 5*time.Millisecond)
+	messageID, err := client.SendText(ctx, "prof", "5500000000001", "Olá")
+	require.NoError(t, err)
+
+	select {
+	case body := <-received:
+		event, err := ParseMessagesUpdate(body)
+		require.NoError(t, err)
+		assert.Equal(t, "prof", event.Instance)
+		assert.Equal(t, []MessageStatusUpdate{{MessageID: messageID, Status: MessageStatusDelivered}}, event.Updates)
+	case <-time.After(time.Second):
+		t.Fatal("webhook não recebeu o recibo")
+	}
+}
+
+func TestFakeEvolutionDeliversRepliesToWebhook(t *testing.T) {
+	received := make(chan []byte, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer webhook.Close()
+	fake := fakeevolution.New(fakeevolution.Options{WebhookURL: webhook.URL})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := newTestEvolutionClient(t, server.URL, "any-key")
+
+	_, _, err := client.CreateInstance(context.Background(), "5500000000000", "prof", true)
+	require.NoError(t, err)
+	_, ok := fake.Reply("prof", "5500000000001", "Ana", "Recebido")
+	assert.False(t, ok, "instância desconectada não recebe mensagens")
+	require.True(t, fake.Pair("prof"))
+	messageID, ok := fake.Reply("prof", "5500000000001", "Ana", "Recebido")
+	require.True(t, ok)
+
+	event, err := ParseMessagesUpsert(<-received)
+	require.NoError(t, err)
+	assert.Equal(t, "prof", event.Instance)
+	require.Len(t, event.Messages, 1)
+	assert.Equal(t, messageID, event.Messages[0].MessageID)
+	assert.Equal(t, "5500000000001", event.Messages[0].Phone)
+	assert.Equal(t, "Recebido", event.Messages[0].Text)
+}
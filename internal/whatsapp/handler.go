@@ -1,7 +1,9 @@
 package whatsapp
 
 import (
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/ThalysSilva/unicast-backend/pkg/api"
 	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
@@ -35,6 +37,8 @@ type Handler interface {
 	ConnectionState() gin.HandlerFunc
 	LogoutInstance() gin.HandlerFunc
 	RestartInstance() gin.HandlerFunc
+	CheckNumbers() gin.HandlerFunc
+	QRCodeStream() gin.HandlerFunc
 }
 
 func NewHandler(service Service) Handler {
@@ -82,7 +86,9 @@ func (h *handler) CreateInstance() gin.HandlerFunc {
 
 // @OperationId getInstances
 // @Summary Busca todas as instâncias do WhatsApp
-// @Description Busca todas as instâncias do WhatsApp para o usuário
+// @Description Busca todas as instâncias do WhatsApp para o usuário.
+// @Description Quando uma instância conectada cai, o aviso por email sai só por uma conta OAuth (Google/Microsoft) ou por um relay SMTP sem autenticação: contas SMTP com senha dependem do JWE do professor, que o monitor não tem.
+// @Description Se o aviso não puder ser enviado, disconnectAlertError traz o motivo; o campo volta a null quando um aviso é enviado ou a instância reconecta.
 // @Tags whatsapp
 // @Accept json
 // @Produce json
@@ -145,7 +151,7 @@ func (h *handler) DeleteInstance() gin.HandlerFunc {
 // @Param Authorization header string true "Bearer token"
 // @Security BearerAuth
 // @Param id path string true "Instance ID"
-// @Success 200 {object} api.DefaultResponse[connectResponse]
+// @Success 200 {object} api.DefaultResponse[ConnectResponse]
 // @Failure 400 {object} api.ErrorResponse
 // @Router /whatsapp/instance/{id}/connect [post]
 func (h *handler) ConnectInstance() gin.HandlerFunc {
@@ -159,7 +165,7 @@ func (h *handler) ConnectInstance() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, api.DefaultResponse[connectResponse]{Message: "Instância conectando...", Data: *resp})
+		c.JSON(http.StatusOK, api.DefaultResponse[ConnectResponse]{Message: "Instância conectando...", Data: *resp})
 	}
 }
 
@@ -236,3 +242,85 @@ func (h *handler) RestartInstance() gin.HandlerFunc {
 		c.JSON(http.StatusOK, api.MessageResponse{Message: "Instância reiniciada com sucesso."})
 	}
 }
+
+// @OperationId checkWhatsAppNumbers
+// @Summary Confere quais telefones têm WhatsApp
+// @Description Consulta pela instância telefones avulsos (numbers), alunos (studentIds) ou turmas inteiras (disciplineIds, programIds, campusIds, statuses).
+// @Description Celulares brasileiros não encontrados são consultados também com ou sem o nono dígito; sendTo indica o número que receberá as mensagens.
+// @Tags whatsapp
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param id path string true "Instance ID"
+// @Param body body CheckNumbersInput true "Telefones, alunos ou turmas"
+// @Success 200 {object} api.DefaultResponse[[]NumberCheckResult]
+// @Failure 400 {object} api.ErrorResponse
+// @Router /whatsapp/instance/{id}/check-numbers [post]
+func (h *handler) CheckNumbers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input CheckNumbersInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(err)
+			return
+		}
+
+		results, err := h.service.CheckNumbers(c.Request.Context(), c.GetString("userID"), c.Param("id"), input)
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, api.DefaultResponse[[]NumberCheckResult]{Message: "Números consultados com sucesso.", Data: results})
+	}
+}
+
+// qrCodeHeartbeat mantém a conexão aberta em proxies que encerram streams ociosos.
+const qrCodeHeartbeat = 15 * time.Second
+
+// @OperationId streamInstanceQRCode
+// @Summary Acompanha o pareamento da instância em tempo real
+// @Description Stream Server-Sent Events com um evento qrcode a cada QR novo, até a instância conectar.
+// @Description O stream termina com connected (traz o telefone que leu o QR, gravado na instância se mudou), timeout (QR não lido em 3 minutos) ou error.
+// @Tags whatsapp
+// @Produce text/event-stream
+// @Param Authorization header string true "Bearer token"
+// @Security BearerAuth
+// @Param id path string true "Instance ID"
+// @Success 200 {object} PairingEvent
+// @Failure 404 {object} api.ErrorResponse
+// @Router /whatsapp/instance/{id}/qrcode/stream [get]
+func (h *handler) QRCodeStream() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		events, err := h.service.PairingStream(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+		if err != nil {
+			customerror.HandleResponse(c, err)
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(qrCodeHeartbeat)
+		defer heartbeat.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-heartbeat.C:
+				_, err := io.WriteString(w, ": heartbeat\n\n")
+				return err == nil
+			case event, ok := <-events:
+				if !ok {
+					return false
+				}
+				c.SSEvent(string(event.Type), event)
+				return !event.Final()
+			}
+		})
+	}
+}
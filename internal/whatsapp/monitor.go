@@ -0,0 +1,114 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+// monitorCallTimeout limita a consulta de cada instância, para uma Evolution lenta não travar a rodada.
+const monitorCallTimeout = 10 * time.Second
+
+// DisconnectNotifier avisa o professor quando uma instância conectada cai.
+type DisconnectNotifier interface {
+	InstanceDisconnected(ctx context.Context, instance *Instance) error
+}
+
+// Monitor consulta periodicamente o estado de todas as instâncias na Evolution e grava as transições,
+// para a queda do celular aparecer antes de um envio falhar para a turma inteira.
+type Monitor struct {
+	repository Repository
+	evolution  EvolutionClient
+	notifier   DisconnectNotifier
+	interval   time.Duration
+	now        func() time.Time
+}
+
+func NewMonitor(repository Repository, evolution EvolutionClient, notifier DisconnectNotifier, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Monitor{
+		repository: repository,
+		evolution:  evolution,
+		notifier:   notifier,
+		interval:   interval,
+		now:        time.Now,
+	}
+}
+
+// Run bloqueia até o contexto ser cancelado.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Poll(ctx); err != nil {
+			log.Printf("monitor de whatsapp: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll faz uma rodada de consultas. Falhas de uma instância são registradas no log e não interrompem as demais;
+// o estado só muda com uma resposta da Evolution.
+func (m *Monitor) Poll(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	instances, err := m.repository.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if ctx.Err() != nil {
+			return nil
+		}
+		m.check(ctx, instance)
+	}
+	return nil
+}
+
+func (m *Monitor) check(ctx context.Context, instance *Instance) {
+	callCtx, cancel := context.WithTimeout(ctx, monitorCallTimeout)
+	state, err := m.evolution.ConnectionState(callCtx, instance.InstanceName)
+	cancel()
+	if err != nil {
+		log.Printf("monitor de whatsapp: falha ao consultar instância %s: %v", instance.ID, err)
+		return
+	}
+	previous, changed, err := m.repository.RecordConnectionState(ctx, instance.ID, state, StateSourceMonitor, m.now())
+	if err != nil {
+		log.Printf("monitor de whatsapp: %v", err)
+		return
+	}
+	// Só avisa quem estava conectado: instâncias que nunca parearam também aparecem como close.
+	if !changed || previous != ConnectionOpen || state != ConnectionClose || m.notifier == nil {
+		return
+	}
+	instance.ConnectionStatus = state
+	var alertError *string
+	if err := m.notifier.InstanceDisconnected(ctx, instance); err != nil {
+		log.Printf("monitor de whatsapp: falha ao avisar queda da instância %s: %v", instance.ID, err)
+		text := alertErrorText(err)
+		alertError = &text
+	}
+	if err := m.repository.RecordDisconnectAlert(ctx, instance.ID, alertError); err != nil {
+		log.Printf("monitor de whatsapp: %v", err)
+	}
+}
+
+// alertErrorText guarda só a mensagem pública do erro, que é exibida ao professor.
+func alertErrorText(err error) string {
+	customErr := &customerror.CustomError{}
+	if errors.As(err, &customErr) {
+		return customErr.PublicMessage()
+	}
+	return "falha ao enviar o aviso de queda por email"
+}
@@ -0,0 +1,147 @@
+package whatsapp
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/smtp"
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp/fakeevolution"
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+	"github.com/ThalysSilva/unicast-backend/pkg/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stateChange struct {
+	instanceID, from, to, source string
+}
+
+type fakeInstanceRepository struct {
+	Repository
+	instances []*Instance
+	changes   []stateChange
+	alerts    map[string]*string
+}
+
+func (f *fakeInstanceRepository) FindByID(ctx context.Context, id string) (*Instance, error) {
+	for _, instance := range f.instances {
+		if instance.ID == id {
+			return instance, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeInstanceRepository) FindAll(ctx context.Context) ([]*Instance, error) {
+	return f.instances, nil
+}
+
+func (f *fakeInstanceRepository) RecordConnectionState(ctx context.Context, id, state, source string, at time.Time) (string, bool, error) {
+	for _, instance := range f.instances {
+		if instance.ID != id {
+			continue
+		}
+		previous := instance.ConnectionStatus
+		if previous == state {
+			return previous, false, nil
+		}
+		instance.ConnectionStatus = state
+		instance.ConnectionStatusUpdatedAt = &at
+		f.changes = append(f.changes, stateChange{instanceID: id, from: previous, to: state, source: source})
+		return previous, true, nil
+	}
+	return "", false, nil
+}
+
+func (f *fakeInstanceRepository) Update(ctx context.Context, id string, fields map[string]any) error {
+	for _, instance := range f.instances {
+		if instance.ID == id {
+			if phone, ok := fields["phone"].(string); ok {
+				instance.Phone = phone
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fakeInstanceRepository) RecordDisconnectAlert(ctx context.Context, id string, alertError *string) error {
+	if f.alerts == nil {
+		f.alerts = map[string]*string{}
+	}
+	f.alerts[id] = alertError
+	return nil
+}
+
+type fakeNotifier struct {
+	notified []string
+	err      error
+}
+
+func (f *fakeNotifier) InstanceDisconnected(ctx context.Context, instance *Instance) error {
+	f.notified = append(f.notified, instance.ID)
+	return f.err
+}
+
+func TestMonitorRecordsTransitionsAndNotifiesOnlyWhenConnectedInstanceDrops(t *testing.T) {
+	fake := fakeevolution.New(fakeevolution.Options{})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := newTestEvolutionClient(t, server.URL, "test-api-key")
+	ctx := context.Background()
+	for _, name := range []string{"prof-a", "prof-b"} {
+		_, _, err := client.CreateInstance(ctx, "5500000000000", name, true)
+		require.NoError(t, err)
+	}
+	repo := &fakeInstanceRepository{instances: []*Instance{
+		{ID: "wa-a", InstanceName: "prof-a", ConnectionStatus: "unknown"},
+		{ID: "wa-b", InstanceName: "prof-b", ConnectionStatus: "unknown"},
+	}}
+	notifier := &fakeNotifier{}
+	monitor := NewMonitor(repo, client, notifier, time.Minute)
+
+	require.True(t, fake.Pair("prof-a"))
+	require.NoError(t, monitor.Poll(ctx))
+	require.True(t, fake.Disconnect("prof-a"))
+	require.True(t, fake.Disconnect("prof-b"))
+	require.NoError(t, monitor.Poll(ctx))
+	require.NoError(t, monitor.Poll(ctx))
+
+	assert.Equal(t, []stateChange{
+		{instanceID: "wa-a", from: "unknown", to: ConnectionOpen, source: StateSourceMonitor},
+		{instanceID: "wa-b", from: "unknown", to: ConnectionConnecting, source: StateSourceMonitor},
+		{instanceID: "wa-a", from: ConnectionOpen, to: ConnectionClose, source: StateSourceMonitor},
+		{instanceID: "wa-b", from: ConnectionConnecting, to: ConnectionClose, source: StateSourceMonitor},
+	}, repo.changes)
+	assert.Equal(t, []string{"wa-a"}, notifier.notified, "instância que nunca pareou não gera aviso, e a queda avisa uma vez")
+	assert.NotNil(t, repo.instances[0].ConnectionStatusUpdatedAt)
+}
+
+func TestMonitorRecordsFailedDisconnectAlertForTheUI(t *testing.T) {
+	fake := fakeevolution.New(fakeevolution.Options{})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := newTestEvolutionClient(t, server.URL, "test-api-key")
+	ctx := context.Background()
+	_, _, err := client.CreateInstance(ctx, "5500000000000", "prof-a", true)
+	require.NoError(t, err)
+	repo := &fakeInstanceRepository{instances: []*Instance{{ID: "wa-a", InstanceName: "prof-a", ConnectionStatus: ConnectionOpen}}}
+	monitor := NewMonitor(repo, client, &fakeNotifier{err: customerror.Trace("InstanceDisconnected", NoSmtpForAlert)}, time.Minute)
+
+	require.True(t, fake.Disconnect("prof-a"))
+	require.NoError(t, monitor.Poll(ctx))
+
+	require.NotNil(t, repo.alerts["wa-a"])
+	assert.Equal(t, NoSmtpForAlert.PublicMessage(), *repo.alerts["wa-a"])
+}
+
+func TestAlertSmtpInstancePrefersInstancesUsableWithoutJwe(t *testing.T) {
+	password := &smtp.Instance{ID: "password", AuthMode: smtp.AuthModePassword, AuthMechanism: mailer.AuthPlain}
+	relay := &smtp.Instance{ID: "relay", AuthMode: smtp.AuthModePassword, AuthMechanism: mailer.AuthNone}
+	oauth := &smtp.Instance{ID: "oauth", AuthMode: smtp.AuthModeOAuth}
+
+	assert.Equal(t, oauth, alertSmtpInstance([]*smtp.Instance{password, relay, oauth}))
+	assert.Equal(t, relay, alertSmtpInstance([]*smtp.Instance{password, relay}))
+	assert.Nil(t, alertSmtpInstance([]*smtp.Instance{password}))
+}
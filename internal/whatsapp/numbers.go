@@ -0,0 +1,189 @@
+package whatsapp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/student"
+)
+
+// NumberCheck é o resultado da consulta de um número normalizado (só dígitos, com DDI).
+type NumberCheck struct {
+	Number string `json:"number"`
+	Exists bool   `json:"exists"`
+	// SendTo é o número que recebe as mensagens; difere de Number quando só a variante com ou sem
+	// o nono dígito tem conta no WhatsApp.
+	SendTo string `json:"sendTo,omitempty"`
+}
+
+// CheckNumbersInput seleciona o que consultar: telefones avulsos, alunos e/ou turmas inteiras pelo filtro de destinatários.
+type CheckNumbersInput struct {
+	Numbers    []string `json:"numbers"`
+	StudentIDs []string `json:"studentIds" binding:"omitempty,dive,uuid"`
+	student.RecipientFilter
+}
+
+// NumberCheckResult é a linha da resposta de POST /whatsapp/instance/:id/check-numbers; Reason explica
+// por que o número não receberia mensagens.
+type NumberCheckResult struct {
+	StudentID string  `json:"studentId,omitempty"`
+	Name      *string `json:"name,omitempty"`
+	Phone     string  `json:"phone"`
+	NumberCheck
+	Reason string `json:"reason,omitempty"`
+}
+
+const (
+	// maxNumbersPerCheck limita uma consulta a algumas turmas; a Evolution consulta os números um a um.
+	maxNumbersPerCheck = 1000
+	// numberLookupBatch limita quantos números vão em cada chamada à Evolution.
+	numberLookupBatch = 100
+	// Números sem conta são consultados de novo mais cedo: o aluno pode criar a conta depois.
+	numberFoundTTL   = 24 * time.Hour
+	numberMissingTTL = time.Hour
+	// numberSweepInterval espaça as varreduras que removem do cache as consultas vencidas.
+	numberSweepInterval = time.Hour
+)
+
+type cachedNumber struct {
+	check     NumberCheck
+	expiresAt time.Time
+}
+
+// numberCache guarda as consultas por número; a existência no WhatsApp não depende da instância usada.
+// Entradas vencidas saem na leitura e numa varredura periódica, para o mapa não crescer sem limite.
+type numberCache struct {
+	mu        sync.Mutex
+	entries   map[string]cachedNumber
+	lookup    func(ctx context.Context, instanceName string, numbers []string) ([]whatsappNumberResponse, error)
+	now       func() time.Time
+	nextSweep time.Time
+}
+
+func newNumberCache(lookup func(ctx context.Context, instanceName string, numbers []string) ([]whatsappNumberResponse, error)) *numberCache {
+	return &numberCache{entries: map[string]cachedNumber{}, lookup: lookup, now: time.Now}
+}
+
+// check confere pela instância quais números têm WhatsApp. Para números brasileiros não encontrados,
+// tenta também a variante com ou sem o nono dígito. Os números devem vir de NormalizeNumber.
+func (c *numberCache) check(ctx context.Context, instanceName string, numbers []string) (map[string]NumberCheck, error) {
+	results := make(map[string]NumberCheck, len(numbers))
+	pending := []string{}
+	now := c.now()
+
+	c.mu.Lock()
+	for _, number := range numbers {
+		if _, seen := results[number]; seen {
+			continue
+		}
+		if cached, ok := c.entries[number]; ok {
+			if now.Before(cached.expiresAt) {
+				results[number] = cached.check
+				continue
+			}
+			delete(c.entries, number)
+		}
+		results[number] = NumberCheck{Number: number}
+		pending = append(pending, number)
+	}
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	candidates := make([]string, 0, len(pending))
+	for _, number := range pending {
+		candidates = append(candidates, number)
+		if variant := brazilianVariant(number); variant != "" {
+			candidates = append(candidates, variant)
+		}
+	}
+	found := map[string]string{}
+	for start := 0; start < len(candidates); start += numberLookupBatch {
+		end := min(start+numberLookupBatch, len(candidates))
+		responses, err := c.lookup(ctx, instanceName, candidates[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, response := range responses {
+			if !response.Exists || response.Number == "" {
+				continue
+			}
+			sendTo := digitsOnly(strings.SplitN(response.Jid, "@", 2)[0])
+			if sendTo == "" {
+				sendTo = digitsOnly(response.Number)
+			}
+			found[digitsOnly(response.Number)] = sendTo
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	for _, number := range pending {
+		check := NumberCheck{Number: number}
+		sendTo, ok := found[number]
+		if variant := brazilianVariant(number); !ok && variant != "" {
+			sendTo, ok = found[variant]
+		}
+		ttl := numberMissingTTL
+		if ok {
+			check.Exists, check.SendTo = true, sendTo
+			ttl = numberFoundTTL
+		}
+		results[number] = check
+		c.entries[number] = cachedNumber{check: check, expiresAt: now.Add(ttl)}
+	}
+	return results, nil
+}
+
+// sweep remove as entradas vencidas, no máximo uma vez por numberSweepInterval. Chamar com mu travado.
+func (c *numberCache) sweep(now time.Time) {
+	if now.Before(c.nextSweep) {
+		return
+	}
+	for number, cached := range c.entries {
+		if !now.Before(cached.expiresAt) {
+			delete(c.entries, number)
+		}
+	}
+	c.nextSweep = now.Add(numberSweepInterval)
+}
+
+// NumberVariants devolve o número e, para celulares brasileiros, a variante com ou sem o nono dígito:
+// contas antigas ainda aparecem no JID sem o nono dígito.
+func NumberVariants(number string) []string {
+	variants := []string{number}
+	if variant := brazilianVariant(number); variant != "" {
+		variants = append(variants, variant)
+	}
+	return variants
+}
+
+// brazilianVariant devolve o celular brasileiro com o nono dígito removido ou acrescentado; vazio para
+// números de outros países e fixos.
+func brazilianVariant(number string) string {
+	if !strings.HasPrefix(number, "55") {
+		return ""
+	}
+	local := number[2:]
+	switch {
+	case len(local) == 11 && local[2] == '9':
+		return "55" + local[:2] + local[3:]
+	case len(local) == 10 && local[2] >= '6':
+		return "55" + local[:2] + "9" + local[2:]
+	}
+	return ""
+}
+
+func digitsOnly(value string) string {
+	digits := make([]rune, 0, len(value))
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	return string(digits)
+}
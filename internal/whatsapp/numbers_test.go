@@ -0,0 +1,97 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrazilianVariant(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "celular com nono dígito", in: "5511988887777", want: "551188887777"},
+		{name: "celular sem nono dígito", in: "551188887777", want: "5511988887777"},
+		{name: "fixo", in: "551133334444", want: ""},
+		{name: "outro país", in: "14155550123", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, brazilianVariant(tt.in))
+		})
+	}
+}
+
+func TestCheckNumbersTriesNinthDigitVariantAndCaches(t *testing.T) {
+	var calls [][]string
+	cache := newNumberCache(func(ctx context.Context, instanceName string, numbers []string) ([]whatsappNumberResponse, error) {
+		calls = append(calls, numbers)
+		return []whatsappNumberResponse{
+			{Number: "551188887777", Exists: false},
+			{Number: "5511988887777", Exists: true, Jid: "5511988887777@s.whatsapp.net"},
+			{Number: "14155550123", Exists: false},
+		}, nil
+	})
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	checks, err := cache.check(context.Background(), "prof", []string{"551188887777", "14155550123"})
+
+	require.NoError(t, err)
+	assert.Equal(t, NumberCheck{Number: "551188887777", Exists: true, SendTo: "5511988887777"}, checks["551188887777"])
+	assert.Equal(t, NumberCheck{Number: "14155550123"}, checks["14155550123"])
+	require.Len(t, calls, 1)
+	assert.Equal(t, []string{"551188887777", "5511988887777", "14155550123"}, calls[0])
+
+	now = now.Add(2 * time.Hour)
+	checks, err = cache.check(context.Background(), "prof", []string{"551188887777", "14155550123"})
+
+	require.NoError(t, err)
+	assert.True(t, checks["551188887777"].Exists)
+	require.Len(t, calls, 2, "números sem conta expiram antes dos encontrados")
+	assert.Equal(t, []string{"14155550123"}, calls[1])
+}
+
+func TestCheckNumbersDoesNotCacheFailedLookups(t *testing.T) {
+	fail := true
+	cache := newNumberCache(func(ctx context.Context, instanceName string, numbers []string) ([]whatsappNumberResponse, error) {
+		if fail {
+			return nil, errors.New("instância desconectada")
+		}
+		return []whatsappNumberResponse{{Number: "5511988887777", Exists: true, Jid: "5511988887777@s.whatsapp.net"}}, nil
+	})
+
+	_, err := cache.check(context.Background(), "prof", []string{"5511988887777"})
+	require.Error(t, err)
+
+	fail = false
+	checks, err := cache.check(context.Background(), "prof", []string{"5511988887777"})
+	require.NoError(t, err)
+	assert.True(t, checks["5511988887777"].Exists)
+}
+
+func TestCheckNumbersEvictsExpiredEntries(t *testing.T) {
+	cache := newNumberCache(func(ctx context.Context, instanceName string, numbers []string) ([]whatsappNumberResponse, error) {
+		return nil, nil
+	})
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	_, err := cache.check(context.Background(), "prof", []string{"14155550123", "14155550124"})
+	require.NoError(t, err)
+	require.Len(t, cache.entries, 2)
+
+	now = now.Add(2 * time.Hour)
+	_, err = cache.check(context.Background(), "prof", []string{"14155550125"})
+	require.NoError(t, err)
+
+	assert.Len(t, cache.entries, 1, "consultas vencidas saem do cache mesmo sem nova leitura")
+	assert.Contains(t, cache.entries, "14155550125")
+}
@@ -0,0 +1,143 @@
+package whatsapp
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// PairingEventType identifica os eventos de GET /whatsapp/instance/:id/qrcode/stream.
+type PairingEventType string
+
+const (
+	// PairingQRCode traz um QR (ou código de pareamento) novo para exibir.
+	PairingQRCode PairingEventType = "qrcode"
+	// PairingConnected é o evento final quando a instância chega a open.
+	PairingConnected PairingEventType = "connected"
+	// PairingTimeout encerra o stream quando o QR não é lido a tempo; basta abrir o stream de novo.
+	PairingTimeout PairingEventType = "timeout"
+	// PairingError encerra o stream quando a Evolution não gera o QR.
+	PairingError PairingEventType = "error"
+)
+
+type PairingEvent struct {
+	Type        PairingEventType `json:"type"`
+	Code        string           `json:"code,omitempty"`
+	Base64      string           `json:"base64,omitempty"`
+	PairingCode string           `json:"pairingCode,omitempty"`
+	Count       int              `json:"count,omitempty"`
+	State       string           `json:"state,omitempty"`
+	// Phone é o telefone da instância pareada, no evento connected.
+	Phone   string    `json:"phone,omitempty"`
+	Message string    `json:"message,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Final indica que o stream termina depois do evento.
+func (e PairingEvent) Final() bool {
+	return e.Type != PairingQRCode
+}
+
+// pairingTiming controla o polling do pareamento; os testes encurtam os intervalos.
+type pairingTiming struct {
+	// poll é o intervalo entre consultas do estado.
+	poll time.Duration
+	// refresh é o intervalo entre buscas de QR; o QR do WhatsApp expira em cerca de 20 segundos.
+	refresh time.Duration
+	// timeout encerra o stream se o QR não for lido.
+	timeout time.Duration
+}
+
+var defaultPairingTiming = pairingTiming{poll: 2 * time.Second, refresh: 20 * time.Second, timeout: 3 * time.Minute}
+
+// PairingStream acompanha o pareamento da instância até ela conectar. Os QRs vêm da sessão aberta no
+// primeiro connect; o canal é fechado depois do evento final ou quando ctx é cancelado.
+func (s *service) PairingStream(ctx context.Context, userID, instanceID string) (<-chan PairingEvent, error) {
+	instance, err := s.ensureOwnership(ctx, userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan PairingEvent, 1)
+	go s.followPairing(ctx, instance, events)
+	return events, nil
+}
+
+func (s *service) followPairing(ctx context.Context, instance *Instance, events chan<- PairingEvent) {
+	defer close(events)
+	emit := func(event PairingEvent) bool {
+		event.At = time.Now()
+		select {
+		case events <- event:
+			return !event.Final()
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	timing := s.pairing
+	deadline := time.NewTimer(timing.timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(timing.poll)
+	defer poll.Stop()
+
+	lastCode := ""
+	var lastRefresh time.Time
+	for {
+		state, err := s.evolution.ConnectionState(ctx, instance.InstanceName)
+		if err != nil {
+			log.Printf("pareamento da instância %s: falha ao consultar estado: %v", instance.ID, err)
+		}
+		if err == nil && state == ConnectionOpen {
+			if _, _, err := s.whatsappInstanceRepository.RecordConnectionState(ctx, instance.ID, state, StateSourceStatus, time.Now()); err != nil {
+				log.Printf("pareamento da instância %s: %v", instance.ID, err)
+			}
+			emit(PairingEvent{Type: PairingConnected, State: state, Phone: s.pairedPhone(ctx, instance)})
+			return
+		}
+
+		if lastRefresh.IsZero() || time.Since(lastRefresh) >= timing.refresh {
+			lastRefresh = time.Now()
+			resp, err := s.evolution.ConnectInstance(ctx, instance.InstanceName, instance.Phone)
+			if err != nil {
+				if ctx.Err() == nil {
+					emit(PairingEvent{Type: PairingError, Message: "Falha ao gerar o QR code na Evolution"})
+				}
+				return
+			}
+			if resp.Code != "" && resp.Code != lastCode {
+				lastCode = resp.Code
+				if !emit(PairingEvent{Type: PairingQRCode, Code: resp.Code, Base64: resp.Base64, PairingCode: resp.PairingCode, Count: resp.Count, State: state}) {
+					return
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			emit(PairingEvent{Type: PairingTimeout, Message: "O QR code não foi lido a tempo"})
+			return
+		case <-poll.C:
+		}
+	}
+}
+
+// pairedPhone devolve o número que a Evolution reporta depois do pareamento e atualiza a instância quando
+// o QR foi lido por outro celular. Se a Evolution não informar o número, mantém o telefone cadastrado.
+func (s *service) pairedPhone(ctx context.Context, instance *Instance) string {
+	phone, err := s.evolution.OwnerNumber(ctx, instance.InstanceName)
+	if err != nil {
+		log.Printf("pareamento da instância %s: falha ao consultar o número pareado: %v", instance.ID, err)
+		return instance.Phone
+	}
+	if phone == "" || phone == instance.Phone {
+		return instance.Phone
+	}
+	if err := s.whatsappInstanceRepository.Update(ctx, instance.ID, map[string]any{"phone": phone}); err != nil {
+		log.Printf("pareamento da instância %s: falha ao atualizar o telefone: %v", instance.ID, err)
+		return phone
+	}
+	instance.Phone = phone
+	return phone
+}
@@ -0,0 +1,103 @@
+package whatsapp
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/internal/whatsapp/fakeevolution"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPairingService(t *testing.T, timing pairingTiming) (*service, *fakeevolution.Server, *fakeInstanceRepository) {
+	fake := fakeevolution.New(fakeevolution.Options{})
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client := newTestEvolutionClient(t, server.URL, "test-api-key")
+	_, _, err := client.CreateInstance(context.Background(), "5500000000000", "prof", false)
+	require.NoError(t, err)
+	repo := &fakeInstanceRepository{instances: []*Instance{
+		{ID: "wa-1", UserID: "user-1", Phone: "5500000000000", InstanceName: "prof", ConnectionStatus: ConnectionClose},
+	}}
+	return &service{whatsappInstanceRepository: repo, evolution: client, pairing: timing}, fake, repo
+}
+
+func nextPairingEvent(t *testing.T, events <-chan PairingEvent) PairingEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "stream encerrado antes do evento")
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("nenhum evento de pareamento")
+		return PairingEvent{}
+	}
+}
+
+func TestPairingStreamPushesRefreshedQRCodesUntilConnected(t *testing.T) {
+	svc, fake, repo := newPairingService(t, pairingTiming{poll: 5 * time.Millisecond, refresh: 20 * time.Millisecond, timeout: 5 * time.Second})
+
+	events, err := svc.PairingStream(context.Background(), "user-1", "wa-1")
+	require.NoError(t, err)
+
+	first := nextPairingEvent(t, events)
+	assert.Equal(t, PairingQRCode, first.Type)
+	assert.NotEmpty(t, first.Code)
+	assert.Contains(t, first.Base64, "data:image/png;base64,")
+	second := nextPairingEvent(t, events)
+	assert.Equal(t, PairingQRCode, second.Type)
+	assert.NotEqual(t, first.Code, second.Code, "o QR renovado substitui o anterior")
+	assert.Greater(t, second.Count, first.Count)
+
+	require.True(t, fake.Pair("prof"))
+	final := nextPairingEvent(t, events)
+	for final.Type == PairingQRCode {
+		final = nextPairingEvent(t, events)
+	}
+	assert.Equal(t, PairingConnected, final.Type)
+	assert.Equal(t, ConnectionOpen, final.State)
+	assert.Equal(t, "5500000000000", final.Phone)
+	_, open := <-events
+	assert.False(t, open, "o stream termina depois do evento connected")
+	assert.Equal(t, []stateChange{{instanceID: "wa-1", from: ConnectionClose, to: ConnectionOpen, source: StateSourceStatus}}, repo.changes)
+}
+
+func TestPairingStreamReportsThePhoneThatScannedTheQRCode(t *testing.T) {
+	svc, fake, repo := newPairingService(t, pairingTiming{poll: 5 * time.Millisecond, refresh: time.Second, timeout: 5 * time.Second})
+
+	events, err := svc.PairingStream(context.Background(), "user-1", "wa-1")
+	require.NoError(t, err)
+	assert.Equal(t, PairingQRCode, nextPairingEvent(t, events).Type)
+
+	require.True(t, fake.PairAs("prof", "5511988887777"))
+	final := nextPairingEvent(t, events)
+	for final.Type == PairingQRCode {
+		final = nextPairingEvent(t, events)
+	}
+
+	assert.Equal(t, PairingConnected, final.Type)
+	assert.Equal(t, "5511988887777", final.Phone)
+	assert.Equal(t, "5511988887777", repo.instances[0].Phone, "a instância passa a ter o número que leu o QR")
+}
+
+func TestPairingStreamEndsWithTimeoutWhenQRCodeIsNotScanned(t *testing.T) {
+	svc, _, _ := newPairingService(t, pairingTiming{poll: 5 * time.Millisecond, refresh: time.Second, timeout: 30 * time.Millisecond})
+
+	events, err := svc.PairingStream(context.Background(), "user-1", "wa-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, PairingQRCode, nextPairingEvent(t, events).Type)
+	assert.Equal(t, PairingTimeout, nextPairingEvent(t, events).Type)
+	_, open := <-events
+	assert.False(t, open)
+}
+
+func TestPairingStreamRejectsInstanceOfAnotherUser(t *testing.T) {
+	svc, _, _ := newPairingService(t, defaultPairingTiming)
+
+	_, err := svc.PairingStream(context.Background(), "user-2", "wa-1")
+
+	assert.ErrorIs(t, err, InstanceForbidden)
+}
@@ -5,32 +5,44 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/ThalysSilva/unicast-backend/internal/student"
 	"github.com/ThalysSilva/unicast-backend/internal/user"
 	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
 	"github.com/ThalysSilva/unicast-backend/pkg/database"
 )
 
 type Service interface {
-	CreateInstance(ctx context.Context, userId, phone string) (*Instance, *connectResponse, error)
+	CreateInstance(ctx context.Context, userId, phone string) (*Instance, *ConnectResponse, error)
 	GetInstances(ctx context.Context, userID string) ([]*Instance, error)
 	DeleteInstance(ctx context.Context, userID, instanceID string) error
-	ConnectInstance(ctx context.Context, userID, instanceID string) (*connectResponse, error)
+	ConnectInstance(ctx context.Context, userID, instanceID string) (*ConnectResponse, error)
 	ConnectionState(ctx context.Context, userID, instanceID string) (string, error)
 	LogoutInstance(ctx context.Context, userID, instanceID string) error
 	RestartInstance(ctx context.Context, userID, instanceID string) error
+	CheckNumbers(ctx context.Context, userID, instanceID string, input CheckNumbersInput) ([]NumberCheckResult, error)
+	PairingStream(ctx context.Context, userID, instanceID string) (<-chan PairingEvent, error)
 }
 
 type service struct {
 	whatsappInstanceRepository Repository
 	userRepository             user.Repository
+	studentRepository          student.Repository
+	evolution                  EvolutionClient
+	defaultCountryCode         string
+	pairing                    pairingTiming
 }
 
-func NewService(whatsappRepo Repository, userRepo user.Repository) Service {
+func NewService(whatsappRepo Repository, userRepo user.Repository, studentRepo student.Repository, evolution EvolutionClient, defaultCountryCode string) Service {
 	return &service{
 		whatsappInstanceRepository: whatsappRepo,
 		userRepository:             userRepo,
+		studentRepository:          studentRepo,
+		evolution:                  evolution,
+		defaultCountryCode:         defaultCountryCode,
+		pairing:                    defaultPairingTiming,
 	}
 }
 
@@ -39,9 +51,11 @@ var (
 	UserNotFound       = customerror.Make("Usuário não encontrado", http.StatusNotFound, errors.New("userNotFound"))
 	InstanceNotFound   = customerror.Make("Instância não encontrada", http.StatusNotFound, errors.New("instanceNotFound"))
 	InstanceForbidden  = customerror.Make("Você não tem permissão para esta instância", http.StatusForbidden, errors.New("instanceForbidden"))
+	NoNumbersToCheck   = customerror.Make("Informe números, alunos ou turmas para consultar", http.StatusBadRequest, errors.New("noNumbersToCheck"))
+	TooManyNumbers     = customerror.Make(fmt.Sprintf("Consulte no máximo %d números por vez", maxNumbersPerCheck), http.StatusBadRequest, errors.New("tooManyNumbers"))
 )
 
-func (s *service) CreateInstance(ctx context.Context, userId, phone string) (*Instance, *connectResponse, error) {
+func (s *service) CreateInstance(ctx context.Context, userId, phone string) (*Instance, *ConnectResponse, error) {
 	var instance *Instance
 	normalizedPhone, err := NormalizeNumber(phone, "")
 	if err != nil {
@@ -67,7 +81,7 @@ func (s *service) CreateInstance(ctx context.Context, userId, phone string) (*In
 	}
 
 	// Fase 2: cria instância na Evolution (fora da transação para evitar órfãos).
-	remoteInstanceName, qr, err := s.createRemoteInstance(instanceName, phone)
+	remoteInstanceName, qr, err := s.createRemoteInstance(ctx, instanceName, phone)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -82,7 +96,7 @@ func (s *service) CreateInstance(ctx context.Context, userId, phone string) (*In
 		}
 		if err := s.persistInstance(ctx, waRepo, phone, userId, instanceName); err != nil {
 			// Em caso de falha, idealmente deletar a instância remota.
-			_ = deleteEvolutionInstance(remoteInstanceName)
+			_ = s.evolution.DeleteInstance(ctx, remoteInstanceName)
 			return err
 		}
 		var errFetch error
@@ -100,10 +114,10 @@ func (s *service) CreateInstance(ctx context.Context, userId, phone string) (*In
 	}
 
 	// Fase 4: conecta/gera QR atualizado (pairing/code) na Evolution
-	connectResp, err := connectEvolutionInstance(instanceName, phone)
+	connectResp, err := s.evolution.ConnectInstance(ctx, instanceName, phone)
 	if err != nil {
 		// Se falhar, retornamos o QR da criação mesmo assim.
-		return instance, &connectResponse{
+		return instance, &ConnectResponse{
 			Code: qr,
 			Qrcode: struct {
 				Code   string `json:"code"`
@@ -141,7 +155,7 @@ func (s *service) DeleteInstance(ctx context.Context, userID, instanceID string)
 
 	// Deleta na Evolution antes de remover localmente.
 	if err := retryEvolution(ctx, 3, 500*time.Millisecond, func() error {
-		return deleteEvolutionInstance(instance.InstanceName)
+		return s.evolution.DeleteInstance(ctx, instance.InstanceName)
 	}); err != nil {
 		return fmt.Errorf("falha ao deletar instância na Evolution: %w", err)
 	}
@@ -149,12 +163,12 @@ func (s *service) DeleteInstance(ctx context.Context, userID, instanceID string)
 	return s.whatsappInstanceRepository.Delete(ctx, instanceID)
 }
 
-func (s *service) ConnectInstance(ctx context.Context, userID, instanceID string) (*connectResponse, error) {
+func (s *service) ConnectInstance(ctx context.Context, userID, instanceID string) (*ConnectResponse, error) {
 	instance, err := s.ensureOwnership(ctx, userID, instanceID)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := connectEvolutionInstance(instance.InstanceName, instance.Phone)
+	resp, err := s.evolution.ConnectInstance(ctx, instance.InstanceName, instance.Phone)
 	return resp, err
 }
 
@@ -163,11 +177,11 @@ func (s *service) ConnectionState(ctx context.Context, userID, instanceID string
 	if err != nil {
 		return "", err
 	}
-	state, err := connectionStateEvolution(instance.InstanceName)
+	state, err := s.evolution.ConnectionState(ctx, instance.InstanceName)
 	if err != nil {
 		return "", err
 	}
-	if err := s.whatsappInstanceRepository.Update(ctx, instance.ID, map[string]any{"connection_status": state}); err != nil {
+	if _, _, err := s.whatsappInstanceRepository.RecordConnectionState(ctx, instance.ID, state, StateSourceStatus, time.Now()); err != nil {
 		return "", fmt.Errorf("falha ao atualizar status da instância: %w", err)
 	}
 	return state, nil
@@ -178,7 +192,7 @@ func (s *service) LogoutInstance(ctx context.Context, userID, instanceID string)
 	if err != nil {
 		return err
 	}
-	return logoutEvolutionInstance(instance.InstanceName)
+	return s.evolution.LogoutInstance(ctx, instance.InstanceName)
 }
 
 func (s *service) RestartInstance(ctx context.Context, userID, instanceID string) error {
@@ -186,7 +200,90 @@ func (s *service) RestartInstance(ctx context.Context, userID, instanceID string
 	if err != nil {
 		return err
 	}
-	return restartEvolutionInstance(instance.InstanceName)
+	return s.evolution.RestartInstance(ctx, instance.InstanceName)
+}
+
+// CheckNumbers normaliza os telefones informados e os dos alunos selecionados e consulta quais têm WhatsApp.
+func (s *service) CheckNumbers(ctx context.Context, userID, instanceID string, input CheckNumbersInput) ([]NumberCheckResult, error) {
+	instance, err := s.ensureOwnership(ctx, userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]NumberCheckResult, 0, len(input.Numbers))
+	for _, phone := range input.Numbers {
+		results = append(results, NumberCheckResult{Phone: phone})
+	}
+	students, err := s.studentsToCheck(ctx, userID, input)
+	if err != nil {
+		return nil, customerror.Trace("CheckNumbers", err)
+	}
+	for _, stud := range students {
+		result := NumberCheckResult{StudentID: stud.ID, Name: stud.Name}
+		if stud.Phone != nil {
+			result.Phone = *stud.Phone
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil, customerror.Trace("CheckNumbers", NoNumbersToCheck)
+	}
+	if len(results) > maxNumbersPerCheck {
+		return nil, customerror.Trace("CheckNumbers", TooManyNumbers)
+	}
+
+	numbers := make([]string, 0, len(results))
+	for i := range results {
+		if strings.TrimSpace(results[i].Phone) == "" {
+			results[i].Reason = "sem telefone cadastrado"
+			continue
+		}
+		normalized, err := NormalizeNumber(results[i].Phone, s.defaultCountryCode)
+		if err != nil {
+			results[i].Reason = "telefone inválido"
+			continue
+		}
+		results[i].Number = normalized
+		numbers = append(numbers, normalized)
+	}
+
+	checks, err := s.evolution.CheckNumbers(ctx, instance.InstanceName, numbers)
+	if err != nil {
+		return nil, customerror.Trace("CheckNumbers", err)
+	}
+	for i := range results {
+		if results[i].Number == "" {
+			continue
+		}
+		results[i].NumberCheck = checks[results[i].Number]
+		if !results[i].Exists {
+			results[i].Reason = "número sem conta no WhatsApp"
+		}
+	}
+	return results, nil
+}
+
+func (s *service) studentsToCheck(ctx context.Context, userID string, input CheckNumbersInput) ([]*student.Student, error) {
+	ids := append([]string{}, input.StudentIDs...)
+	if input.HasTargets() {
+		filtered, err := s.studentRepository.FindIDsByRecipientFilter(ctx, userID, input.RecipientFilter)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, filtered...)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return s.studentRepository.FindByIDs(ctx, userID, unique)
 }
 
 func (s *service) ensureNoExistingInstance(ctx context.Context, repo Repository, phone, userID string) error {
@@ -211,8 +308,8 @@ func (s *service) fetchUser(ctx context.Context, repo user.Repository, userID st
 	return u, nil
 }
 
-func (s *service) createRemoteInstance(instanceName, phone string) (string, string, error) {
-	createdName, newQrCode, err := createEvolutionInstance(phone, instanceName, true)
+func (s *service) createRemoteInstance(ctx context.Context, instanceName, phone string) (string, string, error) {
+	createdName, newQrCode, err := s.evolution.CreateInstance(ctx, phone, instanceName, true)
 	if err != nil {
 		return "", "", customerror.Trace("CreateInstance", err)
 	}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
 	"github.com/ThalysSilva/unicast-backend/pkg/database"
 )
 
@@ -40,7 +42,7 @@ func (r *sqlRepository) Create(ctx context.Context, phone, userID, instanceID st
 
 func (r *sqlRepository) FindByID(ctx context.Context, id string) (*Instance, error) {
 	query := `
-		SELECT id, phone, connection_status, created_at, updated_at, user_id, instance_name
+		SELECT id, phone, connection_status, created_at, updated_at, user_id, instance_name, connection_status_updated_at, disconnect_alert_error
 		FROM whatsapp_instances
 		WHERE id = $1
 	`
@@ -48,7 +50,7 @@ func (r *sqlRepository) FindByID(ctx context.Context, id string) (*Instance, err
 
 	instance := &Instance{}
 	var instanceName string
-	err := row.Scan(&instance.ID, &instance.Phone, &instance.ConnectionStatus, &instance.CreatedAt, &instance.UpdatedAt, &instance.UserID, &instanceName)
+	err := row.Scan(&instance.ID, &instance.Phone, &instance.ConnectionStatus, &instance.CreatedAt, &instance.UpdatedAt, &instance.UserID, &instanceName, &instance.ConnectionStatusUpdatedAt, &instance.DisconnectAlertError)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -59,9 +61,26 @@ func (r *sqlRepository) FindByID(ctx context.Context, id string) (*Instance, err
 	return instance, nil
 }
 
+func (r *sqlRepository) FindByInstanceName(ctx context.Context, instanceName string) (*Instance, error) {
+	query := `
+		SELECT id, phone, connection_status, created_at, updated_at, user_id, instance_name, connection_status_updated_at, disconnect_alert_error
+		FROM whatsapp_instances
+		WHERE instance_name = $1
+	`
+	instance := &Instance{}
+	err := r.db.QueryRowContext(ctx, query, instanceName).Scan(&instance.ID, &instance.Phone, &instance.ConnectionStatus, &instance.CreatedAt, &instance.UpdatedAt, &instance.UserID, &instance.InstanceName, &instance.ConnectionStatusUpdatedAt, &instance.DisconnectAlertError)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("falha ao buscar instância %s: %w", instanceName, err)
+	}
+	return instance, nil
+}
+
 func (r *sqlRepository) FindByPhoneAndUserId(ctx context.Context, phone, userId string) (*Instance, error) {
 	query := `
-		SELECT id, phone, connection_status, created_at, updated_at, user_id, instance_name
+		SELECT id, phone, connection_status, created_at, updated_at, user_id, instance_name, connection_status_updated_at, disconnect_alert_error
 		FROM whatsapp_instances
 		WHERE phone = $1 AND user_id = $2
 	`
@@ -69,7 +88,7 @@ func (r *sqlRepository) FindByPhoneAndUserId(ctx context.Context, phone, userId
 
 	instance := &Instance{}
 	var instanceName string
-	err := row.Scan(&instance.ID, &instance.Phone, &instance.ConnectionStatus, &instance.CreatedAt, &instance.UpdatedAt, &instance.UserID, &instanceName)
+	err := row.Scan(&instance.ID, &instance.Phone, &instance.ConnectionStatus, &instance.CreatedAt, &instance.UpdatedAt, &instance.UserID, &instanceName, &instance.ConnectionStatusUpdatedAt, &instance.DisconnectAlertError)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -82,7 +101,7 @@ func (r *sqlRepository) FindByPhoneAndUserId(ctx context.Context, phone, userId
 
 func (r *sqlRepository) FindAllByUserId(ctx context.Context, userId string) ([]*Instance, error) {
 	query := `
-		SELECT id, phone, connection_status, created_at, updated_at, user_id, instance_name
+		SELECT id, phone, connection_status, created_at, updated_at, user_id, instance_name, connection_status_updated_at, disconnect_alert_error
 		FROM whatsapp_instances
 		WHERE user_id = $1
 	`
@@ -96,7 +115,7 @@ func (r *sqlRepository) FindAllByUserId(ctx context.Context, userId string) ([]*
 	for rows.Next() {
 		instance := &Instance{}
 		var instanceName string
-		err := rows.Scan(&instance.ID, &instance.Phone, &instance.ConnectionStatus, &instance.CreatedAt, &instance.UpdatedAt, &instance.UserID, &instanceName)
+		err := rows.Scan(&instance.ID, &instance.Phone, &instance.ConnectionStatus, &instance.CreatedAt, &instance.UpdatedAt, &instance.UserID, &instanceName, &instance.ConnectionStatusUpdatedAt, &instance.DisconnectAlertError)
 		if err != nil {
 			return nil, fmt.Errorf("falha ao escanear instância: %w", err)
 		}
@@ -109,6 +128,76 @@ func (r *sqlRepository) FindAllByUserId(ctx context.Context, userId string) ([]*
 	return instances, nil
 }
 
+func (r *sqlRepository) FindAll(ctx context.Context) ([]*Instance, error) {
+	query := `
+		SELECT id, phone, connection_status, created_at, updated_at, user_id, instance_name, connection_status_updated_at, disconnect_alert_error
+		FROM whatsapp_instances
+		ORDER BY created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao buscar instâncias: %w", err)
+	}
+	defer rows.Close()
+
+	var instances []*Instance
+	for rows.Next() {
+		instance := &Instance{}
+		err := rows.Scan(&instance.ID, &instance.Phone, &instance.ConnectionStatus, &instance.CreatedAt, &instance.UpdatedAt, &instance.UserID, &instance.InstanceName, &instance.ConnectionStatusUpdatedAt, &instance.DisconnectAlertError)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao escanear instância: %w", err)
+		}
+		instances = append(instances, instance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao iterar instâncias: %w", err)
+	}
+	return instances, nil
+}
+
+// RecordConnectionState trava a instância, troca o estado e grava a transição em um único comando,
+// para que o monitor e as consultas do professor não registrem a mesma mudança duas vezes.
+func (r *sqlRepository) RecordConnectionState(ctx context.Context, id, state, source string, at time.Time) (string, bool, error) {
+	query := `
+		WITH previous AS (
+			SELECT id, connection_status
+			FROM whatsapp_instances
+			WHERE id = $1
+			FOR UPDATE
+		), updated AS (
+			UPDATE whatsapp_instances w
+			SET connection_status = $2, connection_status_updated_at = $4, updated_at = NOW(),
+			    disconnect_alert_error = CASE WHEN $2 = 'open' THEN NULL ELSE w.disconnect_alert_error END
+			FROM previous p
+			WHERE w.id = p.id AND p.connection_status <> $2
+			RETURNING p.connection_status AS from_status
+		), recorded AS (
+			INSERT INTO whatsapp_connection_state_changes (instance_id, from_status, to_status, source, changed_at)
+			SELECT $1, from_status, $2, $3, $4 FROM updated
+		)
+		SELECT p.connection_status, EXISTS (SELECT 1 FROM updated)
+		FROM previous p
+	`
+	var previous string
+	var changed bool
+	err := r.db.QueryRowContext(ctx, query, id, state, source, at).Scan(&previous, &changed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("falha ao gravar estado da instância %s: %w", id, err)
+	}
+	return previous, changed, nil
+}
+
+func (r *sqlRepository) RecordDisconnectAlert(ctx context.Context, id string, alertError *string) error {
+	query := `UPDATE whatsapp_instances SET disconnect_alert_error = $2 WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id, alertError); err != nil {
+		return fmt.Errorf("falha ao gravar aviso de queda da instância %s: %w", id, err)
+	}
+	return nil
+}
+
 // Atualiza os campos de uma instância no banco de dados. Campos não fornecidos não serão atualizados.
 // Campos: phone, user_id, instance_id
 func (r *sqlRepository) Update(ctx context.Context, id string, fields map[string]any) error {
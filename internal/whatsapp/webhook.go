@@ -0,0 +1,256 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessageStatus é o estado de entrega de uma mensagem enviada, na ordem em que a Evolution o reporta.
+type MessageStatus string
+
+const (
+	MessageStatusSent      MessageStatus = "SENT"
+	MessageStatusDelivered MessageStatus = "DELIVERED"
+	MessageStatusRead      MessageStatus = "READ"
+	MessageStatusFailed    MessageStatus = "FAILED"
+)
+
+// MessageStatusUpdate é o recibo de uma mensagem enviada pela instância.
+type MessageStatusUpdate struct {
+	MessageID string
+	Status    MessageStatus
+}
+
+// MessagesUpdateEvent reúne os recibos de um evento MESSAGES_UPDATE de uma instância.
+type MessagesUpdateEvent struct {
+	Instance string
+	Updates  []MessageStatusUpdate
+}
+
+type webhookPayload struct {
+	Event    string          `json:"event"`
+	Instance string          `json:"instance"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// messageUpdateData cobre os dois formatos da Evolution: v2 (keyId/status texto) e v1 (key/update.status numérico).
+type messageUpdateData struct {
+	KeyID  string          `json:"keyId"`
+	FromMe *bool           `json:"fromMe"`
+	Status json.RawMessage `json:"status"`
+	Key    struct {
+		ID     string `json:"id"`
+		FromMe *bool  `json:"fromMe"`
+	} `json:"key"`
+	Update struct {
+		Status json.RawMessage `json:"status"`
+	} `json:"update"`
+}
+
+// evolutionAckStatus traduz o ack do WhatsApp (texto na v2, número na v1); PENDING não muda nada.
+var evolutionAckStatus = map[string]MessageStatus{
+	"ERROR":        MessageStatusFailed,
+	"0":            MessageStatusFailed,
+	"SERVER_ACK":   MessageStatusSent,
+	"2":            MessageStatusSent,
+	"DELIVERY_ACK": MessageStatusDelivered,
+	"3":            MessageStatusDelivered,
+	"READ":         MessageStatusRead,
+	"4":            MessageStatusRead,
+	"PLAYED":       MessageStatusRead,
+	"5":            MessageStatusRead,
+}
+
+// ParseMessagesUpdate lê o corpo de um webhook da Evolution. Eventos que não são MESSAGES_UPDATE,
+// mensagens recebidas e status desconhecidos são ignorados (retornam sem recibos).
+func ParseMessagesUpdate(body []byte) (*MessagesUpdateEvent, error) {
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("payload do webhook inválido: %w", err)
+	}
+
+	event := &MessagesUpdateEvent{Instance: payload.Instance, Updates: []MessageStatusUpdate{}}
+	name := strings.ToUpper(strings.ReplaceAll(payload.Event, ".", "_"))
+	if name != "MESSAGES_UPDATE" || len(payload.Data) == 0 {
+		return event, nil
+	}
+
+	items := []messageUpdateData{}
+	if strings.HasPrefix(strings.TrimSpace(string(payload.Data)), "[") {
+		if err := json.Unmarshal(payload.Data, &items); err != nil {
+			return nil, fmt.Errorf("dados do webhook inválidos: %w", err)
+		}
+	} else {
+		var item messageUpdateData
+		if err := json.Unmarshal(payload.Data, &item); err != nil {
+			return nil, fmt.Errorf("dados do webhook inválidos: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	for _, item := range items {
+		update, ok := item.toUpdate()
+		if ok {
+			event.Updates = append(event.Updates, update)
+		}
+	}
+	return event, nil
+}
+
+func (d messageUpdateData) toUpdate() (MessageStatusUpdate, bool) {
+	id := d.KeyID
+	if id == "" {
+		id = d.Key.ID
+	}
+	fromMe := d.FromMe
+	if fromMe == nil {
+		fromMe = d.Key.FromMe
+	}
+	if id == "" || (fromMe != nil && !*fromMe) {
+		return MessageStatusUpdate{}, false
+	}
+
+	raw := d.Status
+	if len(raw) == 0 {
+		raw = d.Update.Status
+	}
+	status, ok := evolutionAckStatus[strings.ToUpper(strings.Trim(string(raw), `"`))]
+	if !ok {
+		return MessageStatusUpdate{}, false
+	}
+	return MessageStatusUpdate{MessageID: id, Status: status}, true
+}
+
+// InboundMessage é uma mensagem recebida pela instância, de um contato individual.
+type InboundMessage struct {
+	MessageID string
+	// Phone traz só os dígitos do número de quem enviou, como aparece no JID.
+	Phone    string
+	PushName string
+	// Text é o texto da mensagem ou a legenda da mídia; vazio para mídias sem legenda.
+	Text        string
+	MessageType string
+	SentAt      time.Time
+}
+
+// MessagesUpsertEvent reúne as mensagens recebidas de um evento MESSAGES_UPSERT de uma instância.
+type MessagesUpsertEvent struct {
+	Instance string
+	Messages []InboundMessage
+}
+
+type messageUpsertData struct {
+	Key struct {
+		ID        string `json:"id"`
+		RemoteJID string `json:"remoteJid"`
+		FromMe    bool   `json:"fromMe"`
+		// Com endereçamento @lid, o número real vem em senderPn (ou remoteJidAlt, nas versões mais novas).
+		SenderPN     string `json:"senderPn"`
+		RemoteJIDAlt string `json:"remoteJidAlt"`
+	} `json:"key"`
+	PushName         string          `json:"pushName"`
+	MessageType      string          `json:"messageType"`
+	MessageTimestamp json.RawMessage `json:"messageTimestamp"`
+	Message          struct {
+		Conversation        string `json:"conversation"`
+		ExtendedTextMessage struct {
+			Text string `json:"text"`
+		} `json:"extendedTextMessage"`
+		ImageMessage    mediaCaption `json:"imageMessage"`
+		VideoMessage    mediaCaption `json:"videoMessage"`
+		DocumentMessage mediaCaption `json:"documentMessage"`
+	} `json:"message"`
+}
+
+type mediaCaption struct {
+	Caption string `json:"caption"`
+}
+
+// ParseMessagesUpsert lê o corpo de um webhook da Evolution. Eventos que não são MESSAGES_UPSERT,
+// mensagens enviadas pela própria instância, grupos e status são ignorados (retornam sem mensagens).
+func ParseMessagesUpsert(body []byte) (*MessagesUpsertEvent, error) {
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("payload do webhook inválido: %w", err)
+	}
+
+	event := &MessagesUpsertEvent{Instance: payload.Instance, Messages: []InboundMessage{}}
+	name := strings.ToUpper(strings.ReplaceAll(payload.Event, ".", "_"))
+	if name != "MESSAGES_UPSERT" || len(payload.Data) == 0 {
+		return event, nil
+	}
+
+	items := []messageUpsertData{}
+	if strings.HasPrefix(strings.TrimSpace(string(payload.Data)), "[") {
+		if err := json.Unmarshal(payload.Data, &items); err != nil {
+			return nil, fmt.Errorf("dados do webhook inválidos: %w", err)
+		}
+	} else {
+		var item messageUpsertData
+		if err := json.Unmarshal(payload.Data, &item); err != nil {
+			return nil, fmt.Errorf("dados do webhook inválidos: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	for _, item := range items {
+		message, ok := item.toInbound()
+		if ok {
+			event.Messages = append(event.Messages, message)
+		}
+	}
+	return event, nil
+}
+
+func (d messageUpsertData) toInbound() (InboundMessage, bool) {
+	if d.Key.ID == "" || d.Key.FromMe {
+		return InboundMessage{}, false
+	}
+	jid := d.Key.RemoteJID
+	if strings.HasSuffix(jid, "@lid") {
+		jid = d.Key.SenderPN
+		if jid == "" {
+			jid = d.Key.RemoteJIDAlt
+		}
+	}
+	// Só conversas individuais: grupos (@g.us), status@broadcast e listas de transmissão ficam de fora.
+	if !strings.HasSuffix(jid, "@s.whatsapp.net") {
+		return InboundMessage{}, false
+	}
+	phone := digitsOnly(strings.TrimSuffix(jid, "@s.whatsapp.net"))
+	if phone == "" {
+		return InboundMessage{}, false
+	}
+
+	message := InboundMessage{
+		MessageID:   d.Key.ID,
+		Phone:       phone,
+		PushName:    d.PushName,
+		Text:        d.text(),
+		MessageType: d.MessageType,
+		SentAt:      time.Now(),
+	}
+	seconds, err := strconv.ParseInt(strings.Trim(string(d.MessageTimestamp), `"`), 10, 64)
+	if err == nil && seconds > 0 {
+		message.SentAt = time.Unix(seconds, 0)
+	}
+	return message, true
+}
+
+func (d messageUpsertData) text() string {
+	for _, text := range []string{
+		d.Message.Conversation,
+		d.Message.ExtendedTextMessage.Text,
+		d.Message.ImageMessage.Caption,
+		d.Message.VideoMessage.Caption,
+		d.Message.DocumentMessage.Caption,
+	} {
+		if text != "" {
+			return text
+		}
+	}
+	return ""
+}
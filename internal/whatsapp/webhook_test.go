@@ -0,0 +1,81 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessagesUpdateReadsEvolutionV2Payload(t *testing.T) {
+	body := []byte(`{
+		"event":"messages.update",
+		"instance":"professor@example.com:5500000000000",
+		"data":{"keyId":"3EB0313C9EA80A7ED95190","remoteJid":"5500000000001@s.whatsapp.net","fromMe":true,"status":"DELIVERY_ACK"}
+	}`)
+
+	event, err := ParseMessagesUpdate(body)
+
+	require.NoError(t, err)
+	assert.Equal(t, "professor@example.com:5500000000000", event.Instance)
+	assert.Equal(t, []MessageStatusUpdate{{MessageID: "3EB0313C9EA80A7ED95190", Status: MessageStatusDelivered}}, event.Updates)
+}
+
+func TestParseMessagesUpdateReadsNumericAcksAndSkipsInboundMessages(t *testing.T) {
+	body := []byte(`{
+		"event":"MESSAGES_UPDATE",
+		"instance":"prof",
+		"data":[
+			{"key":{"id":"A1","fromMe":true},"update":{"status":4}},
+			{"key":{"id":"A2","fromMe":false},"update":{"status":4}},
+			{"key":{"id":"A3","fromMe":true},"update":{"status":0}},
+			{"key":{"id":"A4","fromMe":true},"update":{"status":1}}
+		]
+	}`)
+
+	event, err := ParseMessagesUpdate(body)
+
+	require.NoError(t, err)
+	assert.Equal(t, []MessageStatusUpdate{
+		{MessageID: "A1", Status: MessageStatusRead},
+		{MessageID: "A3", Status: MessageStatusFailed},
+	}, event.Updates)
+}
+
+func TestParseMessagesUpdateIgnoresOtherEvents(t *testing.T) {
+	event, err := ParseMessagesUpdate([]byte(`{"event":"connection.update","instance":"prof","data":{"state":"open"}}`))
+
+	require.NoError(t, err)
+	assert.Empty(t, event.Updates)
+
+	_, err = ParseMessagesUpdate([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestParseMessagesUpsertReadsRepliesAndSkipsOwnGroupAndStatusMessages(t *testing.T) {
+	body := []byte(`{
+		"event":"messages.upsert",
+		"instance":"prof",
+		"data":[
+			{"key":{"remoteJid":"5511912345678@s.whatsapp.net","fromMe":false,"id":"R1"},"pushName":"Ana","message":{"conversation":"Recebi, obrigada!"},"messageType":"conversation","messageTimestamp":1760000000},
+			{"key":{"remoteJid":"123456789@lid","senderPn":"551187654321@s.whatsapp.net","fromMe":false,"id":"R2"},"message":{"imageMessage":{"caption":"segue o comprovante"}},"messageType":"imageMessage","messageTimestamp":"1760000060"},
+			{"key":{"remoteJid":"5511912345678@s.whatsapp.net","fromMe":true,"id":"R3"},"message":{"conversation":"enviada pelo professor"}},
+			{"key":{"remoteJid":"120363000000000000@g.us","fromMe":false,"id":"R4"},"message":{"conversation":"grupo"}},
+			{"key":{"remoteJid":"status@broadcast","fromMe":false,"id":"R5"},"message":{"conversation":"status"}}
+		]
+	}`)
+
+	event, err := ParseMessagesUpsert(body)
+
+	require.NoError(t, err)
+	assert.Equal(t, "prof", event.Instance)
+	assert.Equal(t, []InboundMessage{
+		{MessageID: "R1", Phone: "5511912345678", PushName: "Ana", Text: "Recebi, obrigada!", MessageType: "conversation", SentAt: time.Unix(1760000000, 0)},
+		{MessageID: "R2", Phone: "551187654321", Text: "segue o comprovante", MessageType: "imageMessage", SentAt: time.Unix(1760000060, 0)},
+	}, event.Messages)
+
+	other, err := ParseMessagesUpsert([]byte(`{"event":"messages.update","instance":"prof","data":{"keyId":"A1","fromMe":true,"status":"READ"}}`))
+	require.NoError(t, err)
+	assert.Empty(t, other.Messages)
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS message_job_recipients;
+DROP TABLE IF EXISTS message_job_attachments;
+DROP TABLE IF EXISTS message_jobs;
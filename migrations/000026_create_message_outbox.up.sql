@@ -0,0 +1,58 @@
+CREATE TABLE message_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    smtp_id UUID NULL REFERENCES smtp_instances(id) ON DELETE SET NULL,
+    whatsapp_instance_id UUID NULL REFERENCES whatsapp_instances(id) ON DELETE SET NULL,
+    sender_from VARCHAR NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    jwe TEXT NULL,
+    status VARCHAR NOT NULL DEFAULT 'PENDING', -- PENDING ou COMPLETED
+    completed_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER trigger_update_timestamp_message_jobs
+    BEFORE UPDATE ON message_jobs
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_message_jobs_user_created_at
+ON message_jobs (user_id, created_at DESC);
+
+CREATE TABLE message_job_attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_id UUID NOT NULL REFERENCES message_jobs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    file_name VARCHAR NOT NULL,
+    data BYTEA NULL,
+    url TEXT NULL,
+    UNIQUE (job_id, position)
+);
+
+CREATE TABLE message_job_recipients (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_id UUID NOT NULL REFERENCES message_jobs(id) ON DELETE CASCADE,
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    channel VARCHAR NOT NULL, -- EMAIL ou WHATSAPP
+    status VARCHAR NOT NULL DEFAULT 'PENDING', -- PENDING, PROCESSING, SENT ou FAILED
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (job_id, student_id, channel)
+);
+
+CREATE TRIGGER trigger_update_timestamp_message_job_recipients
+    BEFORE UPDATE ON message_job_recipients
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_message_job_recipients_claim
+ON message_job_recipients (status, next_attempt_at);
+
+CREATE INDEX idx_message_job_recipients_job_id
+ON message_job_recipients (job_id);
@@ -0,0 +1,34 @@
+CREATE TABLE message_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    smtp_id UUID NULL REFERENCES smtp_instances(id) ON DELETE SET NULL,
+    whatsapp_instance_id UUID NULL REFERENCES whatsapp_instances(id) ON DELETE SET NULL,
+    sender_from VARCHAR NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    student_ids UUID[] NOT NULL,
+    attachments JSONB NOT NULL DEFAULT '[]',
+    jwe TEXT NULL,
+    send_at TIMESTAMPTZ NOT NULL,
+    recurrence TEXT NULL, -- RRULE (FREQ=DAILY|WEEKLY|MONTHLY; INTERVAL; BYDAY; COUNT; UNTIL)
+    timezone VARCHAR NOT NULL DEFAULT 'America/Sao_Paulo',
+    status VARCHAR NOT NULL DEFAULT 'ACTIVE', -- ACTIVE, PAUSED, CANCELED ou COMPLETED
+    next_run_at TIMESTAMPTZ NULL,
+    last_run_at TIMESTAMPTZ NULL,
+    last_job_id UUID NULL REFERENCES message_jobs(id) ON DELETE SET NULL,
+    last_error TEXT NULL,
+    run_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER trigger_update_timestamp_message_schedules
+    BEFORE UPDATE ON message_schedules
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_message_schedules_due
+ON message_schedules (status, next_run_at);
+
+CREATE INDEX idx_message_schedules_user_id
+ON message_schedules (user_id, created_at DESC);
@@ -0,0 +1,9 @@
+ALTER TABLE message_schedules
+DROP COLUMN IF EXISTS discipline_id,
+DROP COLUMN IF EXISTS template_id;
+
+ALTER TABLE message_jobs
+DROP COLUMN IF EXISTS discipline_id,
+DROP COLUMN IF EXISTS template_id;
+
+DROP TABLE IF EXISTS message_templates;
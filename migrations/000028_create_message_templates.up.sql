@@ -0,0 +1,23 @@
+CREATE TABLE message_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name)
+);
+
+CREATE TRIGGER trigger_update_timestamp_message_templates
+    BEFORE UPDATE ON message_templates
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+ALTER TABLE message_jobs
+ADD COLUMN template_id UUID NULL REFERENCES message_templates(id) ON DELETE SET NULL,
+ADD COLUMN discipline_id UUID NULL REFERENCES disciplines(id) ON DELETE SET NULL;
+
+ALTER TABLE message_schedules
+ADD COLUMN template_id UUID NULL REFERENCES message_templates(id) ON DELETE SET NULL,
+ADD COLUMN discipline_id UUID NULL REFERENCES disciplines(id) ON DELETE SET NULL;
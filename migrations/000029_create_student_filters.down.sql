@@ -0,0 +1,5 @@
+ALTER TABLE message_schedules
+DROP COLUMN IF EXISTS filter_id,
+DROP COLUMN IF EXISTS recipient_filter;
+
+DROP TABLE IF EXISTS student_filters;
@@ -0,0 +1,23 @@
+CREATE TABLE student_filters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR NOT NULL,
+    discipline_ids UUID[] NOT NULL DEFAULT '{}',
+    program_ids UUID[] NOT NULL DEFAULT '{}',
+    campus_ids UUID[] NOT NULL DEFAULT '{}',
+    statuses VARCHAR[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name)
+);
+
+CREATE TRIGGER trigger_update_timestamp_student_filters
+    BEFORE UPDATE ON student_filters
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+-- Agendamentos guardam os alvos e o filtro salvo para expandir os destinatários a cada execução.
+-- filter_id não tem FK: se o filtro for removido, a execução falha com erro explícito em vez de enviar a menos alunos.
+ALTER TABLE message_schedules
+ADD COLUMN recipient_filter JSONB NOT NULL DEFAULT '{}',
+ADD COLUMN filter_id UUID NULL;
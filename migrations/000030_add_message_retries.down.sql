@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_message_logs_retry_of;
+DROP INDEX IF EXISTS idx_message_jobs_retry_of;
+
+ALTER TABLE message_logs
+DROP COLUMN IF EXISTS retry_of;
+
+ALTER TABLE message_jobs
+DROP COLUMN IF EXISTS retry_of;
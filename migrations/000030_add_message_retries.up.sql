@@ -0,0 +1,12 @@
+-- retry_of aponta para o delivery_group_id reenviado. Sem FK: envios antigos não têm job em message_jobs.
+ALTER TABLE message_jobs
+ADD COLUMN retry_of UUID NULL;
+
+ALTER TABLE message_logs
+ADD COLUMN retry_of UUID NULL;
+
+CREATE INDEX idx_message_jobs_retry_of
+ON message_jobs (retry_of);
+
+CREATE INDEX idx_message_logs_retry_of
+ON message_logs (retry_of);
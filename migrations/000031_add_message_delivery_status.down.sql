@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_message_logs_provider_message_id;
+
+ALTER TABLE message_logs
+DROP COLUMN IF EXISTS status_updated_at,
+DROP COLUMN IF EXISTS delivery_status,
+DROP COLUMN IF EXISTS provider_message_id;
@@ -0,0 +1,11 @@
+-- provider_message_id é o ID da mensagem na Evolution, usado para casar os recibos do webhook MESSAGES_UPDATE.
+ALTER TABLE message_logs
+ADD COLUMN provider_message_id VARCHAR NULL,
+ADD COLUMN delivery_status VARCHAR(20) NOT NULL DEFAULT 'SENT',
+ADD COLUMN status_updated_at TIMESTAMPTZ NULL;
+
+UPDATE message_logs SET delivery_status = 'FAILED' WHERE success = false;
+
+CREATE INDEX idx_message_logs_provider_message_id
+ON message_logs (provider_message_id)
+WHERE provider_message_id IS NOT NULL;
@@ -0,0 +1,11 @@
+ALTER TABLE message_logs
+DROP COLUMN IF EXISTS fallback_from;
+
+ALTER TABLE message_job_recipients
+DROP COLUMN IF EXISTS fallback_from;
+
+ALTER TABLE message_schedules
+DROP COLUMN IF EXISTS fallback;
+
+ALTER TABLE message_jobs
+DROP COLUMN IF EXISTS fallback;
@@ -0,0 +1,13 @@
+-- fallback guarda a política de canal alternativo do envio (WHATSAPP_FIRST, EMAIL_FIRST ou EMAIL_IF_NO_PHONE).
+ALTER TABLE message_jobs
+ADD COLUMN fallback VARCHAR(30) NULL;
+
+ALTER TABLE message_schedules
+ADD COLUMN fallback VARCHAR(30) NULL;
+
+-- fallback_from indica o canal que falhou antes de o aluno ser redirecionado para este.
+ALTER TABLE message_job_recipients
+ADD COLUMN fallback_from VARCHAR NULL;
+
+ALTER TABLE message_logs
+ADD COLUMN fallback_from VARCHAR NULL;
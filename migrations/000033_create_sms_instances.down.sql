@@ -0,0 +1,10 @@
+ALTER TABLE message_schedules
+DROP COLUMN IF EXISTS sms_instance_id;
+
+ALTER TABLE message_logs
+DROP COLUMN IF EXISTS sms_instance_id;
+
+ALTER TABLE message_jobs
+DROP COLUMN IF EXISTS sms_instance_id;
+
+DROP TABLE IF EXISTS sms_instances;
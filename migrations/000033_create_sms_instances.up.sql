@@ -0,0 +1,30 @@
+-- sms_instances guarda gateways HTTP de SMS por usuário. O token é cifrado com a chave do servidor,
+-- como o OAuth de email, para que o worker envie sem o JWE do usuário.
+CREATE TABLE sms_instances (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR NOT NULL,
+    gateway_url TEXT NOT NULL,
+    sender VARCHAR NULL,
+    token BYTEA NULL,
+    token_iv BYTEA NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER trigger_update_timestamp_sms_instances
+    BEFORE UPDATE ON sms_instances
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
+
+CREATE INDEX idx_sms_instances_user_id
+ON sms_instances (user_id);
+
+ALTER TABLE message_jobs
+ADD COLUMN sms_instance_id UUID NULL REFERENCES sms_instances(id) ON DELETE SET NULL;
+
+ALTER TABLE message_logs
+ADD COLUMN sms_instance_id UUID NULL REFERENCES sms_instances(id) ON DELETE SET NULL;
+
+ALTER TABLE message_schedules
+ADD COLUMN sms_instance_id UUID NULL REFERENCES sms_instances(id) ON DELETE SET NULL;
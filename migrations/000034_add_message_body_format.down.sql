@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS email_layouts;
+
+ALTER TABLE message_schedules
+DROP COLUMN IF EXISTS body_format;
+
+ALTER TABLE message_jobs
+DROP COLUMN IF EXISTS body_format;
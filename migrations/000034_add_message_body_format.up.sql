@@ -0,0 +1,21 @@
+-- body_format indica se o corpo foi escrito em texto puro (TEXT, padrão quando nulo) ou Markdown (MARKDOWN).
+ALTER TABLE message_jobs
+ADD COLUMN body_format VARCHAR(10) NULL;
+
+ALTER TABLE message_schedules
+ADD COLUMN body_format VARCHAR(10) NULL;
+
+-- email_layouts guarda o layout institucional de cada usuário: cabeçalho e rodapé em Markdown.
+CREATE TABLE email_layouts (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    header TEXT NOT NULL DEFAULT '',
+    footer TEXT NOT NULL DEFAULT '',
+    accent_color VARCHAR(7) NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER trigger_update_timestamp_email_layouts
+    BEFORE UPDATE ON email_layouts
+    FOR EACH ROW
+EXECUTE FUNCTION update_timestamp();
@@ -0,0 +1,4 @@
+ALTER TABLE message_job_attachments
+DROP COLUMN IF EXISTS attachment_id;
+
+DROP TABLE IF EXISTS attachments;
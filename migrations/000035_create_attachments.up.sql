@@ -0,0 +1,23 @@
+-- attachments registra os arquivos enviados por cada usuário. O conteúdo fica no armazenamento de anexos,
+-- endereçado pelo sha256, e é compartilhado entre registros com o mesmo conteúdo.
+CREATE TABLE attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    file_name VARCHAR NOT NULL,
+    content_type VARCHAR NOT NULL,
+    size INTEGER NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, sha256, file_name)
+);
+
+CREATE INDEX idx_attachments_user_created_at
+ON attachments (user_id, created_at DESC);
+
+CREATE INDEX idx_attachments_sha256
+ON attachments (sha256);
+
+-- Sem chave estrangeira: se o anexo for removido, a entrega do job falha com "anexo não encontrado"
+-- em vez de sair sem o arquivo.
+ALTER TABLE message_job_attachments
+ADD COLUMN attachment_id UUID NULL;
@@ -0,0 +1,15 @@
+-- message_idempotency_keys lembra, por 24h, o Idempotency-Key de cada POST /message/send do usuário.
+-- job_id nulo indica que o primeiro pedido com a chave ainda está em andamento.
+CREATE TABLE message_idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    job_id UUID NULL REFERENCES message_jobs(id) ON DELETE CASCADE,
+    student_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX idx_message_idempotency_keys_expires_at
+ON message_idempotency_keys (expires_at);
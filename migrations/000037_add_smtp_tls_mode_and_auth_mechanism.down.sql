@@ -0,0 +1,7 @@
+ALTER TABLE smtp_instances
+DROP CONSTRAINT IF EXISTS smtp_instances_auth_mechanism_check,
+DROP CONSTRAINT IF EXISTS smtp_instances_tls_mode_check;
+
+ALTER TABLE smtp_instances
+DROP COLUMN IF EXISTS auth_mechanism,
+DROP COLUMN IF EXISTS tls_mode;
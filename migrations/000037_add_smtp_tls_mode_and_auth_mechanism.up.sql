@@ -0,0 +1,9 @@
+-- tls_mode: starttls (porta 587), tls (SMTPS, porta 465) ou none (relays internos confiáveis).
+-- auth_mechanism: PLAIN, LOGIN, CRAM-MD5 ou NONE (relays que não exigem autenticação).
+ALTER TABLE smtp_instances
+ADD COLUMN tls_mode VARCHAR(16) NOT NULL DEFAULT 'starttls',
+ADD COLUMN auth_mechanism VARCHAR(16) NOT NULL DEFAULT 'PLAIN';
+
+ALTER TABLE smtp_instances
+ADD CONSTRAINT smtp_instances_tls_mode_check CHECK (tls_mode IN ('starttls', 'tls', 'none')),
+ADD CONSTRAINT smtp_instances_auth_mechanism_check CHECK (auth_mechanism IN ('PLAIN', 'LOGIN', 'CRAM-MD5', 'NONE'));
@@ -0,0 +1,2 @@
+ALTER TABLE smtp_instances
+DROP COLUMN IF EXISTS password_reentry_required;
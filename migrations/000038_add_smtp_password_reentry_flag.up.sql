@@ -0,0 +1,4 @@
+-- password_reentry_required marca senhas SMTP cifradas com uma chave que o usuário não tem mais
+-- (reset administrativo da senha da conta); o envio fica bloqueado até a senha ser informada de novo.
+ALTER TABLE smtp_instances
+ADD COLUMN password_reentry_required BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS whatsapp_connection_state_changes;
+
+ALTER TABLE whatsapp_instances
+DROP COLUMN IF EXISTS connection_status_updated_at;
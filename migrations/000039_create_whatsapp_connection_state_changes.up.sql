@@ -0,0 +1,15 @@
+-- connection_status_updated_at marca a última mudança de estado; as transições ficam no histórico abaixo.
+ALTER TABLE whatsapp_instances
+ADD COLUMN connection_status_updated_at TIMESTAMPTZ;
+
+CREATE TABLE whatsapp_connection_state_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    instance_id UUID NOT NULL REFERENCES whatsapp_instances(id) ON DELETE CASCADE,
+    from_status VARCHAR NOT NULL,
+    to_status VARCHAR NOT NULL,
+    -- source indica quem observou a mudança: monitor, status (consulta do professor) ou send.
+    source VARCHAR NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_whatsapp_connection_state_changes_instance ON whatsapp_connection_state_changes (instance_id, changed_at DESC);
@@ -0,0 +1,25 @@
+-- inbound_messages guarda as respostas dos alunos recebidas pelo WhatsApp (webhook MESSAGES_UPSERT).
+-- delivery_group_id aponta para o último envio por WhatsApp ao aluno antes da resposta, quando houver.
+CREATE TABLE inbound_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    student_id UUID NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    whatsapp_instance_id UUID NULL REFERENCES whatsapp_instances(id) ON DELETE SET NULL,
+    delivery_group_id UUID NULL,
+    provider_message_id VARCHAR NOT NULL,
+    phone VARCHAR NOT NULL,
+    push_name VARCHAR NULL,
+    message_type VARCHAR NULL,
+    body TEXT NOT NULL DEFAULT '',
+    received_at TIMESTAMPTZ NOT NULL,
+    read_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    -- A Evolution reenvia o webhook em caso de falha; o ID da mensagem evita duplicatas.
+    UNIQUE (user_id, provider_message_id)
+);
+
+CREATE INDEX idx_inbound_messages_user_received_at
+ON inbound_messages (user_id, received_at DESC);
+
+CREATE INDEX idx_inbound_messages_user_unread
+ON inbound_messages (user_id) WHERE read_at IS NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE message_jobs
+DROP COLUMN IF EXISTS jwe_reentry_required;
+
+ALTER TABLE message_schedules
+DROP COLUMN IF EXISTS jwe_reentry_required;
@@ -0,0 +1,8 @@
+-- Após uma redefinição de senha pelo admin, o JWE guardado carrega uma chave SMTP que não existe mais.
+-- Ele é descartado e o agendamento (ou job pendente) fica marcado: os envios por SMTP com senha falham
+-- pedindo a senha de novo até o agendamento receber um JWE novo.
+ALTER TABLE message_schedules
+ADD COLUMN jwe_reentry_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE message_jobs
+ADD COLUMN jwe_reentry_required BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE whatsapp_instances
+DROP COLUMN IF EXISTS disconnect_alert_error;
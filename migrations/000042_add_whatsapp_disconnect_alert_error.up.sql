@@ -0,0 +1,4 @@
+-- Guarda por que o último aviso de queda não chegou ao professor (ex.: só há SMTP com senha),
+-- para a tela de integrações mostrar o problema. Volta a NULL quando um aviso é enviado ou a instância reconecta.
+ALTER TABLE whatsapp_instances
+ADD COLUMN disconnect_alert_error TEXT NULL;
@@ -24,6 +24,11 @@ func (e *CustomError) Error() string {
 	return fmt.Sprintf("%s: %s", e.message, e.Err.Error())
 }
 
+// Unwrap permite que errors.Is/As alcancem o erro interno.
+func (e *CustomError) Unwrap() error {
+	return e.Err
+}
+
 func (e *CustomError) PublicMessage() string {
 	return e.message
 }
@@ -0,0 +1,193 @@
+// Package fetcher baixa arquivos de URLs informadas por usuários sem expor a rede interna do servidor.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ThalysSilva/unicast-backend/pkg/customerror"
+)
+
+var (
+	ErrSchemeNotAllowed = customerror.Make("a url do anexo deve usar http ou https", http.StatusBadRequest, errors.New("ErrSchemeNotAllowed"))
+	ErrBlockedAddress   = customerror.Make("a url do anexo aponta para um endereço não permitido", http.StatusBadRequest, errors.New("ErrBlockedAddress"))
+	ErrTooLarge         = customerror.Make("anexo excede o tamanho máximo permitido", http.StatusBadRequest, errors.New("ErrTooLarge"))
+	ErrTooManyRedirects = customerror.Make("a url do anexo redireciona vezes demais", http.StatusBadRequest, errors.New("ErrTooManyRedirects"))
+	ErrTimeout          = customerror.Make("tempo esgotado ao baixar o anexo", http.StatusGatewayTimeout, errors.New("ErrTimeout"))
+	ErrDownloadFailed   = customerror.Make("falha ao baixar o anexo", http.StatusBadGateway, errors.New("ErrDownloadFailed"))
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultMaxRedirects = 3
+)
+
+// blockedPrefixes completa os testes de net.IP com faixas que não são públicas mas não têm método próprio.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+type Options struct {
+	// MaxBytes limita o corpo lido; acima dele o download é interrompido com ErrTooLarge.
+	MaxBytes int64
+	// Timeout vale para o download inteiro, redirecionamentos incluídos. Padrão: 30s.
+	Timeout time.Duration
+	// MaxRedirects padrão: 3.
+	MaxRedirects int
+	// AllowPrivateNetworks libera endereços internos; serve para testes e ambientes de desenvolvimento.
+	AllowPrivateNetworks bool
+}
+
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+	timeout  time.Duration
+}
+
+// New monta um cliente sem proxy cujo dialer confere o IP de cada conexão, inclusive após redirecionamentos.
+// A checagem acontece no IP já resolvido, então um DNS que troca de resposta entre consultas não a contorna.
+func New(opts Options) *Fetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = defaultMaxRedirects
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !opts.AllowPrivateNetworks {
+		dialer.Control = controlAddress
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 15 * time.Second,
+		IdleConnTimeout:       30 * time.Second,
+		MaxIdleConns:          10,
+	}
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return ValidateURL(req.URL.String())
+		},
+	}
+	return &Fetcher{client: client, maxBytes: opts.MaxBytes, timeout: opts.Timeout}
+}
+
+// ValidateURL confere o formato da URL sem acessá-la: http ou https, com host e sem usuário e senha embutidos.
+func ValidateURL(rawURL string) error {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ErrSchemeNotAllowed
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ErrSchemeNotAllowed
+	}
+	if parsed.Hostname() == "" || parsed.User != nil {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// Fetch baixa o conteúdo de rawURL lendo no máximo MaxBytes.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSpace(rawURL), nil)
+	if err != nil {
+		return nil, ErrSchemeNotAllowed
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, classify(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, customerror.Trace("Fetch", fmt.Errorf("%w: status %d", ErrDownloadFailed, resp.StatusCode))
+	}
+	if f.maxBytes > 0 && resp.ContentLength > f.maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	body := io.Reader(resp.Body)
+	if f.maxBytes > 0 {
+		body = io.LimitReader(resp.Body, f.maxBytes+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, classify(ctx, err)
+	}
+	if f.maxBytes > 0 && int64(len(data)) > f.maxBytes {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// classify troca os erros de rede pelos erros públicos do pacote, mantendo o original no Trace.
+func classify(ctx context.Context, err error) error {
+	for _, known := range []error{ErrBlockedAddress, ErrSchemeNotAllowed, ErrTooManyRedirects} {
+		if errors.Is(err, known) {
+			return known
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return customerror.Trace("Fetch", ErrTimeout)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return customerror.Trace("Fetch", ErrTimeout)
+	}
+	return customerror.Trace("Fetch", fmt.Errorf("%w: %v", ErrDownloadFailed, err))
+}
+
+func controlAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrBlockedAddress
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || IsBlocked(addr) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// IsBlocked indica se o endereço é interno: loopback, redes privadas, link-local, multicast e faixas reservadas.
+func IsBlocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsMulticast() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
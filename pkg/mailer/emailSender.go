@@ -2,10 +2,10 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"math"
-	"net/smtp"
+	"net/textproto"
 	"sync"
 	"time"
 
@@ -17,6 +17,9 @@ type SmtpAuthentication struct {
 	Port     int
 	Username string
 	Password string
+	// TLSMode e AuthMechanism vazios equivalem a STARTTLS com AUTH PLAIN.
+	TLSMode       TLSMode
+	AuthMechanism AuthMechanism
 }
 
 type ContentType string
@@ -47,26 +50,87 @@ type MailerData struct {
 	Body               string
 	Attachments        *[]Attachment
 	ContentType        ContentType
+	// TextBody é a alternativa em texto puro de um corpo HTML; com ela o email sai como multipart/alternative.
+	TextBody           string
 	SmtpAuthentication SmtpAuthentication
 }
 
-type EmailSentError struct {
-	From    string
-	To      []string
-	Subject string
-	Body    string
+// RecipientStatus é o resultado final do envio para um endereço.
+type RecipientStatus string
+
+const (
+	// RecipientAccepted indica que o servidor aceitou o email na primeira tentativa.
+	RecipientAccepted RecipientStatus = "accepted"
+	// RecipientRetried indica que o servidor aceitou o email depois de uma nova tentativa.
+	RecipientRetried RecipientStatus = "retried"
+	// RecipientRejected indica que o email não foi aceito; Err traz o motivo.
+	RecipientRejected RecipientStatus = "rejected"
+)
+
+// ErrRecipientRejected casa, via errors.Is, com recusas definitivas (código SMTP 5xx).
+var ErrRecipientRejected = errors.New("destinatário recusado pelo servidor SMTP")
+
+// RecipientError é a falha do envio para um endereço. Code e Message vêm da resposta SMTP;
+// Code é zero quando a falha foi de conexão ou tempo limite.
+type RecipientError struct {
+	Address string
+	Code    int
+	Message string
+	Err     error
+}
+
+func (e *RecipientError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("servidor SMTP recusou o email (%d): %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("falha ao enviar email: %v", e.Err)
+}
+
+func (e *RecipientError) Unwrap() error {
+	return e.Err
 }
 
-type EmailToRetry struct {
-	From    string
-	To      string
-	Subject string
-	Body    string
-	Attachments *[]Attachment
+func (e *RecipientError) Is(target error) bool {
+	return target == ErrRecipientRejected && e.permanent()
 }
 
-func (e *EmailSentError) Error() string {
-	return fmt.Sprintf("Failed to send email from %s to %v with subject %s and body %s", e.From, e.To, e.Subject, e.Body)
+func (e *RecipientError) permanent() bool {
+	return e.Code >= 500
+}
+
+func newRecipientError(address string, err error) *RecipientError {
+	recipientErr := &RecipientError{Address: address, Err: err}
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		recipientErr.Code = protoErr.Code
+		recipientErr.Message = protoErr.Msg
+	}
+	return recipientErr
+}
+
+// RecipientResult é o resultado do envio para um endereço de MailerData.To.
+type RecipientResult struct {
+	Address  string
+	Status   RecipientStatus
+	Attempts int
+	// Err é um *RecipientError quando Status é RecipientRejected; nil nos demais casos.
+	Err error
+}
+
+// SendResult traz um resultado por destinatário, na mesma ordem de MailerData.To.
+type SendResult struct {
+	Recipients []RecipientResult
+}
+
+// Rejected devolve apenas os destinatários que não receberam o email.
+func (r *SendResult) Rejected() []RecipientResult {
+	rejected := []RecipientResult{}
+	for _, recipient := range r.Recipients {
+		if recipient.Status == RecipientRejected {
+			rejected = append(rejected, recipient)
+		}
+	}
+	return rejected
 }
 
 type emailPool interface {
@@ -75,22 +139,46 @@ type emailPool interface {
 }
 
 // newPoolFunc é uma variável interna que pode ser sobrescrita nos testes
-var newPoolFunc = func(host string, pools int, auth smtp.Auth) (emailPool, error) {
-	return email.NewPool(host, pools, auth)
+var newPoolFunc = func(config SmtpAuthentication, pools int) (emailPool, error) {
+	return newSMTPPool(config, pools)
 }
 
-// newEmailChannelsFunc é uma variável interna que pode ser sobrescrita nos testes
-var newEmailChannelsFunc = func(bufferSize int) (chan *email.Email, chan *EmailToRetry) {
-	return make(chan *email.Email, bufferSize), make(chan *EmailToRetry, bufferSize)
-}
+const (
+	defaultPoolsForSend  = 4
+	defaultPoolsForRetry = 4
+	defaultSendTimeout   = 5 * time.Second
+)
 
 type EmailSenderOption func(*emailSenderImpl)
 
+// emailSenderImpl guarda apenas configuração e dados; cada chamada de SendEmails cria a própria
+// sincronização, então envios de professores diferentes não interferem entre si.
 type emailSenderImpl struct {
+	mu            sync.Mutex
 	data          *MailerData
+	poolsForSend  int
+	poolsForRetry int
+	timeout       time.Duration
 	interceptChan chan *email.Email
 }
 
+// WithPools define quantas conexões enviam os emails e quantas fazem a nova tentativa das falhas temporárias.
+func WithPools(poolsForSend, poolsForRetry int) EmailSenderOption {
+	return func(es *emailSenderImpl) {
+		es.poolsForSend = poolsForSend
+		es.poolsForRetry = poolsForRetry
+	}
+}
+
+// WithSendTimeout define o tempo limite de cada email; o prazo do contexto, se menor, prevalece.
+func WithSendTimeout(timeout time.Duration) EmailSenderOption {
+	return func(es *emailSenderImpl) {
+		es.timeout = timeout
+	}
+}
+
+// WithInterceptChan copia cada email para ch antes do envio. Usado nos testes: o canal é fechado
+// ao fim de SendEmails, então vale para um único envio.
 func WithInterceptChan(ch chan *email.Email) EmailSenderOption {
 	return func(es *emailSenderImpl) {
 		es.interceptChan = ch
@@ -98,15 +186,14 @@ func WithInterceptChan(ch chan *email.Email) EmailSenderOption {
 }
 
 type EmailSender interface {
-	// SendEmails envia os emails para os destinatários especificados em concorrência.
-	// O número de pools para envio e retry é especificado pelos parâmetros poolsForSend e poolsForRetry, respectivamente.
-	// O parâmetro groupSize especifica o número de destinatários a serem enviados em cada pool.
-	// O parâmetro timeout especifica o tempo limite para o envio de cada email.
-	// Se o envio falhar, os emails serão enviados novamente usando os pools de retry.
-	// Se o envio falhar novamente, os emails com erro serão retornados.
-	// Se o envio for bem-sucedido, nil será retornado.
-	// Se houver erros, um EmailSentError será retornado com os detalhes do erro.
-	SendEmails(poolsForSend int, poolsForRetry int, groupSize int, timeout time.Duration) error
+	// SendEmails envia um email por destinatário, em concorrência, para que nenhum aluno veja o endereço dos outros.
+	// O número de conexões e o tempo limite de cada email vêm de WithPools e WithSendTimeout.
+	// Falhas temporárias (conexão, tempo limite ou código SMTP 4xx) são enviadas novamente uma vez pelos pools de retry;
+	// recusas definitivas (5xx) não são repetidas.
+	// Quando o contexto é cancelado, os emails ainda não enviados são recusados com o erro do contexto.
+	// O erro indica que nada foi enviado (dados inválidos, contexto encerrado ou falha ao abrir o pool); o resultado de cada
+	// destinatário, aceito ou recusado, vem em SendResult.
+	SendEmails(ctx context.Context) (*SendResult, error)
 	// SetData define os dados do email a serem enviados.
 	// Se os dados não forem válidos, um erro será retornado.
 	// Os dados incluem o remetente, destinatário, assunto, corpo e tipo de conteúdo do email.
@@ -115,13 +202,14 @@ type EmailSender interface {
 	SetData(data *MailerData) error
 }
 
-var wgEmailsDispatch sync.WaitGroup
-var wgEmailsRetry sync.WaitGroup
-var mu sync.Mutex
-
 func NewEmailSender(config SmtpAuthentication, opts ...EmailSenderOption) EmailSender {
 	data := &MailerData{SmtpAuthentication: config}
-	sender := &emailSenderImpl{data: data}
+	sender := &emailSenderImpl{
+		data:          data,
+		poolsForSend:  defaultPoolsForSend,
+		poolsForRetry: defaultPoolsForRetry,
+		timeout:       defaultSendTimeout,
+	}
 	for _, opt := range opts {
 		opt(sender)
 	}
@@ -141,163 +229,157 @@ func (m *emailSenderImpl) SetData(data *MailerData) error {
 	if len(data.Body) == 0 {
 		return errors.New("nenhum corpo fornecido")
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	data.SmtpAuthentication = m.data.SmtpAuthentication
 	m.data = data
 	return nil
 }
 
-func (m *emailSenderImpl) SendEmails(poolsForSend int, poolsForRetry int, groupSize int, timeout time.Duration) error {
-	if m.data == nil {
-		return errors.New("nenhum dado fornecido")
-	}
+func (m *emailSenderImpl) SendEmails(ctx context.Context) (*SendResult, error) {
+	m.mu.Lock()
 	data := m.data
-	expectedGroups := int(math.Ceil(float64(len(data.To)) / float64(groupSize)))
-	if !data.ContentType.IsValid() {
-		return errors.New("conteúdo do email inválido")
+	m.mu.Unlock()
+
+	if data == nil {
+		return nil, errors.New("nenhum dado fornecido")
 	}
-	if poolsForSend <= 0 {
-		return errors.New("pools para envio devem ser maiores que 0")
+	if !data.ContentType.IsValid() {
+		return nil, errors.New("conteúdo do email inválido")
 	}
-	if poolsForRetry <= 0 {
-		return errors.New("pools para retry devem ser maiores que 0")
+	if m.poolsForSend <= 0 {
+		return nil, errors.New("pools para envio devem ser maiores que 0")
 	}
-	if groupSize <= 0 {
-		return errors.New("groupSize deve ser maior que 0")
+	if m.poolsForRetry <= 0 {
+		return nil, errors.New("pools para retry devem ser maiores que 0")
 	}
-	if timeout <= 0 {
-		return errors.New("timeout deve ser maior que 0")
+	if m.timeout <= 0 {
+		return nil, errors.New("timeout deve ser maior que 0")
 	}
 	if len(data.To) == 0 {
-		return errors.New("nenhum destinatário fornecido")
+		return nil, errors.New("nenhum destinatário fornecido")
 	}
 	if len(data.From) == 0 {
-		return errors.New("nenhum remetente fornecido")
+		return nil, errors.New("nenhum remetente fornecido")
 	}
 	if len(data.Subject) == 0 {
-		return errors.New("nenhum assunto fornecido")
+		return nil, errors.New("nenhum assunto fornecido")
 	}
 	if len(data.Body) == 0 {
-		return errors.New("nenhum corpo fornecido")
+		return nil, errors.New("nenhum corpo fornecido")
 	}
-
-	emailsChan, emailsRetryChan := newEmailChannelsFunc(len(data.To))
-	emailsWithErrors := EmailSentError{
-		From:    data.From,
-		Subject: data.Subject,
-		Body:    data.Body,
-		To:      []string{},
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	totalPools := poolsForSend + poolsForRetry
+	// Os emails são montados antes de abrir o pool: um anexo inválido recusa o envio inteiro.
+	emails := make([]*email.Email, len(data.To))
+	for i, address := range data.To {
+		msg := &email.Email{
+			From:    data.From,
+			To:      []string{address},
+			Subject: data.Subject,
+		}
+		setEmailBody(msg, data.ContentType, data.Body, data.TextBody)
+		if err := attachEmailAttachments(msg, data.Attachments); err != nil {
+			return nil, err
+		}
+		emails[i] = msg
+	}
 
-	smtpPlainAuth := smtp.PlainAuth("", data.SmtpAuthentication.Username, data.SmtpAuthentication.Password, data.SmtpAuthentication.Host)
-	pool, err := newPoolFunc(
-		data.SmtpAuthentication.Host+":"+fmt.Sprint(data.SmtpAuthentication.Port),
-		totalPools,
-		smtpPlainAuth,
-	)
+	pool, err := newPoolFunc(data.SmtpAuthentication, m.poolsForSend+m.poolsForRetry)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer closeEmailPoolSafely(pool, 2*time.Second)
-
-	sendChan := emailsChan
 	if m.interceptChan != nil {
-		fanOutChan := make(chan *email.Email, expectedGroups)
-		go func() {
-			defer close(m.interceptChan)
-			defer close(fanOutChan)
-			for email := range emailsChan {
-				m.interceptChan <- email
-				fanOutChan <- email
-			}
-		}()
-		sendChan = fanOutChan
+		defer close(m.interceptChan)
+	}
+
+	// Cada goroutine escreve apenas no índice do email que está enviando.
+	result := &SendResult{Recipients: make([]RecipientResult, len(data.To))}
+	for i, address := range data.To {
+		result.Recipients[i] = RecipientResult{Address: address, Status: RecipientRejected}
+	}
+	send := func(i int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result.Recipients[i].Attempts++
+		return pool.Send(emails[i], m.sendTimeout(ctx))
 	}
 
-	wgEmailsRetry.Add(poolsForRetry)
-	for range poolsForRetry {
+	sendChan := make(chan int, len(emails))
+	retryChan := make(chan int, len(emails))
+	var wgDispatch, wgRetry sync.WaitGroup
+
+	wgRetry.Add(m.poolsForRetry)
+	for range m.poolsForRetry {
 		go func() {
-			defer wgEmailsRetry.Done()
-			for retryEmail := range emailsRetryChan {
-				email := &email.Email{
-					From:    retryEmail.From,
-					To:      []string{retryEmail.To},
-					Subject: retryEmail.Subject,
-				}
-				switch data.ContentType {
-				case TextPlain:
-					email.Text = []byte(retryEmail.Body)
-				case TextHTML:
-					email.HTML = []byte(retryEmail.Body)
-				}
-				if err := attachEmailAttachments(email, retryEmail.Attachments); err != nil {
-					mu.Lock()
-					emailsWithErrors.To = append(emailsWithErrors.To, retryEmail.To)
-					mu.Unlock()
+			defer wgRetry.Done()
+			for i := range retryChan {
+				if err := send(i); err != nil {
+					result.Recipients[i].Err = newRecipientError(data.To[i], err)
 					continue
 				}
-				if err := pool.Send(email, timeout); err != nil {
-					mu.Lock()
-					emailsWithErrors.To = append(emailsWithErrors.To, retryEmail.To)
-					mu.Unlock()
-				}
+				result.Recipients[i].Status = RecipientRetried
+				result.Recipients[i].Err = nil
 			}
 		}()
 	}
-	wgEmailsDispatch.Add(poolsForSend)
-	for range poolsForSend {
+	wgDispatch.Add(m.poolsForSend)
+	for range m.poolsForSend {
 		go func() {
-			defer wgEmailsDispatch.Done()
-			for email := range sendChan {
-				if err := pool.Send(email, timeout); err != nil {
-					for _, emailToRetry := range email.To {
-						retryEmail := &EmailToRetry{
-							From:    data.From,
-							To:      emailToRetry,
-							Subject: data.Subject,
-							Body:    data.Body,
-							Attachments: data.Attachments,
-						}
-						emailsRetryChan <- retryEmail
-					}
+			defer wgDispatch.Done()
+			for i := range sendChan {
+				if m.interceptChan != nil && ctx.Err() == nil {
+					m.interceptChan <- emails[i]
+				}
+				err := send(i)
+				if err == nil {
+					result.Recipients[i].Status = RecipientAccepted
+					continue
+				}
+				recipientErr := newRecipientError(data.To[i], err)
+				result.Recipients[i].Err = recipientErr
+				if !recipientErr.permanent() && ctx.Err() == nil {
+					retryChan <- i
 				}
 			}
 		}()
 	}
 
-	for i := 0; i < len(data.To); i += groupSize {
-		end := min(i+groupSize, len(data.To))
-		email := &email.Email{
-			From:    data.From,
-			To:      data.To[i:end],
-				Subject: data.Subject,
-			}
-			switch data.ContentType {
-			case TextPlain:
-				email.Text = []byte(data.Body)
-			case TextHTML:
-				email.HTML = []byte(data.Body)
-			}
-			if err := attachEmailAttachments(email, data.Attachments); err != nil {
-				return err
-			}
-			emailsChan <- email
-		}
+	for i := range emails {
+		sendChan <- i
+	}
+	close(sendChan)
+	wgDispatch.Wait()
+	close(retryChan)
+	wgRetry.Wait()
 
-	close(emailsChan)
-	wgEmailsDispatch.Wait()
-	close(emailsRetryChan)
-	wgEmailsRetry.Wait()
+	return result, nil
+}
 
-	if len(emailsWithErrors.To) == len(data.To) {
-		return errors.New("todos os emails falharam")
+// sendTimeout limita o tempo de cada email ao prazo que ainda resta no contexto.
+func (m *emailSenderImpl) sendTimeout(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return m.timeout
 	}
+	return max(min(m.timeout, time.Until(deadline)), time.Millisecond)
+}
 
-	if len(emailsWithErrors.To) > 0 {
-		return &emailsWithErrors
+// setEmailBody preenche o corpo conforme o tipo; HTML com texto alternativo gera as duas partes.
+func setEmailBody(msg *email.Email, contentType ContentType, body, textBody string) {
+	switch contentType {
+	case TextHTML:
+		msg.HTML = []byte(body)
+		if textBody != "" {
+			msg.Text = []byte(textBody)
+		}
+	default:
+		msg.Text = []byte(body)
 	}
-	return nil
 }
 
 func attachEmailAttachments(msg *email.Email, attachments *[]Attachment) error {
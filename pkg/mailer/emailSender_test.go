@@ -1,10 +1,10 @@
 package mailer
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math"
-	"net/smtp"
+	"net/textproto"
 	"sync"
 	"testing"
 	"time"
@@ -43,11 +43,11 @@ func (m *mockEmailPool) Send(e *email.Email, timeout time.Duration) error {
 
 func (m *mockEmailPool) Close() {}
 
-func mockNewPoolFunc(host string, pools int, auth smtp.Auth) (emailPool, error) {
+func mockNewPoolFunc(config SmtpAuthentication, pools int) (emailPool, error) {
 	return &mockEmailPool{}, nil
 }
 
-func mockNewPoolFuncWithError(host string, pools int, auth smtp.Auth) (emailPool, error) {
+func mockNewPoolFuncWithError(config SmtpAuthentication, pools int) (emailPool, error) {
 	return nil, errors.New("failed to create pool")
 }
 
@@ -76,8 +76,9 @@ func TestSendEmails_Success(t *testing.T) {
 		ContentType: TextPlain,
 	})
 
-	err := sender.SendEmails(2, 2, 1, 5*time.Second)
+	result, err := sender.SendEmails(context.Background())
 	assert.Nil(t, err, "Expected no error on email send")
+	assert.Equal(t, []RecipientResult{{Address: "test123@test.com", Status: RecipientAccepted, Attempts: 1}}, result.Recipients)
 }
 
 // Verificar Erro de Envio com contentType inválido
@@ -102,7 +103,7 @@ func TestSendEmails_InvalidContentType(t *testing.T) {
 		ContentType: "invalid",
 	})
 
-	err := sender.SendEmails(2, 2, 1, 5*time.Second)
+	_, err := sender.SendEmails(context.Background())
 	assert.NotNil(t, err)
 	assert.Equal(t, "conteúdo do email inválido", err.Error())
 }
@@ -125,13 +126,13 @@ func TestSendEmails_FailureOnPoolCreation(t *testing.T) {
 		ContentType: TextPlain,
 	})
 
-	err := sender.SendEmails(2, 2, 1, 5*time.Second)
+	_, err := sender.SendEmails(context.Background())
 	assert.NotNil(t, err)
 	assert.Equal(t, "failed to create pool", err.Error())
 }
 
 func TestSendEmails_FailureOnSend(t *testing.T) {
-	newPoolFunc = func(host string, pools int, auth smtp.Auth) (emailPool, error) {
+	newPoolFunc = func(config SmtpAuthentication, pools int) (emailPool, error) {
 		return &mockEmailPool{sendErr: errors.New("failed to send email")}, nil
 	}
 
@@ -150,8 +151,12 @@ func TestSendEmails_FailureOnSend(t *testing.T) {
 		ContentType: TextPlain,
 	})
 
-	err := sender.SendEmails(2, 2, 1, 5*time.Second)
-	assert.NotNil(t, err)
+	result, err := sender.SendEmails(context.Background())
+	assert.Nil(t, err)
+	if assert.Len(t, result.Rejected(), 1) {
+		assert.Equal(t, 2, result.Recipients[0].Attempts)
+		assert.EqualError(t, result.Recipients[0].Err, "falha ao enviar email: failed to send email")
+	}
 }
 
 // / Teste de envio em massa com interceptação
@@ -162,8 +167,6 @@ func TestSendEmails_MassiveWithInterception(t *testing.T) {
 	})
 
 	numRecipients := 1000
-	groupSize := 50
-	expectedGroups := int(math.Floor(float64(numRecipients) / float64(groupSize)))
 	poolsForSend := 4
 	poolsForRetry := 4
 	timeout := 5 * time.Second
@@ -173,13 +176,13 @@ func TestSendEmails_MassiveWithInterception(t *testing.T) {
 		recipients[i] = fmt.Sprintf("recipient%d@example.com", i)
 	}
 
-	interceptChan := make(chan *email.Email, expectedGroups)
+	interceptChan := make(chan *email.Email, numRecipients)
 	sender := NewEmailSender(SmtpAuthentication{
 		Host:     "smtp.example.com",
 		Port:     587,
 		Username: "user",
 		Password: "pass",
-	}, WithInterceptChan(interceptChan))
+	}, WithInterceptChan(interceptChan), WithPools(poolsForSend, poolsForRetry), WithSendTimeout(timeout))
 
 	err := sender.SetData(&MailerData{
 		From:        "sender@example.com",
@@ -194,24 +197,25 @@ func TestSendEmails_MassiveWithInterception(t *testing.T) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := sender.SendEmails(poolsForSend, poolsForRetry, groupSize, timeout)
+		result, err := sender.SendEmails(context.Background())
 		assert.Nil(t, err, "Esperado sucesso ao enviar emails")
+		assert.Empty(t, result.Rejected())
 	}()
 
 	wg.Wait()
 
-	capturedEmails := make([]*email.Email, 0, expectedGroups)
+	capturedEmails := make([]*email.Email, 0, numRecipients)
+	capturedTo := make([]string, 0, numRecipients)
 	for email := range interceptChan {
 		capturedEmails = append(capturedEmails, email)
+		capturedTo = append(capturedTo, email.To...)
 	}
 
-	assert.Equal(t, expectedGroups, len(capturedEmails), fmt.Sprintf("Deveria ter capturado %d grupos de e-mails", expectedGroups))
+	assert.Equal(t, numRecipients, len(capturedEmails), "Deveria ter capturado um email por destinatário")
+	assert.ElementsMatch(t, recipients, capturedTo)
 
-	for i, email := range capturedEmails {
-		startIdx := i * groupSize
-		endIdx := min(startIdx+groupSize, numRecipients)
-		expectedTo := recipients[startIdx:endIdx]
-		assert.ElementsMatch(t, expectedTo, email.To, fmt.Sprintf("Grupo %d deveria ter os destinatários corretos", i))
+	for _, email := range capturedEmails {
+		assert.Len(t, email.To, 1, "Cada email deveria ter um único destinatário")
 		assert.Equal(t, "sender@example.com", email.From, "Remetente incorreto")
 		assert.Equal(t, "Teste de Envio em Massa", email.Subject, "Assunto incorreto")
 		assert.Equal(t, "Esse é um teste de envio em massa.", string(email.Text), "Corpo incorreto")
@@ -226,8 +230,6 @@ func TestSendEmails_Massive(t *testing.T) {
 	})
 
 	numRecipients := 1000
-	groupSize := 50
-	expectedGroups := int(math.Floor(float64(numRecipients) / float64(groupSize)))
 	poolsForSend := 4
 	poolsForRetry := 4
 	timeout := 5 * time.Second
@@ -242,7 +244,7 @@ func TestSendEmails_Massive(t *testing.T) {
 		Port:     587,
 		Username: "user",
 		Password: "pass",
-	})
+	}, WithPools(poolsForSend, poolsForRetry), WithSendTimeout(timeout))
 
 	if err := senderBench.SetData(&MailerData{
 		From:        "sender@example.com",
@@ -255,10 +257,10 @@ func TestSendEmails_Massive(t *testing.T) {
 	}
 
 	start := time.Now()
-	err := senderBench.SendEmails(poolsForSend, poolsForRetry, groupSize, timeout)
+	_, err := senderBench.SendEmails(context.Background())
 	assert.Nil(t, err)
 	duration := time.Since(start)
-	expectedMinDuration := time.Duration(expectedGroups*qtyMillisecondsToSleep/poolsForSend) * time.Millisecond
+	expectedMinDuration := time.Duration(numRecipients*qtyMillisecondsToSleep/poolsForSend) * time.Millisecond
 	assert.GreaterOrEqual(t, duration, expectedMinDuration, "Tempo de execução deveria ser pelo menos %v", expectedMinDuration)
 	t.Logf("Tempo de execução para %d destinatários: %v", numRecipients, duration)
 }
@@ -269,7 +271,7 @@ func TestSendEmails_RetrySuccess(t *testing.T) {
 		failFirstSend: true,
 		sendErr:       nil,
 	}
-	newPoolFunc = func(host string, pools int, auth smtp.Auth) (emailPool, error) {
+	newPoolFunc = func(config SmtpAuthentication, pools int) (emailPool, error) {
 		return pool, nil
 	}
 	t.Cleanup(func() {
@@ -281,7 +283,7 @@ func TestSendEmails_RetrySuccess(t *testing.T) {
 		Port:     587,
 		Username: "user",
 		Password: "pass",
-	})
+	}, WithPools(1, 1))
 
 	sender.SetData(&MailerData{
 		From:        "sender@example.com",
@@ -291,16 +293,20 @@ func TestSendEmails_RetrySuccess(t *testing.T) {
 		ContentType: TextPlain,
 	})
 
-	err := sender.SendEmails(1, 1, 1, 5*time.Second)
+	result, err := sender.SendEmails(context.Background())
 	assert.Nil(t, err, "Esperado sucesso após retry")
 	assert.Len(t, pool.sentEmails, 3)
 
-	// O mock falha no primeiro envio, mas succeeds no retry, então o resultado deve ser nil
+	// O mock falha apenas no primeiro envio: o primeiro destinatário é aceito na segunda tentativa.
+	assert.Empty(t, result.Rejected())
+	assert.Equal(t, RecipientRetried, result.Recipients[0].Status)
+	assert.Equal(t, 2, result.Recipients[0].Attempts)
+	assert.Equal(t, RecipientAccepted, result.Recipients[1].Status)
 }
 
 // Verificar Erro Persistente
 func TestSendEmails_PersistentFailure(t *testing.T) {
-	newPoolFunc = func(host string, pools int, auth smtp.Auth) (emailPool, error) {
+	newPoolFunc = func(config SmtpAuthentication, pools int) (emailPool, error) {
 		return &mockEmailPool{
 			sendErr: errors.New("falha de erro persistente"),
 		}, nil
@@ -324,9 +330,48 @@ func TestSendEmails_PersistentFailure(t *testing.T) {
 		ContentType: TextPlain,
 	})
 
-	err := sender.SendEmails(1, 1, 1, 5*time.Second)
-	assert.NotNil(t, err, "Esperado erro após falha persistente")
-	assert.Equal(t, "todos os emails falharam", err.Error())
+	result, err := sender.SendEmails(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, result.Rejected(), 2, "Esperado erro por destinatário após falha persistente")
+	for _, recipient := range result.Recipients {
+		assert.Equal(t, RecipientRejected, recipient.Status)
+		assert.Equal(t, 2, recipient.Attempts)
+		assert.ErrorContains(t, recipient.Err, "falha de erro persistente")
+	}
+}
+
+// Recusas definitivas trazem o código SMTP e não são repetidas
+func TestSendEmails_PermanentRejectionIsNotRetried(t *testing.T) {
+	pool := &mockEmailPool{sendErr: &textproto.Error{Code: 550, Msg: "5.1.1 mailbox unavailable"}}
+	newPoolFunc = func(config SmtpAuthentication, pools int) (emailPool, error) {
+		return pool, nil
+	}
+	t.Cleanup(func() {
+		newPoolFunc = originalNewPoolFunc
+	})
+
+	sender := NewEmailSender(SmtpAuthentication{Host: "smtp.example.com", Port: 587})
+	assert.Nil(t, sender.SetData(&MailerData{
+		From:        "sender@example.com",
+		To:          []string{"inexistente@example.com"},
+		Subject:     "Assunto",
+		Body:        "Corpo",
+		ContentType: TextPlain,
+	}))
+
+	result, err := sender.SendEmails(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, pool.sentEmails, 1)
+
+	recipient := result.Recipients[0]
+	assert.Equal(t, RecipientRejected, recipient.Status)
+	assert.Equal(t, 1, recipient.Attempts)
+	assert.ErrorIs(t, recipient.Err, ErrRecipientRejected)
+	var recipientErr *RecipientError
+	if assert.ErrorAs(t, recipient.Err, &recipientErr) {
+		assert.Equal(t, 550, recipientErr.Code)
+		assert.Equal(t, "5.1.1 mailbox unavailable", recipientErr.Message)
+	}
 }
 
 func TestSendEmails_InterceptedEmailIncludesAttachments(t *testing.T) {
@@ -356,7 +401,7 @@ func TestSendEmails_InterceptedEmailIncludesAttachments(t *testing.T) {
 	})
 	assert.Nil(t, err)
 
-	err = sender.SendEmails(1, 1, 1, 5*time.Second)
+	_, err = sender.SendEmails(context.Background())
 	assert.Nil(t, err)
 
 	var captured *email.Email
@@ -376,7 +421,7 @@ func TestSendEmails_RetryPreservesAttachments(t *testing.T) {
 		failFirstSend: true,
 		sendErr:       nil,
 	}
-	newPoolFunc = func(host string, pools int, auth smtp.Auth) (emailPool, error) {
+	newPoolFunc = func(config SmtpAuthentication, pools int) (emailPool, error) {
 		return pool, nil
 	}
 	t.Cleanup(func() {
@@ -403,7 +448,7 @@ func TestSendEmails_RetryPreservesAttachments(t *testing.T) {
 	})
 	assert.Nil(t, err)
 
-	err = sender.SendEmails(1, 1, 1, 5*time.Second)
+	_, err = sender.SendEmails(context.Background())
 	assert.Nil(t, err)
 
 	if assert.GreaterOrEqual(t, len(pool.sentEmails), 2) {
@@ -412,3 +457,199 @@ func TestSendEmails_RetryPreservesAttachments(t *testing.T) {
 		assert.Contains(t, string(raw), "filename=\"retry.txt\"")
 	}
 }
+
+func TestSendEmails_HTMLWithTextBodyIsMultipartAlternative(t *testing.T) {
+	newPoolFunc = mockNewPoolFunc
+	t.Cleanup(func() {
+		newPoolFunc = originalNewPoolFunc
+	})
+
+	interceptChan := make(chan *email.Email, 1)
+	sender := NewEmailSender(SmtpAuthentication{
+		Host:     "smtp.example.com",
+		Port:     587,
+		Username: "user",
+		Password: "pass",
+	}, WithInterceptChan(interceptChan))
+
+	err := sender.SetData(&MailerData{
+		From:        "sender@example.com",
+		To:          []string{"recipient@example.com"},
+		Subject:     "Teste HTML",
+		Body:        "<p>Olá <strong>turma</strong></p>",
+		TextBody:    "Olá turma",
+		ContentType: TextHTML,
+	})
+	assert.Nil(t, err)
+
+	_, err = sender.SendEmails(context.Background())
+	assert.Nil(t, err)
+
+	var captured *email.Email
+	for msg := range interceptChan {
+		captured = msg
+	}
+
+	if assert.NotNil(t, captured) {
+		assert.Equal(t, "Olá turma", string(captured.Text))
+		raw, bytesErr := captured.Bytes()
+		assert.Nil(t, bytesErr)
+		assert.Contains(t, string(raw), "multipart/alternative")
+	}
+}
+
+// funcEmailPool delega o envio para uma função, para os testes de concorrência e contexto.
+type funcEmailPool struct {
+	send func(e *email.Email, timeout time.Duration) error
+}
+
+func (f *funcEmailPool) Send(e *email.Email, timeout time.Duration) error {
+	return f.send(e, timeout)
+}
+
+func (f *funcEmailPool) Close() {}
+
+func newTestData(prefix string, qty int) *MailerData {
+	recipients := make([]string, qty)
+	for i := range qty {
+		recipients[i] = fmt.Sprintf("%s%d@example.com", prefix, i)
+	}
+	return &MailerData{
+		From:        prefix + "@example.com",
+		To:          recipients,
+		Subject:     "Assunto " + prefix,
+		Body:        "Corpo " + prefix,
+		ContentType: TextPlain,
+	}
+}
+
+// Um envio lento não pode segurar o envio de outro professor
+func TestSendEmails_ConcurrentSendersAreIndependent(t *testing.T) {
+	gate := make(chan struct{})
+	var slowSends, fastSends sync.Map
+	newPoolFunc = func(config SmtpAuthentication, pools int) (emailPool, error) {
+		if config.Host == "slow.example.com" {
+			return &funcEmailPool{send: func(e *email.Email, timeout time.Duration) error {
+				<-gate
+				slowSends.Store(e.To[0], e.From)
+				return nil
+			}}, nil
+		}
+		return &funcEmailPool{send: func(e *email.Email, timeout time.Duration) error {
+			fastSends.Store(e.To[0], e.From)
+			return nil
+		}}, nil
+	}
+	t.Cleanup(func() {
+		newPoolFunc = originalNewPoolFunc
+	})
+
+	slow := NewEmailSender(SmtpAuthentication{Host: "slow.example.com", Port: 587})
+	assert.Nil(t, slow.SetData(newTestData("lento", 10)))
+	slowDone := make(chan *SendResult)
+	go func() {
+		result, err := slow.SendEmails(context.Background())
+		assert.Nil(t, err)
+		slowDone <- result
+	}()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prefix := fmt.Sprintf("rapido%d-", i)
+			fast := NewEmailSender(SmtpAuthentication{Host: "fast.example.com", Port: 587}, WithPools(2, 1))
+			assert.Nil(t, fast.SetData(newTestData(prefix, 20)))
+			result, err := fast.SendEmails(context.Background())
+			assert.Nil(t, err)
+			assert.Empty(t, result.Rejected())
+			for j, recipient := range result.Recipients {
+				assert.Equal(t, fmt.Sprintf("%s%d@example.com", prefix, j), recipient.Address)
+			}
+		}()
+	}
+
+	fastDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(fastDone)
+	}()
+	select {
+	case <-fastDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("os envios rápidos ficaram presos esperando o envio lento")
+	}
+
+	close(gate)
+	result := <-slowDone
+	assert.Empty(t, result.Rejected())
+	for _, recipient := range result.Recipients {
+		from, ok := slowSends.Load(recipient.Address)
+		assert.True(t, ok)
+		assert.Equal(t, "lento@example.com", from)
+	}
+	fastSends.Range(func(to, from any) bool {
+		assert.NotEqual(t, "lento@example.com", from, "email de outro envio no pool errado: %v", to)
+		return true
+	})
+}
+
+// Cancelar o contexto interrompe os envios que ainda não começaram
+func TestSendEmails_StopsWhenContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var sent sync.Map
+	newPoolFunc = func(config SmtpAuthentication, pools int) (emailPool, error) {
+		return &funcEmailPool{send: func(e *email.Email, timeout time.Duration) error {
+			sent.Store(e.To[0], true)
+			cancel()
+			return nil
+		}}, nil
+	}
+	t.Cleanup(func() {
+		newPoolFunc = originalNewPoolFunc
+	})
+
+	sender := NewEmailSender(SmtpAuthentication{Host: "smtp.example.com", Port: 587}, WithPools(1, 1))
+	assert.Nil(t, sender.SetData(newTestData("aluno", 5)))
+
+	result, err := sender.SendEmails(ctx)
+	assert.Nil(t, err)
+
+	assert.Equal(t, RecipientAccepted, result.Recipients[0].Status)
+	assert.Len(t, result.Rejected(), 4)
+	for _, recipient := range result.Rejected() {
+		assert.Equal(t, 0, recipient.Attempts)
+		assert.ErrorIs(t, recipient.Err, context.Canceled)
+		_, wasSent := sent.Load(recipient.Address)
+		assert.False(t, wasSent)
+	}
+
+	_, err = sender.SendEmails(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// O prazo do contexto limita o tempo de cada email
+func TestSendEmails_DeadlineLimitsSendTimeout(t *testing.T) {
+	var got time.Duration
+	newPoolFunc = func(config SmtpAuthentication, pools int) (emailPool, error) {
+		return &funcEmailPool{send: func(e *email.Email, timeout time.Duration) error {
+			got = timeout
+			return nil
+		}}, nil
+	}
+	t.Cleanup(func() {
+		newPoolFunc = originalNewPoolFunc
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	sender := NewEmailSender(SmtpAuthentication{Host: "smtp.example.com", Port: 587}, WithPools(1, 1), WithSendTimeout(time.Minute))
+	assert.Nil(t, sender.SetData(newTestData("aluno", 1)))
+
+	_, err := sender.SendEmails(ctx)
+	assert.Nil(t, err)
+	assert.Greater(t, got, time.Duration(0))
+	assert.LessOrEqual(t, got, 200*time.Millisecond)
+}
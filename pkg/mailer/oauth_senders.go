@@ -2,6 +2,7 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -12,7 +13,8 @@ import (
 	"github.com/jordan-wright/email"
 )
 
-func SendWithGmailAPI(accessToken string, data *MailerData) error {
+// SendWithGmailAPI envia pela API do Gmail; client deve ter timeout para não prender o worker.
+func SendWithGmailAPI(ctx context.Context, client *http.Client, accessToken string, data *MailerData) error {
 	msg, err := buildMessageBytes(data)
 	if err != nil {
 		return err
@@ -25,14 +27,14 @@ func SendWithGmailAPI(accessToken string, data *MailerData) error {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, "https://gmail.googleapis.com/gmail/v1/users/me/messages/send", bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://gmail.googleapis.com/gmail/v1/users/me/messages/send", bytes.NewReader(payload))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -45,18 +47,42 @@ func SendWithGmailAPI(accessToken string, data *MailerData) error {
 	return nil
 }
 
+// SendWithMicrosoftGraph envia pelo sendMail do Microsoft Graph em formato MIME, o mesmo montado para o Gmail.
+// graphURL é a raiz da API (https://graph.microsoft.com/v1.0); client deve ter timeout para não prender o worker.
+func SendWithMicrosoftGraph(ctx context.Context, client *http.Client, graphURL, accessToken string, data *MailerData) error {
+	msg, err := buildMessageBytes(data)
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimRight(graphURL, "/") + "/me/sendMail"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(base64.StdEncoding.EncodeToString(msg)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioReadAll(resp.Body)
+		return fmt.Errorf("microsoft graph send failed: %s", strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
 func buildMessageBytes(data *MailerData) ([]byte, error) {
 	msg := &email.Email{
 		From:    data.From,
 		To:      data.To,
 		Subject: data.Subject,
 	}
-	switch data.ContentType {
-	case TextHTML:
-		msg.HTML = []byte(data.Body)
-	default:
-		msg.Text = []byte(data.Body)
-	}
+	setEmailBody(msg, data.ContentType, data.Body, data.TextBody)
 	if err := attachEmailAttachments(msg, data.Attachments); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,299 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jordan-wright/email"
+)
+
+// TLSMode define como a conexão SMTP é protegida.
+type TLSMode string
+
+const (
+	// TLSStartTLS conecta em texto puro e exige STARTTLS antes da autenticação (porta 587).
+	TLSStartTLS TLSMode = "starttls"
+	// TLSImplicit abre a conexão já em TLS (SMTPS, porta 465).
+	TLSImplicit TLSMode = "tls"
+	// TLSNone não usa TLS; apenas para relays internos confiáveis.
+	TLSNone TLSMode = "none"
+)
+
+func (m TLSMode) IsValid() bool {
+	switch m {
+	case TLSStartTLS, TLSImplicit, TLSNone:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuthMechanism é o mecanismo SMTP AUTH usado com usuário e senha.
+type AuthMechanism string
+
+const (
+	AuthPlain   AuthMechanism = "PLAIN"
+	AuthLogin   AuthMechanism = "LOGIN"
+	AuthCRAMMD5 AuthMechanism = "CRAM-MD5"
+	// AuthNone envia sem autenticar; apenas para relays que aceitam a origem da conexão.
+	AuthNone AuthMechanism = "NONE"
+)
+
+func (m AuthMechanism) IsValid() bool {
+	switch m {
+	case AuthPlain, AuthLogin, AuthCRAMMD5, AuthNone:
+		return true
+	default:
+		return false
+	}
+}
+
+// withDefaults completa instâncias antigas, criadas antes do modo TLS e do mecanismo serem configuráveis.
+func (c SmtpAuthentication) withDefaults() SmtpAuthentication {
+	if c.TLSMode == "" {
+		c.TLSMode = TLSStartTLS
+	}
+	if c.AuthMechanism == "" {
+		c.AuthMechanism = AuthPlain
+	}
+	return c
+}
+
+func (c SmtpAuthentication) validate() error {
+	if !c.TLSMode.IsValid() {
+		return fmt.Errorf("modo TLS SMTP inválido: %s", c.TLSMode)
+	}
+	if !c.AuthMechanism.IsValid() {
+		return fmt.Errorf("mecanismo de autenticação SMTP inválido: %s", c.AuthMechanism)
+	}
+	return nil
+}
+
+// newTLSConfig é uma variável interna que pode ser sobrescrita nos testes
+var newTLSConfig = func(host string) *tls.Config {
+	return &tls.Config{ServerName: host}
+}
+
+// smtpConn é uma conexão SMTP pronta para enviar: TLS negociado e autenticação feita.
+type smtpConn struct {
+	*smtp.Client
+	conn net.Conn
+}
+
+// dialSMTP conecta, negocia TLS conforme o modo e autentica com o mecanismo da configuração.
+func dialSMTP(config SmtpAuthentication, timeout time.Duration) (*smtpConn, error) {
+	config = config.withDefaults()
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if config.TLSMode == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, newTLSConfig(config.Host))
+		if err != nil {
+			return nil, fmt.Errorf("tls dial failed: %w", err)
+		}
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("tcp dial failed: %w", err)
+		}
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("smtp client init failed: %w", err)
+	}
+	c := &smtpConn{Client: client, conn: conn}
+	if err := c.setup(config); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+func (c *smtpConn) setup(config SmtpAuthentication) error {
+	if err := c.Hello("localhost"); err != nil {
+		return fmt.Errorf("smtp hello failed: %w", err)
+	}
+
+	if config.TLSMode == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(newTLSConfig(config.Host)); err != nil {
+			return fmt.Errorf("smtp STARTTLS failed: %w", err)
+		}
+	}
+
+	if config.AuthMechanism == AuthNone {
+		return nil
+	}
+	if ok, _ := c.Extension("AUTH"); !ok {
+		return fmt.Errorf("smtp server does not support AUTH")
+	}
+	if err := c.Auth(smtpAuth(config)); err != nil {
+		return fmt.Errorf("smtp AUTH failed: %w", err)
+	}
+	return nil
+}
+
+func smtpAuth(config SmtpAuthentication) smtp.Auth {
+	switch config.AuthMechanism {
+	case AuthLogin:
+		return &loginAuth{username: config.Username, password: config.Password, host: config.Host}
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(config.Username, config.Password)
+	default:
+		return smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+}
+
+// loginAuth implementa AUTH LOGIN, que o pacote net/smtp não oferece. Como PLAIN, só envia a senha
+// em conexão TLS ou para localhost.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+	step     int
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("AUTH LOGIN exige conexão TLS")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("nome do servidor SMTP diferente do configurado")
+	}
+	a.step = 0
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	a.step++
+	prompt := strings.ToLower(strings.TrimSpace(string(fromServer)))
+	switch {
+	case strings.HasPrefix(prompt, "user") || (a.step == 1 && !strings.HasPrefix(prompt, "pass")):
+		return []byte(a.username), nil
+	case strings.HasPrefix(prompt, "pass") || a.step == 2:
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("desafio AUTH LOGIN inesperado: %s", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// smtpPool reaproveita até size conexões autenticadas entre os emails de um mesmo envio.
+type smtpPool struct {
+	config SmtpAuthentication
+	slots  chan struct{}
+	idle   chan *smtpConn
+}
+
+func newSMTPPool(config SmtpAuthentication, size int) (emailPool, error) {
+	config = config.withDefaults()
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &smtpPool{
+		config: config,
+		slots:  make(chan struct{}, size),
+		idle:   make(chan *smtpConn, size),
+	}, nil
+}
+
+// Send espera até timeout por uma conexão livre; o mesmo prazo vale para conectar e transmitir o email.
+func (p *smtpPool) Send(e *email.Email, timeout time.Duration) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-time.After(timeout):
+		return fmt.Errorf("nenhuma conexão SMTP livre em %s", timeout)
+	}
+	defer func() { <-p.slots }()
+
+	var c *smtpConn
+	select {
+	case c = <-p.idle:
+	default:
+		conn, err := dialSMTP(p.config, timeout)
+		if err != nil {
+			return err
+		}
+		c = conn
+	}
+
+	_ = c.conn.SetDeadline(time.Now().Add(timeout))
+	err := sendWithConn(c, e)
+	_ = c.conn.SetDeadline(time.Time{})
+
+	// Uma resposta SMTP deixa a conexão utilizável; erros de rede ou tempo limite não.
+	var protoErr *textproto.Error
+	if err == nil || (errors.As(err, &protoErr) && c.Reset() == nil) {
+		p.idle <- c
+		return err
+	}
+	_ = c.Close()
+	return err
+}
+
+func sendWithConn(c *smtpConn, e *email.Email) error {
+	from, err := mail.ParseAddress(e.From)
+	if err != nil {
+		return err
+	}
+	msg, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		recipient, err := mail.ParseAddress(to)
+		if err != nil {
+			return err
+		}
+		if err := c.Rcpt(recipient.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (p *smtpPool) Close() {
+	for {
+		select {
+		case c := <-p.idle:
+			_ = c.Quit()
+		default:
+			return
+		}
+	}
+}
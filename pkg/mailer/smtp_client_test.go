@@ -0,0 +1,357 @@
+package mailer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer é um servidor SMTP mínimo para os testes de modo TLS e mecanismo de autenticação.
+type fakeSMTPServer struct {
+	implicitTLS bool
+	startTLS    bool
+	mechanisms  []AuthMechanism
+	username    string
+	password    string
+	rejected    map[string]bool
+
+	tlsConfig *tls.Config
+	listener  net.Listener
+
+	mu          sync.Mutex
+	connections int
+	messages    []fakeSMTPMessage
+}
+
+type fakeSMTPMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+func startFakeSMTPServer(t *testing.T, server *fakeSMTPServer) SmtpAuthentication {
+	t.Helper()
+	cert, pool := newTestCertificate(t)
+	server.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	originalTLSConfig := newTLSConfig
+	newTLSConfig = func(host string) *tls.Config {
+		return &tls.Config{ServerName: host, RootCAs: pool}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if server.implicitTLS {
+		listener = tls.NewListener(listener, server.tlsConfig)
+	}
+	server.listener = listener
+	go server.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+		newTLSConfig = originalTLSConfig
+	})
+
+	port, _ := strconv.Atoi(strings.TrimPrefix(listener.Addr().String(), "127.0.0.1:"))
+	return SmtpAuthentication{Host: "127.0.0.1", Port: port, Username: server.username, Password: server.password}
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.connections++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	secure := s.implicitTLS
+	authed := false
+	message := fakeSMTPMessage{}
+	_ = text.PrintfLine("220 fake ESMTP")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"fake"}
+			if s.startTLS && !secure {
+				lines = append(lines, "STARTTLS")
+			}
+			if len(s.mechanisms) > 0 {
+				names := make([]string, 0, len(s.mechanisms))
+				for _, mechanism := range s.mechanisms {
+					names = append(names, string(mechanism))
+				}
+				lines = append(lines, "AUTH "+strings.Join(names, " "))
+			}
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				_ = text.PrintfLine("250%s%s", sep, l)
+			}
+		case "STARTTLS":
+			_ = text.PrintfLine("220 pronto para TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+			secure = true
+		case "AUTH":
+			authed = s.authenticate(text, arg)
+			if authed {
+				_ = text.PrintfLine("235 autenticado")
+			} else {
+				_ = text.PrintfLine("535 5.7.8 credenciais inválidas")
+			}
+		case "MAIL":
+			if len(s.mechanisms) > 0 && !authed {
+				_ = text.PrintfLine("530 5.7.0 autenticação necessária")
+				continue
+			}
+			message = fakeSMTPMessage{From: addressOf(arg)}
+			_ = text.PrintfLine("250 ok")
+		case "RCPT":
+			to := addressOf(arg)
+			if s.rejected[to] {
+				_ = text.PrintfLine("550 5.1.1 mailbox unavailable")
+				continue
+			}
+			message.To = append(message.To, to)
+			_ = text.PrintfLine("250 ok")
+		case "DATA":
+			_ = text.PrintfLine("354 envie o conteúdo")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			message.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			_ = text.PrintfLine("250 ok")
+		case "RSET", "NOOP":
+			_ = text.PrintfLine("250 ok")
+		case "QUIT":
+			_ = text.PrintfLine("221 até logo")
+			return
+		default:
+			_ = text.PrintfLine("502 comando desconhecido")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) authenticate(text *textproto.Conn, arg string) bool {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	readResponse := func(challenge string) string {
+		_ = text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+		line, _ := text.ReadLine()
+		decoded, _ := base64.StdEncoding.DecodeString(line)
+		return string(decoded)
+	}
+	switch AuthMechanism(strings.ToUpper(mechanism)) {
+	case AuthPlain:
+		decoded, _ := base64.StdEncoding.DecodeString(initial)
+		parts := strings.Split(string(decoded), "\x00")
+		return len(parts) == 3 && parts[1] == s.username && parts[2] == s.password
+	case AuthLogin:
+		username := readResponse("Username:")
+		password := readResponse("Password:")
+		return username == s.username && password == s.password
+	case AuthCRAMMD5:
+		challenge := "<123.456@fake>"
+		username, digest, _ := strings.Cut(readResponse(challenge), " ")
+		mac := hmac.New(md5.New, []byte(s.password))
+		mac.Write([]byte(challenge))
+		return username == s.username && digest == hex.EncodeToString(mac.Sum(nil))
+	}
+	return false
+}
+
+func (s *fakeSMTPServer) received() []fakeSMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeSMTPMessage(nil), s.messages...)
+}
+
+func addressOf(arg string) string {
+	_, address, _ := strings.Cut(arg, ":")
+	return strings.Trim(strings.TrimSpace(address), "<>")
+}
+
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake smtp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestSMTPConnection_DefaultsToStartTLSWithPlain(t *testing.T) {
+	server := &fakeSMTPServer{startTLS: true, mechanisms: []AuthMechanism{AuthPlain}, username: "prof", password: "segredo"}
+	config := startFakeSMTPServer(t, server)
+
+	assert.NoError(t, TestSMTPConnection(config, 2*time.Second))
+
+	config.Password = "errada"
+	assert.ErrorContains(t, TestSMTPConnection(config, 2*time.Second), "smtp AUTH failed")
+}
+
+func TestSMTPConnection_StartTLSIsRequiredWhenConfigured(t *testing.T) {
+	server := &fakeSMTPServer{mechanisms: []AuthMechanism{AuthPlain}, username: "prof", password: "segredo"}
+	config := startFakeSMTPServer(t, server)
+
+	assert.ErrorContains(t, TestSMTPConnection(config, 2*time.Second), "does not support STARTTLS")
+}
+
+func TestSMTPConnection_ImplicitTLSWithLogin(t *testing.T) {
+	server := &fakeSMTPServer{implicitTLS: true, mechanisms: []AuthMechanism{AuthLogin}, username: "prof", password: "segredo"}
+	config := startFakeSMTPServer(t, server)
+	config.TLSMode = TLSImplicit
+	config.AuthMechanism = AuthLogin
+
+	assert.NoError(t, TestSMTPConnection(config, 2*time.Second))
+
+	config.TLSMode = TLSStartTLS
+	assert.Error(t, TestSMTPConnection(config, time.Second), "STARTTLS em uma porta SMTPS não deveria conectar")
+}
+
+func TestSMTPConnection_CRAMMD5(t *testing.T) {
+	server := &fakeSMTPServer{startTLS: true, mechanisms: []AuthMechanism{AuthCRAMMD5}, username: "prof", password: "segredo"}
+	config := startFakeSMTPServer(t, server)
+	config.AuthMechanism = AuthCRAMMD5
+
+	assert.NoError(t, TestSMTPConnection(config, 2*time.Second))
+
+	config.Password = "errada"
+	assert.ErrorContains(t, TestSMTPConnection(config, 2*time.Second), "smtp AUTH failed")
+}
+
+func TestSMTPConnection_RejectsUnknownModes(t *testing.T) {
+	err := TestSMTPConnection(SmtpAuthentication{Host: "127.0.0.1", Port: 25, TLSMode: "ssl"}, time.Second)
+	assert.ErrorContains(t, err, "modo TLS SMTP inválido")
+
+	err = TestSMTPConnection(SmtpAuthentication{Host: "127.0.0.1", Port: 25, AuthMechanism: "XOAUTH2"}, time.Second)
+	assert.ErrorContains(t, err, "mecanismo de autenticação SMTP inválido")
+}
+
+func TestSendEmails_TrustedRelayWithoutTLSOrAuth(t *testing.T) {
+	server := &fakeSMTPServer{rejected: map[string]bool{"inexistente@example.com": true}}
+	config := startFakeSMTPServer(t, server)
+	config.TLSMode = TLSNone
+	config.AuthMechanism = AuthNone
+
+	sender := NewEmailSender(config, WithPools(2, 1))
+	require.NoError(t, sender.SetData(&MailerData{
+		From:        "Professor <prof@example.com>",
+		To:          []string{"a@example.com", "inexistente@example.com", "b@example.com"},
+		Subject:     "Aviso",
+		Body:        "Corpo do aviso",
+		ContentType: TextPlain,
+	}))
+
+	result, err := sender.SendEmails(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, RecipientAccepted, result.Recipients[0].Status)
+	assert.Equal(t, RecipientAccepted, result.Recipients[2].Status)
+	rejected := result.Recipients[1]
+	assert.Equal(t, RecipientRejected, rejected.Status)
+	assert.Equal(t, 1, rejected.Attempts)
+	assert.ErrorIs(t, rejected.Err, ErrRecipientRejected)
+	assert.EqualError(t, rejected.Err, "servidor SMTP recusou o email (550): 5.1.1 mailbox unavailable")
+
+	messages := server.received()
+	require.Len(t, messages, 2)
+	for _, message := range messages {
+		assert.Equal(t, "prof@example.com", message.From)
+		assert.Len(t, message.To, 1)
+		assert.Contains(t, message.Data, "Corpo do aviso")
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.LessOrEqual(t, server.connections, 3, "as conexões devem ser reaproveitadas entre os emails")
+}
+
+func TestSendEmails_ImplicitTLSWithPlain(t *testing.T) {
+	server := &fakeSMTPServer{implicitTLS: true, mechanisms: []AuthMechanism{AuthPlain}, username: "prof", password: "segredo"}
+	config := startFakeSMTPServer(t, server)
+	config.TLSMode = TLSImplicit
+
+	sender := NewEmailSender(config, WithPools(1, 1))
+	require.NoError(t, sender.SetData(&MailerData{
+		From:        "prof@example.com",
+		To:          []string{"aluno@example.com"},
+		Subject:     "Aviso",
+		Body:        "Corpo",
+		ContentType: TextPlain,
+	}))
+
+	result, err := sender.SendEmails(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, result.Rejected())
+	require.Len(t, server.received(), 1)
+	assert.Equal(t, []string{"aluno@example.com"}, server.received()[0].To)
+}
+
+func TestLoginAuthRefusesPlainTextConnections(t *testing.T) {
+	auth := &loginAuth{username: "prof", password: "segredo", host: "smtp.example.com"}
+
+	_, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com"})
+	assert.ErrorContains(t, err, "exige conexão TLS")
+
+	mechanism, _, err := auth.Start(&smtp.ServerInfo{Name: "smtp.example.com", TLS: true})
+	require.NoError(t, err)
+	assert.Equal(t, "LOGIN", mechanism)
+	username, _ := auth.Next([]byte("Username:"), true)
+	password, _ := auth.Next([]byte("Password:"), true)
+	assert.Equal(t, "prof", string(username))
+	assert.Equal(t, "segredo", string(password))
+}
@@ -0,0 +1,479 @@
+// Package markdown converte o subconjunto de Markdown usado nas mensagens para HTML seguro,
+// texto puro e a marcação do WhatsApp.
+//
+// Suporta títulos (#), parágrafos, listas (-, *, + e 1.), citações (>), blocos de código (```),
+// linhas horizontais, **negrito**, *itálico*, ~~tachado~~, `código` e [links](https://...).
+// HTML escrito no texto nunca passa adiante: todo texto é escapado e só links http, https e mailto viram <a>.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Escape protege um valor inserido no Markdown (ex.: dado do aluno) para que ele apareça literalmente.
+// Como o valor pode cair no início de uma linha, "-", "+" e "1." / "1)" no começo de cada linha também
+// são escapados, para não abrirem uma lista; "#", ">" e os demais marcadores já saem escapados em qualquer posição.
+func Escape(value string) string {
+	lines := strings.Split(newlines.Replace(escaper.Replace(value)), "\n")
+	for i, line := range lines {
+		lines[i] = escapeBlockStart(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "~", `\~`, "[", `\[`, "]", `\]`, "#", `\#`, ">", `\>`,
+)
+
+var newlines = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// escapeBlockStart escapa o marcador de lista no começo da linha, depois do recuo.
+func escapeBlockStart(line string) string {
+	trimmed := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(trimmed)]
+	if strings.HasPrefix(trimmed, "-") || strings.HasPrefix(trimmed, "+") {
+		return indent + `\` + trimmed
+	}
+	digits := len(trimmed) - len(strings.TrimLeft(trimmed, "0123456789"))
+	if digits > 0 && digits < len(trimmed) && (trimmed[digits] == '.' || trimmed[digits] == ')') {
+		return indent + trimmed[:digits] + `\` + trimmed[digits:]
+	}
+	return line
+}
+
+// ToHTML devolve o fragmento HTML do texto, sem <html>/<body>.
+func ToHTML(source string) string {
+	blocks := parseBlocks(source)
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		parts = append(parts, b.html())
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ToText remove a marcação; links viram "texto (url)". Serve como alternativa em texto puro do email e para SMS.
+func ToText(source string) string {
+	return renderPlain(source, textMarks)
+}
+
+// ToWhatsApp converte para a marcação do WhatsApp: *negrito*, _itálico_, ~tachado~ e ```monoespaçado```.
+func ToWhatsApp(source string) string {
+	return renderPlain(source, whatsAppMarks)
+}
+
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	listBlock
+	quoteBlock
+	codeBlock
+	ruleBlock
+)
+
+type block struct {
+	kind    blockKind
+	level   int
+	ordered bool
+	start   int
+	lines   []string
+}
+
+var (
+	headingPattern   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	unorderedPattern = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	orderedPattern   = regexp.MustCompile(`^\s{0,3}(\d{1,9})[.)]\s+(.*)$`)
+	rulePattern      = regexp.MustCompile(`^\s{0,3}([-*_])(\s*([-*_]))*\s*$`)
+	quotePattern     = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+)
+
+func parseBlocks(source string) []block {
+	lines := strings.Split(strings.ReplaceAll(strings.ReplaceAll(source, "\r\n", "\n"), "\r", "\n"), "\n")
+	blocks := []block{}
+	var current *block
+	flush := func() {
+		if current != nil {
+			blocks = append(blocks, *current)
+			current = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			code := block{kind: codeBlock}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code.lines = append(code.lines, lines[i])
+			}
+			blocks = append(blocks, code)
+		case trimmed == "":
+			flush()
+		case isRule(line):
+			flush()
+			blocks = append(blocks, block{kind: ruleBlock})
+		case headingPattern.MatchString(trimmed):
+			flush()
+			match := headingPattern.FindStringSubmatch(trimmed)
+			blocks = append(blocks, block{kind: headingBlock, level: len(match[1]), lines: []string{match[2]}})
+		case quotePattern.MatchString(line):
+			text := quotePattern.FindStringSubmatch(line)[1]
+			if current == nil || current.kind != quoteBlock {
+				flush()
+				current = &block{kind: quoteBlock}
+			}
+			current.lines = append(current.lines, text)
+		case unorderedPattern.MatchString(line):
+			if current == nil || current.kind != listBlock || current.ordered {
+				flush()
+				current = &block{kind: listBlock}
+			}
+			current.lines = append(current.lines, unorderedPattern.FindStringSubmatch(line)[1])
+		case orderedPattern.MatchString(line):
+			match := orderedPattern.FindStringSubmatch(line)
+			if current == nil || current.kind != listBlock || !current.ordered {
+				flush()
+				start, _ := strconv.Atoi(match[1])
+				current = &block{kind: listBlock, ordered: true, start: start}
+			}
+			current.lines = append(current.lines, match[2])
+		case current != nil && current.kind == listBlock && line != trimmed:
+			// Linha recuada continua o item anterior da lista.
+			last := len(current.lines) - 1
+			current.lines[last] += "\n" + trimmed
+		default:
+			if current == nil || current.kind != paragraphBlock {
+				flush()
+				current = &block{kind: paragraphBlock}
+			}
+			current.lines = append(current.lines, trimmed)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// isRule reconhece "---", "***" e "___" (com três ou mais marcadores iguais).
+func isRule(line string) bool {
+	if !rulePattern.MatchString(line) {
+		return false
+	}
+	compact := strings.Join(strings.Fields(line), "")
+	return len(compact) >= 3 && strings.Count(compact, compact[:1]) == len(compact)
+}
+
+func (b block) html() string {
+	switch b.kind {
+	case headingBlock:
+		tag := "h" + strconv.Itoa(b.level)
+		return "<" + tag + ">" + inlineHTML(parseInline(b.lines[0])) + "</" + tag + ">"
+	case listBlock:
+		tag, attrs := "ul", ""
+		if b.ordered {
+			tag = "ol"
+			if b.start != 1 {
+				attrs = ` start="` + strconv.Itoa(b.start) + `"`
+			}
+		}
+		var sb strings.Builder
+		sb.WriteString("<" + tag + attrs + ">")
+		for _, item := range b.lines {
+			sb.WriteString("<li>" + inlineHTML(parseInline(item)) + "</li>")
+		}
+		sb.WriteString("</" + tag + ">")
+		return sb.String()
+	case quoteBlock:
+		return "<blockquote><p>" + inlineHTML(parseInline(strings.Join(b.lines, "\n"))) + "</p></blockquote>"
+	case codeBlock:
+		return "<pre><code>" + html.EscapeString(strings.Join(b.lines, "\n")) + "</code></pre>"
+	case ruleBlock:
+		return "<hr>"
+	default:
+		return "<p>" + inlineHTML(parseInline(strings.Join(b.lines, "\n"))) + "</p>"
+	}
+}
+
+// marks são os marcadores de cada formato de saída em texto.
+type marks struct {
+	strong, em, strike, code, codeBlock string
+	heading                             func(text string) string
+}
+
+var textMarks = marks{heading: func(text string) string { return text }}
+
+var whatsAppMarks = marks{
+	strong: "*", em: "_", strike: "~", code: "```", codeBlock: "```",
+	heading: func(text string) string { return "*" + text + "*" },
+}
+
+func renderPlain(source string, m marks) string {
+	blocks := parseBlocks(source)
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		switch b.kind {
+		case headingBlock:
+			// O título já é destacado por inteiro; marcadores internos ficariam aninhados.
+			parts = append(parts, m.heading(inlinePlain(parseInline(b.lines[0]), textMarks)))
+		case listBlock:
+			items := make([]string, 0, len(b.lines))
+			for i, item := range b.lines {
+				bullet := "- "
+				if b.ordered {
+					bullet = strconv.Itoa(b.start+i) + ". "
+				}
+				items = append(items, bullet+inlinePlain(parseInline(item), m))
+			}
+			parts = append(parts, strings.Join(items, "\n"))
+		case quoteBlock:
+			lines := strings.Split(inlinePlain(parseInline(strings.Join(b.lines, "\n")), m), "\n")
+			for i, line := range lines {
+				lines[i] = "> " + line
+			}
+			parts = append(parts, strings.Join(lines, "\n"))
+		case codeBlock:
+			code := strings.Join(b.lines, "\n")
+			if m.codeBlock != "" {
+				code = m.codeBlock + "\n" + code + "\n" + m.codeBlock
+			}
+			parts = append(parts, code)
+		case ruleBlock:
+			parts = append(parts, "----------")
+		default:
+			parts = append(parts, inlinePlain(parseInline(strings.Join(b.lines, "\n")), m))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+type nodeKind int
+
+const (
+	textNode nodeKind = iota
+	strongNode
+	emNode
+	strikeNode
+	codeNode
+	linkNode
+	breakNode
+)
+
+type node struct {
+	kind     nodeKind
+	text     string
+	url      string
+	children []node
+}
+
+// parseInline lê a marcação de uma linha; delimitadores sem fechamento ficam como texto.
+func parseInline(s string) []node {
+	nodes := []node{}
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			nodes = append(nodes, node{kind: textNode, text: buf.String()})
+			buf.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			buf.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			flush()
+			nodes = append(nodes, node{kind: breakNode})
+			i++
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				flush()
+				nodes = append(nodes, node{kind: codeNode, text: s[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**"), strings.HasPrefix(s[i:], "__"), strings.HasPrefix(s[i:], "~~"):
+			delim := s[i : i+2]
+			if end := closingDouble(s, i+2, delim); end >= 0 {
+				kind := strongNode
+				if delim == "~~" {
+					kind = strikeNode
+				}
+				flush()
+				nodes = append(nodes, node{kind: kind, children: parseInline(s[i+2 : end])})
+				i = end + 2
+				continue
+			}
+		case c == '*' || c == '_':
+			if end := closingSingle(s, i); end >= 0 {
+				flush()
+				nodes = append(nodes, node{kind: emNode, children: parseInline(s[i+1 : end])})
+				i = end + 1
+				continue
+			}
+		case c == '[':
+			if link, size, ok := parseLink(s[i:]); ok {
+				flush()
+				nodes = append(nodes, link)
+				i += size
+				continue
+			}
+		}
+		buf.WriteByte(c)
+		i++
+	}
+	flush()
+	return nodes
+}
+
+// closingDouble acha o fechamento de **, __ ou ~~; o conteúdo não pode ser vazio nem começar/terminar com espaço.
+func closingDouble(s string, from int, delim string) int {
+	end := strings.Index(s[from:], delim)
+	if end <= 0 {
+		return -1
+	}
+	inner := s[from : from+end]
+	if strings.TrimSpace(inner) != inner {
+		return -1
+	}
+	return from + end
+}
+
+// closingSingle acha o fechamento de *itálico* ou _itálico_. "_" dentro de palavras (snake_case, emails) não conta.
+func closingSingle(s string, open int) int {
+	c := s[open]
+	if open+1 >= len(s) || s[open+1] == ' ' || s[open+1] == c {
+		return -1
+	}
+	if c == '_' && open > 0 && isWordByte(s[open-1]) {
+		return -1
+	}
+	for j := open + 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\':
+			j++
+		case s[j] == '\n':
+			return -1
+		case s[j] == c:
+			if j+1 < len(s) && s[j+1] == c {
+				j++
+				continue
+			}
+			if s[j-1] == ' ' || (c == '_' && j+1 < len(s) && isWordByte(s[j+1])) {
+				continue
+			}
+			return j
+		}
+	}
+	return -1
+}
+
+// parseLink lê [texto](url). Links com esquema não permitido (javascript:, data:, ...) ficam só com o texto.
+func parseLink(s string) (node, int, bool) {
+	closeLabel := strings.Index(s, "](")
+	if closeLabel <= 1 || strings.ContainsAny(s[1:closeLabel], "[\n") {
+		return node{}, 0, false
+	}
+	rest := s[closeLabel+2:]
+	closeURL := strings.IndexByte(rest, ')')
+	if closeURL < 0 {
+		return node{}, 0, false
+	}
+	target := strings.TrimSpace(rest[:closeURL])
+	size := closeLabel + 2 + closeURL + 1
+	link := node{kind: linkNode, children: parseInline(s[1:closeLabel])}
+	if isSafeURL(target) {
+		link.url = target
+	}
+	return link, size, true
+}
+
+func isSafeURL(raw string) bool {
+	if raw == "" || strings.ContainsAny(raw, " \t\n\"'<>") {
+		return false
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		return parsed.Host != ""
+	case "mailto":
+		return parsed.Opaque != ""
+	default:
+		return false
+	}
+}
+
+func inlineHTML(nodes []node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		switch n.kind {
+		case strongNode:
+			sb.WriteString("<strong>" + inlineHTML(n.children) + "</strong>")
+		case emNode:
+			sb.WriteString("<em>" + inlineHTML(n.children) + "</em>")
+		case strikeNode:
+			sb.WriteString("<s>" + inlineHTML(n.children) + "</s>")
+		case codeNode:
+			sb.WriteString("<code>" + html.EscapeString(n.text) + "</code>")
+		case linkNode:
+			if n.url == "" {
+				sb.WriteString(inlineHTML(n.children))
+				continue
+			}
+			sb.WriteString(`<a href="` + html.EscapeString(n.url) + `">` + inlineHTML(n.children) + "</a>")
+		case breakNode:
+			sb.WriteString("<br>\n")
+		default:
+			sb.WriteString(html.EscapeString(n.text))
+		}
+	}
+	return sb.String()
+}
+
+func inlinePlain(nodes []node, m marks) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		switch n.kind {
+		case strongNode:
+			sb.WriteString(m.strong + inlinePlain(n.children, m) + m.strong)
+		case emNode:
+			sb.WriteString(m.em + inlinePlain(n.children, m) + m.em)
+		case strikeNode:
+			sb.WriteString(m.strike + inlinePlain(n.children, m) + m.strike)
+		case codeNode:
+			sb.WriteString(m.code + n.text + m.code)
+		case linkNode:
+			label := inlinePlain(n.children, m)
+			sb.WriteString(label)
+			if n.url != "" && n.url != label {
+				sb.WriteString(" (" + n.url + ")")
+			}
+		case breakNode:
+			sb.WriteString("\n")
+		default:
+			sb.WriteString(n.text)
+		}
+	}
+	return sb.String()
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c >= 0x80 || c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
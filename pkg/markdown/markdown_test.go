@@ -0,0 +1,82 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sample = "# Prova remarcada\n\nOlá **turma**, a prova será *sexta-feira*.\nLevem ~~calculadora~~ lápis.\n\n- Conteúdo: `capítulo 3`\n- Local: [portal](https://portal.example.com/aviso)\n\n> Dúvidas no plantão."
+
+func TestToHTMLRendersSupportedSyntax(t *testing.T) {
+	got := ToHTML(sample)
+
+	assert.Equal(t, "<h1>Prova remarcada</h1>\n"+
+		"<p>Olá <strong>turma</strong>, a prova será <em>sexta-feira</em>.<br>\nLevem <s>calculadora</s> lápis.</p>\n"+
+		`<ul><li>Conteúdo: <code>capítulo 3</code></li><li>Local: <a href="https://portal.example.com/aviso">portal</a></li></ul>`+"\n"+
+		"<blockquote><p>Dúvidas no plantão.</p></blockquote>", got)
+}
+
+func TestToHTMLEscapesRawHTMLAndUnsafeLinks(t *testing.T) {
+	got := ToHTML("<script>alert(1)</script> [clique](javascript:void) [site](https://example.com/?a=1&b=2)")
+
+	assert.Equal(t, `<p>&lt;script&gt;alert(1)&lt;/script&gt; clique <a href="https://example.com/?a=1&amp;b=2">site</a></p>`, got)
+	assert.NotContains(t, got, "<script")
+	assert.NotContains(t, got, "javascript:")
+}
+
+func TestToHTMLRendersOrderedListsAndCodeBlocks(t *testing.T) {
+	got := ToHTML("3. três\n4. quatro\n\n```\n<b>x</b>\n```\n\n---")
+
+	assert.Equal(t, "<ol start=\"3\"><li>três</li><li>quatro</li></ol>\n<pre><code>&lt;b&gt;x&lt;/b&gt;</code></pre>\n<hr>", got)
+}
+
+func TestUnderscoreInsideWordsIsNotItalic(t *testing.T) {
+	assert.Equal(t, "<p>maria_souza@example.com e snake_case_name</p>", ToHTML("maria_souza@example.com e snake_case_name"))
+	assert.Equal(t, "<p><em>ênfase</em></p>", ToHTML("_ênfase_"))
+}
+
+func TestToTextStripsMarkup(t *testing.T) {
+	assert.Equal(t, "Prova remarcada\n\nOlá turma, a prova será sexta-feira.\nLevem calculadora lápis.\n\n"+
+		"- Conteúdo: capítulo 3\n- Local: portal (https://portal.example.com/aviso)\n\n> Dúvidas no plantão.", ToText(sample))
+}
+
+func TestToWhatsAppUsesWhatsAppMarkers(t *testing.T) {
+	assert.Equal(t, "*Prova remarcada*\n\nOlá *turma*, a prova será _sexta-feira_.\nLevem ~calculadora~ lápis.\n\n"+
+		"- Conteúdo: ```capítulo 3```\n- Local: portal (https://portal.example.com/aviso)\n\n> Dúvidas no plantão.", ToWhatsApp(sample))
+}
+
+func TestEscapeKeepsValuesLiteral(t *testing.T) {
+	value := Escape("*Maria* [admin](https://evil.example) <b>")
+
+	assert.Equal(t, "<p>*Maria* [admin](https://evil.example) &lt;b&gt;</p>", ToHTML(value))
+	assert.Equal(t, "*Maria* [admin](https://evil.example) <b>", ToText(value))
+}
+
+func TestEscapeNeutralisesBlockStartersAtLineStart(t *testing.T) {
+	cases := map[string]string{
+		"- Maria":       "<p>- Maria</p>",
+		"+ Maria":       "<p>+ Maria</p>",
+		"  - Maria":     "<p>- Maria</p>",
+		"1. Maria":      "<p>1. Maria</p>",
+		"2) Maria":      "<p>2) Maria</p>",
+		"# Maria":       "<p># Maria</p>",
+		"> Maria":       "<p>&gt; Maria</p>",
+		"---":           "<p>---</p>",
+		"***":           "<p>***</p>",
+		"```":           "<p>```</p>",
+		"Maria\n- João": "<p>Maria<br>\n- João</p>",
+		"Maria\r\n# Jo": "<p>Maria<br>\n# Jo</p>",
+	}
+	for value, want := range cases {
+		assert.Equal(t, want, ToHTML(Escape(value)), value)
+	}
+}
+
+func TestEscapedValueAtLineStartStaysLiteralInTemplate(t *testing.T) {
+	body := "Alunos com pendência:\n" + Escape("- 1 ponto") + "\n\n" + Escape("2024. Turma A")
+
+	assert.Equal(t, "<p>Alunos com pendência:<br>\n- 1 ponto</p>\n<p>2024. Turma A</p>", ToHTML(body))
+	assert.Equal(t, "Alunos com pendência:\n- 1 ponto\n\n2024. Turma A", ToText(body))
+	assert.Equal(t, "- Maria", ToWhatsApp(Escape("- Maria")))
+}